@@ -0,0 +1,70 @@
+#!/usr/bin/env python3
+# Generates the Parquet fixtures of the Parquet reader tests through pyarrow,
+# i.e., a standard writer, along with the expected values of each fixture in
+# a CSV file of the same name. The fixtures cover dictionary and RLE encoded
+# pages, nulls, multiple pages and row groups, data pages of both versions and
+# all the supported compression codecs.
+#
+# usage: python3 _scripts/parquet-fixtures.py [output dir]
+# (the default output dir is _testdata/parquet)
+import csv
+import os
+import sys
+
+import pyarrow as pa
+import pyarrow.parquet as pq
+
+ROWS = 50
+CODECS = ["none", "snappy", "gzip", "zstd", "brotli", "lz4"]
+PAGE_VERSIONS = ["1.0", "2.0"]
+
+
+def columns():
+    ids, categories, values, flags, counts = [], [], [], [], []
+    for i in range(ROWS):
+        ids.append(i)
+        categories.append(None if i % 5 == 0 else "c%d" % (i % 3))
+        values.append((i % 4) / 2.0)
+        flags.append(i % 7 < 3)
+        counts.append(None if i % 6 == 0 else i * 3 - 20)
+    return pa.table({
+        "id": pa.array(ids, pa.int64()),
+        "category": pa.array(categories, pa.string()),
+        "value": pa.array(values, pa.float64()),
+        "flag": pa.array(flags, pa.bool_()),
+        "count": pa.array(counts, pa.int32()),
+    })
+
+
+def text(v):
+    # the textual representation of the values by the reader
+    if v is None:
+        return ""
+    if isinstance(v, bool):
+        return "true" if v else "false"
+    if isinstance(v, float):
+        return "{:g}".format(v)
+    return str(v)
+
+
+def main():
+    out = sys.argv[1] if len(sys.argv) > 1 else os.path.join(
+        os.path.dirname(os.path.abspath(__file__)), "..", "_testdata", "parquet")
+    os.makedirs(out, exist_ok=True)
+    table = columns()
+    for codec in CODECS:
+        for version in PAGE_VERSIONS:
+            name = "pyarrow-%s-v%s" % (codec, version[0])
+            pq.write_table(table, os.path.join(out, name + ".parquet"),
+                           compression=codec, data_page_version=version,
+                           row_group_size=20, data_page_size=64,
+                           use_dictionary=["category", "count"])
+            with open(os.path.join(out, name + ".csv"), "w", newline="") as f:
+                writer = csv.writer(f, lineterminator="\n")
+                writer.writerow(table.column_names)
+                for row in table.to_pylist():
+                    writer.writerow([text(row[c]) for c in table.column_names])
+
+
+if __name__ == "__main__":
+    main()
@@ -12,6 +12,7 @@ type Clustering struct {
 	datasets     []*Dataset               // list of datasets
 	similarities *DatasetSimilarityMatrix // dataset similarities
 	results      *Dendrogram              // holds the clustering results
+	indices      map[string]int           // maps the dataset paths to indices
 	concurrency  int
 }
 
@@ -22,6 +23,10 @@ func NewClustering(similarities *DatasetSimilarityMatrix, datasets []*Dataset) *
 	c.similarities = similarities
 	c.concurrency = 1
 	c.datasets = datasets
+	c.indices = make(map[string]int)
+	for i, d := range datasets {
+		c.indices[d.Path()] = i
+	}
 	return c
 }
 
@@ -50,7 +55,7 @@ func (c *Clustering) getClustersSimilarity(a, b []*Dataset) float64 {
 	sum := 0.0
 	for i := range a {
 		for j := range b {
-			sum += c.similarities.Get(i, j)
+			sum += c.similarities.Get(c.indices[a[i].Path()], c.indices[b[j].Path()])
 		}
 	}
 	return sum / float64(len(a)*len(b))
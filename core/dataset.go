@@ -3,22 +3,42 @@ package core
 import (
 	"bytes"
 	"crypto/rand"
-	"errors"
 	"fmt"
-	"io/ioutil"
-	"log"
+	"io"
+	"math"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 const datasetSeparator = ","
 
+// datasetMaxLineSize is the max size of a dataset line in bytes
+const datasetMaxLineSize = 16 * 1024 * 1024
+
+// datasetSchemaLock guards the inferred schemas of the datasets, which are
+// computed lazily
+var datasetSchemaLock sync.Mutex
+
+// datasetChunkSize is the number of tuples processed at once by the
+// estimators that traverse the datasets in chunks
+const datasetChunkSize = 10000
+
 // Dataset struct represents a dataset object.
 type Dataset struct {
 	id     string
 	path   string
+	format DatasetFormat
+	schema DatasetSchema
 	header []string
 	data   []DatasetTuple
+	hash   string // the hash of the file contents, computed lazily
+
+	inferred DatasetSchema // the inferred schema, computed lazily
+
+	mapping   DatasetColumnMapping // the canonical names of the columns
+	alignment []int                // the file column of each schema column, -1 if missing
+	unaligned DatasetSchema        // the schema of the file columns, if aligned
 }
 
 // NewDataset is the constructor for the Dataset struct. A random ID is assigned
@@ -30,9 +50,106 @@ func NewDataset(path string) *Dataset {
 	rand.Read(buffer)
 	d.id = fmt.Sprintf("%x", buffer)
 	d.path = path
+	d.format = DefaultDatasetFormat()
 	return d
 }
 
+// SetFormat sets the CSV dialect used to parse the dataset file
+func (d *Dataset) SetFormat(format DatasetFormat) {
+	d.format = format
+	d.resetInferredSchema()
+}
+
+// Format getter for dataset
+func (d Dataset) Format() DatasetFormat {
+	return d.format
+}
+
+// SetSchema sets the column types of the dataset, overriding the inferred
+// ones; the columns of the schema correspond to the columns of the file
+func (d *Dataset) SetSchema(schema DatasetSchema) {
+	d.schema = schema
+	d.alignment, d.unaligned = nil, nil
+}
+
+// SetColumnMapping sets the canonical names of the dataset columns, used when
+// the columns of different datasets are matched by their names
+func (d *Dataset) SetColumnMapping(mapping DatasetColumnMapping) {
+	d.mapping = mapping
+	d.resetInferredSchema()
+}
+
+// ColumnMapping getter for dataset
+func (d Dataset) ColumnMapping() DatasetColumnMapping {
+	return d.mapping
+}
+
+// resetInferredSchema discards the inferred schema, so that it is inferred
+// again with the current format
+func (d *Dataset) resetInferredSchema() {
+	datasetSchemaLock.Lock()
+	d.inferred = nil
+	datasetSchemaLock.Unlock()
+}
+
+// align sets a schema to the dataset, the columns of which are given by the
+// alignment (the indices of the file columns, -1 for the missing ones); the
+// dataset is read again if it is in memory and the alignment has changed
+func (d *Dataset) align(schema DatasetSchema, alignment []int, unaligned DatasetSchema) {
+	changed := len(alignment) != len(d.alignment)
+	for i := 0; !changed && i < len(alignment); i++ {
+		changed = alignment[i] != d.alignment[i]
+	}
+	if changed {
+		d.header, d.data = nil, nil
+	}
+	d.schema, d.alignment, d.unaligned = schema, alignment, unaligned
+}
+
+// fileSchema returns the schema of the columns of the dataset file,
+// regardless of its alignment
+func (d *Dataset) fileSchema() (DatasetSchema, error) {
+	if d.alignment != nil {
+		return d.unaligned, nil
+	}
+	return d.Schema()
+}
+
+// Schema returns the column types of the dataset. If no schema was set and
+// the dataset is not in memory, the schema is inferred by a sample of the
+// dataset rows; the inferred schema is kept until the format changes.
+func (d *Dataset) Schema() (DatasetSchema, error) {
+	if d.schema != nil {
+		return d.schema, nil
+	}
+	datasetSchemaLock.Lock()
+	inferred := d.inferred
+	datasetSchemaLock.Unlock()
+	if inferred != nil {
+		return inferred, nil
+	}
+	it, err := d.openIterator()
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+	var records [][]string
+	for len(records) < datasetSchemaSampleSize {
+		record, err := it.reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	inferred = InferDatasetSchema(it.header, records)
+	datasetSchemaLock.Lock()
+	d.inferred = inferred
+	datasetSchemaLock.Unlock()
+	return inferred, nil
+}
+
 // ID getter for dataset
 func (d Dataset) ID() string {
 	return d.id
@@ -64,30 +181,210 @@ func (d *Dataset) ReadFromFile() error {
 	if d.Header() != nil && d.Data() != nil { // previously read
 		return nil
 	}
-	dat, err := ioutil.ReadFile(d.path)
+	it, err := d.Iterator()
 	if err != nil {
 		return err
 	}
-	datSplit := strings.Split(fmt.Sprintf("%s", dat), "\n")
-	if len(datSplit) < 1 {
-		return errors.New("File without contents")
+	defer it.Close()
+	var data []DatasetTuple
+	for it.Next() {
+		data = append(data, it.Tuple())
+	}
+	if it.Err() != nil {
+		return it.Err()
+	}
+	d.header, d.data, d.schema = it.Header(), data, it.schema
+	return nil
+}
+
+// Count returns the number of tuples of the dataset. If the dataset is not
+// in memory, the file is traversed without being loaded.
+func (d *Dataset) Count() (int, error) {
+	if d.Data() != nil {
+		return len(d.Data()), nil
+	}
+	it, err := d.Iterator()
+	if err != nil {
+		return 0, err
+	}
+	defer it.Close()
+	count := 0
+	for it.Next() {
+		count++
+	}
+	return count, it.Err()
+}
+
+// Iterator returns a new DatasetIterator, used to traverse the tuples of the
+// dataset with bounded memory. If the dataset is already in memory, the
+// iterator traverses the in-memory tuples. The iterator must be closed after
+// its usage.
+func (d *Dataset) Iterator() (*DatasetIterator, error) {
+	if d.Header() != nil && d.Data() != nil {
+		it := new(DatasetIterator)
+		it.header, it.tuples, it.schema = d.Header(), d.Data(), d.schema
+		return it, nil
+	}
+	schema, err := d.Schema()
+	if err != nil {
+		return nil, err
+	}
+	it, err := d.openIterator()
+	if err != nil {
+		return nil, err
 	}
+	it.schema = schema
+	if d.alignment != nil {
+		it.alignment = d.alignment
+		it.header = make([]string, len(schema))
+		for i, c := range schema {
+			it.header[i] = c.Name
+		}
+	}
+	return it, nil
+}
 
-	// reading header
-	d.header = make([]string, 0)
-	for _, s := range strings.Split(datSplit[0], datasetSeparator) {
-		d.header = append(d.header, s)
+// openIterator opens the dataset file and reads its header; the returned
+// iterator is not aware of the dataset schema
+func (d *Dataset) openIterator() (*DatasetIterator, error) {
+	reader, err := NewDatasetReader(d.path, d.format)
+	if err != nil {
+		return nil, err
+	}
+	it := new(DatasetIterator)
+	it.reader, it.path, it.format = reader, d.path, d.format
+	it.header = reader.Header()
+	return it, nil
+}
+
+// DatasetIterator traverses the tuples of a dataset one by one, without
+// keeping them in memory. Typical usage:
+//	it, err := d.Iterator()
+//	...
+//	defer it.Close()
+//	for it.Next() {
+//		t := it.Tuple()
+//		...
+//	}
+//	err = it.Err()
+type DatasetIterator struct {
+	reader    DatasetReader
+	path      string
+	format    DatasetFormat
+	tuples    []DatasetTuple // the tuples, in case the dataset is in memory
+	schema    DatasetSchema
+	alignment []int // the file column of each schema column, if aligned
+	index     int
+	records   int // the number of records read so far
+	header    []string
+	current   DatasetTuple
+	err       error
+}
+
+// Schema returns the column types of the traversed dataset
+func (it *DatasetIterator) Schema() DatasetSchema {
+	return it.schema
+}
+
+// Header returns the header of the traversed dataset; it is empty if the
+// dataset has no header
+func (it *DatasetIterator) Header() []string {
+	return it.header
+}
+
+// Next advances the iterator to the next tuple; it returns false when no more
+// tuples exist or an error occurred
+func (it *DatasetIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.reader == nil {
+		if it.index >= len(it.tuples) {
+			return false
+		}
+		it.current = it.tuples[it.index]
+		it.index++
+		return true
 	}
+	for {
+		record, err := it.reader.Read()
+		if err == io.EOF {
+			return false
+		} else if err != nil {
+			it.err = err
+			return false
+		}
+		it.records++
+		tuple, err := it.parseRecord(record)
+		if err != nil {
+			if it.format.InvalidValues == DatasetInvalidValueSkipRow {
+				continue
+			}
+			it.err = err
+			return false
+		}
+		it.current = tuple
+		return true
+	}
+}
 
-	// reading data
-	for i := 1; i < len(datSplit); i++ {
-		if len(datSplit[i]) > 0 {
-			t := new(DatasetTuple)
-			t.Deserialize(datSplit[i])
-			d.data = append(d.data, *t)
+// parseRecord transforms the fields of a record to a tuple, according to the
+// dataset schema and the invalid values policy of the dataset format
+func (it *DatasetIterator) parseRecord(record []string) (DatasetTuple, error) {
+	size := len(record)
+	if it.alignment != nil {
+		size = len(it.alignment)
+	}
+	tuple := DatasetTuple{Data: make([]float64, size)}
+	for i := range tuple.Data {
+		col := i
+		if it.alignment != nil {
+			col = it.alignment[i]
+			if col < 0 || col >= len(record) { // the column is missing
+				tuple.Data[i] = math.NaN()
+				continue
+			}
+		}
+		s := record[col]
+		v, err := it.schema.Type(i).Parse(s)
+		if err != nil {
+			if it.format.InvalidValues == DatasetInvalidValueNaN {
+				v = math.NaN()
+			} else {
+				return tuple, fmt.Errorf("%s: record %d, column %d: invalid value %q",
+					it.path, it.records, col+1, s)
+			}
 		}
+		tuple.Data[i] = v
 	}
+	return tuple, nil
+}
+
+// Tuple returns the current tuple of the iterator
+func (it *DatasetIterator) Tuple() DatasetTuple {
+	return it.current
+}
+
+// NextChunk returns (at most) the next size tuples of the dataset. An empty
+// slice is returned when no more tuples exist.
+func (it *DatasetIterator) NextChunk(size int) []DatasetTuple {
+	chunk := make([]DatasetTuple, 0, size)
+	for len(chunk) < size && it.Next() {
+		chunk = append(chunk, it.Tuple())
+	}
+	return chunk
+}
+
+// Err returns the first error that occurred during the traversal
+func (it *DatasetIterator) Err() error {
+	return it.err
+}
 
+// Close releases the resources held by the iterator
+func (it *DatasetIterator) Close() error {
+	if it.reader != nil {
+		return it.reader.Close()
+	}
 	return nil
 }
 
@@ -96,15 +393,19 @@ type DatasetTuple struct {
 	Data []float64
 }
 
-// Deserialize is used to construct a tuple from a string representation
-func (t *DatasetTuple) Deserialize(data string) {
+// Deserialize is used to construct a tuple from a string representation; an
+// error is returned if any of the values cannot be parsed
+func (t *DatasetTuple) Deserialize(data string) error {
+	var values []float64
 	for _, s := range strings.Split(data, datasetSeparator) {
 		v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
 		if err != nil {
-			log.Println(err)
+			return err
 		}
-		t.Data = append(t.Data, v)
+		values = append(values, v)
 	}
+	t.Data = append(t.Data, values...)
+	return nil
 }
 
 // Serialize transforms the tuple to a string representation
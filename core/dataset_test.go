@@ -19,3 +19,66 @@ func TestDatasetRead(t *testing.T) {
 	}
 
 }
+
+func TestDatasetIterator(t *testing.T) {
+	a, b := NewDataset(trainSet), NewDataset(trainSet)
+	err := a.ReadFromFile()
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	it, err := b.Iterator()
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	defer it.Close()
+	if len(it.Header()) != len(a.Header()) {
+		t.Log("Headers differ")
+		t.Fail()
+	}
+	count := 0
+	for chunk := it.NextChunk(100); len(chunk) > 0; chunk = it.NextChunk(100) {
+		for _, tu := range chunk {
+			if !tu.Equals(a.Data()[count]) {
+				t.Log("Tuples differ", tu, a.Data()[count])
+				t.FailNow()
+			}
+			count++
+		}
+	}
+	if it.Err() != nil {
+		t.Log(it.Err())
+		t.Fail()
+	}
+	if count != len(a.Data()) {
+		t.Log("Expected", len(a.Data()), "tuples, found", count)
+		t.Fail()
+	}
+	if b.Data() != nil {
+		t.Log("Iterator should not load the dataset in memory")
+		t.Fail()
+	}
+	if c, err := b.Count(); err != nil || c != len(a.Data()) {
+		t.Log("Wrong count", c, err)
+		t.Fail()
+	}
+	if _, err := NewDataset(trainSet + "-missing").Iterator(); err == nil {
+		t.Log("Missing files should not be iterated")
+		t.Fail()
+	}
+}
+
+func TestDatasetTupleDeserialize(t *testing.T) {
+	tuple := new(DatasetTuple)
+	if err := tuple.Deserialize("1.50000, -2.00000, NaN"); err != nil ||
+		len(tuple.Data) != 3 || tuple.Data[0] != 1.5 || tuple.Data[1] != -2 {
+		t.Log("Wrong tuple", tuple, err)
+		t.Fail()
+	}
+	tuple = new(DatasetTuple)
+	if err := tuple.Deserialize("1.5, x"); err == nil || len(tuple.Data) != 0 {
+		t.Log("Unparsable values should be rejected", tuple)
+		t.Fail()
+	}
+}
@@ -0,0 +1,275 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DatasetColumnsPolicy determines how the columns that do not appear in all
+// the datasets are treated, when the datasets are aligned by their headers
+type DatasetColumnsPolicy uint8
+
+const (
+	// DatasetColumnsIntersection keeps only the columns of all the datasets
+	DatasetColumnsIntersection DatasetColumnsPolicy = iota
+	// DatasetColumnsUnion keeps the columns of any dataset; the values of the
+	// columns that a dataset lacks are missing (NaN)
+	DatasetColumnsUnion DatasetColumnsPolicy = iota + 1
+	// DatasetColumnsStrict requires all the datasets to have the same columns
+	DatasetColumnsStrict DatasetColumnsPolicy = iota + 2
+)
+
+// NewDatasetColumnsPolicy transforms a string to a DatasetColumnsPolicy
+// object
+func NewDatasetColumnsPolicy(policy string) (DatasetColumnsPolicy, error) {
+	switch strings.ToLower(policy) {
+	case "intersection":
+		return DatasetColumnsIntersection, nil
+	case "union":
+		return DatasetColumnsUnion, nil
+	case "strict":
+		return DatasetColumnsStrict, nil
+	}
+	return DatasetColumnsIntersection, errors.New("unknown columns policy " + policy)
+}
+
+func (p DatasetColumnsPolicy) String() string {
+	switch p {
+	case DatasetColumnsIntersection:
+		return "intersection"
+	case DatasetColumnsUnion:
+		return "union"
+	case DatasetColumnsStrict:
+		return "strict"
+	}
+	return ""
+}
+
+// DatasetColumnMapping maps column names to canonical ones, so that columns
+// renamed among the datasets are aligned
+type DatasetColumnMapping map[string]string
+
+// NewDatasetColumnMapping parses a mapping file. Each line of the file is in
+// the form name,canonical; empty lines and lines starting with # are ignored.
+func NewDatasetColumnMapping(path string) (DatasetColumnMapping, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	mapping := make(DatasetColumnMapping)
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, ",")
+		if len(fields) != 2 || strings.TrimSpace(fields[0]) == "" || strings.TrimSpace(fields[1]) == "" {
+			return nil, fmt.Errorf("%s: line %d: expected name,canonical", path, line)
+		}
+		mapping[strings.TrimSpace(fields[0])] = strings.TrimSpace(fields[1])
+	}
+	return mapping, scanner.Err()
+}
+
+// serialize returns a byte array containing the mapping
+func (m DatasetColumnMapping) serialize() []byte {
+	buffer := new(bytes.Buffer)
+	buffer.Write(getBytesInt(len(m)))
+	for k, v := range m {
+		buffer.WriteString(k + "\n" + v + "\n")
+	}
+	return buffer.Bytes()
+}
+
+// deserializeDatasetColumnMapping reads a mapping, serialized through
+// serialize, from the buffer; nil is returned for an empty mapping
+func deserializeDatasetColumnMapping(buffer *bytes.Buffer) DatasetColumnMapping {
+	tempInt := make([]byte, 4)
+	buffer.Read(tempInt)
+	count := getIntBytes(tempInt)
+	if count == 0 {
+		return nil
+	}
+	m := make(DatasetColumnMapping)
+	for i := 0; i < count; i++ {
+		name, _ := buffer.ReadString('\n')
+		canonical, _ := buffer.ReadString('\n')
+		m[strings.TrimSuffix(name, "\n")] = strings.TrimSuffix(canonical, "\n")
+	}
+	return m
+}
+
+// canonical returns the canonical name of a column
+func (m DatasetColumnMapping) canonical(name string) string {
+	name = strings.TrimSpace(name)
+	if val, ok := m[name]; ok {
+		return val
+	}
+	return name
+}
+
+// datasetHeaders returns the canonical column names of the datasets. A nil
+// slice is returned if the datasets must be aligned by position, i.e., if
+// some dataset has no header or all the datasets have the same header.
+func datasetHeaders(datasets []*Dataset) ([][]string, error) {
+	headers := make([][]string, len(datasets))
+	identical := true
+	for i, d := range datasets {
+		it, err := d.openIterator()
+		if err != nil {
+			return nil, err
+		}
+		header := it.header
+		it.Close()
+		if len(header) == 0 {
+			return nil, nil
+		}
+		headers[i] = make([]string, len(header))
+		for j, h := range header {
+			headers[i][j] = d.mapping.canonical(h)
+		}
+		identical = identical && strings.Join(headers[i], "\n") == strings.Join(headers[0], "\n")
+	}
+	if identical {
+		return nil, nil
+	}
+	return headers, nil
+}
+
+// alignDatasetSchemas matches the columns of the datasets by their names and
+// sets a common schema to them, according to the columns policy. The tuples
+// of each dataset are then given in the order of the common schema.
+func alignDatasetSchemas(datasets []*Dataset, headers [][]string, policy DatasetColumnsPolicy) (DatasetSchema, error) {
+	var names []string
+	indices := make([]map[string]int, len(datasets))
+	present := make(map[string]int)
+	for i, header := range headers {
+		indices[i] = make(map[string]int)
+		for j, name := range header {
+			if _, ok := indices[i][name]; ok {
+				return nil, fmt.Errorf("%s: duplicate column %q", datasets[i].Path(), name)
+			}
+			indices[i][name] = j
+			if present[name] == 0 {
+				names = append(names, name)
+			}
+			present[name]++
+		}
+	}
+	var common DatasetSchema
+	for _, name := range names {
+		if present[name] < len(datasets) {
+			if policy == DatasetColumnsStrict {
+				for i, d := range datasets {
+					if _, ok := indices[i][name]; !ok {
+						return nil, fmt.Errorf("%s: missing column %q", d.Path(), name)
+					}
+				}
+			} else if policy == DatasetColumnsIntersection {
+				continue
+			}
+		}
+		common = append(common, DatasetColumn{Name: name, Type: DatasetColumnNumeric})
+	}
+	if len(common) == 0 {
+		return nil, errors.New("The datasets have no common columns")
+	}
+
+	// the types of the columns are unified among the datasets
+	typed := make([]bool, len(common))
+	schemas := make([]DatasetSchema, len(datasets))
+	alignments := make([][]int, len(datasets))
+	for i, d := range datasets {
+		schema, err := d.fileSchema()
+		if err != nil {
+			return nil, err
+		}
+		schemas[i] = schema
+		alignments[i] = make([]int, len(common))
+		for k := range common {
+			j, ok := indices[i][common[k].Name]
+			if !ok {
+				alignments[i][k] = -1
+				continue
+			}
+			alignments[i][k] = j
+			if !typed[k] {
+				common[k].Type, typed[k] = schema.Type(j), true
+			} else if common[k].Type != schema.Type(j) {
+				common[k].Type = DatasetColumnCategorical
+			}
+		}
+	}
+	for i, d := range datasets {
+		d.align(common, alignments[i], schemas[i])
+	}
+	return common, nil
+}
+
+// conformDatasetSchema sets the common schema of some datasets to a dataset
+// that was not among them, e.g., a dataset appended to an estimator. If the
+// columns of the datasets were matched by name (byName), or if the dataset
+// has a different header, the columns of the dataset are matched by its
+// header according to the policy; else they are matched by position. An
+// error is returned if the dataset cannot be aligned to the schema.
+func conformDatasetSchema(d *Dataset, common DatasetSchema, byName bool, policy DatasetColumnsPolicy) error {
+	it, err := d.openIterator()
+	if err != nil {
+		return err
+	}
+	header := it.header
+	it.Close()
+	names := make([]string, len(header))
+	for j, h := range header {
+		names[j] = d.mapping.canonical(h)
+	}
+	if !byName {
+		same, unnamed := len(names) == 0 || len(names) == len(common), true
+		for k, c := range common {
+			unnamed = unnamed && c.Name == ""
+			same = same && (len(names) == 0 || names[k] == d.mapping.canonical(c.Name))
+		}
+		if same || unnamed {
+			d.SetSchema(common)
+			return nil
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("%s: no header, the columns cannot be matched by name", d.Path())
+	}
+	indices := make(map[string]int)
+	for j, name := range names {
+		if _, ok := indices[name]; ok {
+			return fmt.Errorf("%s: duplicate column %q", d.Path(), name)
+		}
+		indices[name] = j
+	}
+	alignment := make([]int, len(common))
+	for k, c := range common {
+		j, ok := indices[d.mapping.canonical(c.Name)]
+		if !ok {
+			if policy != DatasetColumnsUnion {
+				return fmt.Errorf("%s: missing column %q", d.Path(), c.Name)
+			}
+			j = -1
+		}
+		alignment[k] = j
+	}
+	if policy == DatasetColumnsStrict && len(indices) > len(common) {
+		return fmt.Errorf("%s: columns missing from the rest of the datasets", d.Path())
+	}
+	schema, err := d.fileSchema()
+	if err != nil {
+		return err
+	}
+	d.align(common, alignment, schema)
+	return nil
+}
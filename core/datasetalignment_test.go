@@ -0,0 +1,258 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNewDatasetColumnsPolicy(t *testing.T) {
+	for _, p := range []DatasetColumnsPolicy{DatasetColumnsIntersection, DatasetColumnsUnion, DatasetColumnsStrict} {
+		parsed, err := NewDatasetColumnsPolicy(strings.ToUpper(p.String()))
+		if err != nil || parsed != p {
+			t.Log("Wrong policy parsing", p, parsed, err)
+			t.Fail()
+		}
+	}
+	if _, err := NewDatasetColumnsPolicy("all"); err == nil {
+		t.Log("Unknown policy should not be parsed")
+		t.Fail()
+	}
+}
+
+func TestNewDatasetColumnMapping(t *testing.T) {
+	f, _ := ioutil.TempFile("/tmp", "column-mapping")
+	f.WriteString("# renamed columns\nprice, cost\n\nqty,quantity\n")
+	f.Close()
+	defer os.Remove(f.Name())
+	mapping, err := NewDatasetColumnMapping(f.Name())
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if mapping.canonical(" price") != "cost" || mapping.canonical("qty") != "quantity" ||
+		mapping.canonical("id") != "id" || len(mapping) != 2 {
+		t.Log("Wrong mapping", mapping)
+		t.Fail()
+	}
+
+	f, _ = ioutil.TempFile("/tmp", "column-mapping")
+	f.WriteString("price,cost\nqty\n")
+	f.Close()
+	defer os.Remove(f.Name())
+	if _, err := NewDatasetColumnMapping(f.Name()); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Log("Malformed line should be reported", err)
+		t.Fail()
+	}
+}
+
+func TestAlignDatasetSchemas(t *testing.T) {
+	datasets := []*Dataset{
+		createFormatDataset(t, "a,b,c\n1,2,3\n4,5,6\n", nil),
+		createFormatDataset(t, "c,A,d\n7,8,x\n", nil),
+	}
+	defer cleanDatasets(datasets)
+	datasets[1].SetColumnMapping(DatasetColumnMapping{"A": "a"})
+
+	schema, err := unifyDatasetSchemas(datasets, DatasetColumnsIntersection)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if len(schema) != 2 || schema[0].Name != "a" || schema[1].Name != "c" {
+		t.Log("Wrong intersection schema", schema)
+		t.FailNow()
+	}
+	it, _ := datasets[1].Iterator()
+	it.Next()
+	if h := it.Header(); len(h) != 2 || h[0] != "a" || h[1] != "c" ||
+		it.Tuple().Data[0] != 8 || it.Tuple().Data[1] != 7 {
+		t.Log("Wrong aligned tuple", it.Header(), it.Tuple())
+		t.Fail()
+	}
+	it.Close()
+
+	schema, err = unifyDatasetSchemas(datasets, DatasetColumnsUnion)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if len(schema) != 4 || schema[3].Name != "d" || schema[3].Type != DatasetColumnCategorical {
+		t.Log("Wrong union schema", schema)
+		t.FailNow()
+	}
+	// the aligned datasets are read again in memory
+	for _, d := range datasets {
+		if err := d.ReadFromFile(); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+	}
+	first, second := datasets[0].Data()[1].Data, datasets[1].Data()[0].Data
+	if first[0] != 4 || first[1] != 5 || first[2] != 6 || !math.IsNaN(first[3]) {
+		t.Log("Wrong tuple with missing column", first)
+		t.Fail()
+	}
+	if second[0] != 8 || !math.IsNaN(second[1]) || second[2] != 7 || second[3] != categoricalValue("x") {
+		t.Log("Wrong tuple with missing column", second)
+		t.Fail()
+	}
+
+	_, err = unifyDatasetSchemas(datasets, DatasetColumnsStrict)
+	if err == nil || !strings.Contains(err.Error(), "missing column \"b\"") {
+		t.Log("Strict policy should report the missing column", err)
+		t.Fail()
+	}
+
+	disjoint := []*Dataset{
+		createFormatDataset(t, "a,b\n1,2\n", nil),
+		createFormatDataset(t, "c,d\n1,2\n", nil),
+		createFormatDataset(t, "a,a\n1,2\n", nil),
+	}
+	defer cleanDatasets(disjoint)
+	if _, err := unifyDatasetSchemas(disjoint[:2], DatasetColumnsIntersection); err == nil {
+		t.Log("Datasets without common columns should not be aligned")
+		t.Fail()
+	}
+	if _, err := unifyDatasetSchemas(disjoint[1:], DatasetColumnsUnion); err == nil ||
+		!strings.Contains(err.Error(), "duplicate column") {
+		t.Log("Duplicate columns should be reported", err)
+		t.Fail()
+	}
+}
+
+func TestAlignedSimilarities(t *testing.T) {
+	// the second dataset holds the tuples of the first with reordered columns
+	// and an extra one, the third dataset holds different tuples
+	var datasets []*Dataset
+	for i, header := range []string{"x,y,class", "class,extra,x,y", "x,y,class"} {
+		r := rand.New(rand.NewSource(int64(i / 2)))
+		builder := new(bytes.Buffer)
+		builder.WriteString(header + "\n")
+		for j := 0; j < 200; j++ {
+			x, y, c := r.Float64(), r.Float64()+float64(i/2), r.Float64()
+			if i == 1 {
+				builder.WriteString(fmt.Sprintf("%.5f,%d,%.5f,%.5f\n", c, j, x, y))
+			} else {
+				builder.WriteString(fmt.Sprintf("%.5f,%.5f,%.5f\n", x, y, c))
+			}
+		}
+		f, _ := ioutil.TempFile("/tmp", "aligneddataset")
+		f.Write(builder.Bytes())
+		f.Close()
+		datasets = append(datasets, NewDataset(f.Name()))
+	}
+	defer cleanDatasets(datasets)
+	for _, estType := range []DatasetSimilarityEstimatorType{
+		SimilarityTypeJaccard, SimilarityTypeWasserstein, SimilarityTypeProfile} {
+		est := NewDatasetSimilarityEstimator(estType, datasets)
+		est.Configure(map[string]string{"align": "intersection"})
+		if err := est.Compute(); err != nil {
+			t.Log(estType, err)
+			t.FailNow()
+		}
+		sm := est.SimilarityMatrix()
+		if math.Abs(sm.Get(0, 1)-1.0) > 1e-9 || sm.Get(0, 2) >= sm.Get(0, 1) {
+			t.Log(estType, "reordered columns are not aligned", sm.Get(0, 1), sm.Get(0, 2))
+			t.Fail()
+		}
+	}
+
+	for _, estType := range []DatasetSimilarityEstimatorType{
+		SimilarityTypeJaccard, SimilarityTypeBhattacharyya, SimilarityTypeHellinger} {
+		est := NewDatasetSimilarityEstimator(estType, datasets)
+		est.Configure(map[string]string{"align": "strict"})
+		if err := est.Compute(); err == nil {
+			t.Log(estType, "strict alignment should fail due to the extra column")
+			t.Fail()
+		}
+	}
+}
+
+// createSwappedDatasets creates four datasets; the third holds the tuples of
+// the first with swapped columns and the fourth lacks a common column
+func createSwappedDatasets(t *testing.T) []*Dataset {
+	r := rand.New(rand.NewSource(1))
+	contents := []*bytes.Buffer{new(bytes.Buffer), new(bytes.Buffer), new(bytes.Buffer), new(bytes.Buffer)}
+	for i, header := range []string{"a,b", "b,a,c", "b,a", "a"} {
+		contents[i].WriteString(header + "\n")
+	}
+	for j := 0; j < 100; j++ {
+		a, b := r.Float64(), r.Float64()
+		contents[0].WriteString(fmt.Sprintf("%.5f,%.5f\n", a, b))
+		contents[1].WriteString(fmt.Sprintf("%.5f,%.5f,%d\n", r.Float64(), r.Float64(), j))
+		contents[2].WriteString(fmt.Sprintf("%.5f,%.5f\n", b, a))
+		contents[3].WriteString(fmt.Sprintf("%.5f\n", a))
+	}
+	var datasets []*Dataset
+	for _, c := range contents {
+		datasets = append(datasets, createFormatDataset(t, c.String(), nil))
+	}
+	return datasets
+}
+
+func TestAppendAlignedDatasets(t *testing.T) {
+	datasets := createSwappedDatasets(t)
+	defer cleanDatasets(datasets)
+	for _, estType := range []DatasetSimilarityEstimatorType{
+		SimilarityTypeJaccard, SimilarityTypeMinHash, SimilarityTypeBhattacharyya,
+		SimilarityTypeMMD, SimilarityTypeWasserstein, SimilarityTypeProfile,
+		SimilarityTypeCorrelationMatrix, SimilarityTypeCorrelation} {
+		full := NewDatasetSimilarityEstimator(estType, datasets[:2])
+		full.Configure(map[string]string{"align": "intersection", "column": "a", "dataset.sr": "1"})
+		if err := full.Compute(); err != nil {
+			t.Log(estType, err)
+			t.FailNow()
+		}
+		// both the computed and the deserialized estimators are refreshed
+		for _, est := range []DatasetSimilarityEstimator{
+			DeserializeSimilarityEstimator(full.Serialize()), full} {
+			if err := AppendDatasets(est, datasets[2:3]); err != nil {
+				t.Log(estType, err)
+				t.FailNow()
+			}
+			if s := est.SimilarityMatrix().Get(0, 2); math.Abs(s-1.0) > 1e-6 {
+				t.Log(estType, "appended dataset is not aligned, similarity", s)
+				t.Fail()
+			}
+		}
+		est := DeserializeSimilarityEstimator(full.Serialize())
+		if err := AppendDatasets(est, datasets[3:]); err == nil || len(est.Datasets()) != 3 {
+			t.Log(estType, "dataset lacking a common column should be rejected", err)
+			t.Fail()
+		}
+	}
+}
+
+func TestOutOfSampleAlignedDatasets(t *testing.T) {
+	datasets := createSwappedDatasets(t)
+	defer cleanDatasets(datasets)
+	for _, estType := range []DatasetSimilarityEstimatorType{
+		SimilarityTypeMinHash, SimilarityTypeBhattacharyya, SimilarityTypeMMD,
+		SimilarityTypeWasserstein, SimilarityTypeProfile, SimilarityTypeCorrelationMatrix} {
+		full := NewDatasetSimilarityEstimator(estType, datasets[:2])
+		full.Configure(map[string]string{"align": "intersection", "dataset.sr": "1"})
+		if err := full.Compute(); err != nil {
+			t.Log(estType, err)
+			t.FailNow()
+		}
+		// the datasets that are not indexed are aligned by the computed and
+		// the deserialized estimators, without being modified
+		for _, est := range []DatasetSimilarityEstimator{
+			full, DeserializeSimilarityEstimator(full.Serialize())} {
+			if s := est.Similarity(datasets[2], datasets[0]); math.Abs(s-1.0) > 1e-6 {
+				t.Log(estType, "dataset is not aligned, similarity", s)
+				t.Fail()
+			}
+			if datasets[2].schema != nil {
+				t.Log(estType, "dataset should not be modified")
+				t.Fail()
+			}
+		}
+	}
+}
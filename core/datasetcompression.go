@@ -0,0 +1,173 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// DatasetCompression represents the compression codec of a dataset file
+type DatasetCompression uint8
+
+const (
+	// DatasetCompressionNone represents uncompressed files
+	DatasetCompressionNone DatasetCompression = iota
+	// DatasetCompressionGzip represents gzip compressed files
+	DatasetCompressionGzip DatasetCompression = iota + 1
+	// DatasetCompressionZstd represents zstd compressed files
+	DatasetCompressionZstd DatasetCompression = iota + 2
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// NewDatasetCompression transforms a string to a DatasetCompression object
+func NewDatasetCompression(compression string) (DatasetCompression, error) {
+	switch strings.ToLower(compression) {
+	case "", "none":
+		return DatasetCompressionNone, nil
+	case "gzip", "gz":
+		return DatasetCompressionGzip, nil
+	case "zstd", "zst":
+		return DatasetCompressionZstd, nil
+	}
+	return DatasetCompressionNone, errors.New("unknown compression " + compression)
+}
+
+func (c DatasetCompression) String() string {
+	switch c {
+	case DatasetCompressionNone:
+		return "none"
+	case DatasetCompressionGzip:
+		return "gzip"
+	case DatasetCompressionZstd:
+		return "zstd"
+	}
+	return ""
+}
+
+// Extension returns the file extension of the compressed files
+func (c DatasetCompression) Extension() string {
+	switch c {
+	case DatasetCompressionGzip:
+		return ".gz"
+	case DatasetCompressionZstd:
+		return ".zst"
+	}
+	return ""
+}
+
+// DetectDatasetCompression identifies the compression of a file, based on its
+// magic bytes
+func DetectDatasetCompression(path string) (DatasetCompression, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return DatasetCompressionNone, err
+	}
+	defer f.Close()
+	magic := make([]byte, len(zstdMagic))
+	n, err := io.ReadFull(f, magic)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return DatasetCompressionNone, err
+	}
+	return detectCompression(magic[:n]), nil
+}
+
+func detectCompression(magic []byte) DatasetCompression {
+	if bytes.HasPrefix(magic, gzipMagic) {
+		return DatasetCompressionGzip
+	} else if bytes.HasPrefix(magic, zstdMagic) {
+		return DatasetCompressionZstd
+	}
+	return DatasetCompressionNone
+}
+
+// trimCompressionExtension removes the compression extension of a path, so
+// that the extension of the underlying format is revealed
+func trimCompressionExtension(path string) string {
+	lower := strings.ToLower(path)
+	for _, c := range []DatasetCompression{DatasetCompressionGzip, DatasetCompressionZstd} {
+		if strings.HasSuffix(lower, c.Extension()) {
+			return path[:len(path)-len(c.Extension())]
+		}
+	}
+	return path
+}
+
+// datasetFile is a file, possibly wrapped by a (de)compressor; closing it
+// closes the (de)compressor and the file
+type datasetFile struct {
+	io.Reader
+	io.Writer
+	closers []io.Closer
+}
+
+func (f *datasetFile) Close() error {
+	var err error
+	for _, c := range f.closers {
+		if e := c.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// openDatasetFile opens a dataset file for reading; compressed files are
+// decompressed transparently
+func openDatasetFile(path string) (io.ReadCloser, DatasetCompression, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, DatasetCompressionNone, err
+	}
+	buffered := bufio.NewReader(f)
+	magic, _ := buffered.Peek(len(zstdMagic))
+	compression := detectCompression(magic)
+	switch compression {
+	case DatasetCompressionGzip:
+		reader, err := gzip.NewReader(buffered)
+		if err != nil {
+			f.Close()
+			return nil, compression, err
+		}
+		return &datasetFile{Reader: reader, closers: []io.Closer{reader, f}}, compression, nil
+	case DatasetCompressionZstd:
+		decoder, err := zstd.NewReader(buffered)
+		if err != nil {
+			f.Close()
+			return nil, compression, err
+		}
+		reader := decoder.IOReadCloser()
+		return &datasetFile{Reader: reader, closers: []io.Closer{reader, f}}, compression, nil
+	}
+	return &datasetFile{Reader: buffered, closers: []io.Closer{f}}, compression, nil
+}
+
+// createDatasetFile creates a new dataset file, compressed with the
+// specified codec
+func createDatasetFile(path string, compression DatasetCompression) (io.WriteCloser, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	switch compression {
+	case DatasetCompressionGzip:
+		writer := gzip.NewWriter(f)
+		return &datasetFile{Writer: writer, closers: []io.Closer{writer, f}}, nil
+	case DatasetCompressionZstd:
+		writer, err := zstd.NewWriter(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &datasetFile{Writer: writer, closers: []io.Closer{writer, f}}, nil
+	}
+	return f, nil
+}
@@ -0,0 +1,130 @@
+package core
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func createCompressedDataset(t *testing.T, content, extension string, compression DatasetCompression) string {
+	f, err := ioutil.TempFile("/tmp", "dataset-compression")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	f.Close()
+	os.Remove(f.Name())
+	path := f.Name() + extension
+	w, err := createDatasetFile(path, compression)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	io.WriteString(w, content)
+	if err := w.Close(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	return path
+}
+
+func TestNewDatasetCompression(t *testing.T) {
+	for _, c := range []DatasetCompression{DatasetCompressionNone, DatasetCompressionGzip, DatasetCompressionZstd} {
+		parsed, err := NewDatasetCompression(c.String())
+		if err != nil || parsed != c {
+			t.Log("Wrong compression", c, parsed, err)
+			t.Fail()
+		}
+	}
+	if _, err := NewDatasetCompression("lzma"); err == nil {
+		t.Log("Unknown compression should be rejected")
+		t.Fail()
+	}
+}
+
+func TestCompressedDatasetRead(t *testing.T) {
+	content := "a,b\n1,2\n3,4\n5,6\n"
+	for _, c := range []DatasetCompression{DatasetCompressionNone, DatasetCompressionGzip, DatasetCompressionZstd} {
+		// the compression is identified by the magic bytes, not the extension
+		for _, ext := range []string{".csv" + c.Extension(), ""} {
+			path := createCompressedDataset(t, content, ext, c)
+			defer os.Remove(path)
+			if detected, err := DetectDatasetCompression(path); err != nil || detected != c {
+				t.Log("Expected", c, "found", detected, err)
+				t.Fail()
+			}
+			d := NewDataset(path)
+			if err := d.ReadFromFile(); err != nil {
+				t.Log(err)
+				t.FailNow()
+			}
+			if len(d.Header()) != 2 || len(d.Data()) != 3 || d.Data()[2].Data[1] != 6 {
+				t.Log("Wrong data for", c, d.Header(), d.Data())
+				t.Fail()
+			}
+		}
+	}
+}
+
+func TestCompressedParquetRead(t *testing.T) {
+	path := createParquetTestFile(t)
+	defer os.Remove(path)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	compressed := createCompressedDataset(t, string(data), ".parquet.gz", DatasetCompressionGzip)
+	defer os.Remove(compressed)
+	reader, err := NewDatasetReader(compressed, DefaultDatasetFormat())
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	defer reader.Close()
+	if len(reader.Header()) != 5 || reader.Header()[1] != "name" {
+		t.Log("Wrong header", reader.Header())
+		t.Fail()
+	}
+	count := 0
+	for _, err := reader.Read(); err == nil; _, err = reader.Read() {
+		count++
+	}
+	if count != 5 {
+		t.Log("Expected 5 records, found", count)
+		t.Fail()
+	}
+}
+
+func TestCompressedPartition(t *testing.T) {
+	input := createCompressedDataset(t, "a,b\n1,2\n3,4\n5,6\n7,8\n9,10\n", ".csv.gz", DatasetCompressionGzip)
+	defer os.Remove(input)
+	part := NewDatasetPartitioner(input, input+"-splits/", 3, PartitionerUniform)
+	part.SetCompression(DatasetCompressionZstd)
+	part.Partition()
+	defer part.Delete()
+
+	datasets := DiscoverDatasets(part.output)
+	if len(datasets) != 3 {
+		t.Log("Expected 3 splits, found", len(datasets))
+		t.FailNow()
+	}
+	total := 0
+	for _, d := range datasets {
+		if c, _ := DetectDatasetCompression(d.Path()); c != DatasetCompressionZstd {
+			t.Log("Split not compressed", d.Path())
+			t.Fail()
+		}
+		count, err := d.Count()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		total += count
+	}
+	if total != 5 {
+		t.Log("Data points lost during the partition", total)
+		t.Fail()
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/gob"
 	"errors"
+	"io"
 	"io/ioutil"
 	"log"
 	"math"
@@ -109,13 +110,15 @@ func (e *FileBasedEvaluator) Evaluate(dataset string) (float64, error) {
 
 // DatasetScores is used to store the scores of a set of datasets
 type DatasetScores struct {
-	Scores map[string]float64
+	Scores    map[string]float64
+	Variances map[string]float64 // the variances of the scores (if estimated)
 }
 
 // NewDatasetScores initializes a new DatasetScores struct
 func NewDatasetScores() *DatasetScores {
 	o := new(DatasetScores)
 	o.Scores = make(map[string]float64)
+	o.Variances = make(map[string]float64)
 	return o
 }
 
@@ -127,6 +130,13 @@ func (s *DatasetScores) Serialize() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	// the variances are appended only if present, so that the files without
+	// variances remain compatible with older versions
+	if len(s.Variances) > 0 {
+		if err = e.Encode(s.Variances); err != nil {
+			return nil, err
+		}
+	}
 	return buf.Bytes(), nil
 }
 
@@ -135,5 +145,12 @@ func (s *DatasetScores) Deserialize(buf []byte) error {
 	content := bytes.NewBuffer(buf)
 	d := gob.NewDecoder(content)
 	err := d.Decode(&s.Scores)
-	return err
+	if err != nil {
+		return err
+	}
+	s.Variances = make(map[string]float64)
+	if err = d.Decode(&s.Variances); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
 }
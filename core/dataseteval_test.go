@@ -69,3 +69,36 @@ func TestDatasetEvaluatorFileBased(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestDatasetScoresVariances(t *testing.T) {
+	scores := NewDatasetScores()
+	scores.Scores["a"], scores.Scores["b"] = 1.0, 2.0
+	cnt, err := scores.Serialize()
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	newScores := NewDatasetScores()
+	if err := newScores.Deserialize(cnt); err != nil || len(newScores.Variances) != 0 {
+		t.Log("Scores without variances not deserialized", err)
+		t.Fail()
+	}
+
+	scores.Variances["a"], scores.Variances["b"] = 0.1, 0.2
+	cnt, err = scores.Serialize()
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	newScores = NewDatasetScores()
+	if err := newScores.Deserialize(cnt); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	for k, v := range scores.Variances {
+		if newScores.Variances[k] != v || newScores.Scores[k] != scores.Scores[k] {
+			t.Log("Wrong deserialized value for", k)
+			t.Fail()
+		}
+	}
+}
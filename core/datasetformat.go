@@ -0,0 +1,224 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// DatasetInvalidValuePolicy determines how the cells that cannot be parsed
+// as numbers are treated
+type DatasetInvalidValuePolicy uint8
+
+const (
+	// DatasetInvalidValueError aborts the parsing, returning an error
+	DatasetInvalidValueError DatasetInvalidValuePolicy = iota
+	// DatasetInvalidValueSkipRow ignores the rows that contain invalid cells
+	DatasetInvalidValueSkipRow DatasetInvalidValuePolicy = iota + 1
+	// DatasetInvalidValueNaN replaces the invalid cells with NaN
+	DatasetInvalidValueNaN DatasetInvalidValuePolicy = iota + 2
+)
+
+// NewDatasetInvalidValuePolicy transforms a string to a
+// DatasetInvalidValuePolicy object
+func NewDatasetInvalidValuePolicy(policy string) (DatasetInvalidValuePolicy, error) {
+	switch strings.ToLower(policy) {
+	case "error":
+		return DatasetInvalidValueError, nil
+	case "skip":
+		return DatasetInvalidValueSkipRow, nil
+	case "nan":
+		return DatasetInvalidValueNaN, nil
+	}
+	return DatasetInvalidValueError, errors.New("unknown invalid value policy " + policy)
+}
+
+func (p DatasetInvalidValuePolicy) String() string {
+	switch p {
+	case DatasetInvalidValueError:
+		return "error"
+	case DatasetInvalidValueSkipRow:
+		return "skip"
+	case DatasetInvalidValueNaN:
+		return "nan"
+	}
+	return ""
+}
+
+// DatasetFormat describes the format of a dataset file. The dialect options
+// (Delimiter, Quoted, Header and Comment) only concern CSV files, whereas
+// InvalidValues and Columns concern all the dataset readers.
+type DatasetFormat struct {
+	// Delimiter is the field delimiter
+	Delimiter rune
+	// Quoted determines whether fields may be enclosed in double quotes
+	Quoted bool
+	// Header determines whether the first line of the file is a header
+	Header bool
+	// Comment is the character that denotes a comment line (0 if comments
+	// are not allowed)
+	Comment rune
+	// InvalidValues is the policy for the cells that cannot be parsed
+	InvalidValues DatasetInvalidValuePolicy
+	// Columns holds the names (or indices) of the columns to read; all the
+	// columns are read if empty
+	Columns []string
+}
+
+// DefaultDatasetFormat returns the format of the datasets, used if no other
+// format is specified: comma separated values, with a header and without
+// quotes or comments.
+func DefaultDatasetFormat() DatasetFormat {
+	return DatasetFormat{
+		Delimiter:     ',',
+		Quoted:        false,
+		Header:        true,
+		Comment:       0,
+		InvalidValues: DatasetInvalidValueError,
+	}
+}
+
+// NewDatasetFormat creates a new DatasetFormat, based on the provided
+// configuration; the missing options retain their default values.
+func NewDatasetFormat(conf map[string]string) (DatasetFormat, error) {
+	format := DefaultDatasetFormat()
+	if val, ok := conf["delimiter"]; ok {
+		r, err := parseFormatCharacter(val)
+		if err != nil {
+			return format, err
+		}
+		if r == 0 {
+			return format, errors.New("the delimiter cannot be empty")
+		}
+		format.Delimiter = r
+	}
+	if val, ok := conf["quoted"]; ok {
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return format, err
+		}
+		format.Quoted = b
+	}
+	if val, ok := conf["header"]; ok {
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return format, err
+		}
+		format.Header = b
+	}
+	if val, ok := conf["comment"]; ok {
+		r, err := parseFormatCharacter(val)
+		if err != nil {
+			return format, err
+		}
+		format.Comment = r
+	}
+	if val, ok := conf["invalid"]; ok {
+		p, err := NewDatasetInvalidValuePolicy(val)
+		if err != nil {
+			return format, err
+		}
+		format.InvalidValues = p
+	}
+	if val, ok := conf["columns"]; ok && val != "" {
+		format.Columns = strings.Split(val, ";")
+	}
+	if format.Delimiter == format.Comment || format.Delimiter == '"' ||
+		format.Delimiter == '\n' || format.Delimiter == '\r' {
+		return format, errors.New("invalid delimiter")
+	}
+	return format, nil
+}
+
+// DatasetFormatOptions returns the options accepted by NewDatasetFormat
+func DatasetFormatOptions() map[string]string {
+	return map[string]string{
+		"delimiter": "the field delimiter: a single character or one of comma, semicolon, tab, pipe, space (default is comma)",
+		"quoted":    "whether fields may be enclosed in double quotes (default is false)",
+		"header":    "whether the first line is a header (default is true)",
+		"comment":   "the character that denotes comment lines: a single character or one of hash, percent (default is none)",
+		"invalid":   "policy for cells that cannot be parsed as numbers: one of error, skip, nan (default is error)",
+		"columns":   "semicolon separated names or indices of the columns to read (default is all)",
+	}
+}
+
+func (f DatasetFormat) String() string {
+	comment := ""
+	if f.Comment != 0 {
+		comment = string(f.Comment)
+	}
+	return fmt.Sprintf("delimiter=%q quoted=%t header=%t comment=%q invalid=%s columns=%s",
+		string(f.Delimiter), f.Quoted, f.Header, comment, f.InvalidValues,
+		strings.Join(f.Columns, ";"))
+}
+
+// serialize returns a byte array containing the format
+func (f DatasetFormat) serialize() []byte {
+	buffer := new(bytes.Buffer)
+	buffer.Write(getBytesInt(int(f.Delimiter)))
+	for _, b := range []bool{f.Quoted, f.Header} {
+		if b {
+			buffer.Write(getBytesInt(1))
+		} else {
+			buffer.Write(getBytesInt(0))
+		}
+	}
+	buffer.Write(getBytesInt(int(f.Comment)))
+	buffer.Write(getBytesInt(int(f.InvalidValues)))
+	buffer.Write(getBytesInt(len(f.Columns)))
+	for _, c := range f.Columns {
+		buffer.WriteString(c + "\n")
+	}
+	return buffer.Bytes()
+}
+
+// deserializeDatasetFormat reads a format, serialized through serialize,
+// from the buffer
+func deserializeDatasetFormat(buffer *bytes.Buffer) DatasetFormat {
+	var f DatasetFormat
+	tempInt := make([]byte, 4)
+	buffer.Read(tempInt)
+	f.Delimiter = rune(getIntBytes(tempInt))
+	buffer.Read(tempInt)
+	f.Quoted = getIntBytes(tempInt) == 1
+	buffer.Read(tempInt)
+	f.Header = getIntBytes(tempInt) == 1
+	buffer.Read(tempInt)
+	f.Comment = rune(getIntBytes(tempInt))
+	buffer.Read(tempInt)
+	f.InvalidValues = DatasetInvalidValuePolicy(getIntBytes(tempInt))
+	buffer.Read(tempInt)
+	count := getIntBytes(tempInt)
+	for i := 0; i < count; i++ {
+		line, _ := buffer.ReadString('\n')
+		f.Columns = append(f.Columns, strings.TrimSuffix(line, "\n"))
+	}
+	return f
+}
+
+// parseFormatCharacter returns the character that corresponds to the
+// provided string, either given as a name or literally
+func parseFormatCharacter(val string) (rune, error) {
+	names := map[string]rune{
+		"comma":     ',',
+		"semicolon": ';',
+		"tab":       '\t',
+		"pipe":      '|',
+		"space":     ' ',
+		"hash":      '#',
+		"percent":   '%',
+		"none":      0,
+		"":          0,
+	}
+	if r, ok := names[strings.ToLower(val)]; ok {
+		return r, nil
+	}
+	if utf8.RuneCountInString(val) != 1 {
+		return 0, errors.New("invalid format character " + val)
+	}
+	r, _ := utf8.DecodeRuneInString(val)
+	return r, nil
+}
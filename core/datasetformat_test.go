@@ -0,0 +1,154 @@
+package core
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+)
+
+func createFormatDataset(t *testing.T, content string, conf map[string]string) *Dataset {
+	f, err := ioutil.TempFile("/tmp", "dataset-format")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	f.WriteString(content)
+	f.Close()
+	format, err := NewDatasetFormat(conf)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	d := NewDataset(f.Name())
+	d.SetFormat(format)
+	return d
+}
+
+func TestNewDatasetFormat(t *testing.T) {
+	format, err := NewDatasetFormat(map[string]string{
+		"delimiter": "semicolon",
+		"quoted":    "true",
+		"header":    "false",
+		"comment":   "#",
+		"invalid":   "nan",
+	})
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if format.Delimiter != ';' || !format.Quoted || format.Header ||
+		format.Comment != '#' || format.InvalidValues != DatasetInvalidValueNaN {
+		t.Log("Wrong format", format)
+		t.Fail()
+	}
+	for _, conf := range []map[string]string{
+		{"delimiter": "ab"},
+		{"delimiter": ""},
+		{"header": "maybe"},
+		{"invalid": "ignore"},
+		{"delimiter": "#", "comment": "#"},
+	} {
+		if _, err := NewDatasetFormat(conf); err == nil {
+			t.Log("Configuration should be rejected", conf)
+			t.Fail()
+		}
+	}
+}
+
+func TestDatasetFormatRead(t *testing.T) {
+	d := createFormatDataset(t, "# a comment\n\"a\";\"b\"\n\"1.5\";2\n# another\n3;\"4\"\n",
+		map[string]string{"delimiter": ";", "quoted": "true", "comment": "#"})
+	defer os.Remove(d.Path())
+	err := d.ReadFromFile()
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if len(d.Header()) != 2 || d.Header()[0] != "a" {
+		t.Log("Wrong header", d.Header())
+		t.Fail()
+	}
+	if len(d.Data()) != 2 || d.Data()[0].Data[0] != 1.5 || d.Data()[1].Data[1] != 4 {
+		t.Log("Wrong data", d.Data())
+		t.Fail()
+	}
+
+	d = createFormatDataset(t, "1|2\n3|4\n", map[string]string{"delimiter": "pipe", "header": "false"})
+	defer os.Remove(d.Path())
+	if err := d.ReadFromFile(); err != nil || len(d.Data()) != 2 || len(d.Header()) != 0 {
+		t.Log("Headerless dataset not parsed correctly", err, d.Data())
+		t.Fail()
+	}
+}
+
+func TestDatasetFormatInvalidValues(t *testing.T) {
+	content := "a,b\n1,2\n3,x\n5,6\n"
+	d := createFormatDataset(t, content, map[string]string{"invalid": "error"})
+	defer os.Remove(d.Path())
+	if err := d.ReadFromFile(); err == nil {
+		t.Log("Invalid value should produce an error")
+		t.Fail()
+	}
+
+	d = createFormatDataset(t, content, map[string]string{"invalid": "skip"})
+	defer os.Remove(d.Path())
+	if err := d.ReadFromFile(); err != nil || len(d.Data()) != 2 {
+		t.Log("Invalid row should be skipped", err, d.Data())
+		t.Fail()
+	}
+
+	d = createFormatDataset(t, content, map[string]string{"invalid": "nan"})
+	defer os.Remove(d.Path())
+	if err := d.ReadFromFile(); err != nil || len(d.Data()) != 3 || !math.IsNaN(d.Data()[1].Data[1]) {
+		t.Log("Invalid value should be NaN", err, d.Data())
+		t.Fail()
+	}
+}
+
+func TestDatasetFormatSerialization(t *testing.T) {
+	format, err := NewDatasetFormat(map[string]string{"delimiter": "semicolon",
+		"quoted": "true", "comment": "hash", "invalid": "nan", "columns": "x;cost"})
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if f := deserializeDatasetFormat(bytes.NewBuffer(format.serialize())); f.String() != format.String() {
+		t.Log("Wrong format deserialization", f, format)
+		t.Fail()
+	}
+
+	// the deserialized estimators keep the format and the column mapping of
+	// their datasets, which are read again when datasets are appended
+	conf := map[string]string{"delimiter": "semicolon", "comment": "hash"}
+	datasets := []*Dataset{
+		createFormatDataset(t, "# prices\nx;cost\n1;2\n3;4\n5;6\n", conf),
+		createFormatDataset(t, "x;price\n1;2\n7;8\n", conf),
+		createFormatDataset(t, "x;cost\n1;2\n3;4\n5;6\n", conf),
+	}
+	defer cleanDatasets(datasets)
+	datasets[1].SetColumnMapping(DatasetColumnMapping{"price": "cost"})
+	est := NewDatasetSimilarityEstimator(SimilarityTypeJaccard, datasets[:2])
+	est.Configure(nil)
+	if err := est.Compute(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	est = DeserializeSimilarityEstimator(est.Serialize())
+	for i, d := range est.Datasets() {
+		if d.Format().String() != datasets[i].Format().String() ||
+			len(d.mapping) != len(datasets[i].mapping) || d.mapping["price"] != datasets[i].mapping["price"] {
+			t.Log("Wrong format or mapping", d.Format(), d.mapping)
+			t.Fail()
+		}
+	}
+	if err := AppendDatasets(est, datasets[2:]); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if s := est.SimilarityMatrix().Get(0, 2); s != 1.0 {
+		t.Log("Deserialized datasets are not read in their format, similarity", s)
+		t.Fail()
+	}
+}
@@ -3,6 +3,7 @@ package core
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"math/rand"
@@ -35,18 +36,17 @@ func DatasetsIntersection(a, b *Dataset) []DatasetTuple {
 func DatasetsUnion(a, b *Dataset) []DatasetTuple {
 	a.ReadFromFile()
 	b.ReadFromFile()
-	dict := make(map[string]bool)
+	// the tuples are kept as they are, since their serialized form is rounded
+	dict := make(map[string]DatasetTuple)
 	for _, dt := range a.Data() {
-		dict[dt.Serialize()] = true
+		dict[dt.Serialize()] = dt
 	}
 	for _, dt := range b.Data() {
-		dict[dt.Serialize()] = true
+		dict[dt.Serialize()] = dt
 	}
 	result := make([]DatasetTuple, 0)
-	for k := range dict {
-		t := new(DatasetTuple)
-		t.Deserialize(k)
-		result = append(result, *t)
+	for _, t := range dict {
+		result = append(result, t)
 	}
 
 	return result
@@ -63,10 +63,11 @@ const (
 // DatasetPartitioner accepts a single dataset and it is responsible to
 // partition it.
 type DatasetPartitioner struct {
-	input         string          // the input file to partition
-	output        string          // the output dir that holds the partitions
-	splits        int             // number of files to generate
-	partitionType PartitionerType // type of the partitioner
+	input         string             // the input file to partition
+	output        string             // the output dir that holds the partitions
+	splits        int                // number of files to generate
+	partitionType PartitionerType    // type of the partitioner
+	compression   DatasetCompression // compression of the generated files
 }
 
 // NewDatasetPartitioner initializes a new DatasetPartitioner object
@@ -76,28 +77,48 @@ func NewDatasetPartitioner(input, output string, splits int, partitionType Parti
 	a.output = output
 	a.splits = splits
 	a.partitionType = partitionType
+	a.compression = DatasetCompressionNone
 	return a
 }
 
+// SetCompression sets the compression of the generated splits
+func (a *DatasetPartitioner) SetCompression(compression DatasetCompression) {
+	a.compression = compression
+}
+
 // Delete function deletes the output directory, containing the Dataset splits
 func (a *DatasetPartitioner) Delete() {
 	os.RemoveAll(a.output)
 }
 
-// Partition function is used to execute the partitioning
+// Partition function is used to execute the partitioning. Compressed input
+// files are decompressed transparently.
 func (a *DatasetPartitioner) Partition() {
 	os.Mkdir(a.output, 0777)
-	newFiles := make([]*os.File, a.splits)
+	file, _, err := openDatasetFile(a.input)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	defer file.Close()
+	newFiles := make([]io.WriteCloser, a.splits)
 	for i := 0; i < a.splits; i++ {
-		fileName := fmt.Sprintf("%s/split-%d", a.output, i)
-		newFiles[i], _ = os.Create(fileName)
+		fileName := fmt.Sprintf("%s/split-%d%s", a.output, i, a.compression.Extension())
+		newFiles[i], err = createDatasetFile(fileName, a.compression)
+		if err != nil {
+			log.Println(err)
+			for _, f := range newFiles[:i] {
+				f.Close()
+			}
+			return
+		}
 	}
-	file, _ := os.Open(a.input)
 	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), datasetMaxLineSize)
 	scanner.Scan()
 	header := scanner.Text()
 	for _, f := range newFiles {
-		f.WriteString(header + "\n")
+		io.WriteString(f, header+"\n")
 	}
 
 	if a.partitionType == PartitionerUniform {
@@ -105,17 +126,17 @@ func (a *DatasetPartitioner) Partition() {
 	}
 
 	for _, f := range newFiles {
-		f.Close()
+		if err := f.Close(); err != nil {
+			log.Println(err)
+		}
 	}
-	file.Close()
-
 }
 
-func (a *DatasetPartitioner) uniform(scanner *bufio.Scanner, newFiles []*os.File) {
+func (a *DatasetPartitioner) uniform(scanner *bufio.Scanner, newFiles []io.WriteCloser) {
 	rand.Seed(int64(time.Now().Nanosecond()))
 	for scanner.Scan() {
 		fileChosen := rand.Int() % a.splits
-		newFiles[fileChosen].WriteString(scanner.Text() + "\n")
+		io.WriteString(newFiles[fileChosen], scanner.Text()+"\n")
 	}
 }
 
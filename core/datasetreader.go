@@ -0,0 +1,182 @@
+package core
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DatasetReader is the interface that the dataset file parsers obey. A reader
+// returns the records of a dataset file as string fields, which are then
+// transformed to tuples according to the dataset schema.
+type DatasetReader interface {
+	// Header returns the column names of the dataset; it is empty if the
+	// dataset has no header
+	Header() []string
+	// Read returns the next record of the dataset, or io.EOF if no more
+	// records exist
+	Read() ([]string, error)
+	// Close releases the resources held by the reader
+	Close() error
+}
+
+// DatasetReaderFactory instantiates a new DatasetReader for the given file
+type DatasetReaderFactory func(path string, format DatasetFormat) (DatasetReader, error)
+
+var (
+	datasetReadersLock sync.RWMutex
+	datasetReaders     = map[string]DatasetReaderFactory{
+		".parquet": newParquetDatasetReader,
+	}
+)
+
+// RegisterDatasetReader registers a DatasetReaderFactory for the files with
+// the given extension (e.g., ".parquet"). Files with unregistered extensions
+// are parsed as CSV files.
+func RegisterDatasetReader(extension string, factory DatasetReaderFactory) {
+	datasetReadersLock.Lock()
+	defer datasetReadersLock.Unlock()
+	datasetReaders[strings.ToLower(extension)] = factory
+}
+
+// NewDatasetReader returns a new DatasetReader for the given file, according
+// to its extension; the extensions of compressed files (e.g., ".csv.gz") are
+// ignored
+func NewDatasetReader(path string, format DatasetFormat) (DatasetReader, error) {
+	datasetReadersLock.RLock()
+	factory, ok := datasetReaders[strings.ToLower(filepath.Ext(trimCompressionExtension(path)))]
+	datasetReadersLock.RUnlock()
+	if !ok {
+		factory = newCSVDatasetReader
+	}
+	return factory(path, format)
+}
+
+// projectionIndices returns the indices of the projected columns. Each
+// column is given either by its name or by its index (starting from 0). A nil
+// slice is returned if no projection is requested.
+func projectionIndices(header []string, columns []string) ([]int, error) {
+	if len(columns) == 0 {
+		return nil, nil
+	}
+	indices := make([]int, len(columns))
+	for i, c := range columns {
+		found := false
+		for j, h := range header {
+			if strings.TrimSpace(h) == c {
+				indices[i], found = j, true
+				break
+			}
+		}
+		if !found {
+			idx, err := strconv.Atoi(c)
+			if err != nil || idx < 0 || (len(header) > 0 && idx >= len(header)) {
+				return nil, errors.New("unknown column " + c)
+			}
+			indices[i] = idx
+		}
+	}
+	return indices, nil
+}
+
+// project returns the fields of a record that correspond to the indices
+func project(record []string, indices []int) []string {
+	if indices == nil {
+		return record
+	}
+	result := make([]string, len(indices))
+	for i, idx := range indices {
+		if idx < len(record) {
+			result[i] = record[idx]
+		}
+	}
+	return result
+}
+
+// csvDatasetReader parses CSV files, according to the dataset format;
+// compressed files are decompressed transparently
+type csvDatasetReader struct {
+	file       io.ReadCloser
+	format     DatasetFormat
+	scanner    *bufio.Scanner // used for unquoted files
+	reader     *csv.Reader    // used for quoted files
+	header     []string
+	projection []int
+}
+
+func newCSVDatasetReader(path string, format DatasetFormat) (DatasetReader, error) {
+	f, _, err := openDatasetFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &csvDatasetReader{file: f, format: format}
+	if format.Quoted {
+		r.reader = csv.NewReader(f)
+		r.reader.Comma = format.Delimiter
+		r.reader.Comment = format.Comment
+		r.reader.FieldsPerRecord = -1
+	} else {
+		r.scanner = bufio.NewScanner(f)
+		r.scanner.Buffer(make([]byte, 64*1024), datasetMaxLineSize)
+	}
+	r.header = make([]string, 0)
+	if format.Header {
+		record, err := r.readRecord()
+		if err == io.EOF {
+			err = errors.New("File without contents")
+		}
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		r.header = record
+	}
+	r.projection, err = projectionIndices(r.header, format.Columns)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if format.Header {
+		r.header = project(r.header, r.projection)
+	}
+	return r, nil
+}
+
+func (r *csvDatasetReader) Header() []string {
+	return r.header
+}
+
+func (r *csvDatasetReader) Read() ([]string, error) {
+	record, err := r.readRecord()
+	if err != nil {
+		return nil, err
+	}
+	return project(record, r.projection), nil
+}
+
+// readRecord returns the fields of the next non-empty and non-comment record
+func (r *csvDatasetReader) readRecord() ([]string, error) {
+	if r.reader != nil {
+		return r.reader.Read()
+	}
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+		if len(line) == 0 || (r.format.Comment != 0 && strings.HasPrefix(line, string(r.format.Comment))) {
+			continue
+		}
+		return strings.Split(line, string(r.format.Delimiter)), nil
+	}
+	if r.scanner.Err() != nil {
+		return nil, r.scanner.Err()
+	}
+	return nil, io.EOF
+}
+
+func (r *csvDatasetReader) Close() error {
+	return r.file.Close()
+}
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"io"
+	"os"
+	"testing"
+)
+
+// memoryDatasetReader returns the same records for any file
+type memoryDatasetReader struct {
+	records [][]string
+	index   int
+}
+
+func (r *memoryDatasetReader) Header() []string {
+	return []string{"a", "b"}
+}
+
+func (r *memoryDatasetReader) Read() ([]string, error) {
+	if r.index >= len(r.records) {
+		return nil, io.EOF
+	}
+	r.index++
+	return r.records[r.index-1], nil
+}
+
+func (r *memoryDatasetReader) Close() error {
+	return nil
+}
+
+func TestDatasetReaderProjection(t *testing.T) {
+	d := createFormatDataset(t, "a,b,c\n1,2,3\n4,5,6\n", map[string]string{"columns": "c;0"})
+	defer os.Remove(d.Path())
+	if err := d.ReadFromFile(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if len(d.Header()) != 2 || d.Header()[0] != "c" || d.Header()[1] != "a" {
+		t.Log("Wrong header", d.Header())
+		t.Fail()
+	}
+	if len(d.Data()) != 2 || d.Data()[0].Data[0] != 3 || d.Data()[1].Data[1] != 4 {
+		t.Log("Wrong data", d.Data())
+		t.Fail()
+	}
+
+	d = createFormatDataset(t, "a,b\n1,2\n", map[string]string{"columns": "d"})
+	defer os.Remove(d.Path())
+	if err := d.ReadFromFile(); err == nil {
+		t.Log("Unknown column should be rejected")
+		t.Fail()
+	}
+}
+
+func TestRegisterDatasetReader(t *testing.T) {
+	RegisterDatasetReader(".MEM", func(path string, format DatasetFormat) (DatasetReader, error) {
+		return &memoryDatasetReader{records: [][]string{{"1", "2"}, {"3", "4"}}}, nil
+	})
+	defer func() {
+		datasetReadersLock.Lock()
+		delete(datasetReaders, ".mem")
+		datasetReadersLock.Unlock()
+	}()
+	d := NewDataset("/nonexistent/dataset.mem")
+	count, err := d.Count()
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if count != 2 {
+		t.Log("Expected 2 tuples, found", count)
+		t.Fail()
+	}
+	if err := d.ReadFromFile(); err != nil || d.Data()[1].Data[0] != 3 || d.Header()[1] != "b" {
+		t.Log("Wrong data", err, d.Data(), d.Header())
+		t.Fail()
+	}
+}
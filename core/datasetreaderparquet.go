@@ -0,0 +1,535 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// parquetMagic is the magic number found at the start and the end of Parquet
+// files
+const parquetMagic = "PAR1"
+
+// parquetPageHeaderSize is the initial portion of a column chunk read for a
+// page header; larger headers (e.g., holding long statistics) are read in
+// larger portions
+const parquetPageHeaderSize = 1024
+
+// parquetJulianUnixEpoch is the Julian day of the Unix epoch, used by the
+// INT96 timestamps
+const parquetJulianUnixEpoch = 2440588
+
+// Parquet converted types (only the ones affecting the value representation)
+const (
+	parquetConvertedDecimal         = 5
+	parquetConvertedDate            = 6
+	parquetConvertedTimestampMillis = 9
+	parquetConvertedTimestampMicros = 10
+)
+
+// parquetColumn describes a leaf column of a Parquet file
+type parquetColumn struct {
+	name          string
+	physicalType  int
+	typeLength    int
+	optional      bool
+	convertedType int
+	scale         int
+	timestampUnit int64 // nanoseconds per unit, 0 for non timestamp columns
+}
+
+// parquetDatasetReader parses Parquet files. Only flat schemas (i.e., without
+// nested or repeated columns) are supported. The file is traversed one row
+// group at a time and only the projected columns are decoded; the values are
+// returned in their textual representation, with nulls returned as empty
+// strings. The column chunks are read page by page, hence the memory is
+// bounded by the decoded values of a single row group and a single
+// compressed page (compressed files, e.g. file.parquet.gz, are an exception,
+// since they are decompressed in memory to allow random access).
+type parquetDatasetReader struct {
+	file       io.ReaderAt
+	closer     io.Closer
+	size       int64
+	path       string
+	columns    []parquetColumn
+	projection []int
+	header     []string
+	rowGroups  []interface{}
+	group      int
+	rows       [][]string // the rows of the current row group
+	row        int
+}
+
+func newParquetDatasetReader(path string, format DatasetFormat) (DatasetReader, error) {
+	r := &parquetDatasetReader{path: path}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	if err := r.readMetadata(format); err != nil {
+		r.Close()
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return r, nil
+}
+
+// open opens the file for random access; compressed files are decompressed
+// in memory
+func (r *parquetDatasetReader) open() error {
+	compression, err := DetectDatasetCompression(r.path)
+	if err != nil {
+		return err
+	}
+	if compression != DatasetCompressionNone {
+		f, _, err := openDatasetFile(r.path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		data, err := ioutil.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		r.file, r.size = bytes.NewReader(data), int64(len(data))
+		return nil
+	}
+	f, err := os.Open(r.path)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file, r.closer, r.size = f, f, info.Size()
+	return nil
+}
+
+// readMetadata parses the file footer, which contains the schema and the
+// locations of the row groups
+func (r *parquetDatasetReader) readMetadata(format DatasetFormat) error {
+	size := r.size
+	if size < int64(2*len(parquetMagic)+4) {
+		return errors.New("not a parquet file")
+	}
+	footer := make([]byte, 8)
+	if _, err := r.file.ReadAt(footer, size-8); err != nil {
+		return err
+	}
+	if string(footer[4:]) != parquetMagic {
+		return errors.New("not a parquet file")
+	}
+	length := int64(binary.LittleEndian.Uint32(footer))
+	if length > size-12 {
+		return errors.New("invalid metadata length")
+	}
+	buf := make([]byte, length)
+	if _, err := r.file.ReadAt(buf, size-8-length); err != nil {
+		return err
+	}
+	decoder := &thriftCompactDecoder{buf: buf}
+	metadata, err := decoder.readStruct()
+	if err != nil {
+		return err
+	}
+	schema := metadata.List(2)
+	if len(schema) == 0 {
+		return errors.New("empty schema")
+	}
+	var names []string
+	for _, e := range schema[1:] {
+		elem, _ := e.(thriftFields)
+		if elem.Int(5, 0) > 0 {
+			return errors.New("nested schemas are not supported")
+		}
+		if elem.Int(3, 0) == 2 {
+			return errors.New("repeated columns are not supported")
+		}
+		col := parquetColumn{
+			name:          elem.String(4),
+			physicalType:  int(elem.Int(1, -1)),
+			typeLength:    int(elem.Int(2, 0)),
+			optional:      elem.Int(3, 0) == 1,
+			convertedType: int(elem.Int(6, -1)),
+			scale:         int(elem.Int(7, 0)),
+		}
+		switch col.convertedType {
+		case parquetConvertedTimestampMillis:
+			col.timestampUnit = int64(time.Millisecond)
+		case parquetConvertedTimestampMicros:
+			col.timestampUnit = int64(time.Microsecond)
+		}
+		if ts := elem.Struct(10).Struct(8); ts != nil { // logical TIMESTAMP
+			unit := ts.Struct(2)
+			switch {
+			case unit.Struct(1) != nil:
+				col.timestampUnit = int64(time.Millisecond)
+			case unit.Struct(2) != nil:
+				col.timestampUnit = int64(time.Microsecond)
+			case unit.Struct(3) != nil:
+				col.timestampUnit = int64(time.Nanosecond)
+			}
+		}
+		r.columns = append(r.columns, col)
+		names = append(names, col.name)
+	}
+	r.projection, err = projectionIndices(names, format.Columns)
+	if err != nil {
+		return err
+	}
+	r.header = project(names, r.projection)
+	r.rowGroups = metadata.List(4)
+	return nil
+}
+
+func (r *parquetDatasetReader) Header() []string {
+	return r.header
+}
+
+func (r *parquetDatasetReader) Read() ([]string, error) {
+	for r.row >= len(r.rows) {
+		if r.group >= len(r.rowGroups) {
+			return nil, io.EOF
+		}
+		if err := r.readRowGroup(r.group); err != nil {
+			return nil, fmt.Errorf("%s: row group %d: %s", r.path, r.group, err)
+		}
+		r.group++
+	}
+	r.row++
+	return r.rows[r.row-1], nil
+}
+
+func (r *parquetDatasetReader) Close() error {
+	if r.closer != nil {
+		return r.closer.Close()
+	}
+	return nil
+}
+
+// readRowGroup decodes the projected columns of a row group
+func (r *parquetDatasetReader) readRowGroup(index int) error {
+	group, _ := r.rowGroups[index].(thriftFields)
+	chunks := group.List(1)
+	if len(chunks) != len(r.columns) {
+		return errors.New("column chunks do not match the schema")
+	}
+	numRows := int(group.Int(3, 0))
+	columns := r.projection
+	if columns == nil {
+		columns = make([]int, len(r.columns))
+		for i := range columns {
+			columns[i] = i
+		}
+	}
+	rows := make([][]string, numRows)
+	for i := range rows {
+		rows[i] = make([]string, len(columns))
+	}
+	for j, c := range columns {
+		chunk, _ := chunks[c].(thriftFields)
+		values, err := r.readColumnChunk(chunk.Struct(3), r.columns[c], numRows)
+		if err != nil {
+			return fmt.Errorf("column %s: %s", r.columns[c].name, err)
+		}
+		for i := range rows {
+			rows[i][j] = values[i]
+		}
+	}
+	r.rows, r.row = rows, 0
+	return nil
+}
+
+// readColumnChunk reads the pages of a column chunk and returns its values
+func (r *parquetDatasetReader) readColumnChunk(metadata thriftFields, col parquetColumn, numRows int) ([]string, error) {
+	if metadata == nil {
+		return nil, errors.New("missing column metadata")
+	}
+	codec := metadata.Int(4, 0)
+	offset := metadata.Int(9, 0)
+	if dict := metadata.Int(11, 0); dict > 0 && dict < offset {
+		offset = dict
+	}
+	size := metadata.Int(7, 0)
+	if size < 0 || offset < 0 || offset+size > r.size {
+		return nil, errors.New("invalid column chunk size")
+	}
+	var dictionary []string
+	values := make([]string, 0, numRows)
+	pos, end := offset, offset+size
+	for len(values) < numRows && pos < end {
+		header, headerSize, err := r.readPageHeader(pos, end-pos)
+		if err != nil {
+			return nil, err
+		}
+		pos += int64(headerSize)
+		compressedSize := header.Int(3, 0)
+		if compressedSize < 0 || pos+compressedSize > end {
+			return nil, errors.New("invalid page size")
+		}
+		page := make([]byte, compressedSize)
+		if _, err := r.file.ReadAt(page, pos); err != nil {
+			return nil, err
+		}
+		pos += compressedSize
+		uncompressedSize := int(header.Int(2, 0))
+		if uncompressedSize < 0 {
+			return nil, errors.New("invalid page size")
+		}
+		switch header.Int(1, -1) {
+		case 2: // dictionary page
+			data, err := parquetDecompress(page, codec, uncompressedSize)
+			if err != nil {
+				return nil, err
+			}
+			raw, err := decodePlain(data, col.physicalType, col.typeLength,
+				int(header.Struct(7).Int(1, 0)))
+			if err != nil {
+				return nil, err
+			}
+			dictionary = make([]string, len(raw))
+			for i, v := range raw {
+				dictionary[i] = col.format(v)
+			}
+		case 0: // data page
+			data, err := parquetDecompress(page, codec, uncompressedSize)
+			if err != nil {
+				return nil, err
+			}
+			pageHeader := header.Struct(5)
+			count := int(pageHeader.Int(1, 0))
+			var levels []int
+			if col.optional {
+				if len(data) < 4 {
+					return nil, errors.New("unexpected end of definition levels")
+				}
+				length := int(binary.LittleEndian.Uint32(data))
+				if length < 0 || 4+length > len(data) {
+					return nil, errors.New("unexpected end of definition levels")
+				}
+				if levels, _, err = decodeRLEHybrid(data[4:4+length], 1, count); err != nil {
+					return nil, err
+				}
+				data = data[4+length:]
+			}
+			if values, err = col.appendValues(values, data, int(pageHeader.Int(2, 0)),
+				levels, count, dictionary); err != nil {
+				return nil, err
+			}
+		case 3: // data page v2, with uncompressed levels
+			pageHeader := header.Struct(8)
+			count := int(pageHeader.Int(1, 0))
+			defLength, repLength := int(pageHeader.Int(5, 0)), int(pageHeader.Int(6, 0))
+			if defLength < 0 || repLength < 0 || defLength+repLength > len(page) {
+				return nil, errors.New("invalid level lengths")
+			}
+			var levels []int
+			if col.optional {
+				if levels, _, err = decodeRLEHybrid(page[repLength:repLength+defLength], 1, count); err != nil {
+					return nil, err
+				}
+			}
+			data := page[repLength+defLength:]
+			if pageHeader.Bool(7, true) {
+				if data, err = parquetDecompress(data, codec,
+					uncompressedSize-repLength-defLength); err != nil {
+					return nil, err
+				}
+			}
+			if values, err = col.appendValues(values, data, int(pageHeader.Int(4, 0)),
+				levels, count, dictionary); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(values) != numRows {
+		return nil, fmt.Errorf("expected %d values, found %d", numRows, len(values))
+	}
+	return values, nil
+}
+
+// readPageHeader reads the header of the page found at offset, which spans up
+// to limit bytes. Since the size of the header is not known in advance, it is
+// read in increasing portions.
+func (r *parquetDatasetReader) readPageHeader(offset, limit int64) (thriftFields, int, error) {
+	for size := int64(parquetPageHeaderSize); ; size *= 2 {
+		if size > limit {
+			size = limit
+		}
+		buf := make([]byte, size)
+		if _, err := r.file.ReadAt(buf, offset); err != nil {
+			return nil, 0, err
+		}
+		decoder := &thriftCompactDecoder{buf: buf}
+		header, err := decoder.readStruct()
+		if err == nil {
+			return header, decoder.pos, nil
+		} else if size == limit {
+			return nil, 0, err
+		}
+	}
+}
+
+// appendValues decodes the values of a data page and appends them to values.
+// The definition levels denote the null values (if levels is nil, all the
+// values are defined).
+func (col parquetColumn) appendValues(values []string, data []byte, encoding int,
+	levels []int, count int, dictionary []string) ([]string, error) {
+	defined := count
+	if levels != nil {
+		defined = 0
+		for _, l := range levels {
+			defined += l
+		}
+	}
+	var decoded []string
+	switch encoding {
+	case 0: // PLAIN
+		raw, err := decodePlain(data, col.physicalType, col.typeLength, defined)
+		if err != nil {
+			return nil, err
+		}
+		decoded = make([]string, len(raw))
+		for i, v := range raw {
+			decoded[i] = col.format(v)
+		}
+	case 3: // RLE, used for booleans
+		if col.physicalType != parquetBoolean {
+			return nil, fmt.Errorf("unsupported encoding %d", encoding)
+		}
+		if len(data) < 4 {
+			return nil, errors.New("unexpected end of values")
+		}
+		length := int(binary.LittleEndian.Uint32(data))
+		if length < 0 || 4+length > len(data) {
+			return nil, errors.New("unexpected end of values")
+		}
+		raw, _, err := decodeRLEHybrid(data[4:4+length], 1, defined)
+		if err != nil {
+			return nil, err
+		}
+		decoded = make([]string, len(raw))
+		for i, v := range raw {
+			decoded[i] = col.format(v == 1)
+		}
+	case 2, 8: // PLAIN_DICTIONARY, RLE_DICTIONARY
+		if dictionary == nil {
+			return nil, errors.New("dictionary page not found")
+		}
+		if defined > 0 && len(data) == 0 {
+			return nil, errors.New("unexpected end of dictionary indices")
+		}
+		decoded = make([]string, defined)
+		if defined > 0 {
+			indices, _, err := decodeRLEHybrid(data[1:], int(data[0]), defined)
+			if err != nil {
+				return nil, err
+			}
+			for i, idx := range indices {
+				if idx >= len(dictionary) {
+					return nil, errors.New("invalid dictionary index")
+				}
+				decoded[i] = dictionary[idx]
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported encoding %d", encoding)
+	}
+	if levels == nil {
+		return append(values, decoded...), nil
+	}
+	next := 0
+	for _, l := range levels {
+		if l == 0 {
+			values = append(values, "")
+		} else {
+			values = append(values, decoded[next])
+			next++
+		}
+	}
+	return values, nil
+}
+
+// format returns the textual representation of a decoded value
+func (col parquetColumn) format(value interface{}) string {
+	switch v := value.(type) {
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		switch {
+		case col.timestampUnit > 0:
+			return time.Unix(0, v*col.timestampUnit).UTC().Format(time.RFC3339Nano)
+		case col.convertedType == parquetConvertedDate:
+			return time.Unix(v*86400, 0).UTC().Format("2006-01-02")
+		case col.convertedType == parquetConvertedDecimal:
+			return strconv.FormatFloat(float64(v)/math.Pow10(col.scale), 'g', -1, 64)
+		}
+		return strconv.FormatInt(v, 10)
+	case float64:
+		if col.physicalType == parquetFloat {
+			return strconv.FormatFloat(v, 'g', -1, 32)
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case []byte:
+		if col.physicalType == parquetInt96 {
+			nanos := int64(binary.LittleEndian.Uint64(v))
+			days := int64(binary.LittleEndian.Uint32(v[8:]))
+			return time.Unix((days-parquetJulianUnixEpoch)*86400, nanos).UTC().Format(time.RFC3339Nano)
+		}
+		if col.convertedType == parquetConvertedDecimal {
+			// big-endian two's complement unscaled value
+			unscaled := new(big.Int).SetBytes(v)
+			if len(v) > 0 && v[0]&0x80 != 0 {
+				unscaled.Sub(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(8*len(v))))
+			}
+			f, _ := new(big.Float).SetInt(unscaled).Float64()
+			return strconv.FormatFloat(f/math.Pow10(col.scale), 'g', -1, 64)
+		}
+		return string(v)
+	}
+	return ""
+}
+
+// parquetDecompress decompresses a page according to the compression codec;
+// size is the length of the decompressed page
+func parquetDecompress(data []byte, codec int64, size int) ([]byte, error) {
+	switch codec {
+	case 0: // UNCOMPRESSED
+		return data, nil
+	case 1: // SNAPPY
+		return decodeSnappy(data)
+	case 2: // GZIP
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return ioutil.ReadAll(reader)
+	case 4: // BROTLI
+		return ioutil.ReadAll(brotli.NewReader(bytes.NewReader(data)))
+	case 5: // LZ4 (deprecated)
+		return decodeHadoopLZ4(data, size)
+	case 6: // ZSTD
+		decoder, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		defer decoder.Close()
+		return decoder.DecodeAll(data, make([]byte, 0, size))
+	case 7: // LZ4_RAW
+		return decodeLZ4Block(data, size)
+	}
+	return nil, fmt.Errorf("unsupported compression codec %d", codec)
+}
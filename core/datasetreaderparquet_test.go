@@ -0,0 +1,610 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/csv"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+type thriftTestField struct {
+	id    int16
+	typ   byte
+	value interface{}
+}
+
+type thriftTestList struct {
+	typ   byte
+	items []interface{}
+}
+
+// writeThriftStruct encodes a struct through the Thrift compact protocol
+func writeThriftStruct(buf *bytes.Buffer, fields []thriftTestField) {
+	var lastID int16
+	for _, f := range fields {
+		typ := f.typ
+		if typ == thriftBooleanTrue && !f.value.(bool) {
+			typ = thriftBooleanFalse
+		}
+		if delta := f.id - lastID; delta > 0 && delta < 16 {
+			buf.WriteByte(byte(delta)<<4 | typ)
+		} else {
+			buf.WriteByte(typ)
+			writeThriftVarint(buf, uint64(int64(f.id)<<1^int64(f.id)>>63))
+		}
+		lastID = f.id
+		if typ != thriftBooleanTrue && typ != thriftBooleanFalse {
+			writeThriftValue(buf, typ, f.value)
+		}
+	}
+	buf.WriteByte(thriftStop)
+}
+
+func writeThriftValue(buf *bytes.Buffer, typ byte, value interface{}) {
+	switch typ {
+	case thriftI16, thriftI32, thriftI64:
+		v := value.(int64)
+		writeThriftVarint(buf, uint64(v<<1^v>>63))
+	case thriftBinary:
+		s := value.(string)
+		writeThriftVarint(buf, uint64(len(s)))
+		buf.WriteString(s)
+	case thriftList:
+		list := value.(thriftTestList)
+		if len(list.items) < 15 {
+			buf.WriteByte(byte(len(list.items))<<4 | list.typ)
+		} else {
+			buf.WriteByte(0xf0 | list.typ)
+			writeThriftVarint(buf, uint64(len(list.items)))
+		}
+		for _, item := range list.items {
+			writeThriftValue(buf, list.typ, item)
+		}
+	case thriftStruct:
+		writeThriftStruct(buf, value.([]thriftTestField))
+	}
+}
+
+func writeThriftVarint(buf *bytes.Buffer, v uint64) {
+	tmp := make([]byte, binary.MaxVarintLen64)
+	buf.Write(tmp[:binary.PutUvarint(tmp, v)])
+}
+
+// encodeSnappyLiterals compresses data to the Snappy raw format, using only
+// literals
+func encodeSnappyLiterals(data []byte) []byte {
+	buf := new(bytes.Buffer)
+	writeThriftVarint(buf, uint64(len(data)))
+	for len(data) > 0 {
+		n := len(data)
+		if n > 60 {
+			n = 60
+		}
+		buf.WriteByte(byte(n-1) << 2)
+		buf.Write(data[:n])
+		data = data[n:]
+	}
+	return buf.Bytes()
+}
+
+// encodeLZ4Literals compresses data to the LZ4 raw format, as a single
+// sequence of literals
+func encodeLZ4Literals(data []byte) []byte {
+	buf := new(bytes.Buffer)
+	if len(data) < 15 {
+		buf.WriteByte(byte(len(data)) << 4)
+	} else {
+		buf.WriteByte(0xf0)
+		n := len(data) - 15
+		for ; n >= 255; n -= 255 {
+			buf.WriteByte(255)
+		}
+		buf.WriteByte(byte(n))
+	}
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+// parquetTestColumn describes a column of a test Parquet file; nil values
+// denote nulls
+type parquetTestColumn struct {
+	name          string
+	physicalType  int
+	optional      bool
+	convertedType int64
+	dictionary    bool // dictionary encoded values, RLE encoded for booleans
+	codec         int64
+	values        []interface{}
+}
+
+func encodePlainTestValues(physicalType int, values []interface{}) []byte {
+	buf := new(bytes.Buffer)
+	if physicalType == parquetBoolean {
+		packed := make([]byte, (len(values)+7)/8)
+		for i, v := range values {
+			if v.(bool) {
+				packed[i/8] |= 1 << uint(i%8)
+			}
+		}
+		return packed
+	}
+	for _, v := range values {
+		switch physicalType {
+		case parquetInt32:
+			binary.Write(buf, binary.LittleEndian, int32(v.(int64)))
+		case parquetInt64:
+			binary.Write(buf, binary.LittleEndian, v.(int64))
+		case parquetDouble:
+			binary.Write(buf, binary.LittleEndian, math.Float64bits(v.(float64)))
+		case parquetByteArray:
+			binary.Write(buf, binary.LittleEndian, uint32(len(v.(string))))
+			buf.WriteString(v.(string))
+		}
+	}
+	return buf.Bytes()
+}
+
+// compressTestPage compresses a page with the given codec
+func compressTestPage(t *testing.T, data []byte, codec int64) []byte {
+	buf := new(bytes.Buffer)
+	switch codec {
+	case 1: // SNAPPY
+		return encodeSnappyLiterals(data)
+	case 2: // GZIP
+		writer := gzip.NewWriter(buf)
+		writer.Write(data)
+		writer.Close()
+	case 4: // BROTLI
+		writer := brotli.NewWriter(buf)
+		writer.Write(data)
+		writer.Close()
+	case 5: // LZ4, with the Hadoop framing
+		block := encodeLZ4Literals(data)
+		binary.Write(buf, binary.BigEndian, uint32(len(data)))
+		binary.Write(buf, binary.BigEndian, uint32(len(block)))
+		buf.Write(block)
+	case 6: // ZSTD
+		encoder, err := zstd.NewWriter(nil)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		defer encoder.Close()
+		return encoder.EncodeAll(data, nil)
+	case 7: // LZ4_RAW
+		return encodeLZ4Literals(data)
+	default:
+		return data
+	}
+	return buf.Bytes()
+}
+
+// writeTestPage appends a page, along with its header, to the file buffer
+func writeTestPage(t *testing.T, file *bytes.Buffer, pageType int64, data []byte, codec int64, pageHeader thriftTestField) {
+	compressed := compressTestPage(t, data, codec)
+	writeThriftStruct(file, []thriftTestField{
+		{1, thriftI32, pageType},
+		{2, thriftI32, int64(len(data))},
+		{3, thriftI32, int64(len(compressed))},
+		pageHeader,
+	})
+	file.Write(compressed)
+}
+
+// writeTestDataPage appends a data page holding the values of a column; the
+// values are encoded through the dictionary, if not nil
+func writeTestDataPage(t *testing.T, file *bytes.Buffer, c parquetTestColumn, values, dictionary []interface{}) {
+	var levels []int
+	var defined []interface{}
+	for _, v := range values {
+		if v == nil {
+			levels = append(levels, 0)
+		} else {
+			levels = append(levels, 1)
+			defined = append(defined, v)
+		}
+	}
+	encoding := int64(0)
+	var data []byte
+	if c.dictionary && c.physicalType == parquetBoolean {
+		// booleans are not dictionary encoded, but RLE encoded
+		bits := make([]int, len(defined))
+		for i, v := range defined {
+			if v.(bool) {
+				bits[i] = 1
+			}
+		}
+		encoded := encodeBitPacked(bits, 1)
+		data = make([]byte, 4)
+		binary.LittleEndian.PutUint32(data, uint32(len(encoded)))
+		data = append(data, encoded...)
+		encoding = 3
+	} else if dictionary != nil {
+		var indices []int
+		for _, v := range defined {
+			for i, d := range dictionary {
+				if d == v {
+					indices = append(indices, i)
+				}
+			}
+		}
+		width := bitWidth(len(dictionary) - 1)
+		data = append([]byte{byte(width)}, encodeBitPacked(indices, width)...)
+		encoding = 8
+	} else {
+		data = encodePlainTestValues(c.physicalType, defined)
+	}
+	if c.optional {
+		encoded := encodeBitPacked(levels, 1)
+		prefix := make([]byte, 4)
+		binary.LittleEndian.PutUint32(prefix, uint32(len(encoded)))
+		data = append(append(prefix, encoded...), data...)
+	}
+	pageHeader := []thriftTestField{
+		{1, thriftI32, int64(len(values))},
+		{2, thriftI32, encoding},
+		{3, thriftI32, int64(3)},
+		{4, thriftI32, int64(3)},
+	}
+	if c.physicalType == parquetByteArray && len(defined) > 0 {
+		// the statistics of the page, as written by most writers
+		min, max := defined[0].(string), defined[0].(string)
+		for _, v := range defined {
+			if v.(string) < min {
+				min = v.(string)
+			}
+			if v.(string) > max {
+				max = v.(string)
+			}
+		}
+		pageHeader = append(pageHeader, thriftTestField{5, thriftStruct, []thriftTestField{
+			{5, thriftBinary, max},
+			{6, thriftBinary, min},
+		}})
+	}
+	writeTestPage(t, file, 0, data, c.codec, thriftTestField{5, thriftStruct, pageHeader})
+}
+
+// writeParquetTestFile writes the columns to a Parquet file, split to row
+// groups of the given sizes; the column chunks are split to data pages of
+// pageSize values (a single page per chunk if pageSize is 0)
+func writeParquetTestFile(t *testing.T, columns []parquetTestColumn, groupSizes []int, pageSize int) string {
+	file := bytes.NewBufferString(parquetMagic)
+	schema := []interface{}{[]thriftTestField{
+		{4, thriftBinary, "schema"},
+		{5, thriftI32, int64(len(columns))},
+	}}
+	for _, c := range columns {
+		repetition := int64(0)
+		if c.optional {
+			repetition = 1
+		}
+		elem := []thriftTestField{
+			{1, thriftI32, int64(c.physicalType)},
+			{3, thriftI32, repetition},
+			{4, thriftBinary, c.name},
+		}
+		if c.convertedType >= 0 {
+			elem = append(elem, thriftTestField{6, thriftI32, c.convertedType})
+		}
+		schema = append(schema, elem)
+	}
+	var rowGroups []interface{}
+	start := 0
+	for _, size := range groupSizes {
+		var chunks []interface{}
+		for _, c := range columns {
+			values := c.values[start : start+size]
+			chunkOffset := int64(file.Len())
+			dictOffset, encoding := int64(0), int64(0)
+			var dictionary []interface{}
+			if c.dictionary && c.physicalType == parquetBoolean {
+				encoding = 3
+			} else if c.dictionary {
+				for _, v := range values {
+					found := v == nil
+					for _, d := range dictionary {
+						found = found || d == v
+					}
+					if !found {
+						dictionary = append(dictionary, v)
+					}
+				}
+				dictOffset = int64(file.Len())
+				writeTestPage(t, file, 2, encodePlainTestValues(c.physicalType, dictionary), c.codec,
+					thriftTestField{7, thriftStruct, []thriftTestField{
+						{1, thriftI32, int64(len(dictionary))},
+						{2, thriftI32, int64(2)},
+					}})
+				encoding = 8
+			}
+			dataOffset := int64(file.Len())
+			for page := values; len(page) > 0; {
+				n := len(page)
+				if pageSize > 0 && pageSize < n {
+					n = pageSize
+				}
+				writeTestDataPage(t, file, c, page[:n], dictionary)
+				page = page[n:]
+			}
+			metadata := []thriftTestField{
+				{1, thriftI32, int64(c.physicalType)},
+				{2, thriftList, thriftTestList{thriftI32, []interface{}{encoding}}},
+				{3, thriftList, thriftTestList{thriftBinary, []interface{}{c.name}}},
+				{4, thriftI32, c.codec},
+				{5, thriftI64, int64(size)},
+				{6, thriftI64, int64(file.Len()) - chunkOffset},
+				{7, thriftI64, int64(file.Len()) - chunkOffset},
+				{9, thriftI64, dataOffset},
+			}
+			if dictionary != nil {
+				metadata = append(metadata, thriftTestField{11, thriftI64, dictOffset})
+			}
+			chunks = append(chunks, []thriftTestField{
+				{2, thriftI64, chunkOffset},
+				{3, thriftStruct, metadata},
+			})
+		}
+		rowGroups = append(rowGroups, []thriftTestField{
+			{1, thriftList, thriftTestList{thriftStruct, chunks}},
+			{2, thriftI64, int64(0)},
+			{3, thriftI64, int64(size)},
+		})
+		start += size
+	}
+	footer := new(bytes.Buffer)
+	writeThriftStruct(footer, []thriftTestField{
+		{1, thriftI32, int64(1)},
+		{2, thriftList, thriftTestList{thriftStruct, schema}},
+		{3, thriftI64, int64(start)},
+		{4, thriftList, thriftTestList{thriftStruct, rowGroups}},
+	})
+	file.Write(footer.Bytes())
+	binary.Write(file, binary.LittleEndian, uint32(footer.Len()))
+	file.WriteString(parquetMagic)
+
+	f, err := ioutil.TempFile("/tmp", "dataset-parquet")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	f.Close()
+	path := f.Name() + ".parquet"
+	os.Remove(f.Name())
+	if err := ioutil.WriteFile(path, file.Bytes(), 0644); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	return path
+}
+
+func createParquetTestFile(t *testing.T) string {
+	return writeParquetTestFile(t, []parquetTestColumn{
+		{"x", parquetDouble, false, -1, false, 1,
+			[]interface{}{1.5, 2.5, -3.0, 4.0, 5.25}},
+		{"name", parquetByteArray, true, 0, true, 0,
+			[]interface{}{"a", "b", nil, "a", "c"}},
+		{"ts", parquetInt64, true, parquetConvertedTimestampMillis, false, 1,
+			[]interface{}{int64(0), nil, int64(86400000), int64(1500), int64(60000)}},
+		{"n", parquetInt32, false, -1, false, 0,
+			[]interface{}{int64(1), int64(-2), int64(3), int64(4), int64(5)}},
+		{"flag", parquetBoolean, false, -1, false, 0,
+			[]interface{}{true, false, true, true, false}},
+	}, []int{3, 2}, 0)
+}
+
+func TestParquetDatasetReader(t *testing.T) {
+	path := createParquetTestFile(t)
+	defer os.Remove(path)
+	reader, err := NewDatasetReader(path, DefaultDatasetFormat())
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	defer reader.Close()
+	header := reader.Header()
+	if len(header) != 5 || header[0] != "x" || header[4] != "flag" {
+		t.Log("Wrong header", header)
+		t.Fail()
+	}
+	expected := [][]string{
+		{"1.5", "a", "1970-01-01T00:00:00Z", "1", "true"},
+		{"2.5", "b", "", "-2", "false"},
+		{"-3", "", "1970-01-02T00:00:00Z", "3", "true"},
+		{"4", "a", "1970-01-01T00:00:01.5Z", "4", "true"},
+		{"5.25", "c", "1970-01-01T00:01:00Z", "5", "false"},
+	}
+	for i, e := range expected {
+		record, err := reader.Read()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		for j := range e {
+			if record[j] != e[j] {
+				t.Log("Row", i, "expected", e, "found", record)
+				t.Fail()
+				break
+			}
+		}
+	}
+	if _, err := reader.Read(); err == nil {
+		t.Log("Expected EOF")
+		t.Fail()
+	}
+}
+
+func TestParquetDatasetReaderCodecs(t *testing.T) {
+	// dictionary (and RLE) encoded columns, split to multiple pages and row
+	// groups; the long category yields page headers larger than
+	// parquetPageHeaderSize
+	var ids, categories, values, flags []interface{}
+	var expected [][]string
+	for i := 0; i < 23; i++ {
+		category := interface{}("c" + strconv.Itoa(i%3))
+		if i%3 == 2 {
+			category = strings.Repeat("c", 2*parquetPageHeaderSize)
+		}
+		if i%5 == 0 {
+			category = nil
+		}
+		ids = append(ids, int64(i))
+		categories = append(categories, category)
+		values = append(values, float64(i%4)/2)
+		flags = append(flags, i%7 < 3)
+		row := []string{strconv.Itoa(i), "", strconv.FormatFloat(float64(i%4)/2, 'g', -1, 64),
+			strconv.FormatBool(i%7 < 3)}
+		if category != nil {
+			row[1] = category.(string)
+		}
+		expected = append(expected, row)
+	}
+	codecs := map[int64]string{0: "uncompressed", 1: "snappy", 2: "gzip", 4: "brotli",
+		5: "lz4", 6: "zstd", 7: "lz4_raw"}
+	for codec, name := range codecs {
+		path := writeParquetTestFile(t, []parquetTestColumn{
+			{"id", parquetInt64, false, -1, false, codec, ids},
+			{"category", parquetByteArray, true, 0, true, codec, categories},
+			{"value", parquetDouble, false, -1, true, codec, values},
+			{"flag", parquetBoolean, false, -1, true, codec, flags},
+		}, []int{10, 13}, 4)
+		defer os.Remove(path)
+		reader, err := NewDatasetReader(path, DefaultDatasetFormat())
+		if err != nil {
+			t.Log(name, err)
+			t.FailNow()
+		}
+		for i, e := range expected {
+			record, err := reader.Read()
+			if err != nil {
+				t.Log(name, err)
+				t.FailNow()
+			}
+			for j := range e {
+				if record[j] != e[j] {
+					t.Log(name, "row", i, "expected", e, "found", record)
+					t.FailNow()
+				}
+			}
+		}
+		if _, err := reader.Read(); err == nil {
+			t.Log(name, "expected EOF")
+			t.Fail()
+		}
+		reader.Close()
+	}
+}
+
+func TestParquetFixtures(t *testing.T) {
+	// fixtures produced by a standard writer, through _scripts/parquet-fixtures.py
+	paths, _ := filepath.Glob("../_testdata/parquet/*.parquet")
+	if len(paths) == 0 {
+		t.Skip("no fixtures found, run _scripts/parquet-fixtures.py to generate them")
+	}
+	for _, path := range paths {
+		f, err := os.Open(strings.TrimSuffix(path, ".parquet") + ".csv")
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		expected, err := csv.NewReader(f).ReadAll()
+		f.Close()
+		if err != nil || len(expected) == 0 {
+			t.Log(path, "invalid expected values", err)
+			t.FailNow()
+		}
+		reader, err := NewDatasetReader(path, DefaultDatasetFormat())
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		if strings.Join(reader.Header(), ",") != strings.Join(expected[0], ",") {
+			t.Log(path, "wrong header", reader.Header())
+			t.Fail()
+		}
+		for i, e := range expected[1:] {
+			record, err := reader.Read()
+			if err != nil {
+				t.Log(path, err)
+				t.FailNow()
+			}
+			if strings.Join(record, ",") != strings.Join(e, ",") {
+				t.Log(path, "row", i, "expected", e, "found", record)
+				t.FailNow()
+			}
+		}
+		if _, err := reader.Read(); err != io.EOF {
+			t.Log(path, "expected EOF, found", err)
+			t.Fail()
+		}
+		reader.Close()
+	}
+}
+
+func TestParquetDataset(t *testing.T) {
+	path := createParquetTestFile(t)
+	defer os.Remove(path)
+	d := NewDataset(path)
+	format := DefaultDatasetFormat()
+	format.Columns = []string{"n", "x"}
+	d.SetFormat(format)
+	if err := d.ReadFromFile(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if len(d.Header()) != 2 || d.Header()[0] != "n" {
+		t.Log("Wrong header", d.Header())
+		t.Fail()
+	}
+	if len(d.Data()) != 5 || d.Data()[1].Data[0] != -2 || d.Data()[4].Data[1] != 5.25 {
+		t.Log("Wrong data", d.Data())
+		t.Fail()
+	}
+
+	d = NewDataset(path)
+	format = DefaultDatasetFormat()
+	format.InvalidValues = DatasetInvalidValueNaN // for the null timestamp
+	d.SetFormat(format)
+	schema, err := d.Schema()
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if schema.Type(1) != DatasetColumnCategorical || schema.Type(2) != DatasetColumnTimestamp ||
+		schema.Type(4) != DatasetColumnBoolean {
+		t.Log("Wrong schema", schema)
+		t.Fail()
+	}
+	if count, err := d.Count(); err != nil || count != 5 {
+		t.Log("Expected 5 tuples, found", count, err)
+		t.Fail()
+	}
+}
+
+func TestParquetDatasetReaderInvalid(t *testing.T) {
+	f, err := ioutil.TempFile("/tmp", "dataset-parquet")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	f.WriteString("a,b\n1,2\n")
+	f.Close()
+	path := f.Name() + ".parquet"
+	os.Rename(f.Name(), path)
+	defer os.Remove(path)
+	if _, err := NewDatasetReader(path, DefaultDatasetFormat()); err == nil {
+		t.Log("CSV file should not be parsed as Parquet")
+		t.Fail()
+	}
+}
@@ -0,0 +1,259 @@
+package core
+
+import (
+	"errors"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// datasetSchemaSampleSize is the number of rows examined for the inference
+// of the schema of a dataset
+const datasetSchemaSampleSize = 100
+
+// datasetTimestampLayouts are the layouts recognized for timestamp columns
+var datasetTimestampLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+}
+
+// DatasetColumnType represents the type of a dataset column
+type DatasetColumnType uint8
+
+const (
+	// DatasetColumnNumeric represents real valued columns
+	DatasetColumnNumeric DatasetColumnType = iota
+	// DatasetColumnCategorical represents columns with discrete string values;
+	// each value is encoded through a hash function, so that equal values are
+	// mapped to equal numbers across all datasets
+	DatasetColumnCategorical DatasetColumnType = iota + 1
+	// DatasetColumnBoolean represents boolean columns, encoded as 0 and 1
+	DatasetColumnBoolean DatasetColumnType = iota + 2
+	// DatasetColumnTimestamp represents timestamps, encoded as Unix time
+	DatasetColumnTimestamp DatasetColumnType = iota + 3
+)
+
+// NewDatasetColumnType transforms a string to a DatasetColumnType object
+func NewDatasetColumnType(t string) (DatasetColumnType, error) {
+	switch strings.ToLower(t) {
+	case "numeric":
+		return DatasetColumnNumeric, nil
+	case "categorical":
+		return DatasetColumnCategorical, nil
+	case "boolean":
+		return DatasetColumnBoolean, nil
+	case "timestamp":
+		return DatasetColumnTimestamp, nil
+	}
+	return DatasetColumnNumeric, errors.New("unknown column type " + t)
+}
+
+func (t DatasetColumnType) String() string {
+	switch t {
+	case DatasetColumnNumeric:
+		return "numeric"
+	case DatasetColumnCategorical:
+		return "categorical"
+	case DatasetColumnBoolean:
+		return "boolean"
+	case DatasetColumnTimestamp:
+		return "timestamp"
+	}
+	return ""
+}
+
+// Discrete returns true for the column types that contain discrete values,
+// which must be compared through exact matching
+func (t DatasetColumnType) Discrete() bool {
+	return t == DatasetColumnCategorical || t == DatasetColumnBoolean
+}
+
+// Parse encodes a cell value of the specific type to a float64
+func (t DatasetColumnType) Parse(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	switch t {
+	case DatasetColumnCategorical:
+		return categoricalValue(value), nil
+	case DatasetColumnBoolean:
+		b, err := parseBoolean(value)
+		if err != nil {
+			return 0, err
+		}
+		if b {
+			return 1.0, nil
+		}
+		return 0.0, nil
+	case DatasetColumnTimestamp:
+		ts, err := parseTimestamp(value)
+		if err != nil {
+			return 0, err
+		}
+		return float64(ts.UnixNano()) / 1e9, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// DatasetColumn represents a column of a dataset
+type DatasetColumn struct {
+	Name string
+	Type DatasetColumnType
+}
+
+// DatasetSchema represents the columns of a dataset
+type DatasetSchema []DatasetColumn
+
+// Columns returns the indices of the columns that satisfy the filter
+func (s DatasetSchema) Columns(filter func(DatasetColumnType) bool) []int {
+	var result []int
+	for i, c := range s {
+		if filter(c.Type) {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+// Type returns the type of the i-th column; columns not described by the
+// schema are considered numeric
+func (s DatasetSchema) Type(i int) DatasetColumnType {
+	if i < len(s) {
+		return s[i].Type
+	}
+	return DatasetColumnNumeric
+}
+
+// NewDatasetSchema parses a schema given in the form type1,type2,...
+func NewDatasetSchema(header []string, types string) (DatasetSchema, error) {
+	var schema DatasetSchema
+	for i, t := range strings.Split(types, ",") {
+		colType, err := NewDatasetColumnType(strings.TrimSpace(t))
+		if err != nil {
+			return nil, err
+		}
+		name := ""
+		if i < len(header) {
+			name = header[i]
+		}
+		schema = append(schema, DatasetColumn{Name: name, Type: colType})
+	}
+	return schema, nil
+}
+
+// InferDatasetSchema infers the column types of a dataset from its header and
+// a sample of its rows. A column is assigned the first of the numeric,
+// boolean and timestamp types that the majority of its non-empty values
+// satisfy, else it is considered categorical. Numeric identifiers are thus
+// considered numeric, unless a schema declaring them categorical is set.
+func InferDatasetSchema(header []string, records [][]string) DatasetSchema {
+	columns := len(header)
+	for _, r := range records {
+		if len(r) > columns {
+			columns = len(r)
+		}
+	}
+	schema := make(DatasetSchema, columns)
+	for i := range schema {
+		if i < len(header) {
+			schema[i].Name = header[i]
+		}
+		numeric, boolean, timestamp, total := 0, 0, 0, 0
+		for _, r := range records {
+			if i >= len(r) || strings.TrimSpace(r[i]) == "" {
+				continue
+			}
+			v := strings.TrimSpace(r[i])
+			total++
+			if _, err := strconv.ParseFloat(v, 64); err == nil {
+				numeric++
+			}
+			if _, err := parseBoolean(v); err == nil {
+				boolean++
+			}
+			if _, err := parseTimestamp(v); err == nil {
+				timestamp++
+			}
+		}
+		switch {
+		case total == 0 || 2*numeric > total:
+			schema[i].Type = DatasetColumnNumeric
+		case 2*boolean > total:
+			schema[i].Type = DatasetColumnBoolean
+		case 2*timestamp > total:
+			schema[i].Type = DatasetColumnTimestamp
+		default:
+			schema[i].Type = DatasetColumnCategorical
+		}
+	}
+	return schema
+}
+
+// categoricalValue encodes a categorical value to a float64, through the
+// FNV-1a hash function. The hash is truncated to 53 bits, so that it is
+// exactly represented by a float64.
+func categoricalValue(value string) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(value))
+	return float64(h.Sum64() & (1<<53 - 1))
+}
+
+// parseBoolean parses the textual boolean representations; numeric values are
+// not accepted, so that they are not confused with numeric columns
+func parseBoolean(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "true", "t", "yes", "y":
+		return true, nil
+	case "false", "f", "no", "n":
+		return false, nil
+	}
+	return false, errors.New("invalid boolean value " + value)
+}
+
+// parseTimestamp parses a timestamp according to the recognized layouts
+func parseTimestamp(value string) (time.Time, error) {
+	for _, layout := range datasetTimestampLayouts {
+		if ts, err := time.Parse(layout, value); err == nil {
+			return ts, nil
+		}
+	}
+	return time.Time{}, errors.New("invalid timestamp value " + value)
+}
+
+// unifyDatasetSchemas sets a common schema to the provided datasets, so that
+// their values are encoded consistently. If the datasets have different
+// headers, their columns are matched by name and the columns that some
+// datasets lack are treated according to the policy; else, the columns are
+// matched by position. Columns with conflicting types among the datasets are
+// considered categorical.
+func unifyDatasetSchemas(datasets []*Dataset, policy DatasetColumnsPolicy) (DatasetSchema, error) {
+	headers, err := datasetHeaders(datasets)
+	if err != nil {
+		return nil, err
+	}
+	if headers != nil {
+		return alignDatasetSchemas(datasets, headers, policy)
+	}
+	var common DatasetSchema
+	for _, d := range datasets {
+		schema, err := d.fileSchema()
+		if err != nil {
+			return nil, err
+		}
+		for i, c := range schema {
+			if i >= len(common) {
+				common = append(common, c)
+			} else if common[i].Type != c.Type {
+				common[i].Type = DatasetColumnCategorical
+			}
+		}
+	}
+	for _, d := range datasets {
+		d.SetSchema(common)
+	}
+	return common, nil
+}
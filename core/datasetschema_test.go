@@ -0,0 +1,138 @@
+package core
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+// createCategoricalDatasets creates datasets with a numeric and a categorical
+// column; the categories of the i-th dataset are given by categories[i]
+func createCategoricalDatasets(t *testing.T, tuples int, categories [][]string) []*Dataset {
+	var datasets []*Dataset
+	for _, cats := range categories {
+		f, err := ioutil.TempFile("/tmp", "dataset-categorical")
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		f.WriteString("value,country\n")
+		for i := 0; i < tuples; i++ {
+			f.WriteString(fmt.Sprintf("%.2f,%s\n", rand.Float64(), cats[i%len(cats)]))
+		}
+		f.Close()
+		datasets = append(datasets, NewDataset(f.Name()))
+	}
+	return datasets
+}
+
+func TestInferDatasetSchema(t *testing.T) {
+	header := []string{"value", "flag", "date", "country", "product_id", "empty"}
+	records := [][]string{
+		{"1.5", "true", "2017-01-02", "GR", "12", ""},
+		{"2", "no", "2017-01-03 10:00:00", "US", "13", ""},
+		{"x", "yes", "2017-01-04T10:00:00Z", "GR", "14", ""},
+	}
+	schema := InferDatasetSchema(header, records)
+	expected := []DatasetColumnType{DatasetColumnNumeric, DatasetColumnBoolean,
+		DatasetColumnTimestamp, DatasetColumnCategorical, DatasetColumnNumeric,
+		DatasetColumnNumeric}
+	if len(schema) != len(expected) {
+		t.Log("Wrong number of columns", schema)
+		t.FailNow()
+	}
+	for i := range expected {
+		if schema[i].Type != expected[i] || schema[i].Name != header[i] {
+			t.Log("Column", i, "expected", expected[i], "found", schema[i].Type)
+			t.Fail()
+		}
+	}
+}
+
+func TestDatasetSchemaCached(t *testing.T) {
+	d := createFormatDataset(t, "a;b\n1;x\n2;y\n", map[string]string{"delimiter": "semicolon"})
+	defer os.Remove(d.Path())
+	schema, err := d.Schema()
+	if err != nil || len(schema) != 2 || schema.Type(1) != DatasetColumnCategorical {
+		t.Log("Wrong schema", schema, err)
+		t.FailNow()
+	}
+	// the inferred schema is kept while the format remains unchanged
+	ioutil.WriteFile(d.Path(), []byte("a;b;c\n1;2;3\n"), 0644)
+	if schema, _ = d.Schema(); len(schema) != 2 {
+		t.Log("Schema inferred again", schema)
+		t.Fail()
+	}
+	d.SetFormat(d.Format())
+	if schema, _ = d.Schema(); len(schema) != 3 || schema.Type(1) != DatasetColumnNumeric {
+		t.Log("Schema not inferred after the format change", schema)
+		t.Fail()
+	}
+}
+
+func TestDatasetColumnTypeParse(t *testing.T) {
+	if v, err := DatasetColumnBoolean.Parse("Yes"); err != nil || v != 1.0 {
+		t.Log("Wrong boolean value", v, err)
+		t.Fail()
+	}
+	if v, err := DatasetColumnTimestamp.Parse("1970-01-02"); err != nil || v != 86400 {
+		t.Log("Wrong timestamp value", v, err)
+		t.Fail()
+	}
+	a, _ := DatasetColumnCategorical.Parse("GR")
+	b, _ := DatasetColumnCategorical.Parse(" GR ")
+	c, _ := DatasetColumnCategorical.Parse("US")
+	if a != b || a == c || a != math.Trunc(a) {
+		t.Log("Wrong categorical values", a, b, c)
+		t.Fail()
+	}
+	if _, err := DatasetColumnNumeric.Parse("GR"); err == nil {
+		t.Log("Numeric parsing should fail")
+		t.Fail()
+	}
+}
+
+func TestDatasetCategoricalRead(t *testing.T) {
+	datasets := createCategoricalDatasets(t, 50, [][]string{{"GR", "US"}})
+	defer cleanDatasets(datasets)
+	err := datasets[0].ReadFromFile()
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	schema, _ := datasets[0].Schema()
+	if schema.Type(1) != DatasetColumnCategorical {
+		t.Log("Column should be categorical", schema)
+		t.Fail()
+	}
+	if datasets[0].Data()[0].Data[1] != categoricalValue("GR") {
+		t.Log("Wrong categorical encoding")
+		t.Fail()
+	}
+}
+
+func TestCategoricalSimilarities(t *testing.T) {
+	datasets := createCategoricalDatasets(t, 500,
+		[][]string{{"GR", "US"}, {"GR", "US"}, {"FR", "DE"}})
+	defer cleanDatasets(datasets)
+	for _, estType := range []DatasetSimilarityEstimatorType{SimilarityTypeBhattacharyya, SimilarityTypeJaccard} {
+		est := NewDatasetSimilarityEstimator(estType, datasets)
+		est.Configure(map[string]string{"concurrency": "2", "partitions": "4"})
+		if err := est.Compute(); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		sm := est.SimilarityMatrix()
+		if sm.Get(0, 2) != 0 || sm.Get(1, 2) != 0 {
+			t.Log(estType, "datasets with disjoint categories should have zero similarity", sm.Get(0, 2), sm.Get(1, 2))
+			t.Fail()
+		}
+		if sm.Get(0, 1) <= 0 {
+			t.Log(estType, "datasets with common categories should be similar", sm.Get(0, 1))
+			t.Fail()
+		}
+	}
+}
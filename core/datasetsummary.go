@@ -0,0 +1,528 @@
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// datasetSummaryVersion is the version of the summary format; summaries of
+// a different version are not reused
+const datasetSummaryVersion = 3
+
+// datasetSummaryBins is the number of histogram bins of each column summary
+const datasetSummaryBins = 20
+
+// datasetHashLock guards the hashes of the datasets, which are computed lazily
+var datasetHashLock sync.Mutex
+
+// DatasetPartitionCounts holds the number of tuples of a dataset that fall
+// into each partition, for each combination of discrete values (bucket)
+type DatasetPartitionCounts struct {
+	// Buckets holds the combinations of discrete values, in order of appearance
+	Buckets []string
+	// Counts holds the partition counts of each bucket
+	Counts [][]int
+}
+
+// DatasetColumnSummary holds the statistics of a dataset column; NaN values
+// are ignored, and so are infinite values by the histogram
+type DatasetColumnSummary struct {
+	Min, Max, Mean float64
+	// Histogram holds the number of values that fall into each of the equal
+	// width bins, the first of which starts at Low; the bins cover all the
+	// values between Min and Max
+	Histogram  []int
+	Low, Width float64
+	count      int // the number of values, used during the computation
+}
+
+// add updates the statistics with a value. The histogram range is extended
+// on demand, by doubling the bin width and merging the adjacent bins, so that
+// the column is summarized in a single pass.
+func (c *DatasetColumnSummary) add(v float64) {
+	if math.IsNaN(v) {
+		return
+	}
+	if c.count == 0 {
+		c.Min, c.Max, c.Low = v, v, v
+	}
+	c.count++
+	c.Min, c.Max = math.Min(c.Min, v), math.Max(c.Max, v)
+	c.Mean += (v - c.Mean) / float64(c.count)
+	if math.IsInf(v, 0) {
+		return
+	}
+	if c.Width == 0 && v != c.Low { // the second distinct value sets the bins
+		bins := float64(len(c.Histogram) - 1)
+		if v > c.Low {
+			c.Width = (v - c.Low) / bins
+		} else {
+			c.Histogram[0], c.Histogram[len(c.Histogram)-1] = 0, c.Histogram[0]
+			c.Low, c.Width = v, (c.Low-v)/bins
+		}
+	}
+	c.Histogram[c.bin(v)]++
+}
+
+// bin returns the histogram bin of a value, after extending the histogram
+// range to include it
+func (c *DatasetColumnSummary) bin(v float64) int {
+	if c.Width == 0 {
+		return 0
+	}
+	bins := len(c.Histogram)
+	for !math.IsInf(c.Width, 0) {
+		b := math.Floor((v - c.Low) / c.Width)
+		if b >= 0 && b < float64(bins) {
+			return int(b)
+		}
+		// merge the pairs of adjacent bins to the lower (upper) half of the
+		// histogram, so that it extends to higher (lower) values
+		merged := make([]int, bins)
+		offset := 0
+		if b < 0 {
+			offset = bins / 2
+			c.Low -= float64(bins) * c.Width
+		}
+		for i, h := range c.Histogram {
+			merged[offset+i/2] += h
+		}
+		c.Histogram, c.Width = merged, 2*c.Width
+	}
+	if v < c.Low {
+		return 0
+	}
+	return bins - 1
+}
+
+// DatasetSummary holds the statistics of a dataset, which are reused among
+// different estimator executions
+type DatasetSummary struct {
+	// Key identifies the contents, the format and the schema of the dataset
+	Key     string
+	Count   int
+	Header  []string
+	Columns []DatasetColumnSummary
+	// Partitions holds the partition counts of the dataset, indexed by the
+	// fingerprint of the partitioning
+	Partitions map[string]*DatasetPartitionCounts
+	// Features holds the state that the estimators compute for each dataset
+	// independently (e.g., signatures, profiles, samples), indexed by the
+	// fingerprint of the estimator parameters; the fingerprints are stored as
+	// lines, hence they must not contain newlines
+	Features map[string][]float64
+}
+
+// NewDatasetSummary computes the summary of a dataset, i.e., its number of
+// tuples and the statistics of its columns. The dataset is traversed once,
+// without being loaded in memory.
+func NewDatasetSummary(d *Dataset) (*DatasetSummary, error) {
+	key, err := d.summaryKey()
+	if err != nil {
+		return nil, err
+	}
+	s := &DatasetSummary{Key: key,
+		Partitions: make(map[string]*DatasetPartitionCounts),
+		Features:   make(map[string][]float64)}
+	it, err := d.Iterator()
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+	for it.Next() {
+		t := it.Tuple()
+		for len(s.Columns) < len(t.Data) {
+			s.Columns = append(s.Columns,
+				DatasetColumnSummary{Histogram: make([]int, datasetSummaryBins)})
+		}
+		for i, v := range t.Data {
+			s.Columns[i].add(v)
+		}
+		s.Count++
+	}
+	s.Header = it.Header()
+	for i := range s.Columns {
+		if s.Columns[i].count == 0 {
+			c := &s.Columns[i]
+			c.Min, c.Max, c.Mean = math.NaN(), math.NaN(), math.NaN()
+		}
+	}
+	return s, it.Err()
+}
+
+// Serialize returns a byte slice representing the summary
+func (s *DatasetSummary) Serialize() []byte {
+	buffer := new(bytes.Buffer)
+	buffer.WriteString(s.Key + "\n")
+	buffer.Write(getBytesInt(s.Count))
+	buffer.Write(getBytesInt(len(s.Header)))
+	for _, h := range s.Header {
+		buffer.WriteString(h + "\n")
+	}
+	buffer.Write(getBytesInt(len(s.Columns)))
+	for _, c := range s.Columns {
+		for _, f := range []float64{c.Min, c.Max, c.Mean, c.Low, c.Width} {
+			buffer.Write(getBytesFloat(f))
+		}
+		buffer.Write(getBytesInt(len(c.Histogram)))
+		for _, h := range c.Histogram {
+			buffer.Write(getBytesInt(h))
+		}
+	}
+	buffer.Write(getBytesInt(len(s.Partitions)))
+	for k, p := range s.Partitions {
+		buffer.WriteString(k + "\n")
+		buffer.Write(getBytesInt(len(p.Buckets)))
+		for i, b := range p.Buckets {
+			buffer.WriteString(b + "\n")
+			buffer.Write(getBytesInt(len(p.Counts[i])))
+			for _, c := range p.Counts[i] {
+				buffer.Write(getBytesInt(c))
+			}
+		}
+	}
+	buffer.Write(getBytesInt(len(s.Features)))
+	for k, f := range s.Features {
+		buffer.WriteString(k + "\n")
+		buffer.Write(getBytesInt(len(f)))
+		for _, v := range f {
+			buffer.Write(getBytesFloat(v))
+		}
+	}
+	return buffer.Bytes()
+}
+
+// Deserialize parses a byte slice and instantiates the summary
+func (s *DatasetSummary) Deserialize(b []byte) error {
+	buffer := bytes.NewBuffer(b)
+	tempInt, tempFloat := make([]byte, 4), make([]byte, 8)
+	readInt := func() (int, error) {
+		if _, err := io.ReadFull(buffer, tempInt); err != nil {
+			return 0, err
+		}
+		return getIntBytes(tempInt), nil
+	}
+	// readLength reads the length of a collection, which cannot exceed the
+	// remaining bytes
+	readLength := func() (int, error) {
+		length, err := readInt()
+		if err == nil && (length < 0 || length > buffer.Len()) {
+			err = errors.New("invalid summary")
+		}
+		return length, err
+	}
+	readLine := func() (string, error) {
+		line, err := buffer.ReadString('\n')
+		return strings.TrimSuffix(line, "\n"), err
+	}
+	var err error
+	if s.Key, err = readLine(); err != nil {
+		return err
+	}
+	if s.Count, err = readInt(); err != nil {
+		return err
+	}
+	headers, err := readLength()
+	if err != nil {
+		return err
+	}
+	s.Header = make([]string, headers)
+	for i := range s.Header {
+		if s.Header[i], err = readLine(); err != nil {
+			return err
+		}
+	}
+	columns, err := readLength()
+	if err != nil {
+		return err
+	}
+	s.Columns = make([]DatasetColumnSummary, columns)
+	for i := range s.Columns {
+		c := &s.Columns[i]
+		for _, f := range []*float64{&c.Min, &c.Max, &c.Mean, &c.Low, &c.Width} {
+			if _, err := io.ReadFull(buffer, tempFloat); err != nil {
+				return err
+			}
+			*f = getFloatBytes(tempFloat)
+		}
+		bins, err := readLength()
+		if err != nil {
+			return err
+		}
+		c.Histogram = make([]int, bins)
+		for j := range c.Histogram {
+			if c.Histogram[j], err = readInt(); err != nil {
+				return err
+			}
+		}
+	}
+	partitions, err := readLength()
+	if err != nil {
+		return err
+	}
+	s.Partitions = make(map[string]*DatasetPartitionCounts)
+	for i := 0; i < partitions; i++ {
+		key, err := readLine()
+		if err != nil {
+			return err
+		}
+		buckets, err := readLength()
+		if err != nil {
+			return err
+		}
+		p := &DatasetPartitionCounts{Buckets: make([]string, buckets), Counts: make([][]int, buckets)}
+		for j := range p.Buckets {
+			if p.Buckets[j], err = readLine(); err != nil {
+				return err
+			}
+			length, err := readLength()
+			if err != nil {
+				return err
+			}
+			p.Counts[j] = make([]int, length)
+			for k := range p.Counts[j] {
+				if p.Counts[j][k], err = readInt(); err != nil {
+					return err
+				}
+			}
+		}
+		s.Partitions[key] = p
+	}
+	features, err := readLength()
+	if err != nil {
+		return err
+	}
+	s.Features = make(map[string][]float64)
+	for i := 0; i < features; i++ {
+		key, err := readLine()
+		if err != nil {
+			return err
+		}
+		length, err := readLength()
+		if err != nil {
+			return err
+		}
+		f := make([]float64, length)
+		for j := range f {
+			if _, err := io.ReadFull(buffer, tempFloat); err != nil {
+				return err
+			}
+			f[j] = getFloatBytes(tempFloat)
+		}
+		s.Features[key] = f
+	}
+	return nil
+}
+
+// DatasetSummaryCache stores the summaries of the datasets in a directory.
+// The summaries are indexed by the hash of the dataset contents, so that
+// they are reused for as long as the dataset files remain unchanged.
+type DatasetSummaryCache struct {
+	dir       string
+	lock      sync.Mutex
+	summaries map[string]*DatasetSummary
+	pending   map[string]*sync.Mutex // locks the summaries being computed
+}
+
+// NewDatasetSummaryCache returns a new cache that stores the summaries in
+// the given directory, which is created if it does not exist
+func NewDatasetSummaryCache(dir string) (*DatasetSummaryCache, error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	c := new(DatasetSummaryCache)
+	c.dir = dir
+	c.summaries = make(map[string]*DatasetSummary)
+	c.pending = make(map[string]*sync.Mutex)
+	return c, nil
+}
+
+// Summary returns the summary of a dataset. The summary is computed only if
+// it is not found in the cache.
+func (c *DatasetSummaryCache) Summary(d *Dataset) (*DatasetSummary, error) {
+	key, err := d.summaryKey()
+	if err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	if s, ok := c.summaries[key]; ok {
+		c.lock.Unlock()
+		return s, nil
+	}
+	keyLock, ok := c.pending[key]
+	if !ok {
+		keyLock = new(sync.Mutex)
+		c.pending[key] = keyLock
+	}
+	c.lock.Unlock()
+
+	keyLock.Lock()
+	defer keyLock.Unlock()
+	c.lock.Lock()
+	s, ok := c.summaries[key]
+	c.lock.Unlock()
+	if ok { // computed by a concurrent call
+		return s, nil
+	}
+	s = new(DatasetSummary)
+	if b, err := ioutil.ReadFile(c.path(key)); err == nil && s.Deserialize(b) == nil && s.Key == key {
+		log.Println("Summary of", d.Path(), "found in cache")
+	} else {
+		log.Println("Computing the summary of", d.Path())
+		if s, err = NewDatasetSummary(d); err != nil {
+			return nil, err
+		}
+		if err := c.store(s); err != nil {
+			log.Println(err)
+		}
+	}
+	c.lock.Lock()
+	c.summaries[key] = s
+	delete(c.pending, key)
+	c.lock.Unlock()
+	return s, nil
+}
+
+// Count returns the number of tuples of a dataset, based on its summary
+func (c *DatasetSummaryCache) Count(d *Dataset) (int, error) {
+	s, err := c.Summary(d)
+	if err != nil {
+		return 0, err
+	}
+	return s.Count, nil
+}
+
+// partitions returns the partition counts of a dataset for the given
+// partitioning fingerprint, if they are cached
+func (c *DatasetSummaryCache) partitions(d *Dataset, fingerprint string) (*DatasetPartitionCounts, bool) {
+	s, err := c.Summary(d)
+	if err != nil {
+		log.Println(err)
+		return nil, false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	p, ok := s.Partitions[fingerprint]
+	return p, ok
+}
+
+// setPartitions stores the partition counts of a dataset for the given
+// partitioning fingerprint
+func (c *DatasetSummaryCache) setPartitions(d *Dataset, fingerprint string, p *DatasetPartitionCounts) error {
+	s, err := c.Summary(d)
+	if err != nil {
+		return err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	s.Partitions[fingerprint] = p
+	return c.store(s)
+}
+
+// features returns the features of a dataset for the given estimator
+// fingerprint, if they are cached
+func (c *DatasetSummaryCache) features(d *Dataset, fingerprint string) ([]float64, bool) {
+	s, err := c.Summary(d)
+	if err != nil {
+		log.Println(err)
+		return nil, false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	f, ok := s.Features[fingerprint]
+	return f, ok
+}
+
+// setFeatures stores the features of a dataset for the given estimator
+// fingerprint
+func (c *DatasetSummaryCache) setFeatures(d *Dataset, fingerprint string, f []float64) error {
+	s, err := c.Summary(d)
+	if err != nil {
+		return err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	s.Features[fingerprint] = f
+	return c.store(s)
+}
+
+// path returns the file that holds the summary with the given key
+func (c *DatasetSummaryCache) path(key string) string {
+	return filepath.Join(c.dir, key+".summary")
+}
+
+// store writes a summary to the cache directory; the summary is first
+// written to a temporary file, so that concurrent readers never observe a
+// partially written summary
+func (c *DatasetSummaryCache) store(s *DatasetSummary) error {
+	f, err := ioutil.TempFile(c.dir, "summary")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(s.Serialize())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), c.path(s.Key))
+}
+
+// Hash returns the SHA-256 hash of the contents of the dataset file
+func (d *Dataset) Hash() (string, error) {
+	datasetHashLock.Lock()
+	hash := d.hash
+	datasetHashLock.Unlock()
+	if hash != "" {
+		return hash, nil
+	}
+	f, err := os.Open(d.path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	hash = fmt.Sprintf("%x", h.Sum(nil))
+	datasetHashLock.Lock()
+	d.hash = hash
+	datasetHashLock.Unlock()
+	return hash, nil
+}
+
+// summaryKey returns the key of the dataset summary, which depends on the
+// contents, the format and the schema of the dataset
+func (d *Dataset) summaryKey() (string, error) {
+	hash, err := d.Hash()
+	if err != nil {
+		return "", err
+	}
+	schema, err := d.Schema()
+	if err != nil {
+		return "", err
+	}
+	types := make([]string, len(schema))
+	for i, c := range schema {
+		types[i] = c.Type.String()
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\n%s\n%s\n%s", datasetSummaryVersion, hash, d.format, strings.Join(types, ","))
+	if d.alignment != nil {
+		fmt.Fprintf(h, "\n%v", d.alignment)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
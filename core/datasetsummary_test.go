@@ -0,0 +1,246 @@
+package core
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestNewDatasetSummary(t *testing.T) {
+	d := createFormatDataset(t, "a,b\n1,10\n2,nan\n3,30\n4,40\n", map[string]string{"invalid": "nan"})
+	defer os.Remove(d.Path())
+	d.SetSchema(DatasetSchema{{"a", DatasetColumnNumeric}, {"b", DatasetColumnNumeric}})
+	s, err := NewDatasetSummary(d)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if s.Count != 4 || len(s.Header) != 2 || len(s.Columns) != 2 {
+		t.Log("Wrong summary", s)
+		t.FailNow()
+	}
+	a, b := s.Columns[0], s.Columns[1]
+	if a.Min != 1 || a.Max != 4 || a.Mean != 2.5 || b.Min != 10 || b.Max != 40 || b.Mean != 80.0/3 {
+		t.Log("Wrong column statistics", a, b)
+		t.Fail()
+	}
+	if a.Histogram[0] != 1 || histogramTotal(b.Histogram) != 3 {
+		t.Log("Wrong histograms, NaN values should be ignored", a.Histogram, b.Histogram)
+		t.Fail()
+	}
+
+	s.Partitions["test"] = &DatasetPartitionCounts{Buckets: []string{"x"}, Counts: [][]int{{1, 2, 3}}}
+	s.Features["test"] = []float64{0.5, -1}
+	other := new(DatasetSummary)
+	if err := other.Deserialize(s.Serialize()); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	p, f := other.Partitions["test"], other.Features["test"]
+	if other.Key != s.Key || other.Count != s.Count || other.Header[1] != "b" ||
+		len(other.Columns) != 2 || other.Columns[1].Mean != b.Mean ||
+		other.Columns[0].Width != a.Width || other.Columns[0].Histogram[0] != 1 ||
+		p == nil || len(p.Buckets) != 1 || p.Buckets[0] != "x" ||
+		len(p.Counts) != 1 || len(p.Counts[0]) != 3 || p.Counts[0][2] != 3 ||
+		len(f) != 2 || f[0] != 0.5 || f[1] != -1 {
+		t.Log("Wrong deserialized summary", other)
+		t.Fail()
+	}
+}
+
+func histogramTotal(histogram []int) int {
+	total := 0
+	for _, h := range histogram {
+		total += h
+	}
+	return total
+}
+
+func TestDatasetColumnSummaryHistogram(t *testing.T) {
+	values := []float64{5, 5, 3, 4, -100, 1000, math.NaN(), math.Inf(1), 7}
+	c := DatasetColumnSummary{Histogram: make([]int, datasetSummaryBins)}
+	for _, v := range values {
+		c.add(v)
+	}
+	if c.Min != -100 || !math.IsInf(c.Max, 1) || histogramTotal(c.Histogram) != 7 {
+		t.Log("Wrong column summary", c)
+		t.FailNow()
+	}
+	// each finite value falls into the bin of the final range
+	if c.Low > -100 || c.Low+float64(len(c.Histogram))*c.Width <= 1000 {
+		t.Log("The histogram range does not cover the values", c.Low, c.Width)
+		t.Fail()
+	}
+	expected := make([]int, len(c.Histogram))
+	for _, v := range values {
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			expected[int((v-c.Low)/c.Width)]++
+		}
+	}
+	for i := range expected {
+		if expected[i] != c.Histogram[i] {
+			t.Log("Expected", expected, "found", c.Histogram)
+			t.FailNow()
+		}
+	}
+}
+
+func TestDatasetSummaryCache(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp", "summaries")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	d := createFormatDataset(t, "a\n1\n2\n3\n", nil)
+	defer os.Remove(d.Path())
+	cache, err := NewDatasetSummaryCache(dir)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if count, err := cache.Count(d); err != nil || count != 3 {
+		t.Log("Expected 3 tuples, found", count, err)
+		t.Fail()
+	}
+
+	// a new cache reuses the stored summary
+	content, _ := ioutil.ReadFile(d.Path())
+	key, _ := d.summaryKey()
+	summary := new(DatasetSummary)
+	stored, _ := ioutil.ReadFile(cache.path(key))
+	if err := summary.Deserialize(stored); err != nil || summary.Count != 3 {
+		t.Log("Summary not stored", err)
+		t.Fail()
+	}
+	// fake statistics, proving that the cached summary is used
+	summary.Count, summary.Columns[0].Mean = 42, 42
+	ioutil.WriteFile(cache.path(key), summary.Serialize(), 0644)
+	cache, _ = NewDatasetSummaryCache(dir)
+	if count, _ := cache.Count(NewDataset(d.Path())); count != 42 {
+		t.Log("Cached summary not used, found", count)
+		t.Fail()
+	}
+	cached, err := cache.Summary(NewDataset(d.Path()))
+	if err != nil || len(cached.Columns) != 1 || cached.Columns[0].Mean != 42 ||
+		cached.Columns[0].Min != 1 || cached.Columns[0].Max != 3 ||
+		histogramTotal(cached.Columns[0].Histogram) != 3 {
+		t.Log("Wrong cached column statistics", cached, err)
+		t.Fail()
+	}
+
+	// a modified file gets a new summary
+	ioutil.WriteFile(d.Path(), append(content, []byte("4\n")...), 0644)
+	if count, _ := cache.Count(NewDataset(d.Path())); count != 4 {
+		t.Log("Expected 4 tuples for the modified file, found", count)
+		t.Fail()
+	}
+}
+
+func TestSummaryCacheEstimators(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp", "summaries")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	cache, _ := NewDatasetSummaryCache(dir)
+	datasets := createPoolBasedDatasets(1000, 5, 2)
+	defer cleanDatasets(datasets)
+
+	// the cache is set before the configuration, since some estimators
+	// traverse the datasets while being configured
+	est := NewDatasetSimilarityEstimator(SimilarityTypeSize, datasets)
+	est.SetSummaryCache(cache)
+	est.Configure(map[string]string{"concurrency": "2"})
+	if err := est.Compute(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	smSanityCheck(est.SimilarityMatrix(), t)
+
+	conf := map[string]string{"partitions": "8"}
+	bhat := NewDatasetSimilarityEstimator(SimilarityTypeBhattacharyya, datasets[:3]).(*BhattacharyyaEstimator)
+	bhat.SetSummaryCache(cache)
+	bhat.Configure(conf)
+	if err := bhat.Compute(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	p, size, _ := bhat.partitionCounts(datasets[4])
+	direct := p.Counts[0]
+	for i := 0; i < 2; i++ { // the second call hits the cache
+		counts, cachedSize, err := bhat.regionCounts(datasets[4], false)
+		if err != nil || cachedSize != size || len(counts) != len(direct) {
+			t.Log("Wrong cached counts", counts, direct, err)
+			t.FailNow()
+		}
+		for j := range counts {
+			if counts[j] != direct[j] {
+				t.Log("Wrong cached counts", counts, direct)
+				t.FailNow()
+			}
+		}
+	}
+	if _, ok := cache.partitions(datasets[4], bhat.fingerprint()); !ok {
+		t.Log("Region counts not cached")
+		t.Fail()
+	}
+
+	// an identical configuration in a later run yields the same partitioner,
+	// hence the cached counts are reused
+	other := NewDatasetSimilarityEstimator(SimilarityTypeBhattacharyya, datasets[:3]).(*BhattacharyyaEstimator)
+	cache, _ = NewDatasetSummaryCache(dir)
+	other.SetSummaryCache(cache)
+	other.Configure(conf)
+	if other.fingerprint() != bhat.fingerprint() {
+		t.Log("The partitioner of an identical configuration differs")
+		t.FailNow()
+	}
+	if _, ok := cache.partitions(datasets[4], other.fingerprint()); !ok {
+		t.Log("Cached region counts not found by a later run")
+		t.Fail()
+	}
+}
+
+func TestSummaryCacheFeatures(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp", "summaries")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	datasets := createPoolBasedDatasets(500, 4, 2)
+	defer cleanDatasets(datasets)
+
+	for _, estType := range []DatasetSimilarityEstimatorType{
+		SimilarityTypeMinHash, SimilarityTypeProfile, SimilarityTypeCorrelationMatrix} {
+		var matrices []*DatasetSimilarityMatrix
+		for run := 0; run < 2; run++ {
+			cache, _ := NewDatasetSummaryCache(dir)
+			est := NewDatasetSimilarityEstimator(estType, datasets)
+			est.SetSummaryCache(cache)
+			est.Configure(map[string]string{"concurrency": "2"})
+			if err := est.Compute(); err != nil {
+				t.Log(estType, err)
+				t.FailNow()
+			}
+			matrices = append(matrices, est.SimilarityMatrix())
+		}
+		for i := range datasets {
+			for j := range datasets {
+				if matrices[0].Get(i, j) != matrices[1].Get(i, j) {
+					t.Log(estType, "cached features yield a different matrix")
+					t.FailNow()
+				}
+			}
+		}
+		cache, _ := NewDatasetSummaryCache(dir)
+		s, _ := cache.Summary(datasets[0])
+		if len(s.Features) == 0 {
+			t.Log(estType, "features not cached")
+			t.Fail()
+		}
+	}
+}
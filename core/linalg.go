@@ -0,0 +1,500 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// symmetricEigen calculates the eigenvalues and eigenvectors of a symmetric
+// matrix, using Householder tridiagonalization followed by the QL algorithm.
+// The eigenvalues are returned in descending order and the i-th column of the
+// vectors matrix (vectors[*][i]) holds the eigenvector of the i-th eigenvalue.
+// The provided matrix is not modified.
+func symmetricEigen(a [][]float64) ([]float64, [][]float64) {
+	n := len(a)
+	v := make([][]float64, n)
+	for i := range a {
+		v[i] = make([]float64, n)
+		copy(v[i], a[i])
+	}
+	d, e := make([]float64, n), make([]float64, n)
+	if n == 0 {
+		return d, v
+	}
+	eigenTridiagonalize(v, d, e)
+	eigenDiagonalize(v, d, e)
+
+	// sort in descending order
+	idx := eigenOrder{make([]int, n), d}
+	for i := range idx.idx {
+		idx.idx[i] = i
+	}
+	sort.Stable(sort.Reverse(idx))
+	values := make([]float64, n)
+	vectors := make([][]float64, n)
+	for i := range vectors {
+		vectors[i] = make([]float64, n)
+	}
+	for j, k := range idx.idx {
+		values[j] = d[k]
+		for i := 0; i < n; i++ {
+			vectors[i][j] = v[i][k]
+		}
+	}
+	return values, vectors
+}
+
+// eigenOrder is used to sort the eigenvalue indices according to their values
+type eigenOrder struct {
+	idx    []int
+	values []float64
+}
+
+func (o eigenOrder) Len() int           { return len(o.idx) }
+func (o eigenOrder) Less(i, j int) bool { return o.values[o.idx[i]] < o.values[o.idx[j]] }
+func (o eigenOrder) Swap(i, j int)      { o.idx[i], o.idx[j] = o.idx[j], o.idx[i] }
+
+// eigenTridiagonalize reduces the symmetric matrix v to a tridiagonal form
+// (Householder reduction). Derived from the Algol procedure tred2 by Bowdler,
+// Martin, Reinsch and Wilkinson, Handbook for Auto. Comp., Vol.ii-Linear
+// Algebra, and the corresponding EISPACK routine.
+func eigenTridiagonalize(v [][]float64, d, e []float64) {
+	n := len(v)
+	for j := 0; j < n; j++ {
+		d[j] = v[n-1][j]
+	}
+	for i := n - 1; i > 0; i-- {
+		scale, h := 0.0, 0.0
+		for k := 0; k < i; k++ {
+			scale += math.Abs(d[k])
+		}
+		if scale == 0.0 {
+			e[i] = d[i-1]
+			for j := 0; j < i; j++ {
+				d[j] = v[i-1][j]
+				v[i][j] = 0.0
+				v[j][i] = 0.0
+			}
+		} else {
+			for k := 0; k < i; k++ {
+				d[k] /= scale
+				h += d[k] * d[k]
+			}
+			f := d[i-1]
+			g := math.Sqrt(h)
+			if f > 0 {
+				g = -g
+			}
+			e[i] = scale * g
+			h = h - f*g
+			d[i-1] = f - g
+			for j := 0; j < i; j++ {
+				e[j] = 0.0
+			}
+			for j := 0; j < i; j++ {
+				f = d[j]
+				v[j][i] = f
+				g = e[j] + v[j][j]*f
+				for k := j + 1; k <= i-1; k++ {
+					g += v[k][j] * d[k]
+					e[k] += v[k][j] * f
+				}
+				e[j] = g
+			}
+			f = 0.0
+			for j := 0; j < i; j++ {
+				e[j] /= h
+				f += e[j] * d[j]
+			}
+			hh := f / (h + h)
+			for j := 0; j < i; j++ {
+				e[j] -= hh * d[j]
+			}
+			for j := 0; j < i; j++ {
+				f = d[j]
+				g = e[j]
+				for k := j; k <= i-1; k++ {
+					v[k][j] -= (f*e[k] + g*d[k])
+				}
+				d[j] = v[i-1][j]
+				v[i][j] = 0.0
+			}
+		}
+		d[i] = h
+	}
+
+	// accumulate transformations
+	for i := 0; i < n-1; i++ {
+		v[n-1][i] = v[i][i]
+		v[i][i] = 1.0
+		h := d[i+1]
+		if h != 0.0 {
+			for k := 0; k <= i; k++ {
+				d[k] = v[k][i+1] / h
+			}
+			for j := 0; j <= i; j++ {
+				g := 0.0
+				for k := 0; k <= i; k++ {
+					g += v[k][i+1] * v[k][j]
+				}
+				for k := 0; k <= i; k++ {
+					v[k][j] -= g * d[k]
+				}
+			}
+		}
+		for k := 0; k <= i; k++ {
+			v[k][i+1] = 0.0
+		}
+	}
+	for j := 0; j < n; j++ {
+		d[j] = v[n-1][j]
+		v[n-1][j] = 0.0
+	}
+	v[n-1][n-1] = 1.0
+	e[0] = 0.0
+}
+
+// eigenDiagonalize diagonalizes the tridiagonal matrix produced by
+// eigenTridiagonalize (symmetric QL algorithm). Derived from the Algol
+// procedure tql2 by Bowdler, Martin, Reinsch and Wilkinson, Handbook for Auto.
+// Comp., Vol.ii-Linear Algebra, and the corresponding EISPACK routine.
+func eigenDiagonalize(v [][]float64, d, e []float64) {
+	n := len(v)
+	for i := 1; i < n; i++ {
+		e[i-1] = e[i]
+	}
+	e[n-1] = 0.0
+
+	f, tst1 := 0.0, 0.0
+	eps := math.Pow(2.0, -52.0)
+	for l := 0; l < n; l++ {
+		// find small subdiagonal element
+		tst1 = math.Max(tst1, math.Abs(d[l])+math.Abs(e[l]))
+		m := l
+		for m < n {
+			if math.Abs(e[m]) <= eps*tst1 {
+				break
+			}
+			m++
+		}
+		if m == n { // should not happen, e[n-1] is zero
+			m = n - 1
+		}
+
+		// if m == l, d[l] is an eigenvalue, otherwise iterate
+		if m > l {
+			for iter := 0; iter < 30*n; iter++ {
+				// compute implicit shift
+				g := d[l]
+				p := (d[l+1] - g) / (2.0 * e[l])
+				r := math.Hypot(p, 1.0)
+				if p < 0 {
+					r = -r
+				}
+				d[l] = e[l] / (p + r)
+				d[l+1] = e[l] * (p + r)
+				dl1 := d[l+1]
+				h := g - d[l]
+				for i := l + 2; i < n; i++ {
+					d[i] -= h
+				}
+				f += h
+
+				// implicit QL transformation
+				p = d[m]
+				c, c2, c3 := 1.0, 1.0, 1.0
+				el1 := e[l+1]
+				s, s2 := 0.0, 0.0
+				for i := m - 1; i >= l; i-- {
+					c3 = c2
+					c2 = c
+					s2 = s
+					g = c * e[i]
+					h = c * p
+					r = math.Hypot(p, e[i])
+					e[i+1] = s * r
+					s = e[i] / r
+					c = p / r
+					p = c*d[i] - s*g
+					d[i+1] = h + s*(c*g+s*d[i])
+
+					// accumulate transformation
+					for k := 0; k < n; k++ {
+						h = v[k][i+1]
+						v[k][i+1] = s*v[k][i] + c*h
+						v[k][i] = c*v[k][i] - s*h
+					}
+				}
+				p = -s * s2 * c3 * el1 * e[l] / dl1
+				e[l] = s * p
+				d[l] = c * p
+
+				// check for convergence
+				if math.Abs(e[l]) <= eps*tst1 {
+					break
+				}
+			}
+		}
+		d[l] += f
+		e[l] = 0.0
+	}
+}
+
+// doubleCenter returns the double centered matrix B = -1/2 J D^2 J of a
+// distance matrix D, J being the centering matrix. B is the inner product
+// matrix utilized by classical MDS.
+func doubleCenter(distances [][]float64) [][]float64 {
+	n := len(distances)
+	b := make([][]float64, n)
+	rowMeans := make([]float64, n)
+	totalMean := 0.0
+	for i := range distances {
+		b[i] = make([]float64, n)
+		for j := range distances[i] {
+			b[i][j] = distances[i][j] * distances[i][j]
+			rowMeans[i] += b[i][j]
+		}
+		totalMean += rowMeans[i]
+		rowMeans[i] /= float64(n)
+	}
+	totalMean /= float64(n * n)
+	for i := range b {
+		for j := range b[i] {
+			// the matrix is symmetric, thus row means equal column means
+			b[i][j] = -0.5 * (b[i][j] - rowMeans[i] - rowMeans[j] + totalMean)
+		}
+	}
+	return b
+}
+
+// solveLinearSystem solves the linear system A x = b through Gaussian
+// elimination with partial pivoting. A and b are not modified. An error is
+// returned if A is singular.
+func solveLinearSystem(a [][]float64, b []float64) ([]float64, error) {
+	n := len(a)
+	m := make([][]float64, n)
+	for i := range a {
+		if len(a[i]) != n || len(b) != n {
+			return nil, errors.New("invalid system dimensions")
+		}
+		m[i] = make([]float64, n+1)
+		copy(m[i], a[i])
+		m[i][n] = b[i]
+	}
+	for col := 0; col < n; col++ {
+		pivot := col
+		for i := col + 1; i < n; i++ {
+			if math.Abs(m[i][col]) > math.Abs(m[pivot][col]) {
+				pivot = i
+			}
+		}
+		if math.Abs(m[pivot][col]) < 1e-12 {
+			return nil, errors.New("singular system")
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		for i := col + 1; i < n; i++ {
+			f := m[i][col] / m[col][col]
+			for j := col; j <= n; j++ {
+				m[i][j] -= f * m[col][j]
+			}
+		}
+	}
+	x := make([]float64, n)
+	for i := n - 1; i >= 0; i-- {
+		sum := m[i][n]
+		for j := i + 1; j < n; j++ {
+			sum -= m[i][j] * x[j]
+		}
+		x[i] = sum / m[i][i]
+	}
+	return x, nil
+}
+
+// cholesky returns the lower triangular matrix L, such that A = L L^T, of a
+// symmetric positive definite matrix A. An error is returned if A is not
+// positive definite.
+func cholesky(a [][]float64) ([][]float64, error) {
+	n := len(a)
+	l := make([][]float64, n)
+	for i := range l {
+		l[i] = make([]float64, n)
+		for j := 0; j <= i; j++ {
+			sum := a[i][j]
+			for k := 0; k < j; k++ {
+				sum -= l[i][k] * l[j][k]
+			}
+			if i == j {
+				if sum <= 0 || math.IsNaN(sum) {
+					return nil, errors.New("matrix is not positive definite")
+				}
+				l[i][i] = math.Sqrt(sum)
+			} else {
+				l[i][j] = sum / l[j][j]
+			}
+		}
+	}
+	return l, nil
+}
+
+// forwardSubstitution solves L x = b for a lower triangular matrix L
+func forwardSubstitution(l [][]float64, b []float64) []float64 {
+	x := make([]float64, len(b))
+	for i := range x {
+		sum := b[i]
+		for k := 0; k < i; k++ {
+			sum -= l[i][k] * x[k]
+		}
+		x[i] = sum / l[i][i]
+	}
+	return x
+}
+
+// backSubstitution solves L^T x = b for a lower triangular matrix L
+func backSubstitution(l [][]float64, b []float64) []float64 {
+	x := make([]float64, len(b))
+	for i := len(x) - 1; i >= 0; i-- {
+		sum := b[i]
+		for k := i + 1; k < len(x); k++ {
+			sum -= l[k][i] * x[k]
+		}
+		x[i] = sum / l[i][i]
+	}
+	return x
+}
+
+// psdRepair returns a positive semi-definite approximation of a symmetric
+// matrix. If shift is false, the negative eigenvalues are clipped to zero
+// (the nearest PSD matrix in the Frobenius norm); else, the spectrum is
+// shifted so that the smallest eigenvalue becomes zero. The second value is
+// the smallest eigenvalue of the original matrix.
+func psdRepair(a [][]float64, shift bool) ([][]float64, float64) {
+	n := len(a)
+	values, vectors := symmetricEigen(a)
+	if n == 0 || values[n-1] >= 0 {
+		res := make([][]float64, n)
+		for i := range a {
+			res[i] = make([]float64, n)
+			copy(res[i], a[i])
+		}
+		if n == 0 {
+			return res, 0
+		}
+		return res, values[n-1]
+	}
+	minValue := values[n-1]
+	repaired := make([]float64, n)
+	for k, v := range values {
+		if shift {
+			repaired[k] = v - minValue
+		} else {
+			repaired[k] = math.Max(v, 0)
+		}
+	}
+	res := make([][]float64, n)
+	for i := range res {
+		res[i] = make([]float64, n)
+	}
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			sum := 0.0
+			for k := 0; k < n; k++ {
+				sum += vectors[i][k] * repaired[k] * vectors[j][k]
+			}
+			res[i][j], res[j][i] = sum, sum
+		}
+	}
+	return res, minValue
+}
+
+// positiveEigenspaceProjector returns the orthogonal projector onto the
+// eigenvectors of a symmetric matrix with non-negative eigenvalues, i.e., the
+// eigenspace kept by psdRepair when clipping
+func positiveEigenspaceProjector(a [][]float64) [][]float64 {
+	n := len(a)
+	values, vectors := symmetricEigen(a)
+	res := make([][]float64, n)
+	for i := range res {
+		res[i] = make([]float64, n)
+	}
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			sum := 0.0
+			for k := 0; k < n; k++ {
+				if values[k] >= 0 {
+					sum += vectors[i][k] * vectors[j][k]
+				}
+			}
+			res[i][j], res[j][i] = sum, sum
+		}
+	}
+	return res
+}
+
+// symmetricMatrixLog returns the principal logarithm of a symmetric positive
+// definite matrix, computed through its eigendecomposition. The eigenvalues
+// are bounded from below by floor, so that near-singular matrices produce a
+// finite logarithm.
+func symmetricMatrixLog(a [][]float64, floor float64) [][]float64 {
+	n := len(a)
+	values, vectors := symmetricEigen(a)
+	res := make([][]float64, n)
+	for i := range res {
+		res[i] = make([]float64, n)
+	}
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			sum := 0.0
+			for k, v := range values {
+				sum += vectors[i][k] * math.Log(math.Max(v, floor)) * vectors[j][k]
+			}
+			res[i][j], res[j][i] = sum, sum
+		}
+	}
+	return res
+}
+
+// generalizedEigenvalues returns the eigenvalues of the generalized symmetric
+// eigenproblem A x = lambda B x, B being positive definite. The problem is
+// reduced to the standard eigenproblem of L^-1 A L^-T, where B = L L^T.
+func generalizedEigenvalues(a, b [][]float64) ([]float64, error) {
+	l, err := cholesky(b)
+	if err != nil {
+		return nil, err
+	}
+	n := len(a)
+	// m = L^-1 A, computed column by column
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = make([]float64, n)
+	}
+	col := make([]float64, n)
+	for j := 0; j < n; j++ {
+		for i := 0; i < n; i++ {
+			col[i] = a[i][j]
+		}
+		x := forwardSubstitution(l, col)
+		for i := 0; i < n; i++ {
+			m[i][j] = x[i]
+		}
+	}
+	// c = L^-1 (L^-1 A)^T = L^-1 A L^-T, since A is symmetric
+	c := make([][]float64, n)
+	for i := range c {
+		c[i] = make([]float64, n)
+	}
+	for j := 0; j < n; j++ {
+		x := forwardSubstitution(l, m[j])
+		for i := 0; i < n; i++ {
+			c[i][j] = x[i]
+		}
+	}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			avg := (c[i][j] + c[j][i]) / 2.0
+			c[i][j], c[j][i] = avg, avg
+		}
+	}
+	values, _ := symmetricEigen(c)
+	return values, nil
+}
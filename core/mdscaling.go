@@ -56,13 +56,74 @@ func SerializeCoordinates(coords []DatasetCoordinates) []byte {
 	return buffer.Bytes()
 }
 
+// MDScalingType represents the algorithm utilized by the MDScaling struct
+type MDScalingType uint8
+
+const (
+	// MDScalingScriptType delegates the computation to an external script
+	MDScalingScriptType MDScalingType = iota
+	// MDScalingClassicalType executes classical (Torgerson) MDS natively, the
+	// solution of which is refined through Sammon mapping
+	MDScalingClassicalType MDScalingType = iota + 1
+	// MDScalingSMACOFType executes metric MDS through stress majorization
+	MDScalingSMACOFType MDScalingType = iota + 2
+	// MDScalingSMACOFNonMetricType executes non-metric (ordinal) MDS through
+	// stress majorization
+	MDScalingSMACOFNonMetricType MDScalingType = iota + 3
+	// MDScalingLandmarkType executes landmark MDS, utilizing only the fully
+	// calculated rows of the similarity matrix
+	MDScalingLandmarkType MDScalingType = iota + 4
+)
+
+// NewMDScalingType transforms the MDS type from a string to an MDScalingType
+// object. Unknown types fall back to classical MDS.
+func NewMDScalingType(t string) MDScalingType {
+	switch strings.ToLower(t) {
+	case "script":
+		return MDScalingScriptType
+	case "smacof":
+		return MDScalingSMACOFType
+	case "smacof-nonmetric":
+		return MDScalingSMACOFNonMetricType
+	case "landmark":
+		return MDScalingLandmarkType
+	}
+	return MDScalingClassicalType
+}
+
+func (t MDScalingType) String() string {
+	switch t {
+	case MDScalingScriptType:
+		return "script"
+	case MDScalingClassicalType:
+		return "classical"
+	case MDScalingSMACOFType:
+		return "smacof"
+	case MDScalingSMACOFNonMetricType:
+		return "smacof-nonmetric"
+	case MDScalingLandmarkType:
+		return "landmark"
+	}
+	return ""
+}
+
 // MDScaling is responsible for the execution of a MultiDimensional Scaling
 // algorithm in order to provide coefficients for each dataset, based on a
 // a similarity matrix.
 type MDScaling struct {
-	script string                   // script to be used for the execution
-	k      int                      // number of output dimensions
-	matrix *DatasetSimilarityMatrix // the similarity matrix
+	mdsType MDScalingType            // the MDS algorithm to execute
+	script  string                   // script to be used for the execution
+	k       int                      // number of output dimensions
+	matrix  *DatasetSimilarityMatrix // the similarity matrix
+
+	iterations int     // max number of iterations (SMACOF)
+	tolerance  float64 // relative stress improvement for convergence (SMACOF)
+	restarts   int     // number of random restarts (SMACOF)
+	landmarks  int     // max number of landmarks, 0 for all (landmark)
+
+	maxK         int                  // max number of dimensions (auto k)
+	gofThreshold float64              // target gof, 0 for the elbow (auto k)
+	curve        []MDScalingDimension // the gof/stress curve (auto k)
 
 	coordinates []DatasetCoordinates // the coordinates matrix
 	gof         float64              // the gof factor
@@ -72,17 +133,124 @@ type MDScaling struct {
 // NewMDScaling is the default MDScaling constructor; it initializes a new
 // MDScaling object, based on the provided DatasetSimilarities struct and the
 // k factor that determines the number of target dimensions. If k<1, then
-// auto estimation takes place.
+// auto estimation takes place. If script is empty, classical MDS is executed
+// natively, else the script is used for the computation.
 func NewMDScaling(matrix *DatasetSimilarityMatrix, k int, script string) *MDScaling {
+	if script == "" {
+		return NewMDScalingWithType(matrix, k, MDScalingClassicalType)
+	}
+	mds := NewMDScalingWithType(matrix, k, MDScalingScriptType)
+	mds.script = script
+	return mds
+}
+
+// NewMDScalingWithType initializes a new MDScaling object that executes the
+// specified MDS algorithm. Script based objects must be initialized through
+// NewMDScaling, as they need the path of the script.
+func NewMDScalingWithType(matrix *DatasetSimilarityMatrix, k int, mdsType MDScalingType) *MDScaling {
 	mds := new(MDScaling)
 	mds.matrix = matrix
 	mds.k = k
-	mds.script = script
+	mds.mdsType = mdsType
+	mds.iterations = 300
+	mds.tolerance = 1e-6
+	mds.restarts = 0
+	mds.maxK = 10
 	return mds
 }
 
+// Configure sets the parameters of the iterative MDS algorithms. Unknown
+// parameters are ignored.
+func (md *MDScaling) Configure(conf map[string]string) {
+	if val, ok := conf["iterations"]; ok {
+		conv, err := strconv.ParseInt(val, 10, 32)
+		if err != nil {
+			log.Println(err)
+		} else {
+			md.iterations = int(conv)
+		}
+	}
+	if val, ok := conf["tolerance"]; ok {
+		conv, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			log.Println(err)
+		} else {
+			md.tolerance = conv
+		}
+	}
+	if val, ok := conf["restarts"]; ok {
+		conv, err := strconv.ParseInt(val, 10, 32)
+		if err != nil {
+			log.Println(err)
+		} else {
+			md.restarts = int(conv)
+		}
+	}
+	if val, ok := conf["landmarks"]; ok {
+		conv, err := strconv.ParseInt(val, 10, 32)
+		if err != nil {
+			log.Println(err)
+		} else {
+			md.landmarks = int(conv)
+		}
+	}
+	if val, ok := conf["maxk"]; ok {
+		conv, err := strconv.ParseInt(val, 10, 32)
+		if err != nil {
+			log.Println(err)
+		} else {
+			md.maxK = int(conv)
+		}
+	}
+	if val, ok := conf["gof"]; ok {
+		conv, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			log.Println(err)
+		} else {
+			md.gofThreshold = conv
+		}
+	}
+}
+
+// Options returns a list of applicable parameters
+func (md *MDScaling) Options() map[string]string {
+	return map[string]string{
+		"iterations": "max number of SMACOF iterations (int)",
+		"tolerance":  "relative stress improvement under which SMACOF stops (float)",
+		"restarts":   "number of SMACOF executions from random configurations, apart from the classical MDS one (int)",
+		"landmarks":  "max number of landmarks, randomly chosen among the fully calculated datasets, 0 for all (int)",
+		"maxk":       "max number of dimensions examined when k is automatically selected (int)",
+		"gof":        "target gof of the automatically selected k, 0 for the elbow of the gof curve (float)",
+	}
+}
+
+// Type returns the MDS algorithm used by the object
+func (md *MDScaling) Type() MDScalingType {
+	return md.mdsType
+}
+
 // Compute functions executes the Multidimensional Scaling computation.
 func (md *MDScaling) Compute() error {
+	var err error
+	if md.k < 1 {
+		if err = md.computeAuto(); err != nil {
+			return err
+		}
+	}
+	switch md.mdsType {
+	case MDScalingClassicalType:
+		md.coordinates, md.gof, md.stress, err = md.computeClassical()
+		return err
+	case MDScalingSMACOFType, MDScalingSMACOFNonMetricType:
+		md.coordinates, md.gof, md.stress, err = md.computeSMACOF()
+		return err
+	case MDScalingLandmarkType:
+		md.coordinates, md.gof, md.stress, err = md.computeLandmark()
+		return err
+	}
+	if md.script == "" {
+		return errors.New("MDS script not set")
+	}
 	// create a temp file containing similarity matrix as a csv
 	writer, err := ioutil.TempFile("/tmp", "similarities")
 	if err != nil {
@@ -113,6 +281,18 @@ func (md *MDScaling) Compute() error {
 	return nil
 }
 
+// K returns the number of dimensions of the solution, which is set after
+// Compute when it is automatically selected
+func (md *MDScaling) K() int {
+	return md.k
+}
+
+// Curve returns the gof and stress curve examined by the automatic selection
+// of k, or nil if k was specified
+func (md *MDScaling) Curve() []MDScalingDimension {
+	return md.curve
+}
+
 // Coordinates getter returns the dataset coordinates in a nxk slice (n being
 // the number of datasets).
 func (md *MDScaling) Coordinates() []DatasetCoordinates {
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"errors"
+	"log"
+	"math"
+)
+
+// MDScalingDimension holds the quality of an MDS solution for a given number
+// of dimensions
+type MDScalingDimension struct {
+	K      int     // the number of dimensions
+	Gof    float64 // the gof factor of the solution
+	Stress float64 // the stress of the solution
+}
+
+// Sweep executes the MDS algorithm for k=1 up to maxK (if maxK<1, up to the
+// configured maxk) and returns the gof and stress curves. The sweep stops at
+// the first k for which the algorithm fails, e.g., when it exceeds the number
+// of landmarks.
+func (md *MDScaling) Sweep(maxK int) ([]MDScalingDimension, error) {
+	if maxK < 1 {
+		maxK = md.maxK
+	}
+	if n := md.matrix.Capacity() - 1; maxK < 1 || maxK > n {
+		maxK = n
+	}
+	var curve []MDScalingDimension
+	for k := 1; k <= maxK; k++ {
+		clone := *md
+		clone.k = k
+		if err := clone.Compute(); err != nil {
+			if len(curve) == 0 {
+				return nil, err
+			}
+			log.Println("Stopping the sweep at k =", k, err)
+			break
+		}
+		curve = append(curve, MDScalingDimension{K: k, Gof: clone.gof, Stress: clone.stress})
+	}
+	if len(curve) == 0 {
+		return nil, errors.New("At least two datasets are needed for the sweep")
+	}
+	return curve, nil
+}
+
+// SelectDimensions chooses the number of dimensions from the curve of a
+// sweep. If gofThreshold is positive, the smallest k that reaches it is
+// returned, else (or if no k reaches it) the elbow of the gof curve is
+// returned. The stress curve is used instead when the gof is not available.
+func SelectDimensions(curve []MDScalingDimension, gofThreshold float64) int {
+	if len(curve) == 0 {
+		return 0
+	}
+	if gofThreshold > 0 {
+		for _, c := range curve {
+			if c.Gof >= gofThreshold {
+				return c.K
+			}
+		}
+	}
+	values := make([]float64, len(curve))
+	for i, c := range curve {
+		values[i] = c.Gof
+		if math.IsNaN(c.Gof) || math.IsInf(c.Gof, 0) {
+			for j, c := range curve {
+				values[j] = c.Stress
+			}
+			break
+		}
+	}
+	return curve[curveElbow(values)].K
+}
+
+// computeAuto sweeps k, chooses the number of dimensions and sets it to the
+// MDScaling object
+func (md *MDScaling) computeAuto() error {
+	curve, err := md.Sweep(md.maxK)
+	if err != nil {
+		return err
+	}
+	md.curve = curve
+	md.k = SelectDimensions(curve, md.gofThreshold)
+	log.Println("Selected k =", md.k)
+	return nil
+}
+
+// curveElbow returns the index of the elbow of a monotone curve, i.e., the
+// point that lies the furthest from the line connecting the first and the last
+// point, after both axes are normalized to [0,1]. For less than three points,
+// the last one is returned.
+func curveElbow(values []float64) int {
+	n := len(values)
+	if n < 3 {
+		return n - 1
+	}
+	min, max := values[0], values[0]
+	for _, v := range values {
+		min, max = math.Min(min, v), math.Max(max, v)
+	}
+	if max-min == 0 {
+		return 0
+	}
+	first, last := (values[0]-min)/(max-min), (values[n-1]-min)/(max-min)
+	best, bestDistance := n-1, 0.0
+	for i, v := range values {
+		x, y := float64(i)/float64(n-1), (v-min)/(max-min)
+		// distance from the line (0,first)-(1,last), up to a constant factor
+		distance := math.Abs((last-first)*x - y + first)
+		if distance > bestDistance+1e-9 {
+			best, bestDistance = i, distance
+		}
+	}
+	return best
+}
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestCurveElbow(t *testing.T) {
+	if idx := curveElbow([]float64{0.5, 0.2, 0.05, 0.04, 0.03, 0.02}); idx != 2 {
+		t.Log("Expected elbow at 2, got", idx)
+		t.Fail()
+	}
+	if idx := curveElbow([]float64{0.5, 0.1}); idx != 1 {
+		t.Log("Expected the last point for short curves, got", idx)
+		t.Fail()
+	}
+	curve := []MDScalingDimension{{1, 0.6, 0.3}, {2, 0.85, 0.1}, {3, 0.95, 0.08}, {4, 0.99, 0.07}}
+	if k := SelectDimensions(curve, 0.9); k != 3 {
+		t.Log("Expected k=3 for the gof threshold, got", k)
+		t.Fail()
+	}
+	if k := SelectDimensions(curve, 0.999); k != 2 {
+		t.Log("Expected the elbow when the threshold is not reached, got", k)
+		t.Fail()
+	}
+}
+
+func TestMDScalingAuto(t *testing.T) {
+	points := make([]DatasetCoordinates, 30)
+	for i := range points {
+		points[i] = DatasetCoordinates{rand.Float64() * 0.3, rand.Float64() * 0.3, rand.Float64() * 0.3}
+	}
+	sm := NewDatasetSimilarities(len(points))
+	sm.IndexDisabled(true)
+	for i := range points {
+		for j := i + 1; j < len(points); j++ {
+			sm.Set(i, j, DistanceToSimilarity(euclideanDistance(points[i], points[j])))
+		}
+	}
+	for _, conf := range []map[string]string{{"maxk": "6"}, {"maxk": "6", "gof": "0.99"}} {
+		md := NewMDScalingWithType(sm, 0, MDScalingClassicalType)
+		md.Configure(conf)
+		if err := md.Compute(); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		if len(md.Curve()) != 6 {
+			t.Log("Expected a curve of 6 points, got", md.Curve())
+			t.FailNow()
+		}
+		if md.K() != 3 || len(md.Coordinates()[0]) != 3 {
+			t.Log("Expected k=3, got", md.K(), md.Curve())
+			t.Fail()
+		}
+	}
+	// the sweep stops at the number of landmarks
+	landmark := NewMDScalingWithType(sm, 0, MDScalingLandmarkType)
+	landmark.Configure(map[string]string{"landmarks": "4", "maxk": "6"})
+	curve, err := landmark.Sweep(0)
+	if err != nil || len(curve) != 3 {
+		t.Log("Expected a curve of 3 points, got", curve, err)
+		t.Fail()
+	}
+	if err := NewMDScalingWithType(NewDatasetSimilarities(1), 0, MDScalingClassicalType).Compute(); err == nil {
+		t.Log("A single dataset should be rejected")
+		t.Fail()
+	}
+}
@@ -0,0 +1,203 @@
+package core
+
+import (
+	"errors"
+	"log"
+	"math"
+)
+
+const (
+	// sammonMaxIterations is the max number of iterations of Sammon mapping
+	sammonMaxIterations = 100
+	// sammonMagic is the initial step size of Sammon mapping
+	sammonMagic = 0.2
+	// sammonTolerance is the stress decrease (per 10 iterations) under which
+	// Sammon mapping is considered to have converged
+	sammonTolerance = 1e-4
+)
+
+// computeClassical executes classical (Torgerson) MDS and refines the
+// solution through Sammon mapping, exactly as the mdscaling.R script does. It
+// returns the coordinates, the gof and the stress of the solution. If the
+// Sammon mapping cannot be applied (e.g., due to zero distances), the
+// classical MDS coordinates are returned and the stress equals the gof.
+func (md *MDScaling) computeClassical() ([]DatasetCoordinates, float64, float64, error) {
+	distances := md.distances()
+	points, gof, err := classicalMDS(distances, md.k)
+	if err != nil {
+		return nil, math.NaN(), math.NaN(), err
+	}
+	coordinates, stress, err := sammonMapping(distances, points)
+	if err != nil {
+		log.Println("Sammon mapping failed, using classical MDS solution:", err)
+		return points, gof, gof, nil
+	}
+	return coordinates, gof, stress, nil
+}
+
+// distances returns the distance matrix that corresponds to the similarity
+// matrix of the MDScaling object
+func (md *MDScaling) distances() [][]float64 {
+	n := md.matrix.Capacity()
+	distances := make([][]float64, n)
+	for i := range distances {
+		distances[i] = make([]float64, n)
+		for j := range distances[i] {
+			distances[i][j] = SimilarityToDistance(md.matrix.Get(i, j))
+		}
+	}
+	return distances
+}
+
+// classicalMDS executes classical MDS for the given distance matrix and returns
+// the coordinates in k dimensions along with the gof factor of the solution,
+// defined as the sum of the k largest eigenvalues divided by the sum of the
+// positive eigenvalues. If less than k eigenvalues are positive, the
+// remaining coordinates are set to zero.
+func classicalMDS(distances [][]float64, k int) ([]DatasetCoordinates, float64, error) {
+	n := len(distances)
+	if k < 1 || k > n-1 {
+		return nil, math.NaN(), errors.New("K factor must be between [1, n-1], n being the # of datasets")
+	}
+	values, vectors := symmetricEigen(doubleCenter(distances))
+	positiveSum, kSum := 0.0, 0.0
+	for i, v := range values {
+		if v > 0 {
+			positiveSum += v
+		}
+		if i < k {
+			kSum += v
+		}
+	}
+	if positiveSum == 0 {
+		return nil, math.NaN(), errors.New("no positive eigenvalues found")
+	}
+	coordinates := make([]DatasetCoordinates, n)
+	for i := range coordinates {
+		coordinates[i] = make(DatasetCoordinates, k)
+		for j := 0; j < k; j++ {
+			if values[j] > 0 {
+				coordinates[i][j] = vectors[i][j] * math.Sqrt(values[j])
+			}
+		}
+	}
+	return coordinates, kSum / positiveSum, nil
+}
+
+// sammonMapping applies Sammon's non-linear mapping to the provided distance
+// matrix, using the initial configuration y. It returns the new configuration
+// and the respective Sammon stress. The implementation follows the one of the
+// MASS R package.
+func sammonMapping(distances [][]float64, y []DatasetCoordinates) ([]DatasetCoordinates, float64, error) {
+	n := len(distances)
+	if n < 2 || len(y) != n {
+		return nil, math.NaN(), errors.New("invalid initial configuration")
+	}
+	tot := 0.0
+	for i := 1; i < n; i++ {
+		for j := 0; j < i; j++ {
+			if distances[i][j] <= 0 {
+				return nil, math.NaN(), errors.New("zero or negative distance between objects")
+			}
+			tot += distances[i][j]
+		}
+	}
+	k := len(y[0])
+	current := make([]DatasetCoordinates, n)
+	for i := range y {
+		current[i] = make(DatasetCoordinates, k)
+		copy(current[i], y[i])
+	}
+	stress := func(coords []DatasetCoordinates) float64 {
+		e := 0.0
+		for i := 1; i < n; i++ {
+			for j := 0; j < i; j++ {
+				ee := distances[i][j] - euclideanDistance(coords[i], coords[j])
+				e += ee * ee / distances[i][j]
+			}
+		}
+		return e / tot
+	}
+
+	e := stress(current)
+	ePast, ePrev := e, e
+	magic := sammonMagic
+	next := make([]DatasetCoordinates, n)
+	for i := range next {
+		next[i] = make(DatasetCoordinates, k)
+	}
+	e1, e2, xv := make([]float64, k), make([]float64, k), make([]float64, k)
+	for iter := 1; iter <= sammonMaxIterations; iter++ {
+		for {
+			for j := 0; j < n; j++ {
+				for m := 0; m < k; m++ {
+					e1[m], e2[m] = 0.0, 0.0
+				}
+				for l := 0; l < n; l++ {
+					if j == l {
+						continue
+					}
+					dt := distances[j][l]
+					d1 := 0.0
+					for m := 0; m < k; m++ {
+						xv[m] = current[j][m] - current[l][m]
+						d1 += xv[m] * xv[m]
+					}
+					dpj := math.Sqrt(d1)
+					if dpj == 0 {
+						return nil, math.NaN(), errors.New("configuration has duplicate points")
+					}
+					dq, dr := dt-dpj, dt*dpj
+					for m := 0; m < k; m++ {
+						e1[m] += xv[m] * dq / dr
+						e2[m] += (dq - xv[m]*xv[m]*(1.0+dq/dpj)/dpj) / dr
+					}
+				}
+				for m := 0; m < k; m++ {
+					next[j][m] = current[j][m] + magic*e1[m]/math.Abs(e2[m])
+				}
+			}
+			e = stress(next)
+			if e <= ePrev {
+				break
+			}
+			// step too large, retry with a smaller one
+			e = ePrev
+			magic *= 0.2
+			if magic <= 1.0e-3 {
+				return current, e, nil
+			}
+		}
+		magic = math.Min(magic*1.5, 0.5)
+		ePrev = e
+
+		// move the centroid to the origin and update
+		for m := 0; m < k; m++ {
+			mean := 0.0
+			for j := 0; j < n; j++ {
+				mean += next[j][m]
+			}
+			mean /= float64(n)
+			for j := 0; j < n; j++ {
+				current[j][m] = next[j][m] - mean
+			}
+		}
+
+		if iter%10 == 0 {
+			if e > ePast-sammonTolerance {
+				break
+			}
+			ePast = e
+		}
+	}
+	return current, e, nil
+}
+
+// euclideanDistance returns the euclidean distance between two points
+func euclideanDistance(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		sum += (a[i] - b[i]) * (a[i] - b[i])
+	}
+	return math.Sqrt(sum)
+}
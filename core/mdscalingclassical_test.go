@@ -0,0 +1,82 @@
+package core
+
+import (
+	"math"
+	"testing"
+)
+
+func TestClassicalMDSRecovery(t *testing.T) {
+	// points on a plane should be recovered (up to rotation) in 2 dimensions
+	points := [][]float64{{0, 0}, {1, 0}, {0, 2}, {3, 1}, {2, 2}, {1, 3}}
+	distances := make([][]float64, len(points))
+	for i := range points {
+		distances[i] = make([]float64, len(points))
+		for j := range points {
+			distances[i][j] = euclideanDistance(points[i], points[j])
+		}
+	}
+	coords, gof, err := classicalMDS(distances, 2)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if math.Abs(gof-1.0) > 1e-6 {
+		t.Log("GOF should be 1, found", gof)
+		t.Fail()
+	}
+	for i := range coords {
+		for j := range coords {
+			if d := euclideanDistance(coords[i], coords[j]); math.Abs(d-distances[i][j]) > 1e-6 {
+				t.Log("Distance not preserved", i, j, d, distances[i][j])
+				t.Fail()
+			}
+		}
+	}
+	if _, _, err := classicalMDS(distances, len(points)); err == nil {
+		t.Log("k must be less than n")
+		t.Fail()
+	}
+}
+
+func TestMDScalingClassical(t *testing.T) {
+	datasets := createPoolBasedDatasets(200, 20, 3)
+	est := NewDatasetSimilarityEstimator(SimilarityTypeJaccard, datasets)
+	est.Configure(map[string]string{
+		"concurrency": "10",
+	})
+	err := est.Compute()
+	if err != nil {
+		t.Log(err)
+		t.Fail()
+	}
+	for _, k := range []int{1, 2, 3, 4} {
+		md := NewMDScaling(est.SimilarityMatrix(), k, "")
+		if md.Type() != MDScalingClassicalType {
+			t.Log("Expected classical MDS, found", md.Type())
+			t.Fail()
+		}
+		err = md.Compute()
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		if len(md.Coordinates()) != len(datasets) || len(md.Coordinates()[0]) != k {
+			t.Log("Wrong coordinates dimensions")
+			t.FailNow()
+		}
+		if md.Gof() <= 0 || md.Gof() > 1.0+1e-9 {
+			t.Log("Invalid gof", md.Gof())
+			t.Fail()
+		}
+		if math.IsNaN(md.Stress()) || md.Stress() < 0 {
+			t.Log("Invalid stress", md.Stress())
+			t.Fail()
+		}
+	}
+	md := NewMDScalingWithType(est.SimilarityMatrix(), len(datasets), MDScalingClassicalType)
+	if md.Compute() == nil {
+		t.Log("k should be less than the number of datasets")
+		t.Fail()
+	}
+	cleanDatasets(datasets)
+}
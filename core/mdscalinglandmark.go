@@ -0,0 +1,98 @@
+package core
+
+import (
+	"errors"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// computeLandmark executes landmark MDS (de Silva and Tenenbaum): classical
+// MDS embeds the landmarks, i.e., the datasets the similarities of which have
+// been calculated for all the datasets, and the rest of the datasets are
+// placed through distance-based triangulation to the landmarks. Hence, only
+// the rows of the landmarks are needed, as computed by the approximate
+// population policy. It returns the coordinates, the gof factor of the
+// landmark embedding and the Kruskal stress-1 over the calculated distances.
+func (md *MDScaling) computeLandmark() ([]DatasetCoordinates, float64, float64, error) {
+	landmarks := md.matrix.FullyCalculated()
+	if md.landmarks > 0 && md.landmarks < len(landmarks) {
+		chosen := make([]int, md.landmarks)
+		for i, p := range rand.Perm(len(landmarks))[:md.landmarks] {
+			chosen[i] = landmarks[p]
+		}
+		sort.Ints(chosen)
+		landmarks = chosen
+	}
+	n, l := md.matrix.Capacity(), len(landmarks)
+	if md.k < 1 || md.k > l-1 {
+		return nil, math.NaN(), math.NaN(), errors.New("K factor must be between [1, l-1], l being the # of landmarks")
+	}
+	log.Printf("Landmark MDS with %d landmarks for %d datasets\n", l, n)
+
+	// the distances of the landmarks to all the datasets
+	distances := make([][]float64, l)
+	for a, idx := range landmarks {
+		distances[a] = make([]float64, n)
+		for i := range distances[a] {
+			distances[a][i] = SimilarityToDistance(md.matrix.getCalculated(idx, i))
+		}
+	}
+	landmarkDistances := make([][]float64, l)
+	means := make([]float64, l)
+	for a := range landmarkDistances {
+		landmarkDistances[a] = make([]float64, l)
+		for b, idx := range landmarks {
+			landmarkDistances[a][b] = distances[a][idx]
+			means[a] += distances[a][idx] * distances[a][idx] / float64(l)
+		}
+	}
+
+	values, vectors := symmetricEigen(doubleCenter(landmarkDistances))
+	positiveSum, kSum := 0.0, 0.0
+	for i, v := range values {
+		if v > 0 {
+			positiveSum += v
+		}
+		if i < md.k {
+			kSum += v
+		}
+	}
+	if positiveSum == 0 {
+		return nil, math.NaN(), math.NaN(), errors.New("no positive eigenvalues found")
+	}
+
+	// x = -1/2 L# (d^2 - means), L# being the pseudoinverse of the landmark
+	// coordinates
+	coordinates := make([]DatasetCoordinates, n)
+	for i := range coordinates {
+		coordinates[i] = make(DatasetCoordinates, md.k)
+		for j := 0; j < md.k; j++ {
+			if values[j] <= 0 {
+				continue
+			}
+			for a := range landmarks {
+				coordinates[i][j] += vectors[a][j] * (distances[a][i]*distances[a][i] - means[a])
+			}
+			coordinates[i][j] *= -0.5 / math.Sqrt(values[j])
+		}
+	}
+
+	diff, sum := 0.0, 0.0
+	for a, idx := range landmarks {
+		for i := range coordinates {
+			if i == idx {
+				continue
+			}
+			r := euclideanDistance(coordinates[idx], coordinates[i])
+			diff += (distances[a][i] - r) * (distances[a][i] - r)
+			sum += r * r
+		}
+	}
+	stress := math.NaN()
+	if sum > 0 {
+		stress = math.Sqrt(diff / sum)
+	}
+	return coordinates, kSum / positiveSum, stress, nil
+}
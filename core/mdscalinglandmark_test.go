@@ -0,0 +1,87 @@
+package core
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestMDScalingLandmarkEuclidean(t *testing.T) {
+	points := make([]DatasetCoordinates, 30)
+	for i := range points {
+		points[i] = DatasetCoordinates{rand.Float64() * 0.5, rand.Float64() * 0.5}
+	}
+	sm := NewDatasetSimilarities(len(points))
+	sm.IndexDisabled(true)
+	for i := range points {
+		for j := i + 1; j < len(points); j++ {
+			sm.Set(i, j, DistanceToSimilarity(euclideanDistance(points[i], points[j])))
+		}
+	}
+	// euclidean distances are embedded exactly, even by a few landmarks
+	for _, landmarks := range []string{"0", "5"} {
+		md := NewMDScalingWithType(sm, 2, MDScalingLandmarkType)
+		md.Configure(map[string]string{"landmarks": landmarks})
+		if err := md.Compute(); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		coords := md.Coordinates()
+		for i := range points {
+			for j := range points {
+				expected := euclideanDistance(points[i], points[j])
+				if math.Abs(euclideanDistance(coords[i], coords[j])-expected) > 1e-6 {
+					t.Log("Distances not preserved with", landmarks, "landmarks")
+					t.FailNow()
+				}
+			}
+		}
+		if md.Stress() > 1e-6 || math.Abs(md.Gof()-1) > 1e-6 {
+			t.Log("Wrong stress or gof", md.Stress(), md.Gof())
+			t.Fail()
+		}
+	}
+}
+
+func TestMDScalingLandmarkAprx(t *testing.T) {
+	datasets := createPoolBasedDatasets(200, 60, 3)
+	defer cleanDatasets(datasets)
+	est := NewDatasetSimilarityEstimator(SimilarityTypeJaccard, datasets)
+	est.SetPopulationPolicy(DatasetSimilarityPopulationPolicy{
+		PolicyType: PopulationPolicyAprx,
+		Parameters: map[string]float64{"count": 10},
+	})
+	est.Configure(map[string]string{"concurrency": "10"})
+	if err := est.Compute(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	landmarks := est.SimilarityMatrix().FullyCalculated()
+	if len(landmarks) == 0 || len(landmarks) > 10 {
+		t.Log("Wrong number of fully calculated datasets", len(landmarks))
+		t.FailNow()
+	}
+	md := NewMDScalingWithType(est.SimilarityMatrix(), len(landmarks), MDScalingLandmarkType)
+	if err := md.Compute(); err == nil {
+		t.Log("k must be smaller than the number of landmarks")
+		t.Fail()
+	}
+	// more dimensions represent the calculated distances better
+	stress := make(map[int]float64)
+	for _, k := range []int{1, 5} {
+		md = NewMDScalingWithType(est.SimilarityMatrix(), k, MDScalingLandmarkType)
+		if err := md.Compute(); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		if len(md.Coordinates()) != len(datasets) || len(md.Coordinates()[0]) != k || math.IsNaN(md.Stress()) {
+			t.Log("Wrong landmark MDS solution", len(md.Coordinates()), md.Stress())
+			t.FailNow()
+		}
+		stress[k] = md.Stress()
+	}
+	if stress[5] >= stress[1] {
+		t.Log("Stress should decrease with k", stress)
+		t.Fail()
+	}
+}
@@ -0,0 +1,237 @@
+package core
+
+import (
+	"errors"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// computeSMACOF executes metric or non-metric MDS through the SMACOF stress
+// majorization algorithm. The first execution starts from the classical MDS
+// solution and each restart starts from a random configuration; the solution
+// with the lowest Kruskal stress is kept. It returns the coordinates, the gof
+// factor of the classical MDS solution and the Kruskal stress-1 of the
+// returned configuration.
+func (md *MDScaling) computeSMACOF() ([]DatasetCoordinates, float64, float64, error) {
+	distances := md.distances()
+	n := len(distances)
+	if md.k < 1 || md.k > n-1 {
+		return nil, math.NaN(), math.NaN(), errors.New("K factor must be between [1, n-1], n being the # of datasets")
+	}
+	nonMetric := md.mdsType == MDScalingSMACOFNonMetricType
+
+	initial, gof, err := classicalMDS(distances, md.k)
+	if err != nil {
+		log.Println("Classical MDS failed, using random initial configuration:", err)
+		initial = smacofRandomConfiguration(distances, md.k)
+	}
+	best, bestStress := smacof(distances, initial, md.iterations, md.tolerance, nonMetric)
+	for r := 0; r < md.restarts; r++ {
+		initial = smacofRandomConfiguration(distances, md.k)
+		coords, stress := smacof(distances, initial, md.iterations, md.tolerance, nonMetric)
+		if stress < bestStress {
+			best, bestStress = coords, stress
+		}
+	}
+	return best, gof, bestStress, nil
+}
+
+// smacofRandomConfiguration returns a random nxk configuration, the scale of
+// which is comparable to the provided distances
+func smacofRandomConfiguration(distances [][]float64, k int) []DatasetCoordinates {
+	n := len(distances)
+	max := 0.0
+	for i := range distances {
+		for j := range distances[i] {
+			max = math.Max(max, distances[i][j])
+		}
+	}
+	if max == 0 {
+		max = 1.0
+	}
+	coords := make([]DatasetCoordinates, n)
+	for i := range coords {
+		coords[i] = make(DatasetCoordinates, k)
+		for j := range coords[i] {
+			coords[i][j] = (rand.Float64() - 0.5) * max
+		}
+	}
+	return coords
+}
+
+// smacof executes the SMACOF algorithm (with unit weights) for the given
+// dissimilarities, starting from the initial configuration. If nonMetric is
+// true, the dissimilarities are replaced in each iteration by the disparities
+// obtained through isotonic regression of the configuration distances on the
+// rank order of the dissimilarities. It returns the final configuration and
+// its Kruskal stress-1.
+func smacof(dissimilarities [][]float64, initial []DatasetCoordinates, iterations int, tolerance float64, nonMetric bool) ([]DatasetCoordinates, float64) {
+	n, k := len(dissimilarities), len(initial[0])
+	x := make([]DatasetCoordinates, n)
+	for i := range initial {
+		x[i] = make(DatasetCoordinates, k)
+		copy(x[i], initial[i])
+	}
+
+	// pairs holds the upper triangle indices, ordered by dissimilarity
+	pairs := make([][2]int, 0, n*(n-1)/2)
+	scale := 0.0
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			pairs = append(pairs, [2]int{i, j})
+			scale += dissimilarities[i][j] * dissimilarities[i][j]
+		}
+	}
+	sort.Stable(smacofPairOrder{pairs, dissimilarities})
+
+	disparities := make([][]float64, n)
+	for i := range disparities {
+		disparities[i] = make([]float64, n)
+		copy(disparities[i], dissimilarities[i])
+	}
+	current := configurationDistances(x)
+	if nonMetric {
+		smacofDisparities(pairs, current, disparities, scale)
+	}
+	stress := smacofRawStress(disparities, current)
+	next := make([]DatasetCoordinates, n)
+	for i := range next {
+		next[i] = make(DatasetCoordinates, k)
+	}
+	for iter := 0; iter < iterations; iter++ {
+		// Guttman transform: X = 1/n B(X) X
+		for i := 0; i < n; i++ {
+			for m := 0; m < k; m++ {
+				next[i][m] = 0.0
+			}
+			for j := 0; j < n; j++ {
+				if i == j || current[i][j] == 0 {
+					continue
+				}
+				b := disparities[i][j] / current[i][j]
+				for m := 0; m < k; m++ {
+					next[i][m] += b * (x[i][m] - x[j][m])
+				}
+			}
+			for m := 0; m < k; m++ {
+				next[i][m] /= float64(n)
+			}
+		}
+		x, next = next, x
+		current = configurationDistances(x)
+		if nonMetric {
+			smacofDisparities(pairs, current, disparities, scale)
+		}
+		newStress := smacofRawStress(disparities, current)
+		converged := stress == 0 || (stress-newStress)/stress < tolerance
+		stress = newStress
+		if converged {
+			break
+		}
+	}
+	return x, kruskalStress(disparities, current)
+}
+
+// smacofPairOrder sorts the dataset pairs according to their dissimilarities
+type smacofPairOrder struct {
+	pairs           [][2]int
+	dissimilarities [][]float64
+}
+
+func (o smacofPairOrder) Len() int { return len(o.pairs) }
+func (o smacofPairOrder) Less(i, j int) bool {
+	a, b := o.pairs[i], o.pairs[j]
+	return o.dissimilarities[a[0]][a[1]] < o.dissimilarities[b[0]][b[1]]
+}
+func (o smacofPairOrder) Swap(i, j int) { o.pairs[i], o.pairs[j] = o.pairs[j], o.pairs[i] }
+
+// smacofDisparities calculates the disparities through isotonic regression
+// (pool adjacent violators) of the configuration distances on the order of
+// the pairs, and normalizes them so that their sum of squares equals scale.
+func smacofDisparities(pairs [][2]int, distances, disparities [][]float64, scale float64) {
+	values := make([]float64, len(pairs))
+	for i, p := range pairs {
+		values[i] = distances[p[0]][p[1]]
+	}
+	fitted := isotonicRegression(values)
+	sum := 0.0
+	for _, v := range fitted {
+		sum += v * v
+	}
+	norm := 1.0
+	if sum > 0 {
+		norm = math.Sqrt(scale / sum)
+	}
+	for i, p := range pairs {
+		disparities[p[0]][p[1]] = fitted[i] * norm
+		disparities[p[1]][p[0]] = fitted[i] * norm
+	}
+}
+
+// isotonicRegression returns the non-decreasing least squares fit of the
+// values, using the pool adjacent violators algorithm
+func isotonicRegression(values []float64) []float64 {
+	means, weights := make([]float64, 0, len(values)), make([]int, 0, len(values))
+	for _, v := range values {
+		means, weights = append(means, v), append(weights, 1)
+		for l := len(means) - 1; l > 0 && means[l-1] > means[l]; l-- {
+			w := weights[l-1] + weights[l]
+			means[l-1] = (means[l-1]*float64(weights[l-1]) + means[l]*float64(weights[l])) / float64(w)
+			weights[l-1] = w
+			means, weights = means[:l], weights[:l]
+		}
+	}
+	fitted := make([]float64, 0, len(values))
+	for i, m := range means {
+		for j := 0; j < weights[i]; j++ {
+			fitted = append(fitted, m)
+		}
+	}
+	return fitted
+}
+
+// configurationDistances returns the euclidean distances between the points
+// of a configuration
+func configurationDistances(x []DatasetCoordinates) [][]float64 {
+	distances := make([][]float64, len(x))
+	for i := range x {
+		distances[i] = make([]float64, len(x))
+	}
+	for i := range x {
+		for j := i + 1; j < len(x); j++ {
+			distances[i][j] = euclideanDistance(x[i], x[j])
+			distances[j][i] = distances[i][j]
+		}
+	}
+	return distances
+}
+
+// smacofRawStress returns the raw stress between the disparities and the
+// configuration distances
+func smacofRawStress(disparities, distances [][]float64) float64 {
+	stress := 0.0
+	for i := range distances {
+		for j := i + 1; j < len(distances); j++ {
+			diff := disparities[i][j] - distances[i][j]
+			stress += diff * diff
+		}
+	}
+	return stress
+}
+
+// kruskalStress returns the Kruskal stress-1 between the disparities and the
+// configuration distances
+func kruskalStress(disparities, distances [][]float64) float64 {
+	sum := 0.0
+	for i := range distances {
+		for j := i + 1; j < len(distances); j++ {
+			sum += distances[i][j] * distances[i][j]
+		}
+	}
+	if sum == 0 {
+		return math.NaN()
+	}
+	return math.Sqrt(smacofRawStress(disparities, distances) / sum)
+}
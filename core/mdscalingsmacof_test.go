@@ -0,0 +1,75 @@
+package core
+
+import (
+	"math"
+	"testing"
+)
+
+func TestIsotonicRegression(t *testing.T) {
+	fitted := isotonicRegression([]float64{1, 3, 2, 4, 3, 5})
+	expected := []float64{1, 2.5, 2.5, 3.5, 3.5, 5}
+	for i := range expected {
+		if math.Abs(fitted[i]-expected[i]) > 1e-9 {
+			t.Log("Expected", expected, "found", fitted)
+			t.FailNow()
+		}
+	}
+}
+
+func TestSMACOFEuclidean(t *testing.T) {
+	points := []DatasetCoordinates{{0, 0}, {1, 0}, {0, 2}, {3, 1}, {2, 2}, {1, 3}, {4, 0}}
+	distances := configurationDistances(points)
+	initial, _, err := classicalMDS(distances, 2)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	for _, nonMetric := range []bool{false, true} {
+		_, stress := smacof(distances, initial, 1000, 1e-12, nonMetric)
+		if stress > 0.05 {
+			t.Log("Stress too high", stress, "non-metric:", nonMetric)
+			t.Fail()
+		}
+	}
+}
+
+func TestMDScalingSMACOF(t *testing.T) {
+	datasets := createPoolBasedDatasets(200, 20, 3)
+	est := NewDatasetSimilarityEstimator(SimilarityTypeJaccard, datasets)
+	est.Configure(map[string]string{
+		"concurrency": "10",
+	})
+	err := est.Compute()
+	if err != nil {
+		t.Log(err)
+		t.Fail()
+	}
+	for _, mdsType := range []MDScalingType{MDScalingSMACOFType, MDScalingSMACOFNonMetricType} {
+		if NewMDScalingType(mdsType.String()) != mdsType {
+			t.Log("Type not parsed correctly", mdsType)
+			t.Fail()
+		}
+		for _, k := range []int{1, 2, 3} {
+			md := NewMDScalingWithType(est.SimilarityMatrix(), k, mdsType)
+			md.Configure(map[string]string{
+				"iterations": "100",
+				"tolerance":  "1e-5",
+				"restarts":   "2",
+			})
+			err = md.Compute()
+			if err != nil {
+				t.Log(err)
+				t.FailNow()
+			}
+			if len(md.Coordinates()) != len(datasets) || len(md.Coordinates()[0]) != k {
+				t.Log("Wrong coordinates dimensions")
+				t.FailNow()
+			}
+			if math.IsNaN(md.Stress()) || md.Stress() < 0 || md.Stress() > 1 {
+				t.Log("Invalid stress", md.Stress())
+				t.Fail()
+			}
+		}
+	}
+	cleanDatasets(datasets)
+}
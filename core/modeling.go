@@ -1,6 +1,7 @@
 package core
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -19,16 +20,34 @@ import (
 type ModelerType uint8
 
 const (
-	ScriptBasedModelerType ModelerType = iota
-	KNNModelerType         ModelerType = iota + 1
+	ScriptBasedModelerType     ModelerType = iota
+	KNNModelerType             ModelerType = iota + 1
+	LinearModelerType          ModelerType = iota + 2
+	RidgeModelerType           ModelerType = iota + 3
+	RandomForestModelerType    ModelerType = iota + 4
+	GaussianProcessModelerType ModelerType = iota + 5
+	KernelRidgeModelerType     ModelerType = iota + 6
+	SVRModelerType             ModelerType = iota + 7
 )
 
 func NewModelerType(t string) ModelerType {
-	if strings.ToLower(t) == "script" {
+	switch strings.ToLower(t) {
+	case "script":
 		return ScriptBasedModelerType
-	} else {
-		return KNNModelerType
-	}
+	case "linear", "lm":
+		return LinearModelerType
+	case "ridge":
+		return RidgeModelerType
+	case "rf", "forest", "randomforest":
+		return RandomForestModelerType
+	case "gp", "gaussianprocess":
+		return GaussianProcessModelerType
+	case "krr", "kernelridge":
+		return KernelRidgeModelerType
+	case "svr":
+		return SVRModelerType
+	}
+	return KNNModelerType
 }
 
 // NewModeler is the factory method for the modeler object
@@ -49,6 +68,32 @@ func NewModeler(
 		modeler.samplingRate = sr
 		modeler.evaluator = evaluator
 		return modeler
+	} else if modelerType == LinearModelerType || modelerType == RidgeModelerType {
+		modeler := new(LinearModeler)
+		modeler.datasets = datasets
+		modeler.samplingRate = sr
+		modeler.evaluator = evaluator
+		modeler.ridge = modelerType == RidgeModelerType
+		return modeler
+	} else if modelerType == RandomForestModelerType {
+		modeler := new(RandomForestModeler)
+		modeler.datasets = datasets
+		modeler.samplingRate = sr
+		modeler.evaluator = evaluator
+		return modeler
+	} else if modelerType == GaussianProcessModelerType {
+		modeler := new(GaussianProcessModeler)
+		modeler.datasets = datasets
+		modeler.samplingRate = sr
+		modeler.evaluator = evaluator
+		return modeler
+	} else if modelerType == KernelRidgeModelerType || modelerType == SVRModelerType {
+		modeler := new(KernelModeler)
+		modeler.datasets = datasets
+		modeler.samplingRate = sr
+		modeler.evaluator = evaluator
+		modeler.svr = modelerType == SVRModelerType
+		return modeler
 	}
 	return nil
 }
@@ -67,6 +112,13 @@ type Modeler interface {
 	Samples() map[int]float64
 	// AppxValues returns a slice of the approximated values
 	AppxValues() []float64
+	// AppxVariances returns a slice of the variances of the approximated
+	// values, or nil if the modeler does not estimate them
+	AppxVariances() []float64
+
+	// SetSamplingStrategy sets the strategy used to choose the datasets that
+	// are evaluated. Call it before Run.
+	SetSamplingStrategy(SamplingStrategy)
 
 	// ErrorMetrics returns a list of error metrics for the specified modeler
 	ErrorMetrics() map[string]float64
@@ -75,6 +127,15 @@ type Modeler interface {
 	ExecTime() float64
 	// EvalTime returns the evaluation time of the Modeler
 	EvalTime() float64
+
+	// Serialize returns a byte slice containing the trained state of the
+	// Modeler
+	Serialize() []byte
+	// Deserialize reconstructs a trained Modeler from a byte slice
+	Deserialize([]byte)
+	// Predict returns the approximated value of a dataset, projected to the
+	// space of the trained Modeler
+	Predict(DatasetProjection) (float64, error)
 }
 
 // AbstractModeler implements the common methods of the Modeler structs
@@ -82,9 +143,11 @@ type AbstractModeler struct {
 	datasets  []*Dataset       // the datasets the modeler refers to
 	evaluator DatasetEvaluator // the evaluator struct that gets the values
 
-	samplingRate float64         // the portion of the datasets to examine
-	samples      map[int]float64 // the dataset indices chosen for samples
-	appxValues   []float64       // the appx values of ALL the datasets
+	samplingRate  float64          // the portion of the datasets to examine
+	samples       map[int]float64  // the dataset indices chosen for samples
+	appxValues    []float64        // the appx values of ALL the datasets
+	appxVariances []float64        // the variances of the appx values (if any)
+	sampling      SamplingStrategy // the strategy used to choose the samples
 
 	execTime float64 // the total time in seconds
 	evalTime float64 // the time needed to evaluate the datasets in seconds
@@ -105,9 +168,19 @@ func (a *AbstractModeler) AppxValues() []float64 {
 	return a.appxValues
 }
 
+// AppxVariances returns the variances of the approximated values
+func (a *AbstractModeler) AppxVariances() []float64 {
+	return a.appxVariances
+}
+
+// SetSamplingStrategy sets the strategy used to choose the samples
+func (a *AbstractModeler) SetSamplingStrategy(strategy SamplingStrategy) {
+	a.sampling = strategy
+}
+
 // ErrorMetrics returns a list of error metrics for the specified model
 func (a *AbstractModeler) ErrorMetrics() map[string]float64 {
-	if a.appxValues == nil || len(a.appxValues) == 0 {
+	if a.appxValues == nil || len(a.appxValues) == 0 || a.evaluator == nil {
 		return nil
 	}
 	errors := make(map[string]float64)
@@ -214,21 +287,33 @@ func (a *AbstractModeler) EvalTime() float64 {
 	return a.evalTime
 }
 
+// deploySamples evaluates the datasets chosen by the sampling strategy, in
+// batches, until the sampling budget is exhausted or the strategy stops. If
+// no strategy is set, the datasets are chosen uniformly at random.
 func (m *AbstractModeler) deploySamples() {
 	s := int(math.Floor(m.samplingRate * float64(len(m.datasets))))
-	// sample the datasets
-	permutation := rand.Perm(len(m.datasets))
+	strategy := m.sampling
+	if strategy == nil {
+		strategy = NewSamplingStrategy(UniformSamplingType, m.datasets)
+	}
 	m.samples = make(map[int]float64)
+	excluded := make(map[int]bool)
 	// deploy samples
-	for i := 0; i < len(permutation) && (len(m.samples) < s); i++ {
-		idx := permutation[i]
-		start2 := time.Now()
-		val, err := m.evaluator.Evaluate(m.datasets[idx].Path())
-		m.evalTime += (time.Since(start2).Seconds())
-		if err != nil {
-			log.Printf("%s: %s\n", m.datasets[idx].Path(), err.Error())
-		} else {
-			m.samples[idx] = val
+	for len(m.samples) < s {
+		batch := strategy.Next(m.samples, excluded, s-len(m.samples))
+		if len(batch) == 0 {
+			break
+		}
+		for _, idx := range batch {
+			excluded[idx] = true
+			start2 := time.Now()
+			val, err := m.evaluator.Evaluate(m.datasets[idx].Path())
+			m.evalTime += (time.Since(start2).Seconds())
+			if err != nil {
+				log.Printf("%s: %s\n", m.datasets[idx].Path(), err.Error())
+			} else {
+				m.samples[idx] = val
+			}
 		}
 	}
 }
@@ -279,7 +364,7 @@ func (m *ScriptBasedModeler) Run() error {
 		testSet = append(testSet, v)
 	}
 	testFile := createCSVFile(testSet, false)
-	appx, err := m.executeMLScript(trainFile, testFile)
+	appx, err := m.executeMLScript(trainFile, testFile, len(m.datasets))
 	if err != nil {
 		return err
 	}
@@ -292,7 +377,7 @@ func (m *ScriptBasedModeler) Run() error {
 
 // executeMLScript executes the ML script, utilizing the selected samples (indices)
 // and populates the real and appx values slices
-func (m *ScriptBasedModeler) executeMLScript(trainFile, testFile string) ([]float64, error) {
+func (m *ScriptBasedModeler) executeMLScript(trainFile, testFile string, count int) ([]float64, error) {
 	var result []float64
 	cmd := exec.Command(m.script, trainFile, testFile)
 	out, err := cmd.CombinedOutput()
@@ -301,8 +386,11 @@ func (m *ScriptBasedModeler) executeMLScript(trainFile, testFile string) ([]floa
 	}
 	outputString := string(out)
 	array := strings.Split(outputString, "\n")
-	result = make([]float64, len(m.datasets))
-	for i := 0; i < len(m.datasets); i++ {
+	if len(array) < count {
+		return nil, errors.New("The script returned fewer values than expected")
+	}
+	result = make([]float64, count)
+	for i := 0; i < count; i++ {
 		val, err := strconv.ParseFloat(array[i], 64)
 		if err != nil {
 			log.Println(err)
@@ -313,6 +401,51 @@ func (m *ScriptBasedModeler) executeMLScript(trainFile, testFile string) ([]floa
 	return result, nil
 }
 
+// Serialize returns a byte slice containing the trained state of the modeler
+func (m *ScriptBasedModeler) Serialize() []byte {
+	buffer := new(bytes.Buffer)
+	buffer.Write(getBytesInt(int(ScriptBasedModelerType)))
+	buffer.Write(abstractModelerSerialize(m.AbstractModeler))
+	writeString(buffer, m.script)
+	writeCoordinates(buffer, m.coordinates)
+	return buffer.Bytes()
+}
+
+// Deserialize reconstructs the modeler from a byte slice
+func (m *ScriptBasedModeler) Deserialize(b []byte) {
+	buffer := bytes.NewBuffer(b)
+	buffer.Next(4)
+	m.AbstractModeler = abstractModelerDeserialize(buffer)
+	m.script = readString(buffer)
+	m.coordinates = readCoordinates(buffer)
+}
+
+// Predict trains the script on the samples and returns the approximated value
+// of the projected dataset
+func (m *ScriptBasedModeler) Predict(p DatasetProjection) (float64, error) {
+	if len(m.coordinates) == 0 {
+		return math.NaN(), errors.New("The modeler is not trained")
+	}
+	coordinates, err := projectionCoordinates(p, len(m.coordinates[0]))
+	if err != nil {
+		return math.NaN(), err
+	}
+	var trainingSet [][]float64
+	for idx, val := range m.samples {
+		trainingSet = append(trainingSet, append(m.coordinates[idx], val))
+	}
+	trainFile := createCSVFile(trainingSet, true)
+	testFile := createCSVFile([][]float64{coordinates}, false)
+	defer os.Remove(trainFile)
+	defer os.Remove(testFile)
+	appx, err := m.executeMLScript(trainFile, testFile, 1)
+	if err != nil {
+		log.Println(err)
+		return math.NaN(), err
+	}
+	return appx[0], nil
+}
+
 // KNNModeler utilizes a similarity matrix in order to approximate the training set
 type KNNModeler struct {
 	AbstractModeler
@@ -375,12 +508,24 @@ func (k *KNNModeler) Run() error {
 }
 
 func (k *KNNModeler) approximateValue(id int) float64 {
-	var pList pairList
+	return k.approximate(func(j int) float64 { return k.sm.Get(id, j) })
+}
+
+// approximate estimates a value from the k most similar samples, given the
+// similarities to the samples. Ties are resolved in favor of the samples with
+// the lowest ids, so that the estimation is deterministic.
+func (k *KNNModeler) approximate(similarity func(int) float64) float64 {
+	var ids []int
 	for j := range k.samples {
-		s := k.sm.Get(id, j)
+		ids = append(ids, j)
+	}
+	sort.Ints(ids)
+	var pList pairList
+	for _, j := range ids {
+		s := similarity(j)
 		pList = append(pList, pair{j, s})
 	}
-	sort.Sort(sort.Reverse((pList)))
+	sort.Stable(sort.Reverse((pList)))
 	weights := 0.0
 	values := 0.0
 	if k.regression {
@@ -400,15 +545,56 @@ func (k *KNNModeler) approximateValue(id int) float64 {
 			vals[k.samples[p.Id]] += 1
 		}
 		maxVal, maxOcc := 0.0, 0
-		for k, v := range vals {
-			if v > maxOcc {
-				maxVal, maxOcc = k, v
+		for i := 0; i < len(pList) && i < k.k; i++ {
+			if v := k.samples[pList[i].Id]; vals[v] > maxOcc {
+				maxVal, maxOcc = v, vals[v]
 			}
 		}
 		return maxVal
 	}
 }
 
+// Serialize returns a byte slice containing the trained state of the modeler
+func (k *KNNModeler) Serialize() []byte {
+	buffer := new(bytes.Buffer)
+	buffer.Write(getBytesInt(int(KNNModelerType)))
+	buffer.Write(abstractModelerSerialize(k.AbstractModeler))
+	buffer.Write(getBytesInt(k.k))
+	if k.regression {
+		buffer.Write(getBytesInt(1))
+	} else {
+		buffer.Write(getBytesInt(0))
+	}
+	return buffer.Bytes()
+}
+
+// Deserialize reconstructs the modeler from a byte slice
+func (k *KNNModeler) Deserialize(b []byte) {
+	buffer := bytes.NewBuffer(b)
+	buffer.Next(4)
+	k.AbstractModeler = abstractModelerDeserialize(buffer)
+	tempInt := make([]byte, 4)
+	buffer.Read(tempInt)
+	k.k = getIntBytes(tempInt)
+	buffer.Read(tempInt)
+	k.regression = getIntBytes(tempInt) == 1
+}
+
+// Predict returns the approximated value of the projected dataset, based on
+// its similarities to the samples
+func (k *KNNModeler) Predict(p DatasetProjection) (float64, error) {
+	similarities, err := projectionSimilarities(p, len(k.datasets))
+	if err != nil {
+		return math.NaN(), err
+	}
+	for j := range k.samples {
+		if math.IsNaN(similarities[j]) {
+			return math.NaN(), errors.New("The similarities to the samples are missing")
+		}
+	}
+	return k.approximate(func(j int) float64 { return similarities[j] }), nil
+}
+
 type pair struct {
 	Id         int
 	Similarity float64
@@ -459,14 +645,19 @@ func createCSVFile(matrix [][]float64, output bool) string {
 	return f.Name()
 }
 
-// Return `modeler.AppxValues()` as a `DatasetScores` struct
+// Return `modeler.AppxValues()` as a `DatasetScores` struct. If the modeler
+// estimates the variances of its approximations, they are also included.
 func AppxScores(modeler Modeler) *DatasetScores {
 	appxScores := NewDatasetScores()
 	datasets := modeler.Datasets()
 	appxValues := modeler.AppxValues()
+	appxVariances := modeler.AppxVariances()
 
 	for i := range appxValues {
 		appxScores.Scores[path.Base(datasets[i].Path())] = appxValues[i]
+		if appxVariances != nil {
+			appxScores.Variances[path.Base(datasets[i].Path())] = appxVariances[i]
+		}
 	}
 
 	return appxScores
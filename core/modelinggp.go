@@ -0,0 +1,384 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gpKernel represents the covariance function of a Gaussian process
+type gpKernel uint8
+
+const (
+	gpKernelRBF      gpKernel = iota
+	gpKernelMatern32 gpKernel = iota + 1
+	gpKernelMatern52 gpKernel = iota + 2
+)
+
+// newGPKernel parses the name of a kernel
+func newGPKernel(name string) (gpKernel, error) {
+	switch strings.ToLower(name) {
+	case "rbf":
+		return gpKernelRBF, nil
+	case "matern32":
+		return gpKernelMatern32, nil
+	case "matern52":
+		return gpKernelMatern52, nil
+	}
+	return gpKernelRBF, errors.New("Unknown kernel " + name)
+}
+
+func (k gpKernel) String() string {
+	if k == gpKernelMatern32 {
+		return "matern32"
+	} else if k == gpKernelMatern52 {
+		return "matern52"
+	}
+	return "rbf"
+}
+
+// covariance returns the covariance of two points of the given distance
+func (k gpKernel) covariance(distance, lengthScale, variance float64) float64 {
+	r := distance / lengthScale
+	if k == gpKernelMatern32 {
+		return variance * (1 + math.Sqrt(3)*r) * math.Exp(-math.Sqrt(3)*r)
+	} else if k == gpKernelMatern52 {
+		return variance * (1 + math.Sqrt(5)*r + 5*r*r/3) * math.Exp(-math.Sqrt(5)*r)
+	}
+	return variance * math.Exp(-r*r/2)
+}
+
+// GaussianProcessModeler trains a Gaussian process regression model over the
+// dataset coordinates. Apart from the approximated values, the modeler
+// provides the predictive variance of each dataset. The hyperparameters of
+// the kernel are fit by maximizing the marginal likelihood of the samples.
+type GaussianProcessModeler struct {
+	AbstractModeler
+	coordinates []DatasetCoordinates // the dataset coordinates
+	kernel      gpKernel             // the covariance function
+	lengthScale float64              // the length scale of the kernel
+	variance    float64              // the signal variance of the kernel
+	noise       float64              // the noise variance of the samples
+	optimize    bool                 // true if the hyperparameters are fit
+
+	// the trained model, the values are standardized
+	x             [][]float64
+	chol          [][]float64
+	alpha         []float64
+	yMean, yStd   float64
+	logLikelihood float64
+}
+
+// Configure expects the necessary conf options for the specified struct.
+// Specifically, the following parameters are applicable:
+// - coordinates: the path of the coordinates file (mandatory)
+// - kernel: one of rbf, matern32, matern52 (default is rbf)
+// - lengthscale, variance, noise: the (initial) hyperparameters of the kernel
+// - optimize: whether to fit the hyperparameters (default is true)
+func (m *GaussianProcessModeler) Configure(conf map[string]string) error {
+	coordinates, err := modelerCoordinates(conf)
+	if err != nil {
+		return err
+	}
+	m.coordinates = coordinates
+	m.kernel = gpKernelRBF
+	if val, ok := conf["kernel"]; ok {
+		if m.kernel, err = newGPKernel(val); err != nil {
+			log.Println(err)
+			return err
+		}
+	}
+	// the length scale defaults to the median distance of the coordinates
+	m.lengthScale, m.variance, m.noise = 0.0, 1.0, 0.1
+	params := map[string]*float64{"lengthscale": &m.lengthScale, "variance": &m.variance, "noise": &m.noise}
+	for k, p := range params {
+		if val, ok := conf[k]; ok {
+			conv, err := strconv.ParseFloat(val, 64)
+			if err != nil || conv <= 0 {
+				log.Println("Invalid", k, "parameter", val)
+				return errors.New("Invalid " + k + " parameter")
+			}
+			*p = conv
+		}
+	}
+	m.optimize = true
+	if val, ok := conf["optimize"]; ok {
+		m.optimize = val != "false"
+	}
+	return nil
+}
+
+// Run executes the modeling process and populates the samples, the
+// appxValues and the appxVariances slices.
+func (m *GaussianProcessModeler) Run() error {
+	start := time.Now()
+	if len(m.coordinates) != len(m.datasets) {
+		return errors.New("The coordinates do not match the datasets")
+	}
+	m.deploySamples()
+	x, y := modelerTrainingSet(m.samples, m.coordinates)
+	if len(y) == 0 {
+		return errors.New("No samples were evaluated")
+	}
+	if err := m.fit(x, y); err != nil {
+		log.Println(err)
+		return err
+	}
+	m.appxValues = make([]float64, len(m.datasets))
+	m.appxVariances = make([]float64, len(m.datasets))
+	for i, c := range m.coordinates {
+		m.appxValues[i], m.appxVariances[i] = m.predict(c)
+	}
+	m.execTime = time.Since(start).Seconds()
+	return nil
+}
+
+// fit standardizes the samples, fits the hyperparameters (if needed) and
+// factorizes the covariance matrix of the samples
+func (m *GaussianProcessModeler) fit(x [][]float64, y []float64) error {
+	m.x = x
+	m.yMean, m.yStd = Mean(y), StdDev(y)
+	if m.yStd == 0 || math.IsNaN(m.yStd) {
+		m.yStd = 1.0
+	}
+	standardized := make([]float64, len(y))
+	for i := range y {
+		standardized[i] = (y[i] - m.yMean) / m.yStd
+	}
+	distances := make([][]float64, len(x))
+	for i := range x {
+		distances[i] = make([]float64, len(x))
+		for j := range x {
+			distances[i][j], _ = norm(x[i], x[j], 2)
+		}
+	}
+	if m.lengthScale == 0 {
+		m.lengthScale = gpMedianDistance(distances)
+	}
+	if m.optimize && len(y) > 1 {
+		m.fitHyperparameters(distances, standardized)
+	}
+	chol, alpha, lml, err := m.likelihood(distances, standardized, m.lengthScale, m.variance, m.noise)
+	if err != nil {
+		return err
+	}
+	m.chol, m.alpha, m.logLikelihood = chol, alpha, lml
+	log.Printf("GP hyperparameters: kernel %s, length scale %.5f, variance %.5f, noise %.5f (log likelihood %.5f)\n",
+		m.kernel, m.lengthScale, m.variance, m.noise, lml)
+	return nil
+}
+
+// fitHyperparameters maximizes the log marginal likelihood through the
+// Nelder-Mead method over the logarithms of the hyperparameters
+func (m *GaussianProcessModeler) fitHyperparameters(distances [][]float64, y []float64) {
+	objective := func(p []float64) float64 {
+		for _, v := range p {
+			if v < -12 || v > 12 { // keep the hyperparameters in a sane range
+				return math.Inf(1)
+			}
+		}
+		_, _, lml, err := m.likelihood(distances, y, math.Exp(p[0]), math.Exp(p[1]), math.Exp(p[2]))
+		if err != nil {
+			return math.Inf(1)
+		}
+		return -lml
+	}
+	best := []float64{math.Log(m.lengthScale), math.Log(m.variance), math.Log(m.noise)}
+	bestValue := objective(best)
+	// restarts from different length scales to avoid poor local optima
+	for _, scale := range []float64{1.0, 0.3, 3.0} {
+		start := []float64{best[0] + math.Log(scale), best[1], best[2]}
+		p := nelderMead(objective, start, 1.0, 200)
+		if v := objective(p); v < bestValue {
+			best, bestValue = p, v
+		}
+	}
+	m.lengthScale, m.variance, m.noise = math.Exp(best[0]), math.Exp(best[1]), math.Exp(best[2])
+}
+
+// likelihood returns the Cholesky factor of the covariance matrix, the
+// weights alpha = K^-1 y and the log marginal likelihood of the samples for
+// the given hyperparameters
+func (m *GaussianProcessModeler) likelihood(distances [][]float64, y []float64,
+	lengthScale, variance, noise float64) ([][]float64, []float64, float64, error) {
+	k := make([][]float64, len(y))
+	for i := range k {
+		k[i] = make([]float64, len(y))
+		for j := range k[i] {
+			k[i][j] = m.kernel.covariance(distances[i][j], lengthScale, variance)
+		}
+		k[i][i] += noise
+	}
+	chol, err := cholesky(k)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	alpha := backSubstitution(chol, forwardSubstitution(chol, y))
+	lml := -0.5 * float64(len(y)) * math.Log(2*math.Pi)
+	for i := range y {
+		lml -= 0.5*y[i]*alpha[i] + math.Log(chol[i][i])
+	}
+	return chol, alpha, lml, nil
+}
+
+// predict returns the predictive mean and variance for the given coordinates
+func (m *GaussianProcessModeler) predict(c []float64) (float64, float64) {
+	ks := make([]float64, len(m.x))
+	mean := 0.0
+	for i := range m.x {
+		d, _ := norm(c, m.x[i], 2)
+		ks[i] = m.kernel.covariance(d, m.lengthScale, m.variance)
+		mean += ks[i] * m.alpha[i]
+	}
+	v := forwardSubstitution(m.chol, ks)
+	variance := m.variance
+	for _, vi := range v {
+		variance -= vi * vi
+	}
+	if variance < 0 {
+		variance = 0
+	}
+	return m.yMean + m.yStd*mean, variance * m.yStd * m.yStd
+}
+
+// Serialize returns a byte slice containing the trained state of the
+// modeler; the model is refit from the samples upon deserialization
+func (m *GaussianProcessModeler) Serialize() []byte {
+	buffer := new(bytes.Buffer)
+	buffer.Write(getBytesInt(int(GaussianProcessModelerType)))
+	buffer.Write(abstractModelerSerialize(m.AbstractModeler))
+	buffer.Write(getBytesInt(int(m.kernel)))
+	buffer.Write(getBytesFloat(m.lengthScale))
+	buffer.Write(getBytesFloat(m.variance))
+	buffer.Write(getBytesFloat(m.noise))
+	writeCoordinates(buffer, m.coordinates)
+	return buffer.Bytes()
+}
+
+// Deserialize reconstructs the modeler from a byte slice, factorizing the
+// covariance matrix of the samples with the stored hyperparameters
+func (m *GaussianProcessModeler) Deserialize(b []byte) {
+	buffer := bytes.NewBuffer(b)
+	buffer.Next(4)
+	m.AbstractModeler = abstractModelerDeserialize(buffer)
+	tempInt, tempFloat := make([]byte, 4), make([]byte, 8)
+	buffer.Read(tempInt)
+	m.kernel = gpKernel(getIntBytes(tempInt))
+	for _, p := range []*float64{&m.lengthScale, &m.variance, &m.noise} {
+		buffer.Read(tempFloat)
+		*p = getFloatBytes(tempFloat)
+	}
+	m.coordinates = readCoordinates(buffer)
+	m.optimize = false
+	if x, y := modelerTrainingSet(m.samples, m.coordinates); len(y) > 0 {
+		if err := m.fit(x, y); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// Predict returns the predictive mean of the projected dataset
+func (m *GaussianProcessModeler) Predict(p DatasetProjection) (float64, error) {
+	if m.chol == nil {
+		return math.NaN(), errors.New("The modeler is not trained")
+	}
+	coordinates, err := projectionCoordinates(p, len(m.x[0]))
+	if err != nil {
+		return math.NaN(), err
+	}
+	mean, _ := m.predict(coordinates)
+	return mean, nil
+}
+
+// gpMedianDistance returns the median of the non zero distances, used as the
+// default length scale
+func gpMedianDistance(distances [][]float64) float64 {
+	var values []float64
+	for i := range distances {
+		for j := i + 1; j < len(distances); j++ {
+			if distances[i][j] > 0 {
+				values = append(values, distances[i][j])
+			}
+		}
+	}
+	if len(values) == 0 {
+		return 1.0
+	}
+	sort.Float64s(values)
+	return values[len(values)/2]
+}
+
+// nelderMead minimizes a function through the Nelder-Mead simplex method,
+// starting from the given point with the given initial step
+func nelderMead(f func([]float64) float64, start []float64, step float64, iterations int) []float64 {
+	n := len(start)
+	simplex := make([][]float64, n+1)
+	values := make([]float64, n+1)
+	for i := range simplex {
+		simplex[i] = make([]float64, n)
+		copy(simplex[i], start)
+		if i > 0 {
+			simplex[i][i-1] += step
+		}
+		values[i] = f(simplex[i])
+	}
+	point := func(centroid, p []float64, coeff float64) []float64 {
+		res := make([]float64, n)
+		for j := range res {
+			res[j] = centroid[j] + coeff*(p[j]-centroid[j])
+		}
+		return res
+	}
+	for it := 0; it < iterations; it++ {
+		// order the vertices by increasing value
+		for i := 1; i < len(simplex); i++ {
+			for j := i; j > 0 && values[j] < values[j-1]; j-- {
+				simplex[j], simplex[j-1] = simplex[j-1], simplex[j]
+				values[j], values[j-1] = values[j-1], values[j]
+			}
+		}
+		if math.Abs(values[n]-values[0]) < 1e-8 {
+			break
+		}
+		centroid := make([]float64, n)
+		for _, p := range simplex[:n] {
+			for j := range centroid {
+				centroid[j] += p[j] / float64(n)
+			}
+		}
+		reflected := point(centroid, simplex[n], -1)
+		fr := f(reflected)
+		if fr < values[0] {
+			expanded := point(centroid, simplex[n], -2)
+			if fe := f(expanded); fe < fr {
+				simplex[n], values[n] = expanded, fe
+			} else {
+				simplex[n], values[n] = reflected, fr
+			}
+		} else if fr < values[n-1] {
+			simplex[n], values[n] = reflected, fr
+		} else {
+			contracted := point(centroid, simplex[n], 0.5)
+			if fc := f(contracted); fc < values[n] {
+				simplex[n], values[n] = contracted, fc
+			} else { // shrink towards the best vertex
+				for i := 1; i < len(simplex); i++ {
+					simplex[i] = point(simplex[0], simplex[i], 0.5)
+					values[i] = f(simplex[i])
+				}
+			}
+		}
+	}
+	best := 0
+	for i := range values {
+		if values[i] < values[best] {
+			best = i
+		}
+	}
+	return simplex[best]
+}
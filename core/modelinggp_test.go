@@ -0,0 +1,83 @@
+package core
+
+import (
+	"math"
+	"os"
+	"testing"
+)
+
+func TestGaussianProcessModeler(t *testing.T) {
+	datasets := createPoolBasedDatasets(100, 60, 2)
+	defer cleanDatasets(datasets)
+	coords, eval := createRegressionSetup(datasets, func(x []float64) float64 {
+		return math.Sin(3*x[0]) + x[1]*x[1] - x[2]
+	})
+	defer os.Remove(coords)
+
+	m := NewModeler(GaussianProcessModelerType, datasets, 0.5, eval)
+	if err := m.Configure(map[string]string{"coordinates": coords, "kernel": "foo"}); err == nil {
+		t.Log("Unknown kernel should be rejected")
+		t.Fail()
+	}
+	if err := m.Configure(map[string]string{"coordinates": coords, "noise": "-1"}); err == nil {
+		t.Log("Negative noise should be rejected")
+		t.Fail()
+	}
+	for _, kernel := range []string{"rbf", "matern32", "matern52"} {
+		if err := m.Configure(map[string]string{"coordinates": coords, "kernel": kernel}); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		if err := m.Run(); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		if len(m.AppxValues()) != len(datasets) || len(m.AppxVariances()) != len(datasets) {
+			t.Log("Wrong number of approximated values or variances")
+			t.FailNow()
+		}
+		if r2 := m.ErrorMetrics()["R^2-unknown"]; r2 < 0.9 {
+			t.Log("Smooth function not approximated with", kernel, "kernel, R^2:", r2)
+			t.Fail()
+		}
+		// the samples are more certain than the unknown datasets
+		sampled, unknown := 0.0, 0.0
+		for i, v := range m.AppxVariances() {
+			if v < 0 {
+				t.Log("Negative variance", v)
+				t.Fail()
+			}
+			if _, ok := m.Samples()[i]; ok {
+				sampled += v / float64(len(m.Samples()))
+			} else {
+				unknown += v / float64(len(datasets)-len(m.Samples()))
+			}
+		}
+		if sampled >= unknown {
+			t.Log("The variance of the samples should be smaller", sampled, unknown)
+			t.Fail()
+		}
+	}
+
+	scores := AppxScores(m)
+	if len(scores.Variances) != len(datasets) {
+		t.Log("The variances are not included in the scores")
+		t.Fail()
+	}
+	if AppxScores(NewModeler(KNNModelerType, datasets, 0.5, eval)).Variances == nil {
+		t.Log("The variances map should be initialized")
+		t.Fail()
+	}
+}
+
+func TestNelderMead(t *testing.T) {
+	// the Rosenbrock function, minimized at (1,1)
+	f := func(x []float64) float64 {
+		return (1-x[0])*(1-x[0]) + 100*(x[1]-x[0]*x[0])*(x[1]-x[0]*x[0])
+	}
+	x := nelderMead(f, []float64{-1.0, 2.0}, 0.5, 2000)
+	if math.Abs(x[0]-1) > 1e-2 || math.Abs(x[1]-1) > 1e-2 {
+		t.Log("Wrong minimum", x)
+		t.Fail()
+	}
+}
@@ -0,0 +1,306 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"log"
+	"math"
+	"strconv"
+	"time"
+)
+
+// KernelModeler treats the similarity matrix as a precomputed kernel and
+// trains a kernel ridge regression or a support vector regression model on
+// it, without projecting the datasets to a coordinate space. Since the
+// similarity matrix is not necessarily positive semi-definite, it is repaired
+// before the training, either by clipping its negative eigenvalues or by
+// shifting its spectrum. The similarities of the predicted datasets are
+// corrected accordingly: when clipping, they are projected onto the kept
+// eigenvectors (Nystrom extension), whereas the shift only alters the
+// diagonal of the kernel and leaves them intact.
+type KernelModeler struct {
+	AbstractModeler
+	sm      *DatasetSimilarityMatrix // the similarity matrix
+	svr     bool                     // true for SVR, false for kernel ridge
+	lambda  float64                  // the regularization of kernel ridge
+	c       float64                  // the box constraint of SVR
+	epsilon float64                  // the width of the insensitive zone of SVR
+	shift   bool                     // true if the spectrum is shifted
+
+	kernel       [][]float64 // the repaired kernel matrix
+	projector    [][]float64 // the projector onto the kept eigenvectors (clip only)
+	weights      []float64   // the dual coefficients of the samples
+	coefficients []float64   // the coefficients of the similarities to all datasets
+	bias         float64     // the mean of the samples
+}
+
+// Configure expects the necessary conf options for the specified struct.
+// Specifically, the following parameters are applicable:
+// - smatrix: the path of the similarity matrix file (mandatory)
+// - repair: the PSD repair method, clip or shift (default is clip)
+// - lambda: the regularization parameter of kernel ridge (default is 0.1)
+// - c: the box constraint of SVR (default is 1.0)
+// - epsilon: the insensitive zone of SVR, relative to the std of the samples (default is 0.1)
+func (m *KernelModeler) Configure(conf map[string]string) error {
+	val, ok := conf["smatrix"]
+	if !ok {
+		log.Println("No smatrix parameter provided")
+		return errors.New("No smatrix parameter provided")
+	}
+	buf, err := ioutil.ReadFile(val)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	m.sm = new(DatasetSimilarityMatrix)
+	if err := m.sm.Deserialize(buf); err != nil {
+		log.Println(err)
+		return err
+	}
+	m.shift = false
+	if val, ok := conf["repair"]; ok {
+		if val != "clip" && val != "shift" {
+			log.Println("Invalid repair parameter", val)
+			return errors.New("Invalid repair parameter")
+		}
+		m.shift = val == "shift"
+	}
+	m.lambda, m.c, m.epsilon = 0.1, 1.0, 0.1
+	params := map[string]*float64{"lambda": &m.lambda, "c": &m.c, "epsilon": &m.epsilon}
+	for k, p := range params {
+		if val, ok := conf[k]; ok {
+			conv, err := strconv.ParseFloat(val, 64)
+			if err != nil || conv < 0 {
+				log.Println("Invalid", k, "parameter", val)
+				return errors.New("Invalid " + k + " parameter")
+			}
+			*p = conv
+		}
+	}
+	if m.c == 0 {
+		return errors.New("c parameter must be positive")
+	}
+	return nil
+}
+
+// Run executes the modeling process and populates the samples and the
+// appxValues slices.
+func (m *KernelModeler) Run() error {
+	start := time.Now()
+	if m.sm == nil || m.sm.Capacity() != len(m.datasets) {
+		return errors.New("The similarity matrix does not match the datasets")
+	}
+	m.repairKernel()
+	m.deploySamples()
+	indices := m.sampleIndices()
+	if len(indices) == 0 {
+		return errors.New("No samples were evaluated")
+	}
+	y := make([]float64, len(indices))
+	for i, idx := range indices {
+		y[i] = m.samples[idx]
+	}
+	m.bias = Mean(y)
+	for i := range y {
+		y[i] -= m.bias
+	}
+	var err error
+	if m.svr {
+		m.weights = m.fitSVR(indices, y)
+	} else {
+		m.weights, err = m.fitRidge(indices, y)
+	}
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	m.coefficients = make([]float64, len(m.datasets))
+	for s, idx := range indices {
+		if m.projector == nil {
+			m.coefficients[idx] += m.weights[s]
+			continue
+		}
+		for j := range m.coefficients {
+			m.coefficients[j] += m.weights[s] * m.projector[idx][j]
+		}
+	}
+	m.appxValues = make([]float64, len(m.datasets))
+	for i := range m.datasets {
+		m.appxValues[i] = m.bias
+		for s, idx := range indices {
+			m.appxValues[i] += m.weights[s] * m.kernel[i][idx]
+		}
+	}
+	m.execTime = time.Since(start).Seconds()
+	return nil
+}
+
+// sampleIndices returns the indices of the evaluated samples, in ascending
+// order
+func (m *KernelModeler) sampleIndices() []int {
+	var indices []int
+	for i := range m.datasets {
+		if val, ok := m.samples[i]; ok && !math.IsNaN(val) {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// Serialize returns a byte slice containing the trained state of the modeler
+func (m *KernelModeler) Serialize() []byte {
+	buffer := new(bytes.Buffer)
+	if m.svr {
+		buffer.Write(getBytesInt(int(SVRModelerType)))
+	} else {
+		buffer.Write(getBytesInt(int(KernelRidgeModelerType)))
+	}
+	buffer.Write(abstractModelerSerialize(m.AbstractModeler))
+	for _, v := range []float64{m.lambda, m.c, m.epsilon, m.bias} {
+		buffer.Write(getBytesFloat(v))
+	}
+	if m.shift {
+		buffer.Write(getBytesInt(1))
+	} else {
+		buffer.Write(getBytesInt(0))
+	}
+	writeFloats(buffer, m.weights)
+	writeFloats(buffer, m.coefficients)
+	return buffer.Bytes()
+}
+
+// Deserialize reconstructs the modeler from a byte slice
+func (m *KernelModeler) Deserialize(b []byte) {
+	buffer := bytes.NewBuffer(b)
+	tempInt, tempFloat := make([]byte, 4), make([]byte, 8)
+	buffer.Read(tempInt)
+	m.svr = ModelerType(getIntBytes(tempInt)) == SVRModelerType
+	m.AbstractModeler = abstractModelerDeserialize(buffer)
+	for _, p := range []*float64{&m.lambda, &m.c, &m.epsilon, &m.bias} {
+		buffer.Read(tempFloat)
+		*p = getFloatBytes(tempFloat)
+	}
+	buffer.Read(tempInt)
+	m.shift = getIntBytes(tempInt) == 1
+	m.weights = readFloats(buffer)
+	m.coefficients = readFloats(buffer)
+	if indices := m.sampleIndices(); len(m.coefficients) == 0 && len(indices) == len(m.weights) {
+		// models serialized without the coefficients use the raw similarities
+		m.coefficients = make([]float64, len(m.datasets))
+		for s, idx := range indices {
+			m.coefficients[idx] = m.weights[s]
+		}
+	}
+}
+
+// Predict returns the approximated value of the projected dataset, using its
+// similarities to the datasets, corrected as the repaired kernel, as the
+// kernel values
+func (m *KernelModeler) Predict(p DatasetProjection) (float64, error) {
+	similarities, err := projectionSimilarities(p, len(m.datasets))
+	if err != nil {
+		return math.NaN(), err
+	}
+	if len(m.coefficients) != len(similarities) {
+		return math.NaN(), errors.New("The modeler is not trained")
+	}
+	value := m.bias
+	for j, c := range m.coefficients {
+		if c == 0 {
+			continue
+		}
+		if math.IsNaN(similarities[j]) {
+			return math.NaN(), errors.New("The similarities to the datasets are missing")
+		}
+		value += c * similarities[j]
+	}
+	return value, nil
+}
+
+// repairKernel symmetrizes the similarity matrix and makes it positive
+// semi-definite
+func (m *KernelModeler) repairKernel() {
+	n := len(m.datasets)
+	k := make([][]float64, n)
+	for i := range k {
+		k[i] = make([]float64, n)
+		for j := range k[i] {
+			k[i][j] = (m.sm.Get(i, j) + m.sm.Get(j, i)) / 2
+		}
+	}
+	var minValue float64
+	m.kernel, minValue = psdRepair(k, m.shift)
+	m.projector = nil
+	if minValue < 0 {
+		log.Printf("Kernel repaired, smallest eigenvalue was %.5f\n", minValue)
+		if !m.shift {
+			m.projector = positiveEigenspaceProjector(k)
+		}
+	}
+}
+
+// fitRidge solves the kernel ridge regression problem, (K + lambda I) a = y
+func (m *KernelModeler) fitRidge(indices []int, y []float64) ([]float64, error) {
+	a := make([][]float64, len(indices))
+	for i, idxA := range indices {
+		a[i] = make([]float64, len(indices))
+		for j, idxB := range indices {
+			a[i][j] = m.kernel[idxA][idxB]
+		}
+		a[i][i] += m.lambda
+	}
+	if l, err := cholesky(a); err == nil {
+		return backSubstitution(l, forwardSubstitution(l, y)), nil
+	}
+	weights, err := solveLinearSystem(a, y)
+	if err != nil {
+		return nil, errors.New("Kernel ridge regression failed (" + err.Error() + "), try a positive lambda")
+	}
+	return weights, nil
+}
+
+// fitSVR solves the dual problem of the epsilon-SVR through coordinate
+// descent. The problem is solved without the bias term, since the samples
+// are centered:
+// min 1/2 b'Kb - y'b + eps |b|_1, subject to -C <= b_i <= C
+func (m *KernelModeler) fitSVR(indices []int, y []float64) []float64 {
+	epsilon := m.epsilon * StdDev(y)
+	if math.IsNaN(epsilon) {
+		epsilon = 0
+	}
+	beta, grad := make([]float64, len(y)), make([]float64, len(y))
+	for i := range grad {
+		grad[i] = -y[i]
+	}
+	for it := 0; it < 1000; it++ {
+		maxChange := 0.0
+		for i, idx := range indices {
+			kii := m.kernel[idx][idx]
+			if kii <= 0 {
+				continue
+			}
+			// the exact minimizer of the problem over the i-th coordinate
+			z := beta[i] - grad[i]/kii
+			threshold := epsilon / kii
+			updated := 0.0
+			if z > threshold {
+				updated = z - threshold
+			} else if z < -threshold {
+				updated = z + threshold
+			}
+			updated = math.Max(-m.c, math.Min(m.c, updated))
+			if delta := updated - beta[i]; delta != 0 {
+				for j, idxB := range indices {
+					grad[j] += delta * m.kernel[idxB][idx]
+				}
+				beta[i] = updated
+				maxChange = math.Max(maxChange, math.Abs(delta))
+			}
+		}
+		if maxChange < 1e-8 {
+			break
+		}
+	}
+	return beta
+}
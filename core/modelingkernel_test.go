@@ -0,0 +1,147 @@
+package core
+
+import (
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+// createKernelSimilarityMatrix returns a similarity matrix file holding the
+// (noisy) RBF kernel of the given coordinates
+func createKernelSimilarityMatrix(coordsPath string, noise float64) string {
+	buf, _ := ioutil.ReadFile(coordsPath)
+	coords := DeserializeCoordinates(buf)
+	sm := NewDatasetSimilarities(len(coords))
+	for i := range coords {
+		for j := i; j < len(coords); j++ {
+			d, _ := norm(coords[i], coords[j], 2)
+			val := math.Exp(-d * d / 0.5)
+			if i != j {
+				val = math.Max(0, math.Min(1, val+noise*(rand.Float64()-0.5)))
+			}
+			sm.Set(i, j, val)
+		}
+	}
+	f, _ := ioutil.TempFile("/tmp", "smatrix")
+	f.Write(sm.Serialize())
+	f.Close()
+	return f.Name()
+}
+
+func TestPSDRepair(t *testing.T) {
+	a := [][]float64{{1, 2}, {2, 1}} // eigenvalues 3 and -1
+	clipped, minValue := psdRepair(a, false)
+	if math.Abs(minValue+1) > 1e-9 {
+		t.Log("Wrong smallest eigenvalue", minValue)
+		t.Fail()
+	}
+	shifted, _ := psdRepair(a, true)
+	for i := range a {
+		for j := range a[i] {
+			if math.Abs(clipped[i][j]-1.5) > 1e-9 || math.Abs(shifted[i][j]-2) > 1e-9 {
+				t.Log("Wrong repaired matrices", clipped, shifted)
+				t.FailNow()
+			}
+		}
+	}
+	psd := [][]float64{{2, 1}, {1, 2}}
+	if res, _ := psdRepair(psd, false); res[0][1] != 1 || res[1][1] != 2 {
+		t.Log("PSD matrices should not be modified", res)
+		t.Fail()
+	}
+}
+
+func TestKernelModeler(t *testing.T) {
+	rand.Seed(1)
+	datasets := createPoolBasedDatasets(100, 80, 2)
+	defer cleanDatasets(datasets)
+	coords, eval := createRegressionSetup(datasets, func(x []float64) float64 {
+		return math.Sin(3*x[0]) + x[1] - x[2]
+	})
+	defer os.Remove(coords)
+	smFile := createKernelSimilarityMatrix(coords, 0.05)
+	defer os.Remove(smFile)
+
+	m := NewModeler(KernelRidgeModelerType, datasets, 0.5, eval)
+	if err := m.Configure(map[string]string{}); err == nil {
+		t.Log("Missing smatrix should be rejected")
+		t.Fail()
+	}
+	if err := m.Configure(map[string]string{"smatrix": smFile, "repair": "foo"}); err == nil {
+		t.Log("Invalid repair method should be rejected")
+		t.Fail()
+	}
+	for _, modelerType := range []ModelerType{KernelRidgeModelerType, SVRModelerType} {
+		for _, repair := range []string{"clip", "shift"} {
+			m := NewModeler(modelerType, datasets, 0.5, eval)
+			conf := map[string]string{"smatrix": smFile, "repair": repair, "lambda": "0.01", "c": "10"}
+			if err := m.Configure(conf); err != nil {
+				t.Log(err)
+				t.FailNow()
+			}
+			if err := m.Run(); err != nil {
+				t.Log(err)
+				t.FailNow()
+			}
+			if len(m.AppxValues()) != len(datasets) {
+				t.Log("Wrong number of approximated values")
+				t.FailNow()
+			}
+			if r2 := m.ErrorMetrics()["R^2-unknown"]; r2 < 0.7 {
+				t.Log("Function not approximated by", modelerType, repair, "R^2:", r2)
+				t.Fail()
+			}
+		}
+	}
+}
+
+func TestKernelModelerPredictRepaired(t *testing.T) {
+	rand.Seed(1)
+	datasets := createPoolBasedDatasets(100, 40, 2)
+	defer cleanDatasets(datasets)
+	coords, eval := createRegressionSetup(datasets, func(x []float64) float64 {
+		return x[0] - x[1]
+	})
+	defer os.Remove(coords)
+	// the noise makes the kernel indefinite, so that clipping alters it
+	smFile := createKernelSimilarityMatrix(coords, 0.8)
+	defer os.Remove(smFile)
+	for _, repair := range []string{"clip", "shift"} {
+		m := NewModeler(KernelRidgeModelerType, datasets, 0.5, eval).(*KernelModeler)
+		if err := m.Configure(map[string]string{"smatrix": smFile, "repair": repair}); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		if err := m.Run(); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		if repair == "clip" && m.projector == nil {
+			t.Log("The kernel was not repaired")
+			t.FailNow()
+		}
+		// the raw similarities of a modeled dataset must lead to the value
+		// computed by the repaired kernel
+		restored := DeserializeModeler(m.Serialize())
+		for i := range datasets {
+			if _, ok := m.samples[i]; ok && repair == "shift" {
+				continue // the shift alters the self-similarity of the samples
+			}
+			similarities := make([]float64, len(datasets))
+			for j := range similarities {
+				similarities[j] = m.sm.Get(i, j)
+			}
+			val, err := restored.Predict(DatasetProjection{Similarities: similarities})
+			if err != nil {
+				t.Log(err)
+				t.FailNow()
+			}
+			if math.Abs(val-m.AppxValues()[i]) > 1e-6 {
+				t.Log(repair, "prediction differs from the modeled value", i, val, m.AppxValues()[i])
+				t.FailNow()
+			}
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"math"
+	"sort"
+	"strings"
+)
+
+// DatasetProjection represents a (new) dataset in the space of a trained
+// modeler. The coordinate based modelers expect the coordinates of the
+// dataset, whereas the similarity based modelers (KNN, kernel) expect its
+// similarities to the datasets of the modeler, indexed as Datasets(); only
+// the similarities to the samples are used.
+type DatasetProjection struct {
+	Coordinates  DatasetCoordinates
+	Similarities []float64
+}
+
+// DeserializeModeler returns a trained modeler based on a byte array, created
+// by the Serialize method of the modeler
+func DeserializeModeler(b []byte) Modeler {
+	if len(b) < 4 {
+		log.Println("Malformed modeler stream")
+		return nil
+	}
+	modeler := NewModeler(ModelerType(getIntBytes(b[0:4])), nil, 0, nil)
+	if modeler == nil {
+		log.Println("Unsupported Modeler Type.")
+		return nil
+	}
+	modeler.Deserialize(b)
+	return modeler
+}
+
+// PredictDataset places a new dataset in the space of a trained modeler and
+// returns its predicted value. The similarities of the dataset to the samples
+// are computed through the estimator, whereas its coordinates are computed
+// through the indexer; each one is only needed by the respective modelers.
+func PredictDataset(modeler Modeler, dataset *Dataset,
+	estimator DatasetSimilarityEstimator, indexer *OnlineIndexer) (float64, error) {
+	var projection DatasetProjection
+	if modelerUsesCoordinates(modeler) {
+		if indexer == nil {
+			return math.NaN(), errors.New("An indexer is needed to compute the coordinates")
+		}
+		coordinates, _, err := indexer.Calculate(dataset)
+		if err != nil {
+			log.Println(err)
+			return math.NaN(), err
+		}
+		projection.Coordinates = coordinates
+	} else {
+		if estimator == nil {
+			return math.NaN(), errors.New("An estimator is needed to compute the similarities")
+		}
+		projection.Similarities = make([]float64, len(modeler.Datasets()))
+		for i := range projection.Similarities {
+			projection.Similarities[i] = math.NaN()
+		}
+		for idx := range modeler.Samples() {
+			projection.Similarities[idx] = estimator.Similarity(dataset, modeler.Datasets()[idx])
+		}
+	}
+	return modeler.Predict(projection)
+}
+
+// modelerUsesCoordinates returns true if the modeler expects the coordinates
+// of the datasets, false if it expects their similarities
+func modelerUsesCoordinates(modeler Modeler) bool {
+	switch modeler.(type) {
+	case *KNNModeler, *KernelModeler:
+		return false
+	}
+	return true
+}
+
+// projectionCoordinates checks the coordinates of a projection
+func projectionCoordinates(p DatasetProjection, dimensions int) (DatasetCoordinates, error) {
+	if len(p.Coordinates) != dimensions {
+		return nil, errors.New("The coordinates of the dataset do not match the model")
+	}
+	return p.Coordinates, nil
+}
+
+// projectionSimilarities checks the similarities of a projection
+func projectionSimilarities(p DatasetProjection, datasets int) ([]float64, error) {
+	if len(p.Similarities) != datasets {
+		return nil, errors.New("The similarities of the dataset do not match the model")
+	}
+	return p.Similarities, nil
+}
+
+// abstractModelerSerialize returns a byte array containing the common fields
+// of the modelers, prefixed by its length
+func abstractModelerSerialize(a AbstractModeler) []byte {
+	buffer := new(bytes.Buffer)
+	buffer.Write(getBytesInt(len(a.datasets)))
+	for _, d := range a.datasets {
+		buffer.WriteString(d.Path() + "\n")
+	}
+	buffer.Write(getBytesFloat(a.samplingRate))
+	var indices []int
+	for idx := range a.samples {
+		indices = append(indices, idx)
+	}
+	sort.Ints(indices)
+	buffer.Write(getBytesInt(len(indices)))
+	for _, idx := range indices {
+		buffer.Write(getBytesInt(idx))
+		buffer.Write(getBytesFloat(a.samples[idx]))
+	}
+	writeFloats(buffer, a.appxValues)
+	if a.appxVariances != nil {
+		buffer.Write(getBytesInt(1))
+		writeFloats(buffer, a.appxVariances)
+	} else {
+		buffer.Write(getBytesInt(0))
+	}
+	buffer.Write(getBytesFloat(a.execTime))
+	buffer.Write(getBytesFloat(a.evalTime))
+	cnt := buffer.Bytes()
+	return append(getBytesInt(len(cnt)), cnt...)
+}
+
+// abstractModelerDeserialize consumes the common fields of the modelers from
+// the buffer
+func abstractModelerDeserialize(buffer *bytes.Buffer) AbstractModeler {
+	var a AbstractModeler
+	tempInt, tempFloat := make([]byte, 4), make([]byte, 8)
+	buffer.Read(tempInt)
+	content := bytes.NewBuffer(buffer.Next(getIntBytes(tempInt)))
+
+	content.Read(tempInt)
+	a.datasets = make([]*Dataset, getIntBytes(tempInt))
+	for i := range a.datasets {
+		line, _ := content.ReadString('\n')
+		a.datasets[i] = NewDataset(strings.TrimSpace(line))
+	}
+	content.Read(tempFloat)
+	a.samplingRate = getFloatBytes(tempFloat)
+	content.Read(tempInt)
+	count := getIntBytes(tempInt)
+	a.samples = make(map[int]float64)
+	for i := 0; i < count; i++ {
+		content.Read(tempInt)
+		content.Read(tempFloat)
+		a.samples[getIntBytes(tempInt)] = getFloatBytes(tempFloat)
+	}
+	a.appxValues = readFloats(content)
+	content.Read(tempInt)
+	if getIntBytes(tempInt) == 1 {
+		a.appxVariances = readFloats(content)
+	}
+	content.Read(tempFloat)
+	a.execTime = getFloatBytes(tempFloat)
+	content.Read(tempFloat)
+	a.evalTime = getFloatBytes(tempFloat)
+	return a
+}
+
+// writeFloats writes a float slice, prefixed by its length
+func writeFloats(buffer *bytes.Buffer, values []float64) {
+	buffer.Write(getBytesInt(len(values)))
+	for _, v := range values {
+		buffer.Write(getBytesFloat(v))
+	}
+}
+
+// readFloats reads a float slice written by writeFloats
+func readFloats(buffer *bytes.Buffer) []float64 {
+	tempInt, tempFloat := make([]byte, 4), make([]byte, 8)
+	buffer.Read(tempInt)
+	values := make([]float64, getIntBytes(tempInt))
+	for i := range values {
+		buffer.Read(tempFloat)
+		values[i] = getFloatBytes(tempFloat)
+	}
+	return values
+}
+
+// writeCoordinates writes the dataset coordinates
+func writeCoordinates(buffer *bytes.Buffer, coordinates []DatasetCoordinates) {
+	buffer.Write(getBytesInt(len(coordinates)))
+	for _, c := range coordinates {
+		writeFloats(buffer, c)
+	}
+}
+
+// readCoordinates reads the dataset coordinates written by writeCoordinates
+func readCoordinates(buffer *bytes.Buffer) []DatasetCoordinates {
+	tempInt := make([]byte, 4)
+	buffer.Read(tempInt)
+	coordinates := make([]DatasetCoordinates, getIntBytes(tempInt))
+	for i := range coordinates {
+		coordinates[i] = readFloats(buffer)
+	}
+	return coordinates
+}
+
+// writeString writes a string, prefixed by its length
+func writeString(buffer *bytes.Buffer, s string) {
+	buffer.Write(getBytesInt(len(s)))
+	buffer.WriteString(s)
+}
+
+// readString reads a string written by writeString
+func readString(buffer *bytes.Buffer) string {
+	tempInt := make([]byte, 4)
+	buffer.Read(tempInt)
+	return string(buffer.Next(getIntBytes(tempInt)))
+}
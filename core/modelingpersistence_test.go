@@ -0,0 +1,147 @@
+package core
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestModelerSerialization(t *testing.T) {
+	datasets := createPoolBasedDatasets(100, 40, 2)
+	defer cleanDatasets(datasets)
+	coords, eval := createRegressionSetup(datasets, func(x []float64) float64 {
+		return math.Sin(3*x[0]) + x[1] - x[2]
+	})
+	defer os.Remove(coords)
+	smFile := createKernelSimilarityMatrix(coords, 0.0)
+	defer os.Remove(smFile)
+	buf, _ := ioutil.ReadFile(coords)
+	coordinates := DeserializeCoordinates(buf)
+	buf, _ = ioutil.ReadFile(smFile)
+	sm := new(DatasetSimilarityMatrix)
+	sm.Deserialize(buf)
+
+	confs := map[ModelerType]map[string]string{
+		KNNModelerType:             {"k": "3", "smatrix": smFile},
+		LinearModelerType:          {"coordinates": coords},
+		RidgeModelerType:           {"coordinates": coords},
+		RandomForestModelerType:    {"coordinates": coords, "trees": "10", "leaf": "2"},
+		GaussianProcessModelerType: {"coordinates": coords},
+		KernelRidgeModelerType:     {"smatrix": smFile, "lambda": "0.01"},
+		SVRModelerType:             {"smatrix": smFile, "c": "10"},
+	}
+	for modelerType, conf := range confs {
+		m := NewModeler(modelerType, datasets, 0.5, eval)
+		if err := m.Configure(conf); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		if err := m.Run(); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		restored := DeserializeModeler(m.Serialize())
+		if restored == nil {
+			t.Log("Modeler", modelerType, "not deserialized")
+			t.FailNow()
+		}
+		if len(restored.Datasets()) != len(datasets) || len(restored.Samples()) != len(m.Samples()) ||
+			len(restored.AppxValues()) != len(m.AppxValues()) {
+			t.Log("Modeler", modelerType, "not restored")
+			t.FailNow()
+		}
+		for i, d := range restored.Datasets() {
+			if d.Path() != datasets[i].Path() || restored.AppxValues()[i] != m.AppxValues()[i] {
+				t.Log("Modeler", modelerType, "restored with wrong values")
+				t.FailNow()
+			}
+		}
+		for idx, v := range m.Samples() {
+			if restored.Samples()[idx] != v {
+				t.Log("Modeler", modelerType, "restored with wrong samples")
+				t.FailNow()
+			}
+		}
+		// the prediction of a known dataset matches its approximated value
+		for i := range datasets {
+			if _, ok := m.Samples()[i]; ok && modelerType == KNNModelerType {
+				continue // the samples keep their real values
+			}
+			projection := DatasetProjection{Coordinates: coordinates[i], Similarities: make([]float64, len(datasets))}
+			for j := range datasets {
+				projection.Similarities[j] = sm.Get(i, j)
+			}
+			val, err := restored.Predict(projection)
+			if err != nil {
+				t.Log(err)
+				t.FailNow()
+			}
+			if math.Abs(val-m.AppxValues()[i]) > 1e-4 {
+				t.Log("Modeler", modelerType, "predicted", val, "instead of", m.AppxValues()[i])
+				t.FailNow()
+			}
+		}
+		if _, err := restored.Predict(DatasetProjection{}); err == nil {
+			t.Log("Empty projection should be rejected")
+			t.Fail()
+		}
+	}
+	if DeserializeModeler([]byte{0, 0, 0, 100}) != nil {
+		t.Log("Unknown modeler type should not be deserialized")
+		t.Fail()
+	}
+}
+
+func TestPredictDataset(t *testing.T) {
+	datasets := createPoolBasedDatasets(100, 30, 2)
+	defer cleanDatasets(datasets)
+	coords, eval := createRegressionSetup(datasets, func(x []float64) float64 {
+		return x[0] + x[1]
+	})
+	defer os.Remove(coords)
+	est := NewDatasetSimilarityEstimator(SimilarityTypeJaccard, datasets)
+	est.Configure(map[string]string{"concurrency": "4"})
+	if err := est.Compute(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	f, _ := ioutil.TempFile("/tmp", "smatrix")
+	f.Write(est.SimilarityMatrix().Serialize())
+	f.Close()
+	defer os.Remove(f.Name())
+
+	m := NewModeler(KNNModelerType, datasets, 0.5, eval)
+	m.Configure(map[string]string{"k": "3", "smatrix": f.Name()})
+	if err := m.Run(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	restored := DeserializeModeler(m.Serialize())
+	for i, d := range datasets {
+		if _, ok := m.Samples()[i]; ok {
+			continue
+		}
+		val, err := PredictDataset(restored, d, est, nil)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		if math.Abs(val-m.AppxValues()[i]) > 1e-6 {
+			t.Log("Predicted", val, "instead of", m.AppxValues()[i])
+			t.Fail()
+		}
+	}
+	if _, err := PredictDataset(restored, datasets[0], nil, nil); err == nil {
+		t.Log("Missing estimator should be rejected")
+		t.Fail()
+	}
+
+	linear := NewModeler(LinearModelerType, datasets, 0.5, eval)
+	linear.Configure(map[string]string{"coordinates": coords})
+	linear.Run()
+	if _, err := PredictDataset(linear, datasets[0], est, nil); err == nil {
+		t.Log("Missing indexer should be rejected")
+		t.Fail()
+	}
+}
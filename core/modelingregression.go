@@ -0,0 +1,471 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// LinearModeler trains a linear regression model over the dataset
+// coordinates. If the regularization parameter lambda is positive, ridge
+// regression is executed instead of ordinary least squares.
+type LinearModeler struct {
+	AbstractModeler
+	coordinates []DatasetCoordinates // the dataset coordinates
+	ridge       bool                 // true if the modeler is a ridge regressor
+	lambda      float64              // the L2 regularization parameter
+	weights     []float64            // the coefficients of the coordinates
+	intercept   float64              // the intercept of the model
+}
+
+// Configure expects the necessary conf options for the specified struct.
+// Specifically, the following parameters are applicable:
+// - coordinates: the path of the coordinates file (mandatory)
+// - lambda: the L2 regularization parameter (default is 0, or 1 for ridge)
+func (m *LinearModeler) Configure(conf map[string]string) error {
+	coordinates, err := modelerCoordinates(conf)
+	if err != nil {
+		return err
+	}
+	m.coordinates = coordinates
+	m.lambda = 0.0
+	if m.ridge {
+		m.lambda = 1.0
+	}
+	if val, ok := conf["lambda"]; ok {
+		m.lambda, err = strconv.ParseFloat(val, 64)
+		if err != nil || m.lambda < 0 {
+			log.Println("Invalid lambda parameter", val)
+			return errors.New("Invalid lambda parameter")
+		}
+	}
+	return nil
+}
+
+// Run executes the modeling process and populates the samples and the
+// appxValues slices.
+func (m *LinearModeler) Run() error {
+	start := time.Now()
+	if len(m.coordinates) != len(m.datasets) {
+		return errors.New("The coordinates do not match the datasets")
+	}
+	m.deploySamples()
+	x, y := modelerTrainingSet(m.samples, m.coordinates)
+	if len(y) == 0 {
+		return errors.New("No samples were evaluated")
+	}
+	if err := m.fit(x, y); err != nil {
+		log.Println(err)
+		return err
+	}
+	m.appxValues = make([]float64, len(m.datasets))
+	for i, c := range m.coordinates {
+		m.appxValues[i] = m.predict(c)
+	}
+	m.execTime = time.Since(start).Seconds()
+	return nil
+}
+
+// fit estimates the coefficients of the model. The data are centered, so
+// that the intercept is not regularized.
+func (m *LinearModeler) fit(x [][]float64, y []float64) error {
+	dims := len(x[0])
+	xMean, yMean := make([]float64, dims), Mean(y)
+	for i := range x {
+		for j := range xMean {
+			xMean[j] += x[i][j] / float64(len(x))
+		}
+	}
+	a, b := make([][]float64, dims), make([]float64, dims)
+	for j := range a {
+		a[j] = make([]float64, dims)
+		a[j][j] = m.lambda
+	}
+	for i := range x {
+		for j := 0; j < dims; j++ {
+			xj := x[i][j] - xMean[j]
+			b[j] += xj * (y[i] - yMean)
+			for k := 0; k < dims; k++ {
+				a[j][k] += xj * (x[i][k] - xMean[k])
+			}
+		}
+	}
+	weights, err := solveLinearSystem(a, b)
+	if err != nil {
+		return errors.New("Linear regression failed (" + err.Error() + "), try a positive lambda")
+	}
+	m.weights = weights
+	m.intercept = yMean
+	for j := range weights {
+		m.intercept -= weights[j] * xMean[j]
+	}
+	return nil
+}
+
+// predict returns the estimation of the model for the given coordinates
+func (m *LinearModeler) predict(x []float64) float64 {
+	value := m.intercept
+	for j := range m.weights {
+		value += m.weights[j] * x[j]
+	}
+	return value
+}
+
+// Serialize returns a byte slice containing the trained state of the modeler
+func (m *LinearModeler) Serialize() []byte {
+	buffer := new(bytes.Buffer)
+	if m.ridge {
+		buffer.Write(getBytesInt(int(RidgeModelerType)))
+	} else {
+		buffer.Write(getBytesInt(int(LinearModelerType)))
+	}
+	buffer.Write(abstractModelerSerialize(m.AbstractModeler))
+	buffer.Write(getBytesFloat(m.lambda))
+	buffer.Write(getBytesFloat(m.intercept))
+	writeFloats(buffer, m.weights)
+	writeCoordinates(buffer, m.coordinates)
+	return buffer.Bytes()
+}
+
+// Deserialize reconstructs the modeler from a byte slice
+func (m *LinearModeler) Deserialize(b []byte) {
+	buffer := bytes.NewBuffer(b)
+	tempInt, tempFloat := make([]byte, 4), make([]byte, 8)
+	buffer.Read(tempInt)
+	m.ridge = ModelerType(getIntBytes(tempInt)) == RidgeModelerType
+	m.AbstractModeler = abstractModelerDeserialize(buffer)
+	buffer.Read(tempFloat)
+	m.lambda = getFloatBytes(tempFloat)
+	buffer.Read(tempFloat)
+	m.intercept = getFloatBytes(tempFloat)
+	m.weights = readFloats(buffer)
+	m.coordinates = readCoordinates(buffer)
+}
+
+// Predict returns the approximated value of the projected dataset
+func (m *LinearModeler) Predict(p DatasetProjection) (float64, error) {
+	coordinates, err := projectionCoordinates(p, len(m.weights))
+	if err != nil {
+		return math.NaN(), err
+	}
+	return m.predict(coordinates), nil
+}
+
+// RandomForestModeler trains a random forest of regression trees over the
+// dataset coordinates.
+type RandomForestModeler struct {
+	AbstractModeler
+	coordinates []DatasetCoordinates  // the dataset coordinates
+	trees       int                   // the number of trees of the forest
+	features    int                   // the features examined at each split
+	leafSize    int                   // the min number of samples of each leaf
+	maxDepth    int                   // the max depth of each tree (0 is unlimited)
+	seed        int64                 // the seed of the random generator
+	forest      []*regressionTreeNode // the trained trees
+}
+
+// Configure expects the necessary conf options for the specified struct.
+// Specifically, the following parameters are applicable:
+// - coordinates: the path of the coordinates file (mandatory)
+// - trees: the number of trees (default is 100)
+// - features: the number of features examined at each split (default is a third of them)
+// - leaf: the min number of samples of each leaf (default is 5)
+// - depth: the max depth of each tree (default is 0, i.e., unlimited)
+// - seed: the seed of the random generator (default is random)
+func (m *RandomForestModeler) Configure(conf map[string]string) error {
+	coordinates, err := modelerCoordinates(conf)
+	if err != nil {
+		return err
+	}
+	m.coordinates = coordinates
+	params := map[string]*int{"trees": &m.trees, "features": &m.features, "leaf": &m.leafSize, "depth": &m.maxDepth}
+	defaults := map[string]int{"trees": 100, "features": 0, "leaf": 5, "depth": 0}
+	for k, p := range params {
+		*p = defaults[k]
+		if val, ok := conf[k]; ok {
+			conv, err := strconv.ParseInt(val, 10, 32)
+			if err != nil || conv < 0 {
+				log.Println("Invalid", k, "parameter", val)
+				return errors.New("Invalid " + k + " parameter")
+			}
+			*p = int(conv)
+		}
+	}
+	if m.trees < 1 || m.leafSize < 1 {
+		return errors.New("trees and leaf parameters must be positive")
+	}
+	m.seed = rand.Int63()
+	if val, ok := conf["seed"]; ok {
+		m.seed, err = strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			log.Println(err)
+			return err
+		}
+	}
+	return nil
+}
+
+// Run executes the modeling process and populates the samples and the
+// appxValues slices.
+func (m *RandomForestModeler) Run() error {
+	start := time.Now()
+	if len(m.coordinates) != len(m.datasets) {
+		return errors.New("The coordinates do not match the datasets")
+	}
+	m.deploySamples()
+	x, y := modelerTrainingSet(m.samples, m.coordinates)
+	if len(y) == 0 {
+		return errors.New("No samples were evaluated")
+	}
+	m.fit(x, y)
+	m.appxValues = make([]float64, len(m.datasets))
+	for i, c := range m.coordinates {
+		m.appxValues[i] = m.predict(c)
+	}
+	m.execTime = time.Since(start).Seconds()
+	return nil
+}
+
+// fit trains the trees of the forest, each one over a bootstrap sample of
+// the training set
+func (m *RandomForestModeler) fit(x [][]float64, y []float64) {
+	r := rand.New(rand.NewSource(m.seed))
+	features := m.features
+	if features <= 0 {
+		features = len(x[0]) / 3
+	}
+	if features < 1 {
+		features = 1
+	} else if features > len(x[0]) {
+		features = len(x[0])
+	}
+	m.forest = make([]*regressionTreeNode, m.trees)
+	for t := range m.forest {
+		bootstrap := make([]int, len(y))
+		for i := range bootstrap {
+			bootstrap[i] = r.Intn(len(y))
+		}
+		builder := regressionTreeBuilder{x: x, y: y, features: features,
+			leafSize: m.leafSize, maxDepth: m.maxDepth, r: r}
+		m.forest[t] = builder.build(bootstrap, 0)
+	}
+}
+
+// predict returns the mean estimation of the trees
+func (m *RandomForestModeler) predict(x []float64) float64 {
+	sum := 0.0
+	for _, t := range m.forest {
+		sum += t.predict(x)
+	}
+	return sum / float64(len(m.forest))
+}
+
+// Serialize returns a byte slice containing the trained state of the modeler
+func (m *RandomForestModeler) Serialize() []byte {
+	buffer := new(bytes.Buffer)
+	buffer.Write(getBytesInt(int(RandomForestModelerType)))
+	buffer.Write(abstractModelerSerialize(m.AbstractModeler))
+	buffer.Write(getBytesInt(m.trees))
+	buffer.Write(getBytesInt(m.features))
+	buffer.Write(getBytesInt(m.leafSize))
+	buffer.Write(getBytesInt(m.maxDepth))
+	buffer.Write(getBytesInt(len(m.forest)))
+	for _, t := range m.forest {
+		t.serialize(buffer)
+	}
+	writeCoordinates(buffer, m.coordinates)
+	return buffer.Bytes()
+}
+
+// Deserialize reconstructs the modeler from a byte slice
+func (m *RandomForestModeler) Deserialize(b []byte) {
+	buffer := bytes.NewBuffer(b)
+	buffer.Next(4)
+	m.AbstractModeler = abstractModelerDeserialize(buffer)
+	tempInt := make([]byte, 4)
+	for _, p := range []*int{&m.trees, &m.features, &m.leafSize, &m.maxDepth} {
+		buffer.Read(tempInt)
+		*p = getIntBytes(tempInt)
+	}
+	buffer.Read(tempInt)
+	m.forest = make([]*regressionTreeNode, getIntBytes(tempInt))
+	for t := range m.forest {
+		m.forest[t] = deserializeRegressionTree(buffer)
+	}
+	m.coordinates = readCoordinates(buffer)
+}
+
+// Predict returns the approximated value of the projected dataset
+func (m *RandomForestModeler) Predict(p DatasetProjection) (float64, error) {
+	if len(m.forest) == 0 || len(m.coordinates) == 0 {
+		return math.NaN(), errors.New("The modeler is not trained")
+	}
+	coordinates, err := projectionCoordinates(p, len(m.coordinates[0]))
+	if err != nil {
+		return math.NaN(), err
+	}
+	return m.predict(coordinates), nil
+}
+
+// regressionTreeNode is a node of a regression tree; the leaves hold the
+// mean value of their samples
+type regressionTreeNode struct {
+	feature     int
+	threshold   float64
+	value       float64
+	left, right *regressionTreeNode
+}
+
+func (n *regressionTreeNode) predict(x []float64) float64 {
+	for n.left != nil {
+		if x[n.feature] <= n.threshold {
+			n = n.left
+		} else {
+			n = n.right
+		}
+	}
+	return n.value
+}
+
+// serialize writes the subtree in preorder
+func (n *regressionTreeNode) serialize(buffer *bytes.Buffer) {
+	if n.left == nil {
+		buffer.Write(getBytesInt(1))
+		buffer.Write(getBytesFloat(n.value))
+		return
+	}
+	buffer.Write(getBytesInt(0))
+	buffer.Write(getBytesInt(n.feature))
+	buffer.Write(getBytesFloat(n.threshold))
+	buffer.Write(getBytesFloat(n.value))
+	n.left.serialize(buffer)
+	n.right.serialize(buffer)
+}
+
+// deserializeRegressionTree reads a subtree written by serialize
+func deserializeRegressionTree(buffer *bytes.Buffer) *regressionTreeNode {
+	tempInt, tempFloat := make([]byte, 4), make([]byte, 8)
+	node := new(regressionTreeNode)
+	buffer.Read(tempInt)
+	if getIntBytes(tempInt) == 1 {
+		buffer.Read(tempFloat)
+		node.value = getFloatBytes(tempFloat)
+		return node
+	}
+	buffer.Read(tempInt)
+	node.feature = getIntBytes(tempInt)
+	buffer.Read(tempFloat)
+	node.threshold = getFloatBytes(tempFloat)
+	buffer.Read(tempFloat)
+	node.value = getFloatBytes(tempFloat)
+	node.left = deserializeRegressionTree(buffer)
+	node.right = deserializeRegressionTree(buffer)
+	return node
+}
+
+// regressionTreeBuilder holds the training set and the parameters used to
+// grow a regression tree
+type regressionTreeBuilder struct {
+	x                  [][]float64
+	y                  []float64
+	features           int
+	leafSize, maxDepth int
+	r                  *rand.Rand
+}
+
+// build grows the subtree of the given samples, choosing at each node the
+// split that minimizes the sum of squared errors of the children
+func (b *regressionTreeBuilder) build(idx []int, depth int) *regressionTreeNode {
+	sum, sq := 0.0, 0.0
+	for _, i := range idx {
+		sum += b.y[i]
+		sq += b.y[i] * b.y[i]
+	}
+	n := float64(len(idx))
+	node := &regressionTreeNode{value: sum / n}
+	if len(idx) < 2*b.leafSize || (b.maxDepth > 0 && depth >= b.maxDepth) {
+		return node
+	}
+	bestSSE, bestSplit := sq-sum*sum/n-1e-12, -1
+	var bestOrder []int
+	for _, f := range b.r.Perm(len(b.x[0]))[:b.features] {
+		order := make([]int, len(idx))
+		copy(order, idx)
+		sort.Sort(regressionFeatureOrder{order, b.x, f})
+		sumL, sqL := 0.0, 0.0
+		for i := 0; i < len(order)-b.leafSize; i++ {
+			v := b.y[order[i]]
+			sumL += v
+			sqL += v * v
+			if i+1 < b.leafSize || b.x[order[i]][f] == b.x[order[i+1]][f] {
+				continue
+			}
+			nL, nR := float64(i+1), n-float64(i+1)
+			sumR, sqR := sum-sumL, sq-sqL
+			sse := (sqL - sumL*sumL/nL) + (sqR - sumR*sumR/nR)
+			if sse < bestSSE {
+				bestSSE, bestSplit, bestOrder = sse, i+1, order
+				node.feature = f
+				node.threshold = (b.x[order[i]][f] + b.x[order[i+1]][f]) / 2
+			}
+		}
+	}
+	if bestSplit < 0 {
+		return node
+	}
+	node.left = b.build(bestOrder[:bestSplit], depth+1)
+	node.right = b.build(bestOrder[bestSplit:], depth+1)
+	return node
+}
+
+// regressionFeatureOrder sorts sample indices according to a feature
+type regressionFeatureOrder struct {
+	idx     []int
+	x       [][]float64
+	feature int
+}
+
+func (o regressionFeatureOrder) Len() int { return len(o.idx) }
+func (o regressionFeatureOrder) Less(i, j int) bool {
+	return o.x[o.idx[i]][o.feature] < o.x[o.idx[j]][o.feature]
+}
+func (o regressionFeatureOrder) Swap(i, j int) { o.idx[i], o.idx[j] = o.idx[j], o.idx[i] }
+
+// modelerCoordinates parses the coordinates file of the modeler
+// configuration
+func modelerCoordinates(conf map[string]string) ([]DatasetCoordinates, error) {
+	val, ok := conf["coordinates"]
+	if !ok {
+		log.Println("coordinates parameter is missing")
+		return nil, errors.New("coordinates parameter is missing")
+	}
+	buf, err := ioutil.ReadFile(val)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return DeserializeCoordinates(buf), nil
+}
+
+// modelerTrainingSet returns the coordinates and the values of the samples,
+// ordered by the dataset index
+func modelerTrainingSet(samples map[int]float64, coordinates []DatasetCoordinates) ([][]float64, []float64) {
+	var indices []int
+	for idx, val := range samples {
+		if !math.IsNaN(val) {
+			indices = append(indices, idx)
+		}
+	}
+	sort.Ints(indices)
+	x, y := make([][]float64, len(indices)), make([]float64, len(indices))
+	for i, idx := range indices {
+		x[i], y[i] = coordinates[idx], samples[idx]
+	}
+	return x, y
+}
@@ -0,0 +1,145 @@
+package core
+
+import (
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+// functionEvaluator evaluates the datasets through a function of their
+// coordinates
+type functionEvaluator struct {
+	values map[string]float64
+}
+
+func (e *functionEvaluator) Evaluate(dataset string) (float64, error) {
+	return e.values[dataset], nil
+}
+
+// createRegressionSetup returns a coordinates file and an evaluator that
+// assigns fn(coordinates) to each dataset
+func createRegressionSetup(datasets []*Dataset, fn func([]float64) float64) (string, DatasetEvaluator) {
+	coords := make([]DatasetCoordinates, len(datasets))
+	for i := range coords {
+		coords[i] = DatasetCoordinates{rand.Float64(), rand.Float64(), rand.Float64()}
+	}
+	f, _ := ioutil.TempFile("/tmp", "coordinates")
+	f.Write(SerializeCoordinates(coords))
+	f.Close()
+	// the serialized coordinates are rounded
+	coords = DeserializeCoordinates(SerializeCoordinates(coords))
+	eval := &functionEvaluator{values: make(map[string]float64)}
+	for i, d := range datasets {
+		eval.values[d.Path()] = fn(coords[i])
+	}
+	return f.Name(), eval
+}
+
+func TestNewModelerType(t *testing.T) {
+	types := map[string]ModelerType{
+		"script": ScriptBasedModelerType,
+		"knn":    KNNModelerType,
+		"linear": LinearModelerType,
+		"ridge":  RidgeModelerType,
+		"RF":     RandomForestModelerType,
+		"gp":     GaussianProcessModelerType,
+		"krr":    KernelRidgeModelerType,
+		"SVR":    SVRModelerType,
+	}
+	for k, v := range types {
+		if NewModelerType(k) != v {
+			t.Log("Wrong modeler type for", k)
+			t.Fail()
+		}
+	}
+}
+
+func TestLinearModeler(t *testing.T) {
+	datasets := createPoolBasedDatasets(100, 40, 2)
+	defer cleanDatasets(datasets)
+	coords, eval := createRegressionSetup(datasets, func(x []float64) float64 {
+		return 3*x[0] - 2*x[1] + 0.5*x[2] + 5
+	})
+	defer os.Remove(coords)
+
+	m := NewModeler(LinearModelerType, datasets, 0.5, eval)
+	if err := m.Configure(map[string]string{"lambda": "foo", "coordinates": coords}); err == nil {
+		t.Log("Invalid lambda should be rejected")
+		t.Fail()
+	}
+	if err := m.Configure(map[string]string{}); err == nil {
+		t.Log("Missing coordinates should be rejected")
+		t.Fail()
+	}
+	if err := m.Configure(map[string]string{"coordinates": coords}); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if err := m.Run(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if len(m.Samples()) != 20 || len(m.AppxValues()) != len(datasets) {
+		t.Log("Wrong number of samples or approximated values")
+		t.FailNow()
+	}
+	if rmse := m.ErrorMetrics()["RMSE-all"]; rmse > 1e-6 {
+		t.Log("Linear function not recovered, RMSE:", rmse)
+		t.Fail()
+	}
+
+	// the regularization shrinks the coefficients
+	ridge := NewModeler(RidgeModelerType, datasets, 0.5, eval)
+	ridge.Configure(map[string]string{"coordinates": coords, "lambda": "10"})
+	if err := ridge.Run(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if math.Abs(ridge.(*LinearModeler).weights[0]) >= math.Abs(m.(*LinearModeler).weights[0]) {
+		t.Log("Ridge coefficients should be smaller", ridge.(*LinearModeler).weights, m.(*LinearModeler).weights)
+		t.Fail()
+	}
+}
+
+func TestRandomForestModeler(t *testing.T) {
+	datasets := createPoolBasedDatasets(100, 100, 2)
+	defer cleanDatasets(datasets)
+	coords, eval := createRegressionSetup(datasets, func(x []float64) float64 {
+		if x[0] > 0.5 {
+			return 10.0
+		}
+		return 1.0
+	})
+	defer os.Remove(coords)
+
+	m := NewModeler(RandomForestModelerType, datasets, 0.6, eval)
+	if err := m.Configure(map[string]string{"coordinates": coords, "trees": "0"}); err == nil {
+		t.Log("Zero trees should be rejected")
+		t.Fail()
+	}
+	err := m.Configure(map[string]string{"coordinates": coords, "trees": "50", "features": "3", "leaf": "2", "seed": "1"})
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if err := m.Run(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if len(m.AppxValues()) != len(datasets) {
+		t.Log("Wrong number of approximated values")
+		t.FailNow()
+	}
+	if r2 := m.ErrorMetrics()["R^2-unknown"]; r2 < 0.8 {
+		t.Log("Step function not approximated, R^2:", r2)
+		t.Fail()
+	}
+	for _, v := range m.AppxValues() {
+		if v < 1.0 || v > 10.0 {
+			t.Log("Approximation outside the range of the samples", v)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,487 @@
+package core
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// SamplingStrategyType represents the strategy used by the modelers to choose
+// the datasets that are evaluated
+type SamplingStrategyType uint8
+
+const (
+	// UniformSamplingType picks the datasets uniformly at random
+	UniformSamplingType SamplingStrategyType = iota
+	// KCenterSamplingType picks the datasets through farthest point sampling
+	KCenterSamplingType SamplingStrategyType = iota + 1
+	// ClusterSamplingType picks the datasets proportionally from each cluster
+	ClusterSamplingType SamplingStrategyType = iota + 2
+	// UncertaintySamplingType picks the datasets of the highest uncertainty
+	UncertaintySamplingType SamplingStrategyType = iota + 3
+)
+
+// NewSamplingStrategyType parses the name of a sampling strategy
+func NewSamplingStrategyType(t string) SamplingStrategyType {
+	switch strings.ToLower(t) {
+	case "kcenter", "farthest":
+		return KCenterSamplingType
+	case "cluster", "stratified":
+		return ClusterSamplingType
+	case "uncertainty", "active":
+		return UncertaintySamplingType
+	}
+	return UniformSamplingType
+}
+
+func (t SamplingStrategyType) String() string {
+	if t == KCenterSamplingType {
+		return "kcenter"
+	} else if t == ClusterSamplingType {
+		return "cluster"
+	} else if t == UncertaintySamplingType {
+		return "uncertainty"
+	}
+	return "uniform"
+}
+
+// SamplingStrategy is the interface of the strategies that choose which
+// datasets are evaluated in order to train a Modeler. The datasets are chosen
+// in batches: after each batch is evaluated, the strategy is asked for the
+// next one, until the sampling budget is exhausted or the strategy returns
+// an empty batch.
+type SamplingStrategy interface {
+	// Configure provides the necessary configuration options to the strategy
+	Configure(map[string]string) error
+	// Options returns a list of applicable parameters
+	Options() map[string]string
+	// Next returns at most count dataset indices to evaluate next, given the
+	// values of the evaluated datasets; the excluded indices (evaluated or
+	// failed) must not be returned
+	Next(samples map[int]float64, excluded map[int]bool, count int) []int
+}
+
+// NewSamplingStrategy is the factory method for the sampling strategies
+func NewSamplingStrategy(strategyType SamplingStrategyType, datasets []*Dataset) SamplingStrategy {
+	if strategyType == UniformSamplingType {
+		s := new(UniformSampling)
+		s.datasets = datasets
+		return s
+	} else if strategyType == KCenterSamplingType {
+		s := new(KCenterSampling)
+		s.datasets = datasets
+		return s
+	} else if strategyType == ClusterSamplingType {
+		s := new(ClusterSampling)
+		s.datasets = datasets
+		return s
+	} else if strategyType == UncertaintySamplingType {
+		s := new(UncertaintySampling)
+		s.datasets = datasets
+		return s
+	}
+	return nil
+}
+
+// UniformSampling picks the datasets uniformly at random
+type UniformSampling struct {
+	datasets []*Dataset
+}
+
+// Configure is a no-op, the strategy has no parameters
+func (s *UniformSampling) Configure(conf map[string]string) error {
+	return nil
+}
+
+// Options returns a list of applicable parameters
+func (s *UniformSampling) Options() map[string]string {
+	return map[string]string{}
+}
+
+// Next returns count random datasets that are not excluded
+func (s *UniformSampling) Next(samples map[int]float64, excluded map[int]bool, count int) []int {
+	var res []int
+	for _, idx := range rand.Perm(len(s.datasets)) {
+		if len(res) >= count {
+			break
+		}
+		if !excluded[idx] {
+			res = append(res, idx)
+		}
+	}
+	return res
+}
+
+// KCenterSampling picks the datasets through farthest point (k-center)
+// sampling: each new dataset is the one farthest from the already chosen
+// ones, so that the samples cover the dataset space. The distances are
+// obtained either from the dataset coordinates or the similarity matrix.
+type KCenterSampling struct {
+	datasets []*Dataset
+	distance func(i, j int) float64
+}
+
+// Configure expects the necessary conf options for the specified struct.
+// Specifically, one of the following parameters is necessary:
+// - coordinates: the path of the coordinates file
+// - smatrix: the path of the similarity matrix file
+func (s *KCenterSampling) Configure(conf map[string]string) error {
+	distance, err := samplingDistance(conf, len(s.datasets))
+	if err != nil {
+		return err
+	}
+	s.distance = distance
+	return nil
+}
+
+// Options returns a list of applicable parameters
+func (s *KCenterSampling) Options() map[string]string {
+	return map[string]string{
+		"coordinates": "the coordinates file used to compute the distances",
+		"smatrix":     "the similarity matrix used to compute the distances",
+	}
+}
+
+// Next returns the count datasets chosen by the greedy k-center algorithm;
+// the evaluated datasets are the initial centers
+func (s *KCenterSampling) Next(samples map[int]float64, excluded map[int]bool, count int) []int {
+	var centers []int
+	for idx := range samples {
+		centers = append(centers, idx)
+	}
+	return kCenterSelect(len(s.datasets), s.distance, centers, excluded, count)
+}
+
+// ClusterSampling partitions the datasets through hierarchical clustering
+// over the similarity matrix and picks random datasets from each cluster,
+// proportionally to its size.
+type ClusterSampling struct {
+	datasets []*Dataset
+	clusters [][]int // the dataset indices of each cluster
+}
+
+// Configure expects the necessary conf options for the specified struct.
+// Specifically, the following parameters are applicable:
+// - smatrix: the path of the similarity matrix file (mandatory)
+// - level: the dendrogram level of the clusters (default is 3)
+// - concurrency: the number of threads used for the clustering (default is 1)
+func (s *ClusterSampling) Configure(conf map[string]string) error {
+	sm, err := samplingSimilarityMatrix(conf)
+	if err != nil {
+		return err
+	}
+	if sm.Capacity() != len(s.datasets) {
+		return errors.New("The similarity matrix does not match the datasets")
+	}
+	params := map[string]int{"level": 3, "concurrency": 1}
+	for k := range params {
+		if val, ok := conf[k]; ok {
+			conv, err := strconv.ParseInt(val, 10, 32)
+			if err != nil || conv < 1 {
+				log.Println("Invalid", k, "parameter", val)
+				return errors.New("Invalid " + k + " parameter")
+			}
+			params[k] = int(conv)
+		}
+	}
+	indices := make(map[string]int)
+	for i, d := range s.datasets {
+		indices[d.Path()] = i
+	}
+	s.clusters = nil
+	if len(s.datasets) < 2 {
+		s.clusters = [][]int{{}}
+		for i := range s.datasets {
+			s.clusters[0] = append(s.clusters[0], i)
+		}
+		return nil
+	}
+	clustering := NewClustering(sm, s.datasets)
+	clustering.SetConcurrency(params["concurrency"])
+	if err := clustering.Compute(); err != nil {
+		log.Println(err)
+		return err
+	}
+	for _, cluster := range clustering.Results().GetClusters(params["level"]) {
+		var idx []int
+		for _, d := range cluster {
+			idx = append(idx, indices[d.Path()])
+		}
+		s.clusters = append(s.clusters, idx)
+	}
+	return nil
+}
+
+// Options returns a list of applicable parameters
+func (s *ClusterSampling) Options() map[string]string {
+	return map[string]string{
+		"smatrix":     "the similarity matrix used for the clustering",
+		"level":       "the dendrogram level of the clusters (default is 3)",
+		"concurrency": "max num of threads used for the clustering (int)",
+	}
+}
+
+// Next returns count datasets; each one is picked from the cluster that is
+// most underrepresented in the samples
+func (s *ClusterSampling) Next(samples map[int]float64, excluded map[int]bool, count int) []int {
+	chosen, available := make([]int, len(s.clusters)), make([][]int, len(s.clusters))
+	total := 0
+	for c, cluster := range s.clusters {
+		for _, idx := range cluster {
+			if _, ok := samples[idx]; ok {
+				chosen[c]++
+				total++
+			} else if !excluded[idx] {
+				available[c] = append(available[c], idx)
+			}
+		}
+	}
+	var res []int
+	for len(res) < count {
+		best, bestDeficit := -1, math.Inf(-1)
+		for c, cluster := range s.clusters {
+			if len(available[c]) == 0 {
+				continue
+			}
+			deficit := float64(len(cluster)*(total+1))/float64(len(s.datasets)) - float64(chosen[c])
+			if deficit > bestDeficit {
+				best, bestDeficit = c, deficit
+			}
+		}
+		if best < 0 {
+			break
+		}
+		pick := rand.Intn(len(available[best]))
+		res = append(res, available[best][pick])
+		available[best] = append(available[best][:pick], available[best][pick+1:]...)
+		chosen[best]++
+		total++
+	}
+	return res
+}
+
+// UncertaintySampling iteratively trains a Gaussian process over the dataset
+// coordinates and the evaluated datasets, and picks the datasets of the
+// highest predictive variance. The initial batch is chosen through k-center
+// sampling. The sampling stops early when the estimated error (the root of
+// the mean predictive variance of the datasets that are not evaluated) drops
+// below the target.
+type UncertaintySampling struct {
+	datasets    []*Dataset
+	coordinates []DatasetCoordinates
+	batch       int     // the number of datasets of each batch
+	target      float64 // the target error (0 disables the early stop)
+	kernel      gpKernel
+}
+
+// Configure expects the necessary conf options for the specified struct.
+// Specifically, the following parameters are applicable:
+// - coordinates: the path of the coordinates file (mandatory)
+// - batch: the number of datasets evaluated before re-training (default is 5)
+// - target: the target error of the model (default is 0, i.e., no target)
+// - kernel: the kernel of the Gaussian process (default is rbf)
+func (s *UncertaintySampling) Configure(conf map[string]string) error {
+	coordinates, err := modelerCoordinates(conf)
+	if err != nil {
+		return err
+	}
+	if len(coordinates) != len(s.datasets) {
+		return errors.New("The coordinates do not match the datasets")
+	}
+	s.coordinates = coordinates
+	s.batch = 5
+	if val, ok := conf["batch"]; ok {
+		conv, err := strconv.ParseInt(val, 10, 32)
+		if err != nil || conv < 1 {
+			log.Println("Invalid batch parameter", val)
+			return errors.New("Invalid batch parameter")
+		}
+		s.batch = int(conv)
+	}
+	s.target = 0.0
+	if val, ok := conf["target"]; ok {
+		s.target, err = strconv.ParseFloat(val, 64)
+		if err != nil || s.target < 0 {
+			log.Println("Invalid target parameter", val)
+			return errors.New("Invalid target parameter")
+		}
+	}
+	s.kernel = gpKernelRBF
+	if val, ok := conf["kernel"]; ok {
+		if s.kernel, err = newGPKernel(val); err != nil {
+			log.Println(err)
+			return err
+		}
+	}
+	return nil
+}
+
+// Options returns a list of applicable parameters
+func (s *UncertaintySampling) Options() map[string]string {
+	return map[string]string{
+		"coordinates": "the coordinates file of the datasets",
+		"batch":       "the datasets evaluated before re-training (default is 5)",
+		"target":      "stop when the estimated error drops below target (default is 0)",
+		"kernel":      "the kernel of the Gaussian process [rbf | matern32 | matern52]",
+	}
+}
+
+// Next re-trains the Gaussian process and returns the batch of the datasets
+// with the highest predictive variance
+func (s *UncertaintySampling) Next(samples map[int]float64, excluded map[int]bool, count int) []int {
+	if count > s.batch {
+		count = s.batch
+	}
+	x, y := modelerTrainingSet(samples, s.coordinates)
+	if len(y) < 2 {
+		var centers []int
+		for idx := range samples {
+			centers = append(centers, idx)
+		}
+		distance := func(i, j int) float64 {
+			d, _ := norm(s.coordinates[i], s.coordinates[j], 2)
+			return d
+		}
+		return kCenterSelect(len(s.datasets), distance, centers, excluded, count)
+	}
+	gp := &GaussianProcessModeler{kernel: s.kernel, variance: 1.0, noise: 0.1, optimize: true}
+	if err := gp.fit(x, y); err != nil {
+		log.Println(err)
+		return nil
+	}
+	type candidate struct {
+		idx      int
+		variance float64
+	}
+	var candidates []candidate
+	meanVariance := 0.0
+	for i, c := range s.coordinates {
+		if excluded[i] {
+			continue
+		}
+		_, v := gp.predict(c)
+		candidates = append(candidates, candidate{i, v})
+		meanVariance += v
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	meanVariance /= float64(len(candidates))
+	if s.target > 0 && math.Sqrt(meanVariance) < s.target {
+		log.Printf("Estimated error %.5f below target, sampling stopped\n", math.Sqrt(meanVariance))
+		return nil
+	}
+	// pick the candidates of the highest variance
+	var res []int
+	for len(res) < count && len(candidates) > 0 {
+		best := 0
+		for i := range candidates {
+			if candidates[i].variance > candidates[best].variance {
+				best = i
+			}
+		}
+		res = append(res, candidates[best].idx)
+		candidates = append(candidates[:best], candidates[best+1:]...)
+	}
+	return res
+}
+
+// kCenterSelect greedily picks count points, each one maximizing its distance
+// from the centers and the points already picked. If no centers are given,
+// the first point is picked at random.
+func kCenterSelect(n int, distance func(i, j int) float64, centers []int,
+	excluded map[int]bool, count int) []int {
+	minDistance := make([]float64, n)
+	for i := range minDistance {
+		minDistance[i] = math.Inf(1)
+	}
+	update := func(c int) {
+		for i := range minDistance {
+			if d := distance(i, c); d < minDistance[i] {
+				minDistance[i] = d
+			}
+		}
+	}
+	for _, c := range centers {
+		update(c)
+	}
+	picked := make(map[int]bool)
+	var res []int
+	for len(res) < count {
+		best := -1
+		if len(centers) == 0 && len(res) == 0 {
+			for _, idx := range rand.Perm(n) {
+				if !excluded[idx] {
+					best = idx
+					break
+				}
+			}
+		} else {
+			for i := range minDistance {
+				if !excluded[i] && !picked[i] && (best < 0 || minDistance[i] > minDistance[best]) {
+					best = i
+				}
+			}
+		}
+		if best < 0 {
+			break
+		}
+		picked[best] = true
+		res = append(res, best)
+		update(best)
+	}
+	return res
+}
+
+// samplingDistance returns the distance function of the datasets, based on
+// the coordinates or the similarity matrix of the configuration
+func samplingDistance(conf map[string]string, datasets int) (func(i, j int) float64, error) {
+	if _, ok := conf["coordinates"]; ok {
+		coordinates, err := modelerCoordinates(conf)
+		if err != nil {
+			return nil, err
+		}
+		if len(coordinates) != datasets {
+			return nil, errors.New("The coordinates do not match the datasets")
+		}
+		return func(i, j int) float64 {
+			d, _ := norm(coordinates[i], coordinates[j], 2)
+			return d
+		}, nil
+	}
+	sm, err := samplingSimilarityMatrix(conf)
+	if err != nil {
+		return nil, err
+	}
+	if sm.Capacity() != datasets {
+		return nil, errors.New("The similarity matrix does not match the datasets")
+	}
+	return func(i, j int) float64 {
+		return SimilarityToDistance(sm.Get(i, j))
+	}, nil
+}
+
+// samplingSimilarityMatrix parses the similarity matrix file of the
+// configuration
+func samplingSimilarityMatrix(conf map[string]string) (*DatasetSimilarityMatrix, error) {
+	val, ok := conf["smatrix"]
+	if !ok {
+		log.Println("smatrix parameter is missing")
+		return nil, errors.New("smatrix parameter is missing")
+	}
+	buf, err := ioutil.ReadFile(val)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	sm := new(DatasetSimilarityMatrix)
+	if err := sm.Deserialize(buf); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	return sm, nil
+}
@@ -0,0 +1,152 @@
+package core
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// createBlockSimilarityMatrix returns a similarity matrix file with two
+// groups of datasets, that are similar within each group
+func createBlockSimilarityMatrix(datasets []*Dataset, firstGroup int) string {
+	sm := NewDatasetSimilarities(len(datasets))
+	for i := range datasets {
+		for j := range datasets {
+			if i == j {
+				sm.Set(i, j, 1.0)
+			} else if (i < firstGroup) == (j < firstGroup) {
+				sm.Set(i, j, 0.9)
+			} else {
+				sm.Set(i, j, 0.1)
+			}
+		}
+	}
+	f, _ := ioutil.TempFile("/tmp", "smatrix")
+	f.Write(sm.Serialize())
+	f.Close()
+	return f.Name()
+}
+
+func TestNewSamplingStrategyType(t *testing.T) {
+	for _, s := range []SamplingStrategyType{UniformSamplingType, KCenterSamplingType,
+		ClusterSamplingType, UncertaintySamplingType} {
+		if NewSamplingStrategyType(s.String()) != s {
+			t.Log("Wrong sampling strategy type for", s)
+			t.Fail()
+		}
+	}
+}
+
+func TestKCenterSelect(t *testing.T) {
+	distance := func(i, j int) float64 {
+		if i > j {
+			return float64(i - j)
+		}
+		return float64(j - i)
+	}
+	res := kCenterSelect(10, distance, []int{0}, map[int]bool{0: true}, 2)
+	if len(res) != 2 || res[0] != 9 || (res[1] != 4 && res[1] != 5) {
+		t.Log("Wrong farthest points", res)
+		t.Fail()
+	}
+	res = kCenterSelect(10, distance, nil, map[int]bool{3: true}, 10)
+	if len(res) != 9 {
+		t.Log("Excluded points should not be chosen", res)
+		t.Fail()
+	}
+}
+
+func TestKCenterSampling(t *testing.T) {
+	datasets := make([]*Dataset, 40)
+	for i := range datasets {
+		datasets[i] = NewDataset(fmt.Sprintf("data-%d", i))
+	}
+	smFile := createBlockSimilarityMatrix(datasets, 30)
+	defer os.Remove(smFile)
+	s := NewSamplingStrategy(KCenterSamplingType, datasets)
+	if err := s.Configure(map[string]string{}); err == nil {
+		t.Log("Missing coordinates and smatrix should be rejected")
+		t.Fail()
+	}
+	if err := s.Configure(map[string]string{"smatrix": smFile}); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	// the second dataset must belong to the other group
+	res := s.Next(map[int]float64{35: 1.0}, map[int]bool{35: true}, 2)
+	if len(res) != 2 || res[0] >= 30 {
+		t.Log("The farthest dataset was not chosen", res)
+		t.Fail()
+	}
+}
+
+func TestClusterSampling(t *testing.T) {
+	datasets := make([]*Dataset, 40)
+	for i := range datasets {
+		datasets[i] = NewDataset(fmt.Sprintf("data-%d", i))
+	}
+	smFile := createBlockSimilarityMatrix(datasets, 30)
+	defer os.Remove(smFile)
+	s := NewSamplingStrategy(ClusterSamplingType, datasets)
+	if err := s.Configure(map[string]string{"smatrix": smFile, "level": "1", "concurrency": "4"}); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	res := s.Next(map[int]float64{}, map[int]bool{}, 8)
+	first := 0
+	for _, idx := range res {
+		if idx < 30 {
+			first++
+		}
+	}
+	if len(res) != 8 || first != 6 {
+		t.Log("The samples are not stratified", res)
+		t.Fail()
+	}
+}
+
+func TestUncertaintySampling(t *testing.T) {
+	datasets := createPoolBasedDatasets(100, 60, 2)
+	defer cleanDatasets(datasets)
+	coords, eval := createRegressionSetup(datasets, func(x []float64) float64 {
+		return x[0]*x[0] + x[1] - x[2]
+	})
+	defer os.Remove(coords)
+
+	s := NewSamplingStrategy(UncertaintySamplingType, datasets)
+	if err := s.Configure(map[string]string{"coordinates": coords, "batch": "0"}); err == nil {
+		t.Log("Zero batch should be rejected")
+		t.Fail()
+	}
+	if err := s.Configure(map[string]string{"coordinates": coords, "batch": "4"}); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	m := NewModeler(GaussianProcessModelerType, datasets, 0.4, eval)
+	m.Configure(map[string]string{"coordinates": coords})
+	m.SetSamplingStrategy(s)
+	if err := m.Run(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if len(m.Samples()) != 24 {
+		t.Log("Wrong number of samples", len(m.Samples()))
+		t.Fail()
+	}
+	if r2 := m.ErrorMetrics()["R^2-unknown"]; r2 < 0.9 {
+		t.Log("Smooth function not approximated, R^2:", r2)
+		t.Fail()
+	}
+
+	// a loose target stops the sampling early
+	s.Configure(map[string]string{"coordinates": coords, "batch": "4", "target": "100"})
+	if err := m.Run(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if len(m.Samples()) != 4 {
+		t.Log("The sampling should stop after the initial batch", len(m.Samples()))
+		t.Fail()
+	}
+}
@@ -0,0 +1,411 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TuningResult holds the cross validation results of a modeler configuration
+type TuningResult struct {
+	Configuration map[string]string    // the evaluated configuration
+	Folds         []map[string]float64 // the error metrics of each fold
+	Metrics       map[string]float64   // the mean error metrics of the folds
+}
+
+// CrossValidate executes k-fold cross validation of a modeler configuration
+// over the evaluated datasets: for each fold, the modeler is trained over the
+// samples of the rest of the folds and its approximations are compared to
+// the samples of the fold. No dataset is evaluated by this function.
+func CrossValidate(modelerType ModelerType, datasets []*Dataset, samples map[int]float64,
+	conf map[string]string, folds int, seed int64) (*TuningResult, error) {
+	var indices []int
+	for idx, val := range samples {
+		if !math.IsNaN(val) {
+			indices = append(indices, idx)
+		}
+	}
+	if folds < 2 || len(indices) < folds {
+		return nil, errors.New("The folds must be at least 2 and not more than the samples")
+	}
+	sort.Ints(indices)
+	perm := rand.New(rand.NewSource(seed)).Perm(len(indices))
+	evaluator := &samplesEvaluator{values: make(map[string]float64)}
+	for _, idx := range indices {
+		evaluator.values[datasets[idx].Path()] = samples[idx]
+	}
+
+	result := &TuningResult{Configuration: conf, Metrics: make(map[string]float64)}
+	for f := 0; f < folds; f++ {
+		var train, test []int
+		for i, p := range perm {
+			if i%folds == f {
+				test = append(test, indices[p])
+			} else {
+				train = append(train, indices[p])
+			}
+		}
+		modeler := NewModeler(modelerType, datasets, 1.0, evaluator)
+		if modeler == nil {
+			return nil, errors.New("Unknown modeler type")
+		}
+		if err := modeler.Configure(conf); err != nil {
+			return nil, err
+		}
+		modeler.SetSamplingStrategy(&fixedSampling{train})
+		if err := modeler.Run(); err != nil {
+			return nil, err
+		}
+		actual, predicted := make([]float64, len(test)), make([]float64, len(test))
+		for i, idx := range test {
+			actual[i], predicted[i] = samples[idx], modeler.AppxValues()[idx]
+		}
+		metrics := map[string]float64{
+			"RMSE-validation": RootMeanSquaredError(actual, predicted),
+			"MAE-validation":  MeanAbsoluteError(actual, predicted),
+			"MAPE-validation": MeanAbsolutePercentageError(actual, predicted),
+			"R^2-validation":  RSquared(actual, predicted),
+		}
+		result.Folds = append(result.Folds, metrics)
+		for k, v := range metrics {
+			result.Metrics[k] += v / float64(folds)
+		}
+	}
+	return result, nil
+}
+
+// ModelerTuner searches the configuration space of a modeler through cross
+// validation. Apart from the parameters of the modeler, the space may contain
+// the dimensionality of the MDS that produces the coordinates (mds.k) and the
+// parameters of the similarity estimator that produces the similarity matrix
+// (estimator.<param>).
+type ModelerTuner struct {
+	modelerType ModelerType
+	datasets    []*Dataset
+	evaluator   DatasetEvaluator
+
+	samplingRate  float64                         // the portion of the datasets evaluated
+	folds         int                             // the number of folds
+	trials        int                             // the random configurations (0 for grid search)
+	metric        string                          // the metric used to rank the configurations
+	seed          int64                           // the seed of the random generator
+	estimatorType *DatasetSimilarityEstimatorType // the estimator of estimator.* params
+
+	samples   map[int]float64   // the evaluated datasets
+	tempFiles map[string]string // the similarity matrices and coordinates files
+}
+
+// NewModelerTuner is the constructor of the ModelerTuner
+func NewModelerTuner(modelerType ModelerType, datasets []*Dataset, evaluator DatasetEvaluator) *ModelerTuner {
+	t := new(ModelerTuner)
+	t.modelerType = modelerType
+	t.datasets = datasets
+	t.evaluator = evaluator
+	t.samplingRate, t.folds, t.trials, t.metric = 0.2, 5, 0, "RMSE"
+	t.seed = rand.Int63()
+	return t
+}
+
+// Configure sets the parameters of the tuner. Specifically, the following
+// parameters are applicable:
+// - sr: the portion of the datasets evaluated (default is 0.2)
+// - folds: the number of folds (default is 5)
+// - trials: the number of random configurations, 0 for grid search (default is 0)
+// - metric: the metric used to rank the configurations (default is RMSE)
+// - seed: the seed of the random generator (default is random)
+// - estimator: the similarity estimator used for the estimator.* parameters
+func (t *ModelerTuner) Configure(conf map[string]string) error {
+	if val, ok := conf["sr"]; ok {
+		conv, err := strconv.ParseFloat(val, 64)
+		if err != nil || conv <= 0 || conv > 1 {
+			log.Println("Invalid sr parameter", val)
+			return errors.New("Invalid sr parameter")
+		}
+		t.samplingRate = conv
+		t.samples = nil
+	}
+	params := map[string]*int{"folds": &t.folds, "trials": &t.trials}
+	for k, p := range params {
+		if val, ok := conf[k]; ok {
+			conv, err := strconv.ParseInt(val, 10, 32)
+			if err != nil || conv < 0 {
+				log.Println("Invalid", k, "parameter", val)
+				return errors.New("Invalid " + k + " parameter")
+			}
+			*p = int(conv)
+		}
+	}
+	if t.folds < 2 {
+		return errors.New("At least 2 folds are needed")
+	}
+	if val, ok := conf["metric"]; ok {
+		if _, known := map[string]bool{"RMSE": true, "MAE": true, "MAPE": true, "R^2": true}[val]; !known {
+			return errors.New("Unknown metric " + val)
+		}
+		t.metric = val
+	}
+	if val, ok := conf["seed"]; ok {
+		conv, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			log.Println(err)
+			return err
+		}
+		t.seed = conv
+	}
+	if val, ok := conf["estimator"]; ok {
+		if t.estimatorType = NewDatasetSimilarityEstimatorType(val); t.estimatorType == nil {
+			return errors.New("Unknown estimator " + val)
+		}
+	}
+	return nil
+}
+
+// Options returns a list of applicable parameters
+func (t *ModelerTuner) Options() map[string]string {
+	return map[string]string{
+		"sr":        "the portion of the datasets evaluated (default is 0.2)",
+		"folds":     "the number of folds (default is 5)",
+		"trials":    "the number of random configurations, 0 for grid search (default is 0)",
+		"metric":    "the metric used to rank the configurations [RMSE | MAE | MAPE | R^2]",
+		"seed":      "the seed of the random generator",
+		"estimator": "the similarity estimator used for the estimator.* parameters",
+	}
+}
+
+// Tune evaluates the datasets (once) and cross validates the configurations
+// of the space, each one extending the base configuration. The space maps
+// each parameter to its candidate values. The results are returned in
+// increasing error order, i.e., the best configuration comes first.
+func (t *ModelerTuner) Tune(base map[string]string, space map[string][]string) ([]*TuningResult, error) {
+	if t.samples == nil {
+		pool := AbstractModeler{datasets: t.datasets, evaluator: t.evaluator, samplingRate: t.samplingRate}
+		pool.deploySamples()
+		t.samples = pool.samples
+	}
+	t.tempFiles = make(map[string]string)
+	defer func() {
+		for _, f := range t.tempFiles {
+			os.Remove(f)
+		}
+	}()
+
+	var results []*TuningResult
+	for i, conf := range t.configurations(base, space) {
+		modelerConf, err := t.resolve(conf)
+		if err != nil {
+			log.Println("Configuration", conf, "skipped:", err)
+			continue
+		}
+		res, err := CrossValidate(t.modelerType, t.datasets, t.samples, modelerConf, t.folds, t.seed)
+		if err != nil {
+			log.Println("Configuration", conf, "skipped:", err)
+			continue
+		}
+		res.Configuration = conf
+		log.Printf("Configuration %d: %v, %s: %.5f\n", i, conf, t.metric, res.Metrics[t.metric+"-validation"])
+		results = append(results, res)
+	}
+	if len(results) == 0 {
+		return nil, errors.New("No configuration was successfully validated")
+	}
+	sort.Stable(tuningResultOrder{results, t.metric + "-validation", t.metric == "R^2"})
+	return results, nil
+}
+
+// configurations returns the configurations of the grid, or a random subset
+// of them if trials is positive
+func (t *ModelerTuner) configurations(base map[string]string, space map[string][]string) []map[string]string {
+	var keys []string
+	for k, v := range space {
+		if len(v) > 0 {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	newConf := func(choice func(k string) string) map[string]string {
+		conf := make(map[string]string)
+		for k, v := range base {
+			conf[k] = v
+		}
+		for _, k := range keys {
+			conf[k] = choice(k)
+		}
+		return conf
+	}
+	var confs []map[string]string
+	if t.trials > 0 {
+		r := rand.New(rand.NewSource(t.seed))
+		for i := 0; i < t.trials; i++ {
+			confs = append(confs, newConf(func(k string) string {
+				return space[k][r.Intn(len(space[k]))]
+			}))
+		}
+		return confs
+	}
+	// the cartesian product of the candidate values
+	counters := make([]int, len(keys))
+	for {
+		confs = append(confs, newConf(func(k string) string {
+			return space[k][counters[sort.SearchStrings(keys, k)]]
+		}))
+		i := len(keys) - 1
+		for ; i >= 0; i-- {
+			counters[i]++
+			if counters[i] < len(space[keys[i]]) {
+				break
+			}
+			counters[i] = 0
+		}
+		if i < 0 {
+			return confs
+		}
+	}
+}
+
+// resolve transforms the estimator.* and mds.* parameters of a configuration
+// to the smatrix and coordinates files expected by the modelers
+func (t *ModelerTuner) resolve(conf map[string]string) (map[string]string, error) {
+	modelerConf, estimatorConf := make(map[string]string), make(map[string]string)
+	for k, v := range conf {
+		if strings.HasPrefix(k, "estimator.") {
+			estimatorConf[strings.TrimPrefix(k, "estimator.")] = v
+		} else if k != "mds.k" {
+			modelerConf[k] = v
+		}
+	}
+	if len(estimatorConf) > 0 {
+		if t.estimatorType == nil {
+			return nil, errors.New("The estimator parameter is needed for estimator.* parameters")
+		}
+		smPath, err := t.similarityMatrix(estimatorConf)
+		if err != nil {
+			return nil, err
+		}
+		modelerConf["smatrix"] = smPath
+	}
+	if val, ok := conf["mds.k"]; ok {
+		smPath, ok := modelerConf["smatrix"]
+		if !ok {
+			return nil, errors.New("The smatrix parameter is needed for the mds.k parameter")
+		}
+		k, err := strconv.ParseInt(val, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		coordsPath, err := t.coordinates(smPath, int(k))
+		if err != nil {
+			return nil, err
+		}
+		modelerConf["coordinates"] = coordsPath
+	}
+	return modelerConf, nil
+}
+
+// similarityMatrix computes (once) the similarity matrix of the given
+// estimator options and returns the path of its file
+func (t *ModelerTuner) similarityMatrix(conf map[string]string) (string, error) {
+	key := fmt.Sprintf("sm-%v", conf)
+	if f, ok := t.tempFiles[key]; ok {
+		return f, nil
+	}
+	est := NewDatasetSimilarityEstimator(*t.estimatorType, t.datasets)
+	est.Configure(conf)
+	if err := est.Compute(); err != nil {
+		return "", err
+	}
+	f, err := ioutil.TempFile("/tmp", "tuning-sm")
+	if err != nil {
+		return "", err
+	}
+	f.Write(est.SimilarityMatrix().Serialize())
+	f.Close()
+	t.tempFiles[key] = f.Name()
+	return f.Name(), nil
+}
+
+// coordinates computes (once) the classical MDS coordinates of a similarity
+// matrix for the given k and returns the path of their file
+func (t *ModelerTuner) coordinates(smPath string, k int) (string, error) {
+	key := fmt.Sprintf("coords-%s-%d", path.Clean(smPath), k)
+	if f, ok := t.tempFiles[key]; ok {
+		return f, nil
+	}
+	buf, err := ioutil.ReadFile(smPath)
+	if err != nil {
+		return "", err
+	}
+	sm := new(DatasetSimilarityMatrix)
+	if err := sm.Deserialize(buf); err != nil {
+		return "", err
+	}
+	mds := NewMDScalingWithType(sm, k, MDScalingClassicalType)
+	if err := mds.Compute(); err != nil {
+		return "", err
+	}
+	f, err := ioutil.TempFile("/tmp", "tuning-coords")
+	if err != nil {
+		return "", err
+	}
+	f.Write(SerializeCoordinates(mds.Coordinates()))
+	f.Close()
+	t.tempFiles[key] = f.Name()
+	return f.Name(), nil
+}
+
+// tuningResultOrder sorts the tuning results according to a metric
+type tuningResultOrder struct {
+	results    []*TuningResult
+	metric     string
+	descending bool
+}
+
+func (o tuningResultOrder) Len() int      { return len(o.results) }
+func (o tuningResultOrder) Swap(i, j int) { o.results[i], o.results[j] = o.results[j], o.results[i] }
+func (o tuningResultOrder) Less(i, j int) bool {
+	a, b := o.results[i].Metrics[o.metric], o.results[j].Metrics[o.metric]
+	if math.IsNaN(b) {
+		return !math.IsNaN(a)
+	}
+	if o.descending {
+		return a > b
+	}
+	return a < b
+}
+
+// samplesEvaluator returns the values of the already evaluated datasets
+type samplesEvaluator struct {
+	values map[string]float64
+}
+
+func (e *samplesEvaluator) Evaluate(dataset string) (float64, error) {
+	if val, ok := e.values[dataset]; ok {
+		return val, nil
+	}
+	return math.NaN(), errors.New("Dataset not evaluated")
+}
+
+// fixedSampling is the sampling strategy that returns a predefined set of
+// datasets
+type fixedSampling struct {
+	indices []int
+}
+
+func (s *fixedSampling) Configure(conf map[string]string) error { return nil }
+func (s *fixedSampling) Options() map[string]string             { return map[string]string{} }
+func (s *fixedSampling) Next(samples map[int]float64, excluded map[int]bool, count int) []int {
+	var res []int
+	for _, idx := range s.indices {
+		if len(res) < count && !excluded[idx] {
+			res = append(res, idx)
+		}
+	}
+	return res
+}
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"os"
+	"testing"
+)
+
+func TestCrossValidate(t *testing.T) {
+	datasets := createPoolBasedDatasets(100, 50, 2)
+	defer cleanDatasets(datasets)
+	coords, eval := createRegressionSetup(datasets, func(x []float64) float64 {
+		return 2*x[0] - x[1] + 3*x[2]
+	})
+	defer os.Remove(coords)
+	samples := make(map[int]float64)
+	for i := 0; i < 20; i++ {
+		samples[i], _ = eval.Evaluate(datasets[i].Path())
+	}
+
+	if _, err := CrossValidate(LinearModelerType, datasets, samples,
+		map[string]string{"coordinates": coords}, 30, 1); err == nil {
+		t.Log("More folds than samples should be rejected")
+		t.Fail()
+	}
+	res, err := CrossValidate(LinearModelerType, datasets, samples,
+		map[string]string{"coordinates": coords}, 4, 1)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if len(res.Folds) != 4 {
+		t.Log("Wrong number of folds", len(res.Folds))
+		t.Fail()
+	}
+	if res.Metrics["RMSE-validation"] > 1e-6 {
+		t.Log("Linear function not recovered", res.Metrics)
+		t.Fail()
+	}
+}
+
+func TestModelerTunerConfigurations(t *testing.T) {
+	tuner := NewModelerTuner(KNNModelerType, nil, nil)
+	space := map[string][]string{"k": {"1", "3", "5"}, "regression": {"true", "false"}, "empty": {}}
+	confs := tuner.configurations(map[string]string{"smatrix": "foo"}, space)
+	if len(confs) != 6 {
+		t.Log("Wrong number of grid configurations", len(confs))
+		t.FailNow()
+	}
+	seen := make(map[string]bool)
+	for _, c := range confs {
+		if c["smatrix"] != "foo" {
+			t.Log("The base configuration is missing", c)
+			t.Fail()
+		}
+		seen[c["k"]+c["regression"]] = true
+	}
+	if len(seen) != 6 {
+		t.Log("Duplicate grid configurations", confs)
+		t.Fail()
+	}
+	tuner.Configure(map[string]string{"trials": "4"})
+	if confs = tuner.configurations(nil, space); len(confs) != 4 {
+		t.Log("Wrong number of random configurations", len(confs))
+		t.Fail()
+	}
+	if err := tuner.Configure(map[string]string{"folds": "1"}); err == nil {
+		t.Log("A single fold should be rejected")
+		t.Fail()
+	}
+}
+
+func TestModelerTunerTune(t *testing.T) {
+	datasets := createPoolBasedDatasets(100, 50, 2)
+	defer cleanDatasets(datasets)
+	coords, eval := createRegressionSetup(datasets, func(x []float64) float64 {
+		return x[0] + x[1]*x[1] - x[2]
+	})
+	defer os.Remove(coords)
+	smFile := createKernelSimilarityMatrix(coords, 0.0)
+	defer os.Remove(smFile)
+
+	tuner := NewModelerTuner(KNNModelerType, datasets, eval)
+	if err := tuner.Configure(map[string]string{"sr": "0.5", "folds": "5", "seed": "3"}); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	results, err := tuner.Tune(map[string]string{"smatrix": smFile, "regression": "true"},
+		map[string][]string{"k": {"1", "3", "5"}})
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if len(results) != 3 || len(results[0].Folds) != 5 {
+		t.Log("Wrong number of results or folds")
+		t.FailNow()
+	}
+	for i := 1; i < len(results); i++ {
+		if results[i-1].Metrics["RMSE-validation"] > results[i].Metrics["RMSE-validation"] {
+			t.Log("The results are not ordered")
+			t.Fail()
+		}
+	}
+
+	// the coordinates are computed from the similarity matrix
+	tuner = NewModelerTuner(LinearModelerType, datasets, eval)
+	tuner.Configure(map[string]string{"sr": "0.5", "metric": "R^2"})
+	results, err = tuner.Tune(map[string]string{"smatrix": smFile},
+		map[string][]string{"mds.k": {"1", "2", "3"}})
+	if err != nil || len(results) != 3 {
+		t.Log("MDS dimensionality not tuned", err)
+		t.FailNow()
+	}
+	if results[0].Metrics["R^2-validation"] < results[2].Metrics["R^2-validation"] {
+		t.Log("The results are not ordered by decreasing R^2")
+		t.Fail()
+	}
+
+	// the similarity matrix is computed by the estimator
+	tuner = NewModelerTuner(KNNModelerType, datasets, eval)
+	tuner.Configure(map[string]string{"sr": "0.5", "estimator": "size"})
+	results, err = tuner.Tune(map[string]string{"k": "3"},
+		map[string][]string{"estimator.concurrency": {"1", "2"}})
+	if err != nil || len(results) != 2 {
+		t.Log("Estimator parameters not tuned", err)
+		t.Fail()
+	}
+}
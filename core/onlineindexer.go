@@ -15,7 +15,8 @@ import (
 
 // OnlineIndexer is used to execute online indexing. The user can supply a map containing
 // distances from original datasets and the indexer returns the coordinates of the
-// specified dataset.
+// specified dataset. The coordinates are computed natively, through stress
+// majorization, unless a script is provided.
 type OnlineIndexer struct {
 	coordinates []DatasetCoordinates       // the coordinates of the datasets
 	estimator   DatasetSimilarityEstimator // estimator object to calculate distances
@@ -23,10 +24,12 @@ type OnlineIndexer struct {
 
 	dimensionality    int // the number of dimensions of the coordinates
 	datasetsToCompare int // number of datasets to compare when calculate is called
+	restarts          int // number of random starting points of the native solver
 }
 
 // NewOnlineIndexer is a constructor function used to initialize an OnlineIndexer
-// object.
+// object. If script is empty, the coordinates are computed natively, else the
+// script is used for the computation.
 func NewOnlineIndexer(estimator DatasetSimilarityEstimator,
 	coordinates []DatasetCoordinates,
 	script string) *OnlineIndexer {
@@ -37,6 +40,7 @@ func NewOnlineIndexer(estimator DatasetSimilarityEstimator,
 
 	indexer.dimensionality = len(coordinates[0])
 	indexer.datasetsToCompare = len(coordinates)
+	indexer.restarts = 10
 	return indexer
 }
 
@@ -48,6 +52,14 @@ func (o *OnlineIndexer) DatasetsToCompare(datasets int) {
 	}
 }
 
+// Restarts is a setter method to determine the number of random starting
+// points of the native solver
+func (o *OnlineIndexer) Restarts(restarts int) {
+	if restarts >= 0 {
+		o.restarts = restarts
+	}
+}
+
 // Calculate method is responsible to calculate the coordinates of the specified
 // dataset. In case that such a dataset cannot be represented by the specified
 // coordinates system, an error is returned.
@@ -55,11 +67,113 @@ func (o *OnlineIndexer) Calculate(dataset *Dataset) (DatasetCoordinates, float64
 	// calculate the distances for the new dataset
 	log.Println("Creating dataset permutation")
 	perm := rand.Perm(len(o.estimator.Datasets()))
+	if o.datasetsToCompare < len(perm) {
+		perm = perm[:o.datasetsToCompare]
+	}
+
+	points := make([]DatasetCoordinates, len(perm))
+	actualDistances := make([]float64, len(perm))
+	for c, i := range perm {
+		dat := o.estimator.Datasets()[i]
+		sim := o.estimator.Similarity(dataset, dat)
+		points[c] = o.coordinates[i]
+		actualDistances[c] = SimilarityToDistance(sim)
+	}
+
+	var coords DatasetCoordinates
+	var err error
+	if o.script != "" {
+		coords, err = o.calculateScript(points, actualDistances)
+		if err != nil {
+			return nil, -1.0, err
+		}
+	} else {
+		coords = onlineIndexerPlace(points, actualDistances, o.restarts)
+	}
+	calculatedDistances := make([]float64, len(perm))
+	for c := range points {
+		calculatedDistances[c] = euclideanDistance(coords, points[c])
+	}
+
+	return coords, o.stress(actualDistances, calculatedDistances), err
+}
+
+// IndexingResult holds the outcome of the indexing of a dataset
+type IndexingResult struct {
+	Dataset     *Dataset
+	Coordinates DatasetCoordinates
+	Stress      float64
+	Error       error
+}
+
+// Coordinates returns the coordinates of the reference datasets, i.e., the
+// datasets of the estimator
+func (o *OnlineIndexer) Coordinates() []DatasetCoordinates {
+	return o.coordinates
+}
 
+// CalculateBatch calculates the coordinates of many datasets, utilizing a
+// pool of workers; the results follow the order of the datasets. If update
+// is true, the successfully indexed datasets are appended to the estimator
+// and their coordinates to the reference coordinates, so that they are
+// utilized by the subsequent calculations.
+func (o *OnlineIndexer) CalculateBatch(datasets []*Dataset, workers int, update bool) ([]IndexingResult, error) {
+	if workers < 1 {
+		workers = 1
+	}
+	results := make([]IndexingResult, len(datasets))
+	c, done := make(chan bool, workers), make(chan bool)
+	for j := 0; j < workers; j++ {
+		c <- true
+	}
+	for i := range datasets {
+		go func(i int) {
+			<-c
+			coords, stress, err := o.Calculate(datasets[i])
+			if err != nil {
+				log.Println(datasets[i].Path(), err)
+			}
+			results[i] = IndexingResult{datasets[i], coords, stress, err}
+			c <- true
+			done <- true
+		}(i)
+	}
+	for range datasets {
+		<-done
+	}
+	if !update {
+		return results, nil
+	}
+
+	var appended []*Dataset
+	var coordinates []DatasetCoordinates
+	for _, r := range results {
+		if r.Error == nil {
+			appended = append(appended, r.Dataset)
+			coordinates = append(coordinates, r.Coordinates)
+		}
+	}
+	if err := AppendDatasets(o.estimator, appended); err != nil {
+		log.Println(err)
+		return results, err
+	}
+	previous := o.coordinates
+	o.coordinates = make([]DatasetCoordinates, len(previous), len(previous)+len(coordinates))
+	copy(o.coordinates, previous)
+	o.coordinates = append(o.coordinates, coordinates...)
+	if o.datasetsToCompare == len(previous) {
+		o.datasetsToCompare = len(o.coordinates)
+	}
+	return results, nil
+}
+
+// calculateScript writes the coordinates and the distances of the reference
+// datasets to a CSV file and executes the script to place the new dataset
+func (o *OnlineIndexer) calculateScript(points []DatasetCoordinates, distances []float64) (DatasetCoordinates, error) {
 	writer, err := ioutil.TempFile("/tmp", "coordinates.csv-")
 	if err != nil {
 		log.Println(err)
-		return nil, -1.0, err
+		return nil, err
 	}
 	defer os.Remove(writer.Name())
 
@@ -67,38 +181,112 @@ func (o *OnlineIndexer) Calculate(dataset *Dataset) (DatasetCoordinates, float64
 		fmt.Fprintf(writer, "x%d,", i+1)
 	}
 	fmt.Fprintf(writer, "d\n")
+	for c := range points {
+		for j := 0; j < o.dimensionality; j++ {
+			fmt.Fprintf(writer, "%.5f,", points[c][j])
+		}
+		fmt.Fprintf(writer, "%.5f\n", distances[c])
+	}
+	writer.Close()
+	return o.executeScript(writer.Name())
+}
 
-	actualDistances := make([]float64, o.datasetsToCompare)
-	for c, i := range perm {
-		if c >= o.datasetsToCompare {
-			break
+// onlineIndexerPlace places a new point so that its distances to the
+// reference points approximate the given distances. The Sammon stress of
+// the point is minimized through stress majorization (the Guttman transform
+// of a single point), starting from the closest reference point, the
+// weighted centroid of the reference points and a number of random points
+// around them; the solution with the lowest stress is kept.
+func onlineIndexerPlace(points []DatasetCoordinates, distances []float64, restarts int) DatasetCoordinates {
+	dims := len(points[0])
+	closest, maxDistance := 0, 0.0
+	for i, d := range distances {
+		if d == 0 { // an identical dataset
+			return append(DatasetCoordinates{}, points[i]...)
 		}
-		dat := o.estimator.Datasets()[i]
-		sim := o.estimator.Similarity(dataset, dat)
+		if d < distances[closest] {
+			closest = i
+		}
+		maxDistance = math.Max(maxDistance, d)
+	}
+	centroid, sumWeights := make(DatasetCoordinates, dims), 0.0
+	lower, upper := make([]float64, dims), make([]float64, dims)
+	for j := range lower {
+		lower[j], upper[j] = math.Inf(1), math.Inf(-1)
+	}
+	for i, p := range points {
+		for j := range p {
+			centroid[j] += p[j] / distances[i]
+			lower[j], upper[j] = math.Min(lower[j], p[j]), math.Max(upper[j], p[j])
+		}
+		sumWeights += 1 / distances[i]
+	}
+	for j := range centroid {
+		centroid[j] /= sumWeights
+	}
 
-		for j := 0; j < o.dimensionality; j++ {
-			fmt.Fprintf(writer, "%.5f,", o.coordinates[i][j])
+	starts := []DatasetCoordinates{append(DatasetCoordinates{}, points[closest]...), centroid}
+	for r := 0; r < restarts; r++ {
+		start := make(DatasetCoordinates, dims)
+		for j := range start {
+			start[j] = lower[j] - maxDistance + rand.Float64()*(upper[j]-lower[j]+2*maxDistance)
 		}
-		actualDistances[c] = SimilarityToDistance(sim)
-		fmt.Fprintf(writer, "%.5f\n", actualDistances[c])
+		starts = append(starts, start)
 	}
-	writer.Close()
-	coords, err := o.executeScript(writer.Name())
-	if err != nil {
-		return nil, -1.0, err
+	var best DatasetCoordinates
+	bestStress := math.Inf(1)
+	for _, x := range starts {
+		x, stress := onlineIndexerMajorize(points, distances, x, 500, 1e-10)
+		if stress < bestStress {
+			best, bestStress = x, stress
+		}
 	}
-	calculatedDistances := make([]float64, o.datasetsToCompare)
-	for c, i := range perm {
-		if c >= o.datasetsToCompare {
-			break
+	return best
+}
+
+// onlineIndexerMajorize iteratively applies the Guttman transform to the
+// point, with Sammon weights 1/d, and returns the point and its stress
+func onlineIndexerMajorize(points []DatasetCoordinates, distances []float64,
+	x DatasetCoordinates, iterations int, tolerance float64) (DatasetCoordinates, float64) {
+	sumWeights := 0.0
+	for _, d := range distances {
+		sumWeights += 1 / d
+	}
+	stress := onlineIndexerSammonStress(points, distances, x)
+	for it := 0; it < iterations; it++ {
+		next := make(DatasetCoordinates, len(x))
+		for i, p := range points {
+			w, r := 1/distances[i], euclideanDistance(x, p)
+			for j := range next {
+				next[j] += w * p[j]
+				if r > 0 {
+					next[j] += w * distances[i] * (x[j] - p[j]) / r
+				}
+			}
 		}
-		for j := 0; j < o.dimensionality; j++ {
-			calculatedDistances[c] += (coords[j] - o.coordinates[i][j]) * (coords[j] - o.coordinates[i][j])
+		for j := range next {
+			next[j] /= sumWeights
+		}
+		x = next
+		current := onlineIndexerSammonStress(points, distances, x)
+		if stress-current < tolerance*stress {
+			stress = current
+			break
 		}
-		calculatedDistances[c] = math.Sqrt(calculatedDistances[c])
+		stress = current
 	}
+	return x, stress
+}
 
-	return coords, o.stress(actualDistances, calculatedDistances), err
+// onlineIndexerSammonStress returns the Sammon stress of the point, i.e.,
+// the sum of (d - r)^2 / d over the reference points
+func onlineIndexerSammonStress(points []DatasetCoordinates, distances []float64, x DatasetCoordinates) float64 {
+	stress := 0.0
+	for i, p := range points {
+		r := euclideanDistance(x, p)
+		stress += (distances[i] - r) * (distances[i] - r) / distances[i]
+	}
+	return stress
 }
 
 // returns the stress factor (the difference between the actual and the calculated
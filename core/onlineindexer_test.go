@@ -1,6 +1,7 @@
 package core
 
 import (
+	"math"
 	"math/rand"
 	"testing"
 )
@@ -32,3 +33,107 @@ func TestNewOnlineIndexer(t *testing.T) {
 	}
 	cleanDatasets(datasets)
 }
+
+func TestOnlineIndexerPlace(t *testing.T) {
+	points := []DatasetCoordinates{{0, 0}, {1, 0}, {0, 2}, {3, 1}, {2, 2}, {1, 3}}
+	for _, target := range []DatasetCoordinates{{1.5, 1.5}, {-2, 4}, {5, -1}} {
+		distances := make([]float64, len(points))
+		for i := range points {
+			distances[i] = euclideanDistance(points[i], target)
+		}
+		x := onlineIndexerPlace(points, distances, 5)
+		if euclideanDistance(x, target) > 1e-3 {
+			t.Log("Expected", target, "found", x)
+			t.Fail()
+		}
+	}
+	// identical datasets share their coordinates
+	x := onlineIndexerPlace(points, []float64{1, 0, 1, 1, 1, 1}, 5)
+	if x[0] != 1 || x[1] != 0 {
+		t.Log("Expected the coordinates of the identical dataset, found", x)
+		t.Fail()
+	}
+}
+
+func TestOnlineIndexerNative(t *testing.T) {
+	datasets := createPoolBasedDatasets(200, 40, 4)
+	defer cleanDatasets(datasets)
+	estim := NewDatasetSimilarityEstimator(SimilarityTypeJaccard, datasets)
+	estim.Configure(map[string]string{"concurrency": "10"})
+	if err := estim.Compute(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	md := NewMDScaling(estim.SimilarityMatrix(), 3, "")
+	if err := md.Compute(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	indexer := NewOnlineIndexer(estim, md.Coordinates(), "")
+	// the coordinates of an indexed dataset are close to its original ones
+	// compared to the rest of the datasets
+	for _, idx := range rand.Perm(len(datasets))[:5] {
+		coords, stress, err := indexer.Calculate(datasets[idx])
+		if err != nil || len(coords) != 3 || math.IsNaN(stress) {
+			t.Log(err, coords, stress)
+			t.FailNow()
+		}
+		closer := 0
+		own := euclideanDistance(coords, md.Coordinates()[idx])
+		for _, c := range md.Coordinates() {
+			if euclideanDistance(coords, c) < own {
+				closer++
+			}
+		}
+		if closer > len(datasets)/5 {
+			t.Log("Dataset", idx, "placed away from its coordinates", closer)
+			t.Fail()
+		}
+	}
+}
+
+func TestOnlineIndexerBatch(t *testing.T) {
+	datasets := createPoolBasedDatasets(200, 40, 4)
+	defer cleanDatasets(datasets)
+	estim := NewDatasetSimilarityEstimator(SimilarityTypeJaccard, datasets[:30])
+	estim.Configure(map[string]string{"concurrency": "10"})
+	if err := estim.Compute(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	md := NewMDScaling(estim.SimilarityMatrix(), 2, "")
+	if err := md.Compute(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	indexer := NewOnlineIndexer(estim, md.Coordinates(), "")
+	results, err := indexer.CalculateBatch(datasets[30:35], 3, false)
+	if err != nil || len(results) != 5 || len(estim.Datasets()) != 30 {
+		t.Log("Batch indexing without update failed", err)
+		t.FailNow()
+	}
+	results, err = indexer.CalculateBatch(datasets[30:], 3, true)
+	if err != nil || len(results) != 10 {
+		t.Log(err)
+		t.FailNow()
+	}
+	for i, r := range results {
+		if r.Error != nil || r.Dataset != datasets[30+i] || len(r.Coordinates) != 2 {
+			t.Log("Wrong result", r)
+			t.Fail()
+		}
+	}
+	if len(estim.Datasets()) != 40 || estim.SimilarityMatrix().Capacity() != 40 ||
+		len(indexer.Coordinates()) != 40 {
+		t.Log("The reference set was not updated")
+		t.Fail()
+	}
+	if len(md.Coordinates()) != 30 {
+		t.Log("The original coordinates should not be modified")
+		t.Fail()
+	}
+	if _, err := indexer.CalculateBatch(datasets[35:], 2, true); err == nil {
+		t.Log("Indexed datasets should not be appended again")
+		t.Fail()
+	}
+}
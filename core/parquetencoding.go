@@ -0,0 +1,527 @@
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// This file contains the low level decoders utilized by the Parquet dataset
+// reader: the Thrift compact protocol (used for the file metadata and the
+// page headers), the RLE/bit-packing hybrid encoding, the PLAIN encoding
+// and the Snappy and LZ4 decompression.
+
+// Thrift compact protocol types
+const (
+	thriftStop         = 0
+	thriftBooleanTrue  = 1
+	thriftBooleanFalse = 2
+	thriftByte         = 3
+	thriftI16          = 4
+	thriftI32          = 5
+	thriftI64          = 6
+	thriftDouble       = 7
+	thriftBinary       = 8
+	thriftList         = 9
+	thriftSet          = 10
+	thriftMap          = 11
+	thriftStruct       = 12
+)
+
+// thriftFields holds the fields of a decoded Thrift struct, indexed by their
+// ids. The values are int64, bool, float64, []byte, []interface{},
+// map[interface{}]interface{} or thriftFields objects.
+type thriftFields map[int16]interface{}
+
+// Int returns the integer field with the given id, or def if not present
+func (f thriftFields) Int(id int16, def int64) int64 {
+	if v, ok := f[id].(int64); ok {
+		return v
+	}
+	return def
+}
+
+// Bool returns the boolean field with the given id, or def if not present
+func (f thriftFields) Bool(id int16, def bool) bool {
+	if v, ok := f[id].(bool); ok {
+		return v
+	}
+	return def
+}
+
+// String returns the binary field with the given id as a string
+func (f thriftFields) String(id int16) string {
+	if v, ok := f[id].([]byte); ok {
+		return string(v)
+	}
+	return ""
+}
+
+// Struct returns the struct field with the given id, or nil
+func (f thriftFields) Struct(id int16) thriftFields {
+	if v, ok := f[id].(thriftFields); ok {
+		return v
+	}
+	return nil
+}
+
+// List returns the list field with the given id, or nil
+func (f thriftFields) List(id int16) []interface{} {
+	if v, ok := f[id].([]interface{}); ok {
+		return v
+	}
+	return nil
+}
+
+// thriftCompactDecoder decodes Thrift compact protocol messages
+type thriftCompactDecoder struct {
+	buf []byte
+	pos int
+}
+
+func (d *thriftCompactDecoder) readByte() (byte, error) {
+	if d.pos >= len(d.buf) {
+		return 0, errors.New("thrift: unexpected end of data")
+	}
+	b := d.buf[d.pos]
+	d.pos++
+	return b, nil
+}
+
+func (d *thriftCompactDecoder) readVarint() (uint64, error) {
+	v, n := binary.Uvarint(d.buf[d.pos:])
+	if n <= 0 {
+		return 0, errors.New("thrift: invalid varint")
+	}
+	d.pos += n
+	return v, nil
+}
+
+func (d *thriftCompactDecoder) readZigZag() (int64, error) {
+	v, err := d.readVarint()
+	if err != nil {
+		return 0, err
+	}
+	return int64(v>>1) ^ -int64(v&1), nil
+}
+
+// readStruct decodes a struct, until its stop field
+func (d *thriftCompactDecoder) readStruct() (thriftFields, error) {
+	fields := make(thriftFields)
+	var lastID int16
+	for {
+		header, err := d.readByte()
+		if err != nil {
+			return nil, err
+		}
+		fieldType := header & 0x0f
+		if fieldType == thriftStop {
+			return fields, nil
+		}
+		if delta := int16(header >> 4); delta != 0 {
+			lastID += delta
+		} else {
+			id, err := d.readZigZag()
+			if err != nil {
+				return nil, err
+			}
+			lastID = int16(id)
+		}
+		var value interface{}
+		if fieldType == thriftBooleanTrue || fieldType == thriftBooleanFalse {
+			value = fieldType == thriftBooleanTrue
+		} else if value, err = d.readValue(fieldType); err != nil {
+			return nil, err
+		}
+		fields[lastID] = value
+	}
+}
+
+// readValue decodes a value of the given type
+func (d *thriftCompactDecoder) readValue(t byte) (interface{}, error) {
+	switch t {
+	case thriftBooleanTrue, thriftBooleanFalse: // boolean list elements
+		b, err := d.readByte()
+		return b == thriftBooleanTrue, err
+	case thriftByte:
+		b, err := d.readByte()
+		return int64(int8(b)), err
+	case thriftI16, thriftI32, thriftI64:
+		return d.readZigZag()
+	case thriftDouble:
+		if d.pos+8 > len(d.buf) {
+			return nil, errors.New("thrift: unexpected end of data")
+		}
+		v := math.Float64frombits(binary.LittleEndian.Uint64(d.buf[d.pos:]))
+		d.pos += 8
+		return v, nil
+	case thriftBinary:
+		length, err := d.readVarint()
+		if err != nil {
+			return nil, err
+		}
+		if uint64(len(d.buf)-d.pos) < length {
+			return nil, errors.New("thrift: unexpected end of data")
+		}
+		v := d.buf[d.pos : d.pos+int(length)]
+		d.pos += int(length)
+		return v, nil
+	case thriftList, thriftSet:
+		header, err := d.readByte()
+		if err != nil {
+			return nil, err
+		}
+		size := uint64(header >> 4)
+		if size == 15 {
+			if size, err = d.readVarint(); err != nil {
+				return nil, err
+			}
+		}
+		if size > uint64(len(d.buf)) {
+			return nil, errors.New("thrift: invalid list size")
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			if list[i], err = d.readValue(header & 0x0f); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case thriftMap:
+		size, err := d.readVarint()
+		if err != nil {
+			return nil, err
+		}
+		result := make(map[interface{}]interface{})
+		if size == 0 {
+			return result, nil
+		}
+		types, err := d.readByte()
+		if err != nil {
+			return nil, err
+		}
+		for i := uint64(0); i < size; i++ {
+			k, err := d.readValue(types >> 4)
+			if err != nil {
+				return nil, err
+			}
+			v, err := d.readValue(types & 0x0f)
+			if err != nil {
+				return nil, err
+			}
+			if b, ok := k.([]byte); ok { // []byte keys are not hashable
+				k = string(b)
+			}
+			result[k] = v
+		}
+		return result, nil
+	case thriftStruct:
+		return d.readStruct()
+	}
+	return nil, fmt.Errorf("thrift: unknown type %d", t)
+}
+
+// bitWidth returns the number of bits needed to represent max
+func bitWidth(max int) int {
+	width := 0
+	for max > 0 {
+		width++
+		max >>= 1
+	}
+	return width
+}
+
+// decodeRLEHybrid decodes count values of the given bit width, encoded through
+// the RLE/bit-packing hybrid encoding. It returns the values and the number
+// of consumed bytes.
+func decodeRLEHybrid(buf []byte, width, count int) ([]int, int, error) {
+	values := make([]int, 0, count)
+	pos := 0
+	byteWidth := (width + 7) / 8
+	for len(values) < count {
+		header, n := binary.Uvarint(buf[pos:])
+		if n <= 0 {
+			return nil, pos, errors.New("parquet: invalid RLE header")
+		}
+		pos += n
+		if header&1 == 0 { // RLE run
+			run := int(header >> 1)
+			if pos+byteWidth > len(buf) {
+				return nil, pos, errors.New("parquet: unexpected end of RLE data")
+			}
+			value := 0
+			for i := 0; i < byteWidth; i++ {
+				value |= int(buf[pos+i]) << (8 * uint(i))
+			}
+			pos += byteWidth
+			for i := 0; i < run && len(values) < count; i++ {
+				values = append(values, value)
+			}
+		} else { // bit-packed run
+			groups := int(header >> 1)
+			bytes := groups * width
+			if pos+bytes > len(buf) {
+				return nil, pos, errors.New("parquet: unexpected end of bit-packed data")
+			}
+			for i := 0; i < groups*8 && len(values) < count; i++ {
+				value := 0
+				for b := 0; b < width; b++ {
+					bit := i*width + b
+					if buf[pos+bit/8]&(1<<uint(bit%8)) != 0 {
+						value |= 1 << uint(b)
+					}
+				}
+				values = append(values, value)
+			}
+			pos += bytes
+		}
+	}
+	return values, pos, nil
+}
+
+// decodeSnappy decompresses a block in the Snappy raw format
+func decodeSnappy(src []byte) ([]byte, error) {
+	length, n := binary.Uvarint(src)
+	if n <= 0 || length > uint64(math.MaxInt32) {
+		return nil, errors.New("snappy: invalid length")
+	}
+	dst := make([]byte, 0, length)
+	pos := n
+	for pos < len(src) {
+		tag := src[pos]
+		pos++
+		var offset, size int
+		switch tag & 0x03 {
+		case 0x00: // literal
+			size = int(tag >> 2)
+			if size >= 60 {
+				extra := size - 59
+				if pos+extra > len(src) {
+					return nil, errors.New("snappy: corrupt input")
+				}
+				size = 0
+				for i := 0; i < extra; i++ {
+					size |= int(src[pos+i]) << (8 * uint(i))
+				}
+				pos += extra
+			}
+			size++
+			if size <= 0 || pos+size > len(src) {
+				return nil, errors.New("snappy: corrupt input")
+			}
+			dst = append(dst, src[pos:pos+size]...)
+			pos += size
+			continue
+		case 0x01:
+			if pos >= len(src) {
+				return nil, errors.New("snappy: corrupt input")
+			}
+			size = 4 + int((tag>>2)&0x07)
+			offset = int(tag&0xe0)<<3 | int(src[pos])
+			pos++
+		case 0x02:
+			if pos+2 > len(src) {
+				return nil, errors.New("snappy: corrupt input")
+			}
+			size = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src[pos:]))
+			pos += 2
+		case 0x03:
+			if pos+4 > len(src) {
+				return nil, errors.New("snappy: corrupt input")
+			}
+			size = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src[pos:]))
+			pos += 4
+		}
+		if offset <= 0 || offset > len(dst) {
+			return nil, errors.New("snappy: invalid copy offset")
+		}
+		for i := 0; i < size; i++ { // copies may overlap
+			dst = append(dst, dst[len(dst)-offset])
+		}
+	}
+	if uint64(len(dst)) != length {
+		return nil, errors.New("snappy: length mismatch")
+	}
+	return dst, nil
+}
+
+// decodeLZ4Block decompresses a block in the LZ4 raw format; size is the
+// length of the decompressed block
+func decodeLZ4Block(src []byte, size int) ([]byte, error) {
+	if size < 0 || size > math.MaxInt32 {
+		return nil, errors.New("lz4: invalid length")
+	}
+	// readLength extends a 4-bit length through the following bytes
+	readLength := func(pos, length int) (int, int, error) {
+		if length < 15 {
+			return pos, length, nil
+		}
+		for {
+			if pos >= len(src) {
+				return pos, 0, errors.New("lz4: corrupt input")
+			}
+			b := src[pos]
+			pos++
+			length += int(b)
+			if b != 255 {
+				return pos, length, nil
+			}
+		}
+	}
+	dst := make([]byte, 0, size)
+	pos := 0
+	for pos < len(src) {
+		token := src[pos]
+		pos++
+		var literals, length int
+		var err error
+		if pos, literals, err = readLength(pos, int(token>>4)); err != nil {
+			return nil, err
+		}
+		if pos+literals > len(src) || len(dst)+literals > size {
+			return nil, errors.New("lz4: corrupt input")
+		}
+		dst = append(dst, src[pos:pos+literals]...)
+		pos += literals
+		if pos == len(src) { // the last sequence holds only literals
+			break
+		}
+		if pos+2 > len(src) {
+			return nil, errors.New("lz4: corrupt input")
+		}
+		offset := int(binary.LittleEndian.Uint16(src[pos:]))
+		pos += 2
+		if pos, length, err = readLength(pos, int(token&0x0f)); err != nil {
+			return nil, err
+		}
+		length += 4
+		if offset <= 0 || offset > len(dst) {
+			return nil, errors.New("lz4: invalid copy offset")
+		}
+		if len(dst)+length > size {
+			return nil, errors.New("lz4: corrupt input")
+		}
+		for i := 0; i < length; i++ { // copies may overlap
+			dst = append(dst, dst[len(dst)-offset])
+		}
+	}
+	if len(dst) != size {
+		return nil, errors.New("lz4: length mismatch")
+	}
+	return dst, nil
+}
+
+// decodeHadoopLZ4 decompresses the deprecated LZ4 codec of Parquet, which
+// some writers (e.g., parquet-mr) frame as in Hadoop, i.e., as a sequence of
+// blocks prefixed by their big-endian decompressed and compressed lengths,
+// and others write as a single raw block
+func decodeHadoopLZ4(src []byte, size int) ([]byte, error) {
+	var dst []byte
+	pos := 0
+	for pos+8 <= len(src) && len(dst) < size {
+		blockSize := int(binary.BigEndian.Uint32(src[pos:]))
+		compressedSize := int(binary.BigEndian.Uint32(src[pos+4:]))
+		if blockSize < 0 || len(dst)+blockSize > size ||
+			compressedSize < 0 || pos+8+compressedSize > len(src) {
+			break
+		}
+		block, err := decodeLZ4Block(src[pos+8:pos+8+compressedSize], blockSize)
+		if err != nil {
+			break
+		}
+		dst = append(dst, block...)
+		pos += 8 + compressedSize
+	}
+	if pos == len(src) && len(dst) == size {
+		return dst, nil
+	}
+	return decodeLZ4Block(src, size)
+}
+
+// Parquet physical types
+const (
+	parquetBoolean           = 0
+	parquetInt32             = 1
+	parquetInt64             = 2
+	parquetInt96             = 3
+	parquetFloat             = 4
+	parquetDouble            = 5
+	parquetByteArray         = 6
+	parquetFixedLenByteArray = 7
+)
+
+// decodePlain decodes count values of the given physical type, encoded through
+// the PLAIN encoding. The values are returned as bool, int64, float64 or
+// []byte objects (INT96 values are returned as 12-byte slices).
+func decodePlain(buf []byte, physicalType, typeLength, count int) ([]interface{}, error) {
+	values := make([]interface{}, count)
+	pos := 0
+	need := func(n int) error {
+		if pos+n > len(buf) || n < 0 {
+			return errors.New("parquet: unexpected end of PLAIN data")
+		}
+		return nil
+	}
+	for i := range values {
+		switch physicalType {
+		case parquetBoolean:
+			if i/8 >= len(buf) {
+				return nil, errors.New("parquet: unexpected end of PLAIN data")
+			}
+			values[i] = buf[i/8]&(1<<uint(i%8)) != 0
+		case parquetInt32:
+			if err := need(4); err != nil {
+				return nil, err
+			}
+			values[i] = int64(int32(binary.LittleEndian.Uint32(buf[pos:])))
+			pos += 4
+		case parquetInt64:
+			if err := need(8); err != nil {
+				return nil, err
+			}
+			values[i] = int64(binary.LittleEndian.Uint64(buf[pos:]))
+			pos += 8
+		case parquetInt96:
+			if err := need(12); err != nil {
+				return nil, err
+			}
+			values[i] = buf[pos : pos+12]
+			pos += 12
+		case parquetFloat:
+			if err := need(4); err != nil {
+				return nil, err
+			}
+			values[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(buf[pos:])))
+			pos += 4
+		case parquetDouble:
+			if err := need(8); err != nil {
+				return nil, err
+			}
+			values[i] = math.Float64frombits(binary.LittleEndian.Uint64(buf[pos:]))
+			pos += 8
+		case parquetByteArray:
+			if err := need(4); err != nil {
+				return nil, err
+			}
+			length := int(binary.LittleEndian.Uint32(buf[pos:]))
+			pos += 4
+			if err := need(length); err != nil {
+				return nil, err
+			}
+			values[i] = buf[pos : pos+length]
+			pos += length
+		case parquetFixedLenByteArray:
+			if err := need(typeLength); err != nil {
+				return nil, err
+			}
+			values[i] = buf[pos : pos+typeLength]
+			pos += typeLength
+		default:
+			return nil, fmt.Errorf("parquet: unknown physical type %d", physicalType)
+		}
+	}
+	return values, nil
+}
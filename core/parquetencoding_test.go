@@ -0,0 +1,137 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// encodeBitPacked encodes the values as a bit-packed run of the
+// RLE/bit-packing hybrid encoding
+func encodeBitPacked(values []int, width int) []byte {
+	groups := (len(values) + 7) / 8
+	buf := []byte{byte(groups<<1 | 1)}
+	packed := make([]byte, groups*width)
+	for i, v := range values {
+		for b := 0; b < width; b++ {
+			if v&(1<<uint(b)) != 0 {
+				bit := i*width + b
+				packed[bit/8] |= 1 << uint(bit%8)
+			}
+		}
+	}
+	return append(buf, packed...)
+}
+
+func TestDecodeRLEHybrid(t *testing.T) {
+	// an RLE run of four 5s, followed by a bit-packed run of 0..7
+	buf := []byte{4 << 1, 5}
+	buf = append(buf, encodeBitPacked([]int{0, 1, 2, 3, 4, 5, 6, 7}, 3)...)
+	values, consumed, err := decodeRLEHybrid(buf, 3, 12)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	expected := []int{5, 5, 5, 5, 0, 1, 2, 3, 4, 5, 6, 7}
+	for i := range expected {
+		if values[i] != expected[i] {
+			t.Log("Expected", expected, "found", values)
+			t.FailNow()
+		}
+	}
+	if consumed != len(buf) {
+		t.Log("Expected", len(buf), "consumed bytes, found", consumed)
+		t.Fail()
+	}
+	if _, _, err := decodeRLEHybrid(buf[:4], 3, 12); err == nil {
+		t.Log("Truncated data should be rejected")
+		t.Fail()
+	}
+}
+
+func TestDecodeSnappy(t *testing.T) {
+	// literal "abcd", followed by an 1-byte offset copy of 8 bytes
+	src := []byte{12, 3 << 2, 'a', 'b', 'c', 'd', 0x01 | (8-4)<<2, 4}
+	dst, err := decodeSnappy(src)
+	if err != nil || string(dst) != "abcdabcdabcd" {
+		t.Log("Wrong output", string(dst), err)
+		t.Fail()
+	}
+	// literal "ab", followed by an overlapping 2-byte offset copy
+	src = []byte{6, 1 << 2, 'a', 'b', 0x02 | (4-1)<<2, 2, 0}
+	dst, err = decodeSnappy(src)
+	if err != nil || string(dst) != "ababab" {
+		t.Log("Wrong output", string(dst), err)
+		t.Fail()
+	}
+	if _, err := decodeSnappy([]byte{4, 0x01, 9}); err == nil {
+		t.Log("Invalid offset should be rejected")
+		t.Fail()
+	}
+}
+
+func TestDecodeLZ4(t *testing.T) {
+	// produced by the reference lz4 tool: an extended literal run, followed by
+	// an extended overlapping copy and the final literals
+	expected := strings.Repeat("0123456789abcdefghij", 21) + "xyz"
+	src := []byte{0xff, 0x05, 0x30, 0x31, 0x32, 0x33, 0x34, 0x35, 0x36, 0x37, 0x38,
+		0x39, 0x61, 0x62, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x69, 0x6a, 0x14, 0x00,
+		0xff, 0x7c, 0x50, 0x69, 0x6a, 0x78, 0x79, 0x7a}
+	dst, err := decodeLZ4Block(src, len(expected))
+	if err != nil || string(dst) != expected {
+		t.Log("Wrong output", string(dst), err)
+		t.Fail()
+	}
+
+	// the same block with the Hadoop framing
+	framed := make([]byte, 8)
+	binary.BigEndian.PutUint32(framed, uint32(len(expected)))
+	binary.BigEndian.PutUint32(framed[4:], uint32(len(src)))
+	dst, err = decodeHadoopLZ4(append(framed, src...), len(expected))
+	if err != nil || string(dst) != expected {
+		t.Log("Wrong output", string(dst), err)
+		t.Fail()
+	}
+	dst, err = decodeHadoopLZ4(src, len(expected))
+	if err != nil || string(dst) != expected {
+		t.Log("Raw blocks should be accepted", string(dst), err)
+		t.Fail()
+	}
+
+	if _, err := decodeLZ4Block([]byte{0x10, 'a', 2, 0}, 5); err == nil {
+		t.Log("Invalid offset should be rejected")
+		t.Fail()
+	}
+	if _, err := decodeLZ4Block(src, len(expected)-1); err == nil {
+		t.Log("Output exceeding the length should be rejected")
+		t.Fail()
+	}
+}
+
+func TestThriftCompactDecoder(t *testing.T) {
+	buf := new(bytes.Buffer)
+	writeThriftStruct(buf, []thriftTestField{
+		{1, thriftI32, int64(-3)},
+		{2, thriftBinary, "name"},
+		{20, thriftBooleanTrue, true},
+		{21, thriftList, thriftTestList{thriftI64, []interface{}{int64(1), int64(300)}}},
+		{22, thriftStruct, []thriftTestField{{1, thriftI64, int64(1) << 40}}},
+	})
+	decoder := &thriftCompactDecoder{buf: buf.Bytes()}
+	fields, err := decoder.readStruct()
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if fields.Int(1, 0) != -3 || fields.String(2) != "name" || !fields.Bool(20, false) ||
+		len(fields.List(21)) != 2 || fields.List(21)[1].(int64) != 300 ||
+		fields.Struct(22).Int(1, 0) != 1<<40 {
+		t.Log("Wrong fields", fields)
+		t.Fail()
+	}
+	if decoder.pos != buf.Len() {
+		t.Log("Struct not fully consumed")
+		t.Fail()
+	}
+}
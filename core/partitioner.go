@@ -86,6 +86,8 @@ type KMeansPartitioner struct {
 	centroids []DatasetTuple
 	// the weights of the columns - used for distance normalization
 	weights []float64
+	// the seed of the centroids initialization
+	seed int64
 }
 
 // Options returns the configuration options of the KMeansPartitioner
@@ -94,6 +96,7 @@ func (p *KMeansPartitioner) Options() map[string]string {
 		"partitions": "the number of partitions to use (k)",
 		"weights": "the weights of the columns to utilize for the comparison" +
 			"(default is to 1/(max - min) for each column)",
+		"seed": "the seed of the centroids initialization (default is 0)",
 	}
 }
 
@@ -128,12 +131,22 @@ func (p *KMeansPartitioner) Configure(conf map[string]string) {
 		}
 		log.Println("Setting weights", p.weights)
 	}
+
+	p.seed = 0
+	if val, ok := conf["seed"]; ok {
+		v, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			log.Println(err)
+		} else {
+			p.seed = v
+		}
+	}
 }
 
 // initializeCentroids estimates a very first position of the centroids
 // FIXME: can consider kmeans++
 func (p *KMeansPartitioner) initializeCentroids(tuples []DatasetTuple) {
-	perm := rand.Perm(len(tuples))
+	perm := rand.New(rand.NewSource(p.seed)).Perm(len(tuples))
 	p.centroids = make([]DatasetTuple, p.k)
 	for i := 0; i < p.k; i++ {
 		p.centroids[i] = tuples[perm[i]]
@@ -40,21 +40,37 @@ type DatasetSimilarityEstimator interface {
 	Duration() float64
 	// returns the max number of threads to be used
 	Concurrency() int
+	// sets the cache of the dataset summaries
+	SetSummaryCache(*DatasetSummaryCache)
 
 	// sets the duration
 	setDuration(float64)
 	// sets the similarity matrix
 	setSimilarityMatrix(*DatasetSimilarityMatrix)
+	// sets the datasets slice
+	setDatasets([]*Dataset)
+	// aligns datasets to the common schema of the estimator's datasets
+	alignDatasets([]*Dataset) error
+}
+
+// datasetsUpdater is implemented by the estimators that keep per-dataset
+// state, which must be updated when datasets are appended or removed
+type datasetsUpdater interface {
+	datasetsUpdated(appended []*Dataset, removed []string) error
 }
 
 // AbstractDatasetSimilarityEstimator is the base struct for the similarity
 // estimator objects
 type AbstractDatasetSimilarityEstimator struct {
-	datasets     []*Dataset
-	popPolicy    DatasetSimilarityPopulationPolicy
-	similarities *DatasetSimilarityMatrix
-	duration     float64
-	concurrency  int
+	datasets      []*Dataset
+	popPolicy     DatasetSimilarityPopulationPolicy
+	similarities  *DatasetSimilarityMatrix
+	duration      float64
+	concurrency   int
+	summaries     *DatasetSummaryCache
+	columnsPolicy DatasetColumnsPolicy // columns kept when aligning by header
+	schema        DatasetSchema        // the common schema of the datasets
+	schemaByName  bool                 // true if the columns were matched by name
 }
 
 // Datasets returns the datasets of the estimator
@@ -71,6 +87,11 @@ func (a *AbstractDatasetSimilarityEstimator) setSimilarityMatrix(sm *DatasetSimi
 	a.similarities = sm
 }
 
+// setDatasets sets the datasets slice
+func (a *AbstractDatasetSimilarityEstimator) setDatasets(datasets []*Dataset) {
+	a.datasets = datasets
+}
+
 // SetPopulationPolicy sets the population policy to be used
 func (a *AbstractDatasetSimilarityEstimator) SetPopulationPolicy(pol DatasetSimilarityPopulationPolicy) {
 	a.popPolicy = pol
@@ -96,6 +117,154 @@ func (a *AbstractDatasetSimilarityEstimator) Concurrency() int {
 	return a.concurrency
 }
 
+// SetSummaryCache sets the cache that holds the dataset summaries; if set,
+// the statistics of the unchanged datasets are not recomputed. The cache must
+// be set before Configure, since some estimators (e.g., Bhattacharyya)
+// traverse the datasets during their configuration. It is used by the
+// estimators that summarize each dataset independently (size, histogram,
+// MinHash, profile and correlation matrix estimators), whereas the rest
+// compare the tuples of the datasets and traverse them in every execution.
+func (a *AbstractDatasetSimilarityEstimator) SetSummaryCache(cache *DatasetSummaryCache) {
+	a.summaries = cache
+}
+
+// configureColumnsPolicy parses the "align" option, i.e., the policy for the
+// columns that some of the datasets lack, when the datasets are aligned by
+// their headers
+func (a *AbstractDatasetSimilarityEstimator) configureColumnsPolicy(conf map[string]string, def DatasetColumnsPolicy) {
+	a.columnsPolicy = def
+	if val, ok := conf["align"]; ok {
+		policy, err := NewDatasetColumnsPolicy(val)
+		if err != nil {
+			log.Println(err, "- using default ("+def.String()+")")
+		} else {
+			a.columnsPolicy = policy
+		}
+	}
+}
+
+// unifySchemas sets a common schema to the datasets of the estimator and
+// keeps it, so that the datasets that are later appended are aligned to it
+func (a *AbstractDatasetSimilarityEstimator) unifySchemas() (DatasetSchema, error) {
+	schema, err := unifyDatasetSchemas(a.datasets, a.columnsPolicy)
+	if err != nil {
+		return nil, err
+	}
+	a.schema, a.schemaByName = schema, a.datasets[0].alignment != nil
+	return schema, nil
+}
+
+// alignDatasets sets the common schema of the estimator's datasets to the
+// provided datasets; nothing is done if no common schema has been kept
+func (a *AbstractDatasetSimilarityEstimator) alignDatasets(datasets []*Dataset) error {
+	if a.schema == nil {
+		return nil
+	}
+	for _, d := range datasets {
+		if err := conformDatasetSchema(d, a.schema, a.schemaByName, a.columnsPolicy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// alignedDataset returns a copy of a dataset that is not indexed by the
+// estimator, aligned to the common schema of the estimator's datasets, so
+// that its features are comparable to theirs. The dataset itself is not
+// modified, since it may be examined concurrently.
+func (a *AbstractDatasetSimilarityEstimator) alignedDataset(d *Dataset) (*Dataset, error) {
+	if a.schema == nil {
+		return d, nil
+	}
+	aligned := *d
+	if err := conformDatasetSchema(&aligned, a.schema, a.schemaByName, a.columnsPolicy); err != nil {
+		return nil, err
+	}
+	return &aligned, nil
+}
+
+// columnsPolicyOption returns the description of the "align" option
+func columnsPolicyOption(def DatasetColumnsPolicy) string {
+	return "columns kept when the datasets are aligned by header: one of intersection, union, strict (default is " + def.String() + ")"
+}
+
+// datasetCount returns the number of tuples of a dataset, through the
+// summary cache (if set)
+func (a *AbstractDatasetSimilarityEstimator) datasetCount(d *Dataset) (int, error) {
+	if a.summaries != nil {
+		return a.summaries.Count(d)
+	}
+	return d.Count()
+}
+
+// datasetFeatures returns the features of a dataset computed by compute,
+// through the summary cache (if set); the fingerprint identifies the
+// estimator and the parameters that the features depend on
+func (a *AbstractDatasetSimilarityEstimator) datasetFeatures(d *Dataset, fingerprint string, compute func(*Dataset) ([]float64, error)) ([]float64, error) {
+	if a.summaries == nil {
+		return compute(d)
+	}
+	if f, ok := a.summaries.features(d, fingerprint); ok {
+		return f, nil
+	}
+	f, err := compute(d)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.summaries.setFeatures(d, fingerprint, f); err != nil {
+		log.Println(err)
+	}
+	return f, nil
+}
+
+// parallelDatasets calls f for each dataset, running up to concurrency calls
+// in parallel; the last error returned by f is returned
+func parallelDatasets(datasets []*Dataset, concurrency int, f func(i int, d *Dataset) error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	c, done := make(chan bool, concurrency), make(chan error)
+	for i := 0; i < concurrency; i++ {
+		c <- true
+	}
+	for i, d := range datasets {
+		go func(c chan bool, done chan error, i int, d *Dataset) {
+			<-c
+			err := f(i, d)
+			c <- true
+			done <- err
+		}(c, done, i, d)
+	}
+	var err error
+	for range datasets {
+		if e := <-done; e != nil {
+			log.Println(e)
+			err = e
+		}
+	}
+	return err
+}
+
+// reindexDatasets returns the inverse index of the updated datasets. For the
+// i-th dataset, added(i, k) is called if it is the k-th appended dataset,
+// else kept(i, j) is called if it was previously indexed at j.
+func reindexDatasets(datasets []*Dataset, inverseIndex map[string]int, appended []*Dataset, kept, added func(i, j int)) map[string]int {
+	appendedIndex := make(map[string]int)
+	for k, d := range appended {
+		appendedIndex[d.Path()] = k
+	}
+	result := make(map[string]int)
+	for i, d := range datasets {
+		if k, ok := appendedIndex[d.Path()]; ok {
+			added(i, k)
+		} else if j, ok := inverseIndex[d.Path()]; ok {
+			kept(i, j)
+		}
+		result[d.Path()] = i
+	}
+	return result
+}
+
 // datasetSimilarityEstimatorSerialize is used to generate an array of bytes of
 // the abstract object
 func datasetSimilarityEstimatorSerialize(e AbstractDatasetSimilarityEstimator) []byte {
@@ -115,6 +284,23 @@ func datasetSimilarityEstimatorSerialize(e AbstractDatasetSimilarityEstimator) [
 	buffer.Write(sim)
 	buffer.Write(getBytesInt(e.Concurrency()))
 	buffer.Write(getBytesFloat(e.Duration()))
+	buffer.Write(getBytesInt(int(e.columnsPolicy)))
+	if e.schemaByName {
+		buffer.Write(getBytesInt(1))
+	} else {
+		buffer.Write(getBytesInt(0))
+	}
+	buffer.Write(getBytesInt(len(e.schema)))
+	for _, c := range e.schema {
+		buffer.WriteString(c.Name + "\n")
+		buffer.Write(getBytesInt(int(c.Type)))
+	}
+
+	// the format and the column mapping of each dataset
+	for _, d := range e.Datasets() {
+		buffer.Write(d.format.serialize())
+		buffer.Write(d.mapping.serialize())
+	}
 	cnt := buffer.Bytes()
 	bufLen := getBytesInt(len(cnt))
 	return append(bufLen, cnt...)
@@ -156,6 +342,35 @@ func datasetSimilarityEstimatorDeserialize(b []byte) *AbstractDatasetSimilarityE
 
 	buffer.Read(tempFloat)
 	result.duration = getFloatBytes(tempFloat)
+
+	// columns policy and common schema (absent from older serialized
+	// estimators)
+	if buffer.Len() > 0 {
+		buffer.Read(tempInt)
+		result.columnsPolicy = DatasetColumnsPolicy(getIntBytes(tempInt))
+	}
+	if buffer.Len() > 0 {
+		buffer.Read(tempInt)
+		result.schemaByName = getIntBytes(tempInt) == 1
+		buffer.Read(tempInt)
+		count = getIntBytes(tempInt)
+		for i := 0; i < count; i++ {
+			line, _ := buffer.ReadString('\n')
+			buffer.Read(tempInt)
+			result.schema = append(result.schema, DatasetColumn{
+				Name: strings.TrimSuffix(line, "\n"),
+				Type: DatasetColumnType(getIntBytes(tempInt))})
+		}
+	}
+
+	// format and column mapping of the datasets (absent from older
+	// serialized estimators, the datasets of which have the default format)
+	if buffer.Len() > 0 {
+		for _, d := range result.datasets {
+			d.SetFormat(deserializeDatasetFormat(buffer))
+			d.SetColumnMapping(deserializeDatasetColumnMapping(buffer))
+		}
+	}
 	return result
 }
 
@@ -167,6 +382,24 @@ func datasetSimilarityEstimatorCompute(e DatasetSimilarityEstimator) error {
 	if err != nil {
 		return err
 	}
+	return datasetSimilarityEstimatorPopulate(e)
+}
+
+// datasetSimilarityEstimatorComputeStreaming is the equivalent of
+// datasetSimilarityEstimatorCompute for the estimators that traverse the
+// datasets through DatasetIterator objects; the datasets are not loaded in
+// memory.
+func datasetSimilarityEstimatorComputeStreaming(e DatasetSimilarityEstimator) error {
+	err := initSimilarityMatrix(e)
+	if err != nil {
+		return err
+	}
+	return datasetSimilarityEstimatorPopulate(e)
+}
+
+// datasetSimilarityEstimatorPopulate populates the similarity matrix of the
+// estimator according to its population policy
+func datasetSimilarityEstimatorPopulate(e DatasetSimilarityEstimator) error {
 	start := time.Now()
 	if e.PopulationPolicy().PolicyType == PopulationPolicyFull {
 		e.SimilarityMatrix().IndexDisabled(true) // I don't need the index
@@ -236,14 +469,153 @@ func datasetSimilarityEstimatorCompute(e DatasetSimilarityEstimator) error {
 }
 
 func readDatasets(e DatasetSimilarityEstimator) error {
-	e.setSimilarityMatrix(NewDatasetSimilarities(len(e.Datasets())))
+	err := initSimilarityMatrix(e)
+	if err != nil {
+		return err
+	}
 	log.Println("Fetching datasets in memory")
+	for _, d := range e.Datasets() {
+		if err := d.ReadFromFile(); err != nil {
+			log.Println(err)
+			return err
+		}
+	}
+	return nil
+}
+
+// initSimilarityMatrix initializes an empty similarity matrix for the
+// datasets of the estimator
+func initSimilarityMatrix(e DatasetSimilarityEstimator) error {
+	e.setSimilarityMatrix(NewDatasetSimilarities(len(e.Datasets())))
 	if e.Datasets() == nil || len(e.Datasets()) == 0 {
 		log.Println("No datasets were given")
 		return errors.New("Datasets not set correctly")
 	}
+	return nil
+}
+
+// AppendDatasets appends new datasets to an estimator the similarity matrix
+// of which has already been computed. Only the similarities between the new
+// datasets and the rest are computed; the existing similarities are kept.
+// The new datasets are aligned to the common schema of the existing ones and
+// an error is returned if they cannot be aligned.
+func AppendDatasets(e DatasetSimilarityEstimator, datasets []*Dataset) error {
+	sm := e.SimilarityMatrix()
+	if sm == nil {
+		return errors.New("The similarity matrix has not been computed")
+	}
+	paths := make(map[string]bool)
 	for _, d := range e.Datasets() {
-		d.ReadFromFile()
+		paths[d.Path()] = true
+	}
+	for _, d := range datasets {
+		if paths[d.Path()] {
+			return fmt.Errorf("Dataset %s already exists", d.Path())
+		}
+		paths[d.Path()] = true
+	}
+	if len(datasets) == 0 {
+		return nil
+	}
+	// the datasets of a deserialized estimator have no schema set
+	var unaligned []*Dataset
+	for _, d := range e.Datasets() {
+		if d.schema == nil {
+			unaligned = append(unaligned, d)
+		}
+	}
+	if err := e.alignDatasets(append(unaligned, datasets...)); err != nil {
+		log.Println(err)
+		return err
+	}
+	start := time.Now()
+	previous := e.Datasets()
+	offset := len(previous)
+	all := make([]*Dataset, offset, offset+len(datasets))
+	copy(all, previous)
+	e.setDatasets(append(all, datasets...))
+	if u, ok := e.(datasetsUpdater); ok {
+		if err := u.datasetsUpdated(datasets, nil); err != nil {
+			e.setDatasets(previous)
+			return err
+		}
+	}
+	sm.Append(len(datasets))
+
+	concurrency := e.Concurrency()
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	log.Println("Computing the similarities of", len(datasets), "new datasets using", concurrency, "threads")
+	rows := make([][]float64, len(datasets))
+	c, done := make(chan bool, concurrency), make(chan bool)
+	for j := 0; j < concurrency; j++ {
+		c <- true
+	}
+	for i := offset; i < len(e.Datasets()); i++ {
+		go func(c, done chan bool, i int) {
+			<-c
+			row := make([]float64, i+1)
+			for j := range row {
+				row[j] = e.Similarity(e.Datasets()[i], e.Datasets()[j])
+			}
+			rows[i-offset] = row
+			c <- true
+			done <- true
+		}(c, done, i)
+	}
+	for range rows {
+		<-done
+	}
+	for i, row := range rows {
+		for j, v := range row {
+			sm.Set(offset+i, j, v)
+		}
+	}
+	e.setDuration(e.Duration() + time.Since(start).Seconds())
+	return nil
+}
+
+// RemoveDatasets removes the datasets with the specified paths from an
+// estimator the similarity matrix of which has already been computed. The
+// indices of the remaining datasets are shifted accordingly.
+func RemoveDatasets(e DatasetSimilarityEstimator, paths []string) error {
+	sm := e.SimilarityMatrix()
+	if sm == nil {
+		return errors.New("The similarity matrix has not been computed")
+	}
+	indices := make(map[string]int)
+	for i, d := range e.Datasets() {
+		indices[d.Path()] = i
+	}
+	removed := make(map[int]bool)
+	for _, p := range paths {
+		idx, ok := indices[p]
+		if !ok {
+			return fmt.Errorf("Dataset %s not found", p)
+		}
+		removed[idx] = true
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+	if len(removed) == len(e.Datasets()) {
+		return errors.New("At least one dataset must remain")
+	}
+	var remaining []*Dataset
+	for i := len(e.Datasets()) - 1; i >= 0; i-- {
+		if removed[i] {
+			sm.Remove(i)
+		}
+	}
+	for i, d := range e.Datasets() {
+		if !removed[i] {
+			remaining = append(remaining, d)
+		}
+	}
+	e.setDatasets(remaining)
+	if u, ok := e.(datasetsUpdater); ok {
+		return u.datasetsUpdated(nil, paths)
 	}
 	return nil
 }
@@ -266,6 +638,22 @@ const (
 	SimilarityTypeSize DatasetSimilarityEstimatorType = iota + 6
 	// SimilarityTypeScriptPair estimates the similarity based on a script for each pair
 	SimilarityTypeScriptPair DatasetSimilarityEstimatorType = iota + 7
+	// SimilarityTypeMinHash estimates the Jaccard coefficient through MinHash signatures
+	SimilarityTypeMinHash DatasetSimilarityEstimatorType = iota + 8
+	// SimilarityTypeHellinger estimates the Hellinger distance of the region histograms
+	SimilarityTypeHellinger DatasetSimilarityEstimatorType = iota + 9
+	// SimilarityTypeJensenShannon estimates the Jensen-Shannon divergence of the region histograms
+	SimilarityTypeJensenShannon DatasetSimilarityEstimatorType = iota + 10
+	// SimilarityTypeTotalVariation estimates the total variation distance of the region histograms
+	SimilarityTypeTotalVariation DatasetSimilarityEstimatorType = iota + 11
+	// SimilarityTypeWasserstein estimates the sliced Wasserstein or energy distance of the tuples
+	SimilarityTypeWasserstein DatasetSimilarityEstimatorType = iota + 12
+	// SimilarityTypeMMD estimates the Maximum Mean Discrepancy of the tuples
+	SimilarityTypeMMD DatasetSimilarityEstimatorType = iota + 13
+	// SimilarityTypeProfile compares the statistical profiles of the columns
+	SimilarityTypeProfile DatasetSimilarityEstimatorType = iota + 14
+	// SimilarityTypeCorrelationMatrix compares the correlation matrices of the columns
+	SimilarityTypeCorrelationMatrix DatasetSimilarityEstimatorType = iota + 15
 )
 
 // DatasetSimilarityEstimatorAvailableTypes lists the available similarity types
@@ -277,6 +665,14 @@ var DatasetSimilarityEstimatorAvailableTypes = []DatasetSimilarityEstimatorType{
 	SimilarityTypeScript,
 	SimilarityTypeSize,
 	SimilarityTypeScriptPair,
+	SimilarityTypeMinHash,
+	SimilarityTypeHellinger,
+	SimilarityTypeJensenShannon,
+	SimilarityTypeTotalVariation,
+	SimilarityTypeWasserstein,
+	SimilarityTypeMMD,
+	SimilarityTypeProfile,
+	SimilarityTypeCorrelationMatrix,
 }
 
 // NewDatasetSimilarityEstimatorType transforms the similarity type from a
@@ -284,13 +680,21 @@ var DatasetSimilarityEstimatorAvailableTypes = []DatasetSimilarityEstimatorType{
 func NewDatasetSimilarityEstimatorType(estimatorType string) *DatasetSimilarityEstimatorType {
 	lower := strings.ToLower(estimatorType)
 	types := map[string]DatasetSimilarityEstimatorType{
-		"bhattacharyya": SimilarityTypeBhattacharyya,
-		"jaccard":       SimilarityTypeJaccard,
-		"correlation":   SimilarityTypeCorrelation,
-		"composite":     SimilarityTypeComposite,
-		"script":        SimilarityTypeScript,
-		"size":          SimilarityTypeSize,
-		"scriptpair":    SimilarityTypeScriptPair,
+		"bhattacharyya":  SimilarityTypeBhattacharyya,
+		"jaccard":        SimilarityTypeJaccard,
+		"correlation":    SimilarityTypeCorrelation,
+		"composite":      SimilarityTypeComposite,
+		"script":         SimilarityTypeScript,
+		"size":           SimilarityTypeSize,
+		"scriptpair":     SimilarityTypeScriptPair,
+		"minhash":        SimilarityTypeMinHash,
+		"hellinger":      SimilarityTypeHellinger,
+		"jensenshannon":  SimilarityTypeJensenShannon,
+		"totalvariation": SimilarityTypeTotalVariation,
+		"wasserstein":    SimilarityTypeWasserstein,
+		"mmd":            SimilarityTypeMMD,
+		"profile":        SimilarityTypeProfile,
+		"corrmatrix":     SimilarityTypeCorrelationMatrix,
 	}
 	if val, ok := types[lower]; ok {
 		return &val
@@ -312,6 +716,22 @@ func (t DatasetSimilarityEstimatorType) String() string {
 		return "ScriptPair"
 	} else if t == SimilarityTypeSize {
 		return "Size"
+	} else if t == SimilarityTypeMinHash {
+		return "MinHash"
+	} else if t == SimilarityTypeHellinger {
+		return "Hellinger"
+	} else if t == SimilarityTypeJensenShannon {
+		return "JensenShannon"
+	} else if t == SimilarityTypeTotalVariation {
+		return "TotalVariation"
+	} else if t == SimilarityTypeWasserstein {
+		return "Wasserstein"
+	} else if t == SimilarityTypeMMD {
+		return "MMD"
+	} else if t == SimilarityTypeProfile {
+		return "Profile"
+	} else if t == SimilarityTypeCorrelationMatrix {
+		return "CorrelationMatrix"
 	}
 	return ""
 }
@@ -384,10 +804,12 @@ func NewDatasetSimilarityEstimator(
 		a.SetPopulationPolicy(policy)
 		a.datasets = datasets
 		return a
-	} else if estType == SimilarityTypeBhattacharyya {
+	} else if estType == SimilarityTypeBhattacharyya || estType == SimilarityTypeHellinger ||
+		estType == SimilarityTypeJensenShannon || estType == SimilarityTypeTotalVariation {
 		a := new(BhattacharyyaEstimator)
 		a.SetPopulationPolicy(policy)
 		a.datasets = datasets
+		a.histogramType = estType
 		return a
 	} else if estType == SimilarityTypeScript {
 		a := new(ScriptSimilarityEstimator)
@@ -414,6 +836,31 @@ func NewDatasetSimilarityEstimator(
 		a.SetPopulationPolicy(policy)
 		a.datasets = datasets
 		return a
+	} else if estType == SimilarityTypeMinHash {
+		a := new(MinHashEstimator)
+		a.SetPopulationPolicy(policy)
+		a.datasets = datasets
+		return a
+	} else if estType == SimilarityTypeWasserstein {
+		a := new(WassersteinEstimator)
+		a.SetPopulationPolicy(policy)
+		a.datasets = datasets
+		return a
+	} else if estType == SimilarityTypeMMD {
+		a := new(MMDEstimator)
+		a.SetPopulationPolicy(policy)
+		a.datasets = datasets
+		return a
+	} else if estType == SimilarityTypeProfile {
+		a := new(ProfileEstimator)
+		a.SetPopulationPolicy(policy)
+		a.datasets = datasets
+		return a
+	} else if estType == SimilarityTypeCorrelationMatrix {
+		a := new(CorrelationMatrixEstimator)
+		a.SetPopulationPolicy(policy)
+		a.datasets = datasets
+		return a
 	} else {
 		log.Println("Unsupported Similarity Type.")
 	}
@@ -429,7 +876,8 @@ func DeserializeSimilarityEstimator(b []byte) DatasetSimilarityEstimator {
 		a := new(JaccardEstimator)
 		a.Deserialize(b)
 		return a
-	} else if estimatorType == SimilarityTypeBhattacharyya {
+	} else if estimatorType == SimilarityTypeBhattacharyya || estimatorType == SimilarityTypeHellinger ||
+		estimatorType == SimilarityTypeJensenShannon || estimatorType == SimilarityTypeTotalVariation {
 		a := new(BhattacharyyaEstimator)
 		a.Deserialize(b)
 		return a
@@ -453,6 +901,26 @@ func DeserializeSimilarityEstimator(b []byte) DatasetSimilarityEstimator {
 		a := new(ScriptPairSimilarityEstimator)
 		a.Deserialize(b)
 		return a
+	} else if estimatorType == SimilarityTypeMinHash {
+		a := new(MinHashEstimator)
+		a.Deserialize(b)
+		return a
+	} else if estimatorType == SimilarityTypeWasserstein {
+		a := new(WassersteinEstimator)
+		a.Deserialize(b)
+		return a
+	} else if estimatorType == SimilarityTypeMMD {
+		a := new(MMDEstimator)
+		a.Deserialize(b)
+		return a
+	} else if estimatorType == SimilarityTypeProfile {
+		a := new(ProfileEstimator)
+		a.Deserialize(b)
+		return a
+	} else if estimatorType == SimilarityTypeCorrelationMatrix {
+		a := new(CorrelationMatrixEstimator)
+		a.Deserialize(b)
+		return a
 	} else {
 		log.Println("Unsupported Estimator Type.")
 	}
@@ -507,6 +975,20 @@ func (s *DatasetSimilarityMatrix) FullyCalculatedNodes() int {
 	return count
 }
 
+// FullyCalculated returns the indices of the nodes the similarity of which
+// has been calculated for all the nodes, in ascending order
+func (s *DatasetSimilarityMatrix) FullyCalculated() []int {
+	var result []int
+	for i := 0; i < s.capacity; i++ {
+		if s.indexDisabled {
+			result = append(result, i)
+		} else if idx, _ := s.closestIndex.Get(i); idx == i {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
 func (s *DatasetSimilarityMatrix) allocateStructs() {
 	s.similarities = make([][]float64, s.capacity-1)
 	for i := 0; i < s.capacity-1; i++ {
@@ -520,6 +1002,75 @@ func (s *DatasetSimilarityMatrix) Capacity() int {
 	return s.capacity
 }
 
+// Append extends the matrix by count datasets, the similarities of which
+// are initialized to zero. The existing similarities are preserved.
+func (s *DatasetSimilarityMatrix) Append(count int) {
+	if count <= 0 {
+		return
+	}
+	capacity := s.capacity + count
+	for i := range s.similarities {
+		s.similarities[i] = append(s.similarities[i], make([]float64, count)...)
+	}
+	for i := len(s.similarities); i < capacity-1; i++ {
+		s.similarities = append(s.similarities, make([]float64, capacity-i-1))
+	}
+	if s.closestIndex == nil {
+		s.closestIndex = newClosestIndex(0)
+	}
+	added := newClosestIndex(count)
+	s.closestIndex.closestIdx = append(s.closestIndex.closestIdx, added.closestIdx...)
+	s.closestIndex.similarity = append(s.closestIndex.similarity, added.similarity...)
+	s.capacity = capacity
+}
+
+// Remove deletes the dataset with the specified index from the matrix. The
+// indices of the subsequent datasets are decreased by one.
+func (s *DatasetSimilarityMatrix) Remove(idx int) {
+	if idx < 0 || idx >= s.capacity {
+		return
+	}
+	for i := 0; i < idx && i < len(s.similarities); i++ {
+		row := s.similarities[i]
+		s.similarities[i] = append(row[:idx-i-1], row[idx-i:]...)
+	}
+	if idx < len(s.similarities) {
+		s.similarities = append(s.similarities[:idx], s.similarities[idx+1:]...)
+	}
+	s.capacity--
+	if s.capacity > 0 && len(s.similarities) > s.capacity-1 {
+		s.similarities = s.similarities[:s.capacity-1]
+	}
+	if s.closestIndex == nil {
+		return
+	}
+	ci := s.closestIndex
+	ci.closestIdx = append(ci.closestIdx[:idx], ci.closestIdx[idx+1:]...)
+	ci.similarity = append(ci.similarity[:idx], ci.similarity[idx+1:]...)
+	var stale []int
+	for i, c := range ci.closestIdx {
+		if c == idx {
+			ci.Set(i, -1, -1.0)
+			stale = append(stale, i)
+		} else if c > idx {
+			ci.closestIdx[i] = c - 1
+		}
+	}
+	// the datasets that were closest to the removed one are assigned to their
+	// most similar fully calculated dataset
+	for _, i := range stale {
+		for k, c := range ci.closestIdx {
+			if c == k && k != i {
+				a, b := i, k
+				if a > b {
+					a, b = b, a
+				}
+				ci.CheckAndSet(i, k, s.similarities[a][b-a-1])
+			}
+		}
+	}
+}
+
 // Set is a setter function for the similarity between two datasets
 func (s *DatasetSimilarityMatrix) Set(idxA, idxB int, value float64) {
 	if idxA == idxB { // do nothing
@@ -556,6 +1107,17 @@ func (s *DatasetSimilarityMatrix) Get(idxA, idxB int) float64 {
 	return s.similarities[idxA][idxB-idxA-1]
 }
 
+// getCalculated returns the calculated similarity between two datasets,
+// without resorting to the closest dataset index
+func (s *DatasetSimilarityMatrix) getCalculated(idxA, idxB int) float64 {
+	if idxA == idxB {
+		return 1.0
+	} else if idxA > idxB {
+		idxA, idxB = idxB, idxA
+	}
+	return s.similarities[idxA][idxB-idxA-1]
+}
+
 // LeastSimilar method returns the dataset that presents the lowest
 // similarity among the examined datasets
 func (s *DatasetSimilarityMatrix) LeastSimilar() (int, float64) {
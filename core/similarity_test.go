@@ -171,6 +171,118 @@ func TestDeserializeSimilarityEstimator(t *testing.T) {
 	cleanDatasets(datasets)
 }
 
+func TestDatasetSimilarityMatrixAppendRemove(t *testing.T) {
+	sm := NewDatasetSimilarities(3)
+	sm.IndexDisabled(true)
+	sm.Set(0, 1, 0.1)
+	sm.Set(0, 2, 0.2)
+	sm.Set(1, 2, 0.3)
+	sm.Append(2)
+	if sm.Capacity() != 5 || sm.Get(0, 2) != 0.2 || sm.Get(1, 2) != 0.3 || sm.Get(3, 4) != 0.0 {
+		t.Log("Append did not preserve the similarities")
+		t.Log(sm)
+		t.FailNow()
+	}
+	sm.Set(2, 4, 0.5)
+	sm.Set(3, 4, 0.6)
+	sm.Remove(1)
+	if sm.Capacity() != 4 || sm.Get(0, 1) != 0.2 || sm.Get(1, 3) != 0.5 || sm.Get(2, 3) != 0.6 {
+		t.Log("Remove did not shift the similarities")
+		t.Log(sm)
+		t.FailNow()
+	}
+	other := new(DatasetSimilarityMatrix)
+	other.Deserialize(sm.Serialize())
+	if other.Capacity() != 4 || other.Get(1, 3) != 0.5 {
+		t.Log("Wrong deserialized matrix")
+		t.Fail()
+	}
+	sm.Remove(3)
+	if sm.Capacity() != 3 || sm.Get(0, 1) != 0.2 || sm.Get(0, 2) != 0.0 {
+		t.Log("Wrong matrix after removing the last dataset")
+		t.Log(sm)
+		t.Fail()
+	}
+
+	// closest index
+	sm = NewDatasetSimilarities(3)
+	for j := 0; j < 3; j++ {
+		sm.Set(0, j, []float64{1.0, 0.9, 0.4}[j])
+	}
+	sm.Set(2, 1, 0.8)
+	sm.Remove(1)
+	if idx, _ := sm.closestIndex.Get(1); idx != 0 || sm.FullyCalculatedNodes() != 1 {
+		t.Log("Wrong closest index after remove", sm.closestIndex)
+		t.Fail()
+	}
+	sm.Append(1)
+	if idx, _ := sm.closestIndex.Get(2); idx != -1 {
+		t.Log("Appended datasets should have no closest dataset", sm.closestIndex)
+		t.Fail()
+	}
+}
+
+func TestAppendRemoveDatasets(t *testing.T) {
+	datasets := createPoolBasedDatasets(1000, 8, 3)
+	defer cleanDatasets(datasets)
+	// the configuration must be kept by the serialized estimators, since
+	// they are not configured again before being refreshed
+	confs := map[DatasetSimilarityEstimatorType]map[string]string{
+		SimilarityTypeSize:          {"concurrency": "2"},
+		SimilarityTypeBhattacharyya: {"concurrency": "2", "dataset.sr": "0.3", "seed": "7"},
+		SimilarityTypeCorrelation:   {"concurrency": "2", "column": "class", "normalization": "scale", "align": "strict"},
+	}
+	for _, estType := range []DatasetSimilarityEstimatorType{SimilarityTypeSize, SimilarityTypeBhattacharyya, SimilarityTypeCorrelation} {
+		full := NewDatasetSimilarityEstimator(estType, datasets)
+		full.Configure(confs[estType])
+		if err := full.Compute(); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		// the partitioning depends on the datasets, so the incremental
+		// estimator is deserialized from the full one and its datasets
+		// removed and appended again
+		est := DeserializeSimilarityEstimator(full.Serialize())
+		if err := RemoveDatasets(est, []string{datasets[2].Path(), datasets[7].Path()}); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		if len(est.Datasets()) != 6 || est.SimilarityMatrix().Capacity() != 6 ||
+			est.SimilarityMatrix().Get(2, 5) != full.SimilarityMatrix().Get(3, 6) {
+			t.Log(estType, "wrong matrix after removal")
+			t.FailNow()
+		}
+		if err := AppendDatasets(est, []*Dataset{datasets[2], datasets[7]}); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		order := []int{0, 1, 3, 4, 5, 6, 2, 7}
+		for i := range order {
+			if est.Datasets()[i].Path() != datasets[order[i]].Path() {
+				t.Log(estType, "wrong dataset order")
+				t.FailNow()
+			}
+			for j := range order {
+				a, b := est.SimilarityMatrix().Get(i, j), full.SimilarityMatrix().Get(order[i], order[j])
+				if math.Abs(a-b) > 1e-9 {
+					t.Log(estType, "different similarity", i, j, a, b)
+					t.FailNow()
+				}
+			}
+		}
+		smSanityCheck(est.SimilarityMatrix(), t)
+
+		if AppendDatasets(est, datasets[:1]) == nil {
+			t.Log("Duplicate datasets should be rejected")
+			t.Fail()
+		}
+		if RemoveDatasets(est, []string{"/not/existing"}) == nil {
+			t.Log("Unknown datasets should be rejected")
+			t.Fail()
+		}
+	}
+}
+
 func smGetCountOfOnesAndZeros(sm *DatasetSimilarityMatrix) (int, int) {
 	zeroElem, oneElem := 0, 0
 	for i := 0; i < sm.Capacity(); i++ {
@@ -286,6 +398,10 @@ func estimatorsCheck(a, b AbstractDatasetSimilarityEstimator, t *testing.T) {
 			t.Fail()
 		}
 	}
+	if a.columnsPolicy != b.columnsPolicy {
+		t.Logf("Columns policy: expected %s, found %s\n", a.columnsPolicy, b.columnsPolicy)
+		t.Fail()
+	}
 	count := a.similarities.Capacity()
 	if count != b.similarities.Capacity() {
 		t.Log("SM have different size")
@@ -308,3 +424,35 @@ func smPrint(sm *DatasetSimilarityMatrix, t *testing.T) {
 		t.Logf("\n")
 	}
 }
+
+func TestReindexDatasets(t *testing.T) {
+	datasets := []*Dataset{NewDataset("a"), NewDataset("b"), NewDataset("c")}
+	visited := make([]*Dataset, len(datasets))
+	err := parallelDatasets(datasets, 2, func(i int, d *Dataset) error {
+		visited[i] = d
+		return nil
+	})
+	for i := range datasets {
+		if err != nil || visited[i] != datasets[i] {
+			t.Log("Wrong parallel iteration", err, i)
+			t.Fail()
+		}
+	}
+
+	// b is removed, c is updated and d is appended
+	old := map[string]int{"a": 0, "b": 1, "c": 2}
+	appended := []*Dataset{NewDataset("c"), NewDataset("d")}
+	updated := []*Dataset{datasets[0], appended[0], appended[1]}
+	sources := make([]string, len(updated))
+	inverseIndex := reindexDatasets(updated, old, appended,
+		func(i, j int) { sources[i] = "kept" + datasets[j].Path() },
+		func(i, k int) { sources[i] = "added" + appended[k].Path() })
+	expected := []string{"kepta", "addedc", "addedd"}
+	for i := range expected {
+		if sources[i] != expected[i] || inverseIndex[updated[i].Path()] != i {
+			t.Log("Wrong reindexing", sources, inverseIndex)
+			t.Fail()
+			break
+		}
+	}
+}
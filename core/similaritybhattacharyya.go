@@ -2,6 +2,8 @@ package core
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"log"
 	"math"
@@ -11,16 +13,28 @@ import (
 )
 
 // BhattacharyyaEstimator is the similarity estimator that quantifies the similarity
-// of the distribution between the datasets.
+// of the distribution between the datasets. The numeric dimensions are
+// partitioned by the partitioner, whereas the discrete (categorical and
+// boolean) dimensions are partitioned through exact matching, i.e., each
+// region is defined by a partition of the numeric dimensions and a
+// combination of discrete values (bucket). Apart from the Bhattacharyya
+// coefficient, the region histograms can be compared through the Hellinger,
+// Jensen-Shannon or total variation distance, according to the type of the
+// estimator.
 type BhattacharyyaEstimator struct {
 	AbstractDatasetSimilarityEstimator
 
+	// the coefficient used to compare the region histograms
+	histogramType DatasetSimilarityEstimatorType
+
 	// struct used to map dataset paths to indexes
 	inverseIndex map[string]int
 	// determines the height of the kd tree to be used
 	maxPartitions int
 	// hold the portion of the data examined for constructing the tree
 	datasetSR float64
+	// the seed of the sampling, so that the partitioning is reproducible
+	seed int64
 	// kd tree, utilized for dataset partitioning
 	//	kdTree *kdTreeNode
 	partitioner DataPartitioner
@@ -28,11 +42,20 @@ type BhattacharyyaEstimator struct {
 	pointsPerRegion [][]int
 	// holds the total number of points for each dataset
 	datasetsSize []int
+	// the indices of the numeric and discrete columns
+	numericColumns, discreteColumns []int
+	// maps each combination of discrete values to a bucket index
+	buckets map[string]int
+	// holds the error of the schema unification, returned by Compute
+	schemaErr error
 }
 
 // Compute method constructs the Similarity Matrix
 func (e *BhattacharyyaEstimator) Compute() error {
-	return datasetSimilarityEstimatorCompute(e)
+	if e.schemaErr != nil {
+		return e.schemaErr
+	}
+	return datasetSimilarityEstimatorComputeStreaming(e)
 }
 
 // Similarity returns the similarity between two datasets
@@ -43,45 +66,29 @@ func (e *BhattacharyyaEstimator) Similarity(a, b *Dataset) float64 {
 		indexA = e.pointsPerRegion[idx]
 		countA = e.datasetsSize[idx]
 	} else {
-		err := a.ReadFromFile()
-		if err != nil {
-			log.Println(err)
-		}
-		clusters, err := e.partitioner.Partition(a.Data())
+		var err error
+		indexA, countA, err = e.alignedRegionCounts(a)
 		if err != nil {
 			log.Println(err)
 		}
-		for _, c := range clusters {
-			indexA = append(indexA, len(c))
-		}
-		//		indexA = e.kdTree.GetLeafIndex(a.Data())
-		countA = len(a.Data())
 	}
 
 	if idx, ok := e.inverseIndex[b.Path()]; ok {
 		indexB = e.pointsPerRegion[idx]
 		countB = e.datasetsSize[idx]
 	} else {
-		err := b.ReadFromFile()
-		if err != nil {
-			log.Println(err)
-		}
-		clusters, err := e.partitioner.Partition(b.Data())
+		var err error
+		indexB, countB, err = e.alignedRegionCounts(b)
 		if err != nil {
 			log.Println(err)
 		}
-		for _, c := range clusters {
-			indexB = append(indexB, len(c))
-		}
-
-		//		indexB = e.kdTree.GetLeafIndex(b.Data())
-		countB = len(b.Data())
 	}
 	return e.getValue(indexA, indexB, countA, countB)
 }
 
 // Configure sets a the configuration parameters of the estimator
 func (e *BhattacharyyaEstimator) Configure(conf map[string]string) {
+	e.configureColumnsPolicy(conf, DatasetColumnsIntersection)
 	if val, ok := conf["concurrency"]; ok {
 		conv, err := strconv.ParseInt(val, 10, 32)
 		e.concurrency = int(conv)
@@ -113,6 +120,16 @@ func (e *BhattacharyyaEstimator) Configure(conf map[string]string) {
 		e.datasetSR = 0.1
 	}
 
+	e.seed = 0
+	if val, ok := conf["seed"]; ok {
+		conv, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			log.Println(err)
+		} else {
+			e.seed = conv
+		}
+	}
+
 	partitionerType := DataPartitionerKDTree
 	if val, ok := conf["partitioner.type"]; ok {
 		if "kmeans" == strings.ToLower(val) {
@@ -126,6 +143,7 @@ func (e *BhattacharyyaEstimator) Configure(conf map[string]string) {
 
 	partitionerConf := make(map[string]string)
 	partitionerConf["partitions"] = fmt.Sprintf("%d", e.maxPartitions)
+	partitionerConf["seed"] = fmt.Sprintf("%d", e.seed)
 	// parse partitioner params
 	log.Println(conf)
 	for k, v := range conf {
@@ -145,31 +163,88 @@ func (e *BhattacharyyaEstimator) init(partitionerType DataPartitionerType, parti
 	for i, d := range e.datasets {
 		e.inverseIndex[d.Path()] = i
 	}
-	for _, d := range e.datasets {
-		d.ReadFromFile()
+	schema, err := e.unifySchemas()
+	e.schemaErr = err
+	if err != nil {
+		log.Println(err)
+		return
 	}
+	e.discreteColumns = schema.Columns(func(t DatasetColumnType) bool { return t.Discrete() })
+	e.numericColumns = schema.Columns(func(t DatasetColumnType) bool { return !t.Discrete() })
+	e.buckets = make(map[string]int)
 	//e.kdTree = newKDTreePartition(e.datasets[0].Data())
 	s := e.sampledDataset()
-	e.partitioner = NewDataPartitioner(partitionerType, partitionerConf)
-	e.partitioner.Construct(s)
+	if len(e.discreteColumns) == 0 || len(e.numericColumns) > 0 {
+		e.partitioner = NewDataPartitioner(partitionerType, partitionerConf)
+		e.partitioner.Construct(s)
+	}
 	e.pointsPerRegion = make([][]int, len(e.datasets))
 	e.datasetsSize = make([]int, len(e.datasets))
+	regions := 0
 	for i, d := range e.datasets {
-		clusters, err := e.partitioner.Partition(d.Data())
+		counts, size, err := e.regionCounts(d, true)
 		if err != nil {
 			log.Println(err)
 		} else {
-			for _, c := range clusters {
-				e.pointsPerRegion[i] = append(e.pointsPerRegion[i], len(c))
-			}
-			//			e.pointsPerRegion[i] = e.kdTree.GetLeafIndex(d.Data())
-			e.datasetsSize[i] = len(d.Data())
+			e.pointsPerRegion[i] = counts
+			e.datasetsSize[i] = size
+		}
+		if len(counts) > regions {
+			regions = len(counts)
+		}
+	}
+	// buckets discovered by later datasets are appended to the earlier ones
+	for i := range e.pointsPerRegion {
+		for len(e.pointsPerRegion[i]) < regions {
+			e.pointsPerRegion[i] = append(e.pointsPerRegion[i], 0)
 		}
 	}
 
 	// UP TO THIS POINT
 }
 
+// datasetsUpdated reindexes the region counts of the datasets; the counts of
+// the appended datasets are computed according to the existing partitioning
+func (e *BhattacharyyaEstimator) datasetsUpdated(appended []*Dataset, removed []string) error {
+	regions := 0
+	for _, counts := range e.pointsPerRegion {
+		if len(counts) > regions {
+			regions = len(counts)
+		}
+	}
+	pointsPerRegion := make([][]int, len(e.datasets))
+	datasetsSize := make([]int, len(e.datasets))
+	inverseIndex := make(map[string]int)
+	for i, d := range e.datasets {
+		if idx, ok := e.inverseIndex[d.Path()]; ok {
+			pointsPerRegion[i], datasetsSize[i] = e.pointsPerRegion[idx], e.datasetsSize[idx]
+		} else {
+			counts, size, err := e.regionCounts(d, false)
+			if err != nil {
+				log.Println(err)
+				return err
+			}
+			for len(counts) < regions {
+				counts = append(counts, 0)
+			}
+			pointsPerRegion[i], datasetsSize[i] = counts, size
+		}
+		inverseIndex[d.Path()] = i
+	}
+	e.pointsPerRegion, e.datasetsSize, e.inverseIndex = pointsPerRegion, datasetsSize, inverseIndex
+	return nil
+}
+
+// estimatorType returns the type of the estimator, i.e., the coefficient used
+// to compare the region histograms
+func (e *BhattacharyyaEstimator) estimatorType() DatasetSimilarityEstimatorType {
+	switch e.histogramType {
+	case SimilarityTypeHellinger, SimilarityTypeJensenShannon, SimilarityTypeTotalVariation:
+		return e.histogramType
+	}
+	return SimilarityTypeBhattacharyya
+}
+
 // Options returns a list of parameters that can be set by the user
 func (e *BhattacharyyaEstimator) Options() map[string]string {
 	return map[string]string{
@@ -178,23 +253,75 @@ func (e *BhattacharyyaEstimator) Options() map[string]string {
 		"partitioner.type": "the partitioner type (one of kmeans, kdtree - default is kdtree) ",
 		"partitioner.*":    "provide any argument to the partitioner instance using the partitioner.* prefix (e.g.: partitioner.weights=0.1,0.2 for kmeans)",
 		"dataset.sr":       "determines the portion of datasets to sample for the partitioner construction",
+		"seed":             "seed of the sampling and the partitioner construction (default is 0)",
+		"align":            columnsPolicyOption(DatasetColumnsIntersection),
 		//	"columns":          "comma separated values of column indices to consider (starting from 0)  or all (default)",
 	}
 }
 
 func (e *BhattacharyyaEstimator) getValue(indA, indB []int, countA, countB int) float64 {
+	switch e.histogramType {
+	case SimilarityTypeHellinger:
+		return 1.0 - math.Sqrt(math.Max(1.0-bhattacharyyaCoefficient(indA, indB, countA, countB), 0.0))
+	case SimilarityTypeJensenShannon:
+		return 1.0 - math.Sqrt(jensenShannonDivergence(indA, indB, countA, countB))
+	case SimilarityTypeTotalVariation:
+		return 1.0 - totalVariationDistance(indA, indB, countA, countB)
+	}
+	return bhattacharyyaCoefficient(indA, indB, countA, countB)
+}
+
+// bhattacharyyaCoefficient returns the Bhattacharyya coefficient of two
+// region histograms
+func bhattacharyyaCoefficient(indA, indB []int, countA, countB int) float64 {
 	sum := 0.0
-	for k := 0; k < len(indA); k++ {
+	for k := 0; k < len(indA) && k < len(indB); k++ {
 		sum += math.Sqrt(float64(indA[k] * indB[k]))
 	}
 	sum /= math.Sqrt(float64(countA * countB))
 	return sum
 }
 
+// jensenShannonDivergence returns the Jensen-Shannon divergence (base 2) of two
+// region histograms, which lies in [0,1]
+func jensenShannonDivergence(indA, indB []int, countA, countB int) float64 {
+	sum := 0.0
+	for k := 0; k < len(indA) || k < len(indB); k++ {
+		p, q := histogramProbability(indA, k, countA), histogramProbability(indB, k, countB)
+		m := (p + q) / 2.0
+		if p > 0 {
+			sum += p * math.Log2(p/m)
+		}
+		if q > 0 {
+			sum += q * math.Log2(q/m)
+		}
+	}
+	return math.Min(math.Max(sum/2.0, 0.0), 1.0)
+}
+
+// totalVariationDistance returns the total variation distance of two region
+// histograms
+func totalVariationDistance(indA, indB []int, countA, countB int) float64 {
+	sum := 0.0
+	for k := 0; k < len(indA) || k < len(indB); k++ {
+		sum += math.Abs(histogramProbability(indA, k, countA) - histogramProbability(indB, k, countB))
+	}
+	return math.Min(sum/2.0, 1.0)
+}
+
+// histogramProbability returns the portion of the tuples that fall into the
+// k-th region of a histogram
+func histogramProbability(ind []int, k, count int) float64 {
+	if k >= len(ind) {
+		return 0.0
+	}
+	return float64(ind[k]) / float64(count)
+}
+
 // Serialize returns a byte array containing a serialized form of the estimator
 func (e *BhattacharyyaEstimator) Serialize() []byte {
 	buffer := new(bytes.Buffer)
-	buffer.Write(getBytesInt(int(SimilarityTypeBhattacharyya)))
+	buffer.Write(getBytesInt(int(e.estimatorType())))
 
 	bytes := datasetSimilarityEstimatorSerialize(e.AbstractDatasetSimilarityEstimator)
 	buffer.Write(bytes)
@@ -213,9 +340,29 @@ func (e *BhattacharyyaEstimator) Serialize() []byte {
 	}
 
 	// write kdtree
-	serializedPartitioner := e.partitioner.Serialize()
+	var serializedPartitioner []byte
+	if e.partitioner != nil {
+		serializedPartitioner = e.partitioner.Serialize()
+	}
 	buffer.Write(getBytesInt(len(serializedPartitioner)))
 	buffer.Write(serializedPartitioner)
+
+	// write columns and buckets
+	for _, columns := range [][]int{e.numericColumns, e.discreteColumns} {
+		buffer.Write(getBytesInt(len(columns)))
+		for _, c := range columns {
+			buffer.Write(getBytesInt(c))
+		}
+	}
+	buffer.Write(getBytesInt(len(e.buckets)))
+	for k, v := range e.buckets {
+		buffer.WriteString(k + "\n")
+		buffer.Write(getBytesInt(v))
+	}
+
+	// write the sampling parameters
+	buffer.Write(getBytesFloat(e.datasetSR))
+	buffer.Write(getBytesInt(int(e.seed)))
 	return buffer.Bytes()
 }
 
@@ -225,6 +372,7 @@ func (e *BhattacharyyaEstimator) Deserialize(b []byte) {
 	tempInt := make([]byte, 4)
 	tempFloat := make([]byte, 8)
 	buffer.Read(tempInt) // contains estimator type
+	e.histogramType = DatasetSimilarityEstimatorType(getIntBytes(tempInt))
 
 	var count int
 	buffer.Read(tempInt)
@@ -234,7 +382,7 @@ func (e *BhattacharyyaEstimator) Deserialize(b []byte) {
 		*datasetSimilarityEstimatorDeserialize(absEstBytes)
 
 	buffer.Read(tempInt)
-	e.maxPartitions = getIntBytes(tempFloat)
+	e.maxPartitions = getIntBytes(tempInt)
 
 	e.inverseIndex = make(map[string]int)
 	for i := range e.datasets {
@@ -262,27 +410,272 @@ func (e *BhattacharyyaEstimator) Deserialize(b []byte) {
 	count = getIntBytes(tempInt)
 	tempCustom := make([]byte, count)
 	buffer.Read(tempCustom)
-	e.partitioner = DeserializePartitioner(tempCustom)
+	if count > 0 {
+		e.partitioner = DeserializePartitioner(tempCustom)
+	}
 	//e.kdTree = new(kdTreeNode)
 	//e.kdTree.Deserialize(tempCustom)
 
+	// columns and buckets (absent from older serialized estimators)
+	e.numericColumns, e.discreteColumns = nil, nil
+	e.buckets = make(map[string]int)
+	e.datasetSR, e.seed = 0.1, 0
+	if buffer.Len() == 0 {
+		return
+	}
+	for _, columns := range []*[]int{&e.numericColumns, &e.discreteColumns} {
+		buffer.Read(tempInt)
+		count = getIntBytes(tempInt)
+		for i := 0; i < count; i++ {
+			buffer.Read(tempInt)
+			*columns = append(*columns, getIntBytes(tempInt))
+		}
+	}
+	buffer.Read(tempInt)
+	count = getIntBytes(tempInt)
+	for i := 0; i < count; i++ {
+		key, _ := buffer.ReadString('\n')
+		buffer.Read(tempInt)
+		e.buckets[strings.TrimSuffix(key, "\n")] = getIntBytes(tempInt)
+	}
+
+	// sampling parameters (absent from older serialized estimators)
+	if buffer.Len() == 0 {
+		return
+	}
+	buffer.Read(tempFloat)
+	e.datasetSR = getFloatBytes(tempFloat)
+	buffer.Read(tempInt)
+	e.seed = int64(int32(getIntBytes(tempInt)))
 }
 
 // sampledDataset returns a custom dataset that consist of the tuples of the
-// previous
+// previous. The datasets are traversed twice (once for counting their tuples
+// and once for sampling them) without being loaded in memory; the samples of
+// the unchanged datasets are reused through the summary cache (if set).
 func (e *BhattacharyyaEstimator) sampledDataset() []DatasetTuple {
 	log.Println("Generating a sampled and merged dataset with all tuples")
+	fingerprint := fmt.Sprintf("bhattacharyya.sample/%d/%v/%v/%v",
+		e.seed, e.datasetSR, e.numericColumns, e.discreteColumns)
 	var result []DatasetTuple
 	for _, d := range e.datasets {
-		tuplesToChoose := int(math.Floor(float64(len(d.Data())) * e.datasetSR))
-		log.Printf("%d/%d tuples chosen for %s\n", tuplesToChoose, len(d.Data()), d.path)
-		tuplesIdx := make(map[int]bool)
-		for len(tuplesIdx) < tuplesToChoose {
-			tuplesIdx[rand.Int()%len(d.Data())] = true
+		sample, err := e.datasetFeatures(d, fingerprint, e.sampleTuples)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		// the first value is the width of the sampled tuples
+		if len(sample) == 0 || sample[0] < 1 {
+			continue
 		}
-		for k := range tuplesIdx {
-			result = append(result, d.Data()[k])
+		width := int(sample[0])
+		for i := 1; i+width <= len(sample); i += width {
+			result = append(result, DatasetTuple{Data: sample[i : i+width : i+width]})
 		}
 	}
 	return result
 }
+
+// sampleTuples returns the numeric projections of a uniform sample of the
+// tuples of a dataset, flattened and preceded by their width
+func (e *BhattacharyyaEstimator) sampleTuples(d *Dataset) ([]float64, error) {
+	count, err := e.datasetCount(d)
+	if err != nil {
+		return nil, err
+	}
+	tuplesToChoose := int(math.Floor(float64(count) * e.datasetSR))
+	log.Printf("%d/%d tuples chosen for %s\n", tuplesToChoose, count, d.path)
+	r := rand.New(rand.NewSource(e.seed))
+	tuplesIdx := make(map[int]bool)
+	for len(tuplesIdx) < tuplesToChoose {
+		tuplesIdx[r.Intn(count)] = true
+	}
+	it, err := d.Iterator()
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+	result := []float64{0}
+	for i := 0; it.Next(); i++ {
+		if tuplesIdx[i] {
+			t := e.numericProjection(it.Tuple())
+			result[0] = float64(len(t.Data))
+			result = append(result, t.Data...)
+		}
+	}
+	return result, it.Err()
+}
+
+// regionCounts returns the number of tuples of a dataset that fall into each
+// region, along with the total number of its tuples. The regions are indexed
+// as bucket*partitions + partition. If register is true, new buckets are
+// created for unseen combinations of discrete values, else the respective
+// tuples are not assigned to any region.
+func (e *BhattacharyyaEstimator) regionCounts(d *Dataset, register bool) ([]int, int, error) {
+	p, size, err := e.datasetPartitionCounts(d)
+	if err != nil {
+		return nil, 0, err
+	}
+	var counts []int
+	for i, key := range p.Buckets {
+		b, ok := e.bucketIndex(key, register)
+		if !ok {
+			continue
+		}
+		offset := b * len(p.Counts[i])
+		for len(counts) < offset+len(p.Counts[i]) {
+			counts = append(counts, 0)
+		}
+		for j, c := range p.Counts[i] {
+			counts[offset+j] += c
+		}
+	}
+	return counts, size, nil
+}
+
+// alignedRegionCounts returns the region counts of a dataset that is not
+// indexed by the estimator, which is first aligned to the common schema
+func (e *BhattacharyyaEstimator) alignedRegionCounts(d *Dataset) ([]int, int, error) {
+	d, err := e.alignedDataset(d)
+	if err != nil {
+		return nil, 0, err
+	}
+	return e.regionCounts(d, false)
+}
+
+// datasetPartitionCounts returns the partition counts of a dataset, along with
+// the total number of its tuples. If a summary cache is set, the counts are
+// computed once for each combination of dataset and partitioning.
+func (e *BhattacharyyaEstimator) datasetPartitionCounts(d *Dataset) (*DatasetPartitionCounts, int, error) {
+	if e.summaries == nil {
+		return e.partitionCounts(d)
+	}
+	size, err := e.summaries.Count(d)
+	if err != nil {
+		return nil, 0, err
+	}
+	fingerprint := e.fingerprint()
+	if p, ok := e.summaries.partitions(d, fingerprint); ok {
+		return p, size, nil
+	}
+	p, size, err := e.partitionCounts(d)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := e.summaries.setPartitions(d, fingerprint, p); err != nil {
+		log.Println(err)
+	}
+	return p, size, nil
+}
+
+// partitionCounts traverses a dataset in chunks and returns the number of
+// its tuples that fall into each partition, for each combination of discrete
+// values, along with the total number of its tuples
+func (e *BhattacharyyaEstimator) partitionCounts(d *Dataset) (*DatasetPartitionCounts, int, error) {
+	it, err := d.Iterator()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer it.Close()
+	p := new(DatasetPartitionCounts)
+	index := make(map[string]int)
+	size := 0
+	for chunk := it.NextChunk(datasetChunkSize); len(chunk) > 0; chunk = it.NextChunk(datasetChunkSize) {
+		var keys []string
+		groups := make(map[string][]DatasetTuple)
+		for _, t := range chunk {
+			key := e.bucketKey(t)
+			if _, ok := groups[key]; !ok {
+				keys = append(keys, key)
+			}
+			groups[key] = append(groups[key], e.numericProjection(t))
+		}
+		for _, key := range keys {
+			tuples := groups[key]
+			partitionSizes := []int{len(tuples)}
+			if e.partitioner != nil {
+				clusters, err := e.partitioner.Partition(tuples)
+				if err != nil {
+					return nil, 0, err
+				}
+				partitionSizes = make([]int, len(clusters))
+				for i, c := range clusters {
+					partitionSizes[i] = len(c)
+				}
+			}
+			b, ok := index[key]
+			if !ok {
+				b = len(p.Buckets)
+				index[key] = b
+				p.Buckets = append(p.Buckets, key)
+				p.Counts = append(p.Counts, nil)
+			}
+			for len(p.Counts[b]) < len(partitionSizes) {
+				p.Counts[b] = append(p.Counts[b], 0)
+			}
+			for i, c := range partitionSizes {
+				p.Counts[b][i] += c
+			}
+		}
+		size += len(chunk)
+	}
+	if it.Err() != nil {
+		return nil, 0, it.Err()
+	}
+	if size == 0 {
+		return nil, 0, errors.New("no tuples to partition")
+	}
+	return p, size, nil
+}
+
+// fingerprint returns a hash that identifies the partitioning of the
+// estimator, i.e., the partitioner and the columns
+func (e *BhattacharyyaEstimator) fingerprint() string {
+	h := sha256.New()
+	if e.partitioner != nil {
+		h.Write(e.partitioner.Serialize())
+	}
+	fmt.Fprintln(h, e.numericColumns, e.discreteColumns)
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// bucketIndex returns the bucket index of a combination of discrete values
+func (e *BhattacharyyaEstimator) bucketIndex(key string, register bool) (int, bool) {
+	if len(e.discreteColumns) == 0 {
+		return 0, true
+	}
+	if b, ok := e.buckets[key]; ok {
+		return b, true
+	}
+	if !register {
+		return 0, false
+	}
+	e.buckets[key] = len(e.buckets)
+	return e.buckets[key], true
+}
+
+// bucketKey returns the combination of discrete values of a tuple
+func (e *BhattacharyyaEstimator) bucketKey(t DatasetTuple) string {
+	values := make([]string, len(e.discreteColumns))
+	for i, c := range e.discreteColumns {
+		if c < len(t.Data) {
+			values[i] = strconv.FormatFloat(t.Data[c], 'g', -1, 64)
+		}
+	}
+	return strings.Join(values, ",")
+}
+
+// numericProjection returns a tuple consisting of the numeric dimensions of
+// the provided tuple
+func (e *BhattacharyyaEstimator) numericProjection(t DatasetTuple) DatasetTuple {
+	if len(e.discreteColumns) == 0 {
+		return t
+	}
+	projection := DatasetTuple{Data: make([]float64, 0, len(e.numericColumns))}
+	for _, c := range e.numericColumns {
+		if c < len(t.Data) {
+			projection.Data = append(projection.Data, t.Data[c])
+		}
+	}
+	return projection
+}
@@ -20,7 +20,8 @@ func TestBhattacharyyaSampledDatasets(t *testing.T) {
 	est.Configure(conf)
 	totalNoTuples := 0.0
 	for _, d := range datasets {
-		totalNoTuples += float64(len(d.Data()))
+		count, _ := d.Count()
+		totalNoTuples += float64(count)
 	}
 	s := est.sampledDataset()
 	merged := float64(len(s))
@@ -147,3 +148,71 @@ func TestBhattacharyyaSerialization(t *testing.T) {
 
 	cleanDatasets(datasets)
 }
+
+func TestHistogramDistances(t *testing.T) {
+	a, b := []int{5, 5, 0, 0}, []int{0, 0, 4, 6}
+	est := new(BhattacharyyaEstimator)
+	for _, estType := range []DatasetSimilarityEstimatorType{SimilarityTypeBhattacharyya,
+		SimilarityTypeHellinger, SimilarityTypeJensenShannon, SimilarityTypeTotalVariation} {
+		est.histogramType = estType
+		if v := est.getValue(a, a, 10, 10); math.Abs(v-1.0) > 1e-9 {
+			t.Log(estType, "identical histograms should give 1, got", v)
+			t.Fail()
+		}
+		if v := est.getValue(a, b, 10, 10); math.Abs(v) > 1e-9 {
+			t.Log(estType, "disjoint histograms should give 0, got", v)
+			t.Fail()
+		}
+	}
+	// p=(0.5,0.5), q=(0.9,0.1)
+	c, d := []int{5, 5}, []int{9, 1}
+	bc := math.Sqrt(0.45) + math.Sqrt(0.05)
+	expected := map[DatasetSimilarityEstimatorType]float64{
+		SimilarityTypeBhattacharyya:  bc,
+		SimilarityTypeHellinger:      1.0 - math.Sqrt(1.0-bc),
+		SimilarityTypeTotalVariation: 0.6,
+		SimilarityTypeJensenShannon: 1.0 - math.Sqrt((0.5*math.Log2(0.5/0.7)+0.5*math.Log2(0.5/0.3)+
+			0.9*math.Log2(0.9/0.7)+0.1*math.Log2(0.1/0.3))/2.0),
+	}
+	for estType, v := range expected {
+		est.histogramType = estType
+		if res := est.getValue(c, d, 10, 10); math.Abs(res-v) > 1e-9 {
+			t.Log(estType, "expected", v, "got", res)
+			t.Fail()
+		}
+	}
+	// histograms of different length (buckets discovered later)
+	est.histogramType = SimilarityTypeTotalVariation
+	if v := est.getValue([]int{5, 5}, []int{5, 5, 0}, 10, 10); math.Abs(v-1.0) > 1e-9 {
+		t.Log("Trailing empty regions should be ignored, got", v)
+		t.Fail()
+	}
+}
+
+func TestHistogramDistancesCompute(t *testing.T) {
+	datasets := createPoolBasedDatasets(1000, 10, 2)
+	defer cleanDatasets(datasets)
+	for _, name := range []string{"hellinger", "jensenshannon", "totalvariation"} {
+		estType := *NewDatasetSimilarityEstimatorType(name)
+		est := NewDatasetSimilarityEstimator(estType, datasets)
+		est.Configure(map[string]string{"concurrency": "4", "partitions": "8"})
+		if err := est.Compute(); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		smSanityCheck(est.SimilarityMatrix(), t)
+		restored := DeserializeSimilarityEstimator(est.Serialize())
+		if restored.(*BhattacharyyaEstimator).estimatorType() != estType {
+			t.Log("Wrong deserialized type", restored.(*BhattacharyyaEstimator).estimatorType())
+			t.FailNow()
+		}
+		for i := range datasets {
+			for j := range datasets {
+				if est.Similarity(datasets[i], datasets[j]) != restored.Similarity(datasets[i], datasets[j]) {
+					t.Log("Different similarities after deserialization", name, i, j)
+					t.FailNow()
+				}
+			}
+		}
+	}
+}
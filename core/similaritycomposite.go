@@ -41,8 +41,10 @@ func (e *CompositeEstimator) Similarity(a, b *Dataset) float64 {
 	}
 	params := make(map[string]interface{})
 	for k, est := range e.estimators {
-		if est.SimilarityMatrix() != nil {
-			params[k] = est.SimilarityMatrix().Get(e.datasetIndexes[a.Path()], e.datasetIndexes[b.Path()])
+		idxA, okA := e.datasetIndexes[a.Path()]
+		idxB, okB := e.datasetIndexes[b.Path()]
+		if est.SimilarityMatrix() != nil && okA && okB {
+			params[k] = est.SimilarityMatrix().Get(idxA, idxB)
 		} else {
 			params[k] = est.Similarity(a, b)
 		}
@@ -110,6 +112,10 @@ func (e *CompositeEstimator) Deserialize(b []byte) {
 		est := DeserializeSimilarityEstimator(tempBuff)
 		e.estimators[key] = est
 	}
+	e.datasetIndexes = make(map[string]int)
+	for i, d := range e.datasets {
+		e.datasetIndexes[d.Path()] = i
+	}
 }
 
 // Configure provides the configuration parameters needed by the Estimator
@@ -143,6 +149,29 @@ func (e *CompositeEstimator) Configure(conf map[string]string) {
 
 }
 
+// datasetsUpdated applies the dataset changes to the underlying estimators,
+// so that their similarity matrices remain aligned with the datasets
+func (e *CompositeEstimator) datasetsUpdated(appended []*Dataset, removed []string) error {
+	for k, est := range e.estimators {
+		if est.SimilarityMatrix() == nil {
+			continue
+		}
+		if err := RemoveDatasets(est, removed); err != nil {
+			log.Println(k, err)
+			return err
+		}
+		if err := AppendDatasets(est, appended); err != nil {
+			log.Println(k, err)
+			return err
+		}
+	}
+	e.datasetIndexes = make(map[string]int)
+	for i, d := range e.datasets {
+		e.datasetIndexes[d.Path()] = i
+	}
+	return nil
+}
+
 // Options returns the applicable parameters needed by the Estimator.
 func (e *CompositeEstimator) Options() map[string]string {
 	return map[string]string{
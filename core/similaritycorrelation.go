@@ -2,6 +2,7 @@ package core
 
 import (
 	"bytes"
+	"fmt"
 	"log"
 	"math"
 	"strconv"
@@ -11,11 +12,14 @@ import (
 // CorrelationEstimator estimates the similarity between two datasets based
 // on a correlation metric. This metric can only be used for datasets that
 // consist of a single column and consist of the same number of tuples.
+// The examined column is given either by its index in the aligned schema of
+// the datasets or by its name.
 type CorrelationEstimator struct {
 	AbstractDatasetSimilarityEstimator
-	estType  CorrelationEstimatorType
-	normType CorrelationEstimatorNormalizationType
-	column   int
+	estType    CorrelationEstimatorType
+	normType   CorrelationEstimatorNormalizationType
+	column     int
+	columnName string
 }
 
 // CorrelationEstimatorType represents the type of correlation to be used
@@ -73,6 +77,7 @@ func (s CorrelationEstimatorNormalizationType) String() string {
 // Configure provides a set of configuration options to the CorrelationEstimator
 // struct.
 func (e *CorrelationEstimator) Configure(conf map[string]string) {
+	e.configureColumnsPolicy(conf, DatasetColumnsIntersection)
 	if val, ok := conf["concurrency"]; ok {
 		conv, err := strconv.ParseInt(val, 10, 32)
 		if err != nil {
@@ -84,16 +89,17 @@ func (e *CorrelationEstimator) Configure(conf map[string]string) {
 	} else {
 		e.concurrency = 1
 	}
+	e.column, e.columnName = 0, ""
 	if val, ok := conf["column"]; ok {
 		conv, err := strconv.ParseInt(val, 10, 32)
 		if err != nil {
-			log.Println(err)
+			// not a number, the column is given by its name
+			e.columnName = strings.TrimSpace(val)
+			log.Println("Set column to", e.columnName)
 		} else {
 			e.column = int(conv)
 			log.Println("Set column to", e.column)
 		}
-	} else {
-		e.column = 0
 	}
 	if val, ok := conf["correlation"]; ok {
 		if strings.ToLower(val) == "pearson" {
@@ -127,7 +133,8 @@ func (e *CorrelationEstimator) Options() map[string]string {
 	return map[string]string{
 		"concurrency":   "max number of threads to use",
 		"correlation":   "one of [Pearson], Spearman, Kendall",
-		"column":        "number of column of the datasets to consider - starting from 0 (default)",
+		"column":        "name or number of column of the datasets to consider - starting from 0 (default)",
+		"align":         columnsPolicyOption(DatasetColumnsIntersection),
 		"normalization": "determines how to scale the correlation metric from [-1,1]-> [0,1]. one of: abs scale [pos]",
 	}
 }
@@ -141,6 +148,9 @@ func (e *CorrelationEstimator) Serialize() []byte {
 		datasetSimilarityEstimatorSerialize(
 			e.AbstractDatasetSimilarityEstimator))
 	buffer.Write(getBytesInt(int(e.estType)))
+	buffer.Write(getBytesInt(int(e.normType)))
+	buffer.Write(getBytesInt(e.column))
+	buffer.WriteString(e.columnName + "\n")
 	return buffer.Bytes()
 }
 
@@ -157,11 +167,49 @@ func (e *CorrelationEstimator) Deserialize(b []byte) {
 
 	buffer.Read(tempInt)
 	e.estType = CorrelationEstimatorType(getIntBytes(tempInt))
+
+	// normalization and column (absent from older serialized estimators)
+	e.normType, e.column, e.columnName = CorrelationSimilarityNormalizationPos, 0, ""
+	if buffer.Len() == 0 {
+		return
+	}
+	buffer.Read(tempInt)
+	e.normType = CorrelationEstimatorNormalizationType(getIntBytes(tempInt))
+	buffer.Read(tempInt)
+	e.column = getIntBytes(tempInt)
+	line, _ := buffer.ReadString('\n')
+	e.columnName = strings.TrimSpace(line)
 }
 
 // Compute method constructs the Similarity Matrix
 func (e *CorrelationEstimator) Compute() error {
-	return datasetSimilarityEstimatorCompute(e)
+	schema, err := e.unifySchemas()
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	if e.columnName != "" {
+		if e.column, err = e.columnIndex(schema); err != nil {
+			log.Println(err)
+			return err
+		}
+	}
+	return datasetSimilarityEstimatorComputeStreaming(e)
+}
+
+// columnIndex returns the index of the named column in the schema of the
+// datasets; the name may also be given before its mapping to a canonical one
+func (e *CorrelationEstimator) columnIndex(schema DatasetSchema) (int, error) {
+	names := map[string]bool{e.columnName: true}
+	for _, d := range e.datasets {
+		names[d.mapping.canonical(e.columnName)] = true
+	}
+	for i, c := range schema {
+		if names[strings.TrimSpace(c.Name)] {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown column %q", e.columnName)
 }
 
 // Similarity returns the similarity between two datasets. Since all the
@@ -169,30 +217,107 @@ func (e *CorrelationEstimator) Compute() error {
 // is scaled to [0.0,1.0] by returning (x/2.0 + 0.5), where x is one of
 // Pearson, Spearman and Kendall coefficients.
 func (e *CorrelationEstimator) Similarity(a, b *Dataset) float64 {
-	aTrans, bTrans := e.transformDataset(a), e.transformDataset(b)
 	var val float64
 	if e.estType == CorrelationSimilarityTypePearson {
-		val = Pearson(aTrans, bTrans)
-	} else if e.estType == CorrelationSimilarityTypeSpearman {
-		val = Spearman(aTrans, bTrans)
-	} else if e.estType == CorrelationSimilarityTypeKendall {
-		val = Kendall(aTrans, bTrans)
+		val = e.streamingPearson(a, b)
+	} else {
+		// rank correlations need the whole column
+		aTrans, bTrans := e.transformDataset(a), e.transformDataset(b)
+		if e.estType == CorrelationSimilarityTypeSpearman {
+			val = Spearman(aTrans, bTrans)
+		} else if e.estType == CorrelationSimilarityTypeKendall {
+			val = Kendall(aTrans, bTrans)
+		}
 	}
 	return e.scaleCorrelationValue(val)
 }
 
+// transformDataset returns the values of the examined column of a dataset
 func (e *CorrelationEstimator) transformDataset(d *Dataset) []float64 {
 	var result []float64
-	for _, t := range d.Data() {
-		if e.column < len(t.Data) {
-			result = append(result, t.Data[e.column])
-		} else {
-			log.Printf("Given column number (%d) exceeds data columns (%d)\n", e.column, len(t.Data))
+	it, err := d.Iterator()
+	if err != nil {
+		log.Println(err)
+		return result
+	}
+	defer it.Close()
+	for it.Next() {
+		if v, ok := e.columnValue(it.Tuple()); ok {
+			result = append(result, v)
 		}
 	}
+	if it.Err() != nil {
+		log.Println(it.Err())
+	}
 	return result
 }
 
+// columnValue returns the value of the examined column of a tuple
+func (e *CorrelationEstimator) columnValue(t DatasetTuple) (float64, bool) {
+	if e.column < len(t.Data) {
+		// the column is missing (NaN) from the datasets that lack it
+		return t.Data[e.column], !math.IsNaN(t.Data[e.column])
+	}
+	log.Printf("Given column number (%d) exceeds data columns (%d)\n", e.column, len(t.Data))
+	return 0, false
+}
+
+// streamingPearson calculates the Pearson correlation coefficient of the
+// examined column in a single pass over the two datasets, without keeping
+// them in memory. If the dataset sizes differ, 0 is returned.
+func (e *CorrelationEstimator) streamingPearson(a, b *Dataset) float64 {
+	itA, err := a.Iterator()
+	if err != nil {
+		log.Println(err)
+		return 0.0
+	}
+	defer itA.Close()
+	itB, err := b.Iterator()
+	if err != nil {
+		log.Println(err)
+		return 0.0
+	}
+	defer itB.Close()
+
+	n, meanA, meanB, coMoment, m2A, m2B := 0.0, 0.0, 0.0, 0.0, 0.0, 0.0
+	for {
+		okA, okB := e.nextColumnValue(itA), e.nextColumnValue(itB)
+		if !okA && !okB {
+			break
+		} else if okA != okB {
+			log.Println("Dataset sizes are different")
+			return 0.0
+		}
+		x, y := itA.Tuple().Data[e.column], itB.Tuple().Data[e.column]
+		n++
+		dx, dy := x-meanA, y-meanB
+		meanA += dx / n
+		meanB += dy / n
+		coMoment += dx * (y - meanB)
+		m2A += dx * (x - meanA)
+		m2B += dy * (y - meanB)
+	}
+	if m2A*m2B != 0 {
+		return coMoment / (math.Sqrt(m2A) * math.Sqrt(m2B))
+	}
+	log.Println("Denominator is zero")
+	return .0
+}
+
+// nextColumnValue advances the iterator to the next tuple that contains the
+// examined column
+func (e *CorrelationEstimator) nextColumnValue(it *DatasetIterator) bool {
+	for it.Next() {
+		if _, ok := e.columnValue(it.Tuple()); ok {
+			return true
+		}
+	}
+	if it.Err() != nil {
+		log.Println(it.Err())
+	}
+	return false
+}
+
 func (e *CorrelationEstimator) scaleCorrelationValue(val float64) float64 {
 	if e.normType == CorrelationSimilarityNormalizationAbs {
 		return math.Abs(val)
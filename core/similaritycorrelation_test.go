@@ -1,8 +1,12 @@
 package core
 
 import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
 	"math"
 	"math/rand"
+	"strings"
 	"testing"
 )
 
@@ -94,6 +98,7 @@ func TestCorrelationEstimatorSerialization(t *testing.T) {
 		"correlation":   []string{"pearson", "kendall", "spearman"}[rand.Int()%3],
 		"column":        "2",
 		"normalization": []string{"pos", "abs", "scale"}[rand.Int()%3],
+		"align":         "union",
 	}
 
 	pol := DatasetSimilarityPopulationPolicy{
@@ -112,6 +117,12 @@ func TestCorrelationEstimatorSerialization(t *testing.T) {
 	newEst := *new(CorrelationEstimator)
 	newEst.Deserialize(bytes)
 	estimatorsCheck(est.AbstractDatasetSimilarityEstimator, newEst.AbstractDatasetSimilarityEstimator, t)
+	if est.estType != newEst.estType || est.normType != newEst.normType ||
+		est.column != newEst.column || est.columnName != newEst.columnName {
+		t.Log("Configuration differs", est.estType, est.normType, est.column, est.columnName,
+			newEst.estType, newEst.normType, newEst.column, newEst.columnName)
+		t.Fail()
+	}
 	cleanDatasets(datasets)
 
 }
@@ -167,3 +178,78 @@ func TestCorrelationEvaluation(t *testing.T) {
 	}
 	cleanDatasets(datasets)
 }
+
+func TestCorrelationStreamingPearson(t *testing.T) {
+	datasets := createLinearDatasets(2, 200, 2, rand.Float64())
+	defer cleanDatasets(datasets)
+	est := NewDatasetSimilarityEstimator(SimilarityTypeCorrelation, datasets).(*CorrelationEstimator)
+	est.Configure(map[string]string{"column": "1", "normalization": "scale"})
+	streaming := est.streamingPearson(datasets[0], datasets[1])
+	batch := Pearson(est.transformDataset(datasets[0]), est.transformDataset(datasets[1]))
+	if math.Abs(streaming-batch) > 1e-9 {
+		t.Log("Streaming and batch Pearson differ", streaming, batch)
+		t.Fail()
+	}
+}
+
+func TestCorrelationAlignedColumn(t *testing.T) {
+	// the second dataset holds the tuples of the first with reordered columns
+	// and an extra one
+	r := rand.New(rand.NewSource(1))
+	first, second := new(bytes.Buffer), new(bytes.Buffer)
+	first.WriteString("a,b\n")
+	second.WriteString("c,b,a\n")
+	for i := 0; i < 100; i++ {
+		a, b := r.Float64(), r.Float64()
+		first.WriteString(fmt.Sprintf("%.5f,%.5f\n", a, b))
+		second.WriteString(fmt.Sprintf("%d,%.5f,%.5f\n", i, b, a))
+	}
+	var datasets []*Dataset
+	for _, b := range []*bytes.Buffer{first, second} {
+		f, _ := ioutil.TempFile("/tmp", "correlationdataset")
+		f.Write(b.Bytes())
+		f.Close()
+		datasets = append(datasets, NewDataset(f.Name()))
+	}
+	defer cleanDatasets(datasets)
+	for _, column := range []string{"b", "1"} {
+		est := NewDatasetSimilarityEstimator(SimilarityTypeCorrelation, datasets)
+		est.Configure(map[string]string{"column": column})
+		if err := est.Compute(); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		if s := est.SimilarityMatrix().Get(0, 1); math.Abs(s-1.0) > 1e-9 {
+			t.Log("Column", column, "is not aligned, similarity", s)
+			t.Fail()
+		}
+	}
+
+	est := NewDatasetSimilarityEstimator(SimilarityTypeCorrelation, datasets)
+	est.Configure(map[string]string{"column": "c"})
+	if err := est.Compute(); err == nil {
+		t.Log("Column c is not common to the datasets, Compute should fail")
+		t.Fail()
+	}
+
+	// datasets with the same header are aligned by position, their columns
+	// named by the header fields that may be surrounded by spaces
+	var spaced []*Dataset
+	for i := 0; i < 2; i++ {
+		f, _ := ioutil.TempFile("/tmp", "correlationdataset")
+		f.WriteString(strings.Replace(first.String(), "a,b", "a, b", 1))
+		f.Close()
+		spaced = append(spaced, NewDataset(f.Name()))
+	}
+	defer cleanDatasets(spaced)
+	est = NewDatasetSimilarityEstimator(SimilarityTypeCorrelation, spaced)
+	est.Configure(map[string]string{"column": "b"})
+	if err := est.Compute(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if s := est.SimilarityMatrix().Get(0, 1); math.Abs(s-1.0) > 1e-9 {
+		t.Log("Column b is not found, similarity", s)
+		t.Fail()
+	}
+}
@@ -0,0 +1,404 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// CorrelationMatrixEstimator compares the dependency structure of the
+// datasets. Contrary to the CorrelationEstimator, that compares a single
+// column of datasets of equal size, each dataset is summarized by the
+// correlation (or covariance) matrix of its numeric columns, and the datasets
+// are compared through a distance of their matrices. Therefore, datasets of
+// different size that share the same dependency structure are similar.
+type CorrelationMatrixEstimator struct {
+	AbstractDatasetSimilarityEstimator
+	covariance   bool                                   // if true, covariance matrices are compared
+	distType     CorrelationMatrixEstimatorDistanceType // the distance of the matrices
+	ridge        float64                                // regularization of the matrices' spectrum
+	inverseIndex map[string]int                         // maps the dataset paths to indices
+	columns      []int                                  // the columns of the tuples that are examined
+	matrices     [][][]float64                          // the matrix of each dataset
+}
+
+// CorrelationMatrixEstimatorDistanceType represents the distance used by the
+// CorrelationMatrixEstimator to compare the matrices
+type CorrelationMatrixEstimatorDistanceType uint8
+
+const (
+	// CorrelationMatrixDistanceFrobenius is the Frobenius norm of A-B
+	CorrelationMatrixDistanceFrobenius CorrelationMatrixEstimatorDistanceType = iota
+	// CorrelationMatrixDistanceForstner is the Förstner metric, i.e., the
+	// norm of the logarithms of the generalized eigenvalues of A and B
+	CorrelationMatrixDistanceForstner CorrelationMatrixEstimatorDistanceType = iota + 1
+	// CorrelationMatrixDistanceLogEuclidean is the Frobenius norm of
+	// log(A)-log(B)
+	CorrelationMatrixDistanceLogEuclidean CorrelationMatrixEstimatorDistanceType = iota + 2
+)
+
+// String returns a string representation of the distance type
+func (s CorrelationMatrixEstimatorDistanceType) String() string {
+	if s == CorrelationMatrixDistanceFrobenius {
+		return "frobenius"
+	} else if s == CorrelationMatrixDistanceForstner {
+		return "forstner"
+	} else if s == CorrelationMatrixDistanceLogEuclidean {
+		return "logeuclidean"
+	}
+	return ""
+}
+
+// Compute method constructs the Similarity Matrix
+func (e *CorrelationMatrixEstimator) Compute() error {
+	schema, err := e.unifySchemas()
+	if err == nil {
+		e.columns, err = sampleColumns(schema)
+	}
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	log.Println("Computing the matrices of the datasets")
+	e.matrices, err = e.datasetMatrices(e.datasets)
+	if err != nil {
+		return err
+	}
+	e.inverseIndex = make(map[string]int)
+	for i, d := range e.datasets {
+		e.inverseIndex[d.Path()] = i
+	}
+	return datasetSimilarityEstimatorComputeStreaming(e)
+}
+
+// Similarity returns the similarity between two datasets, i.e., 1/(1+d), d
+// being the distance of their matrices
+func (e *CorrelationMatrixEstimator) Similarity(a, b *Dataset) float64 {
+	matrixA, err := e.datasetMatrix(a)
+	if err != nil {
+		log.Println(err)
+		return 0.0
+	}
+	matrixB, err := e.datasetMatrix(b)
+	if err != nil {
+		log.Println(err)
+		return 0.0
+	}
+	distance, err := e.distance(matrixA, matrixB)
+	if err != nil {
+		log.Println(err)
+		return 0.0
+	}
+	return 1.0 / (1.0 + distance)
+}
+
+// Configure sets the necessary parameters before the similarity execution
+func (e *CorrelationMatrixEstimator) Configure(conf map[string]string) {
+	e.configureColumnsPolicy(conf, DatasetColumnsIntersection)
+	e.concurrency = 1
+	if val, ok := conf["concurrency"]; ok {
+		conv, err := strconv.ParseInt(val, 10, 32)
+		if err != nil {
+			log.Println(err)
+		} else {
+			e.concurrency = int(conv)
+		}
+	}
+	e.covariance = false
+	if val, ok := conf["matrix"]; ok {
+		if "covariance" == strings.ToLower(val) {
+			e.covariance = true
+		} else if "correlation" != strings.ToLower(val) {
+			log.Println("Unknown matrix, using default (correlation)")
+		}
+	}
+	e.distType = CorrelationMatrixDistanceFrobenius
+	if val, ok := conf["distance"]; ok {
+		if strings.ToLower(val) == "frobenius" {
+			e.distType = CorrelationMatrixDistanceFrobenius
+		} else if strings.ToLower(val) == "forstner" {
+			e.distType = CorrelationMatrixDistanceForstner
+		} else if strings.ToLower(val) == "logeuclidean" {
+			e.distType = CorrelationMatrixDistanceLogEuclidean
+		} else {
+			log.Println("Unknown distance, using default (frobenius)")
+		}
+	}
+	e.ridge = 1e-6
+	if val, ok := conf["ridge"]; ok {
+		conv, err := strconv.ParseFloat(val, 64)
+		if err != nil || conv <= 0 {
+			log.Println("Invalid ridge, using default (1e-6)", err)
+		} else {
+			e.ridge = conv
+		}
+	}
+}
+
+// Options returns a list of applicable parameters
+func (e *CorrelationMatrixEstimator) Options() map[string]string {
+	return map[string]string{
+		"concurrency": "max num of threads used (int)",
+		"matrix":      "the matrix of each dataset (one of correlation, covariance - default is correlation)",
+		"distance":    "the distance of the matrices (one of frobenius, forstner, logeuclidean - default is frobenius)",
+		"ridge":       "value added to the eigenvalues of the matrices for the forstner and logeuclidean distances (default is 1e-6)",
+		"align":       columnsPolicyOption(DatasetColumnsIntersection),
+	}
+}
+
+// Serialize returns a byte array containing the estimator.
+func (e *CorrelationMatrixEstimator) Serialize() []byte {
+	buffer := new(bytes.Buffer)
+	buffer.Write(getBytesInt(int(SimilarityTypeCorrelationMatrix)))
+	buffer.Write(
+		datasetSimilarityEstimatorSerialize(e.AbstractDatasetSimilarityEstimator))
+	if e.covariance {
+		buffer.Write(getBytesInt(1))
+	} else {
+		buffer.Write(getBytesInt(0))
+	}
+	buffer.Write(getBytesInt(int(e.distType)))
+	buffer.Write(getBytesFloat(e.ridge))
+
+	buffer.Write(getBytesInt(len(e.columns)))
+	for _, c := range e.columns {
+		buffer.Write(getBytesInt(c))
+	}
+	for _, m := range e.matrices {
+		for _, row := range m {
+			for _, v := range row {
+				buffer.Write(getBytesFloat(v))
+			}
+		}
+	}
+	return buffer.Bytes()
+}
+
+// Deserialize instantiates the estimator based on a byte array
+func (e *CorrelationMatrixEstimator) Deserialize(b []byte) {
+	buffer := bytes.NewBuffer(b)
+	tempInt := make([]byte, 4)
+	tempFloat := make([]byte, 8)
+	buffer.Read(tempInt) // consume estimator type
+
+	buffer.Read(tempInt)
+	absEstBytes := make([]byte, getIntBytes(tempInt))
+	buffer.Read(absEstBytes)
+	e.AbstractDatasetSimilarityEstimator =
+		*datasetSimilarityEstimatorDeserialize(absEstBytes)
+
+	buffer.Read(tempInt)
+	e.covariance = getIntBytes(tempInt) == 1
+	buffer.Read(tempInt)
+	e.distType = CorrelationMatrixEstimatorDistanceType(getIntBytes(tempInt))
+	buffer.Read(tempFloat)
+	e.ridge = getFloatBytes(tempFloat)
+
+	buffer.Read(tempInt)
+	e.columns = make([]int, getIntBytes(tempInt))
+	for i := range e.columns {
+		buffer.Read(tempInt)
+		e.columns[i] = getIntBytes(tempInt)
+	}
+
+	e.inverseIndex = make(map[string]int)
+	e.matrices = make([][][]float64, len(e.datasets))
+	for i, d := range e.datasets {
+		e.inverseIndex[d.Path()] = i
+		e.matrices[i] = make([][]float64, len(e.columns))
+		for j := range e.matrices[i] {
+			e.matrices[i][j] = make([]float64, len(e.columns))
+			for k := range e.matrices[i][j] {
+				buffer.Read(tempFloat)
+				e.matrices[i][j][k] = getFloatBytes(tempFloat)
+			}
+		}
+	}
+}
+
+// datasetsUpdated reindexes the matrices of the datasets and computes the
+// matrices of the appended datasets
+func (e *CorrelationMatrixEstimator) datasetsUpdated(appended []*Dataset, removed []string) error {
+	computed, err := e.datasetMatrices(appended)
+	if err != nil {
+		return err
+	}
+	matrices := make([][][]float64, len(e.datasets))
+	e.inverseIndex = reindexDatasets(e.datasets, e.inverseIndex, appended,
+		func(i, j int) { matrices[i] = e.matrices[j] },
+		func(i, k int) { matrices[i] = computed[k] })
+	e.matrices = matrices
+	return nil
+}
+
+// distance returns the distance of two matrices, according to the configured
+// distance type
+func (e *CorrelationMatrixEstimator) distance(a, b [][]float64) (float64, error) {
+	if e.distType == CorrelationMatrixDistanceFrobenius {
+		return frobeniusDistance(a, b), nil
+	}
+	a, b = e.regularize(a), e.regularize(b)
+	if e.distType == CorrelationMatrixDistanceLogEuclidean {
+		return frobeniusDistance(
+			symmetricMatrixLog(a, e.ridge), symmetricMatrixLog(b, e.ridge)), nil
+	}
+	values, err := generalizedEigenvalues(a, b)
+	if err != nil {
+		return 0.0, err
+	}
+	sum := 0.0
+	for _, v := range values {
+		l := math.Log(math.Max(v, e.ridge))
+		sum += l * l
+	}
+	return math.Sqrt(sum), nil
+}
+
+// regularize returns a copy of the matrix, the diagonal of which is increased
+// by the ridge, so that the matrix is positive definite
+func (e *CorrelationMatrixEstimator) regularize(a [][]float64) [][]float64 {
+	res := make([][]float64, len(a))
+	for i := range a {
+		res[i] = make([]float64, len(a[i]))
+		copy(res[i], a[i])
+		res[i][i] += e.ridge
+	}
+	return res
+}
+
+// frobeniusDistance returns the Frobenius norm of A-B
+func frobeniusDistance(a, b [][]float64) float64 {
+	sum := 0.0
+	for i := range a {
+		for j := range a[i] {
+			sum += (a[i][j] - b[i][j]) * (a[i][j] - b[i][j])
+		}
+	}
+	return math.Sqrt(sum)
+}
+
+// datasetMatrix returns the matrix of a dataset, which is computed if the
+// dataset is not indexed by the estimator
+func (e *CorrelationMatrixEstimator) datasetMatrix(d *Dataset) ([][]float64, error) {
+	if idx, ok := e.inverseIndex[d.Path()]; ok {
+		return e.matrices[idx], nil
+	}
+	d, err := e.alignedDataset(d)
+	if err != nil {
+		return nil, err
+	}
+	return e.cachedMatrix(d)
+}
+
+// datasetMatrices computes the matrices of the datasets in parallel
+func (e *CorrelationMatrixEstimator) datasetMatrices(datasets []*Dataset) ([][][]float64, error) {
+	matrices := make([][][]float64, len(datasets))
+	err := parallelDatasets(datasets, e.concurrency, func(i int, d *Dataset) error {
+		var err error
+		matrices[i], err = e.cachedMatrix(d)
+		return err
+	})
+	return matrices, err
+}
+
+// cachedMatrix returns the matrix of a dataset through the summary cache,
+// where it is stored row by row
+func (e *CorrelationMatrixEstimator) cachedMatrix(d *Dataset) ([][]float64, error) {
+	fingerprint := "corrmatrix/" + strconv.FormatBool(e.covariance) + "/" +
+		fmt.Sprint(e.columns)
+	f, err := e.datasetFeatures(d, fingerprint, func(d *Dataset) ([]float64, error) {
+		m, err := e.matrix(d)
+		if err != nil {
+			return nil, err
+		}
+		f := make([]float64, 0, len(m)*len(m))
+		for _, row := range m {
+			f = append(f, row...)
+		}
+		return f, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	n := len(e.columns)
+	if len(f) != n*n {
+		return nil, errors.New("Invalid cached matrix of " + d.Path())
+	}
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = f[i*n : (i+1)*n]
+	}
+	return m, nil
+}
+
+// matrix traverses a dataset and returns the correlation (or covariance)
+// matrix of the examined columns. The co-moments are updated in a single
+// pass and the tuples with missing values are ignored.
+func (e *CorrelationMatrixEstimator) matrix(d *Dataset) ([][]float64, error) {
+	it, err := d.Iterator()
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+	n := len(e.columns)
+	mean, values := make([]float64, n), make([]float64, n)
+	comoments := make([][]float64, n)
+	for i := range comoments {
+		comoments[i] = make([]float64, n)
+	}
+	count := 0
+	for it.Next() {
+		t := it.Tuple()
+		valid := true
+		for i, c := range e.columns {
+			if c >= len(t.Data) || math.IsNaN(t.Data[c]) {
+				valid = false
+				break
+			}
+			values[i] = t.Data[c]
+		}
+		if !valid {
+			continue
+		}
+		count++
+		// C_ij += (x_i - mean_i) (x_j - newmean_j)
+		for i := range values {
+			delta := values[i] - mean[i]
+			mean[i] += delta / float64(count)
+			for j := 0; j <= i; j++ {
+				comoments[i][j] += (values[j] - mean[j]) * delta
+			}
+		}
+	}
+	if it.Err() != nil {
+		return nil, it.Err()
+	}
+	if count < 2 {
+		return nil, errors.New("Not enough tuples to compute the matrix of " + d.Path())
+	}
+	result := make([][]float64, n)
+	for i := range result {
+		result[i] = make([]float64, n)
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j <= i; j++ {
+			v := comoments[i][j] / float64(count-1)
+			if !e.covariance {
+				// constant columns are considered uncorrelated
+				if i == j {
+					v = 1.0
+				} else if comoments[i][i] > 0 && comoments[j][j] > 0 {
+					v = comoments[i][j] / math.Sqrt(comoments[i][i]*comoments[j][j])
+				} else {
+					v = 0.0
+				}
+			}
+			result[i][j], result[j][i] = v, v
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// createCorrelatedDatasets creates datasets of two columns, the second of
+// which depends on the first with the specified weight
+func createCorrelatedDatasets(weights []float64, sizes []int) []*Dataset {
+	var result []*Dataset
+	for i, w := range weights {
+		r := rand.New(rand.NewSource(int64(i)))
+		builder := new(bytes.Buffer)
+		builder.WriteString("x0,class\n")
+		for j := 0; j < sizes[i]; j++ {
+			x := r.NormFloat64()
+			y := w*x + (1-math.Abs(w))*r.NormFloat64()
+			builder.WriteString(fmt.Sprintf("%.5f,%.5f\n", x, y))
+		}
+		f, _ := ioutil.TempFile("/tmp", "correlateddataset")
+		f.Write(builder.Bytes())
+		f.Close()
+		result = append(result, NewDataset(f.Name()))
+	}
+	return result
+}
+
+func TestMatrixDistances(t *testing.T) {
+	a := [][]float64{{2, 0}, {0, 3}}
+	b := [][]float64{{1, 0}, {0, 1}}
+	if d := frobeniusDistance(a, b); math.Abs(d-math.Sqrt(5)) > 1e-9 {
+		t.Log("Expected Frobenius distance sqrt(5), got", d)
+		t.Fail()
+	}
+	values, err := generalizedEigenvalues(a, [][]float64{{2, 0}, {0, 1}})
+	if err != nil || math.Abs(values[0]-3) > 1e-9 || math.Abs(values[1]-1) > 1e-9 {
+		t.Log("Wrong generalized eigenvalues", values, err)
+		t.Fail()
+	}
+	l := symmetricMatrixLog(a, 1e-12)
+	if math.Abs(l[0][0]-math.Log(2)) > 1e-9 || math.Abs(l[1][1]-math.Log(3)) > 1e-9 ||
+		math.Abs(l[0][1]) > 1e-9 {
+		t.Log("Wrong matrix logarithm", l)
+		t.Fail()
+	}
+}
+
+func TestCorrelationMatrixCompute(t *testing.T) {
+	// same structure with different sizes, followed by weaker dependencies
+	datasets := createCorrelatedDatasets(
+		[]float64{0.9, 0.9, 0.5, 0.0, -0.9}, []int{200, 1000, 500, 500, 300})
+	defer cleanDatasets(datasets)
+	for _, distance := range []string{"frobenius", "forstner", "logeuclidean"} {
+		est := NewDatasetSimilarityEstimator(SimilarityTypeCorrelationMatrix, datasets)
+		est.Configure(map[string]string{"concurrency": "4", "distance": distance})
+		if err := est.Compute(); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		sm := est.SimilarityMatrix()
+		smSanityCheck(sm, t)
+		if !(sm.Get(0, 1) > sm.Get(0, 2) && sm.Get(0, 2) > sm.Get(0, 3) && sm.Get(0, 3) > sm.Get(0, 4)) {
+			t.Log(distance, "similarities do not decrease with the dependency",
+				sm.Get(0, 1), sm.Get(0, 2), sm.Get(0, 3), sm.Get(0, 4))
+			t.Fail()
+		}
+		if sm.Get(0, 1) < 0.5 {
+			t.Log(distance, "datasets of the same structure are not similar", sm.Get(0, 1))
+			t.Fail()
+		}
+	}
+}
+
+func TestCorrelationMatrixSerialization(t *testing.T) {
+	datasets := createCorrelatedDatasets([]float64{0.8, 0.4, 0.0, 0.6}, []int{100, 200, 300, 150})
+	defer cleanDatasets(datasets)
+	est := *new(CorrelationMatrixEstimator)
+	est.datasets = datasets[:3]
+	est.SetPopulationPolicy(DatasetSimilarityPopulationPolicy{PolicyType: PopulationPolicyFull})
+	est.Configure(map[string]string{"matrix": "covariance", "distance": "forstner", "ridge": "0.01"})
+	if err := est.Compute(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	newEst := *new(CorrelationMatrixEstimator)
+	newEst.Deserialize(est.Serialize())
+	estimatorsCheck(est.AbstractDatasetSimilarityEstimator, newEst.AbstractDatasetSimilarityEstimator, t)
+	if !newEst.covariance || newEst.distType != CorrelationMatrixDistanceForstner || newEst.ridge != 0.01 {
+		t.Log("Wrong deserialized parameters")
+		t.FailNow()
+	}
+	for i := range datasets {
+		for j := range datasets {
+			if est.Similarity(datasets[i], datasets[j]) != newEst.Similarity(datasets[i], datasets[j]) {
+				t.Log("Different similarities after deserialization", i, j)
+				t.Fail()
+			}
+		}
+	}
+}
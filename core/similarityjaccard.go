@@ -9,13 +9,18 @@ import (
 // JaccardEstimator estimates the Jaccard coefficients between the different
 // datasets. The Jaccard coefficient between two datasets is defined as
 // the cardinality of the intersection divided by the cardinality of the
-// union of the two datasets.
+// union of the two datasets. Categorical columns are compared through exact
+// matching.
 type JaccardEstimator struct {
 	AbstractDatasetSimilarityEstimator
 }
 
 // Compute method constructs the Similarity Matrix
 func (e *JaccardEstimator) Compute() error {
+	if _, err := e.unifySchemas(); err != nil {
+		log.Println(err)
+		return err
+	}
 	return datasetSimilarityEstimatorCompute(e)
 }
 
@@ -27,8 +32,24 @@ func (e *JaccardEstimator) Similarity(a, b *Dataset) float64 {
 	return value
 }
 
+// datasetsUpdated fetches the datasets in memory, since a deserialized
+// estimator holds no data
+func (e *JaccardEstimator) datasetsUpdated(appended []*Dataset, removed []string) error {
+	if len(appended) == 0 {
+		return nil
+	}
+	for _, d := range e.datasets {
+		if err := d.ReadFromFile(); err != nil {
+			log.Println(err)
+			return err
+		}
+	}
+	return nil
+}
+
 // Configure sets the necessary parameters before the similarity execution
 func (e *JaccardEstimator) Configure(conf map[string]string) {
+	e.configureColumnsPolicy(conf, DatasetColumnsIntersection)
 	if val, ok := conf["concurrency"]; ok {
 		conv, err := strconv.ParseInt(val, 10, 32)
 		if err != nil {
@@ -45,6 +66,7 @@ func (e *JaccardEstimator) Configure(conf map[string]string) {
 func (e *JaccardEstimator) Options() map[string]string {
 	return map[string]string{
 		"concurrency": "max num of threads used (int)",
+		"align":       columnsPolicyOption(DatasetColumnsIntersection),
 	}
 }
 
@@ -0,0 +1,274 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/fnv"
+	"log"
+	"math"
+	"math/rand"
+	"strconv"
+)
+
+// MinHashEstimator approximates the Jaccard coefficients between the
+// datasets through their MinHash signatures. The signature of each dataset is
+// computed once, by traversing the dataset without loading it in memory, and
+// the Jaccard coefficient of two datasets is estimated as the portion of the
+// equal signature elements.
+type MinHashEstimator struct {
+	AbstractDatasetSimilarityEstimator
+	signatureSize int            // the number of hash functions
+	seed          int64          // the seed of the hash functions
+	inverseIndex  map[string]int // maps the dataset paths to indices
+	signatures    [][]uint64     // holds the signature of each dataset
+	hashSeeds     []uint64       // the seeds of the hash functions
+}
+
+// Compute method constructs the Similarity Matrix
+func (e *MinHashEstimator) Compute() error {
+	if _, err := e.unifySchemas(); err != nil {
+		log.Println(err)
+		return err
+	}
+	log.Println("Computing the dataset signatures")
+	signatures, err := e.datasetSignatures(e.datasets)
+	if err != nil {
+		return err
+	}
+	e.signatures = signatures
+	e.inverseIndex = make(map[string]int)
+	for i, d := range e.datasets {
+		e.inverseIndex[d.Path()] = i
+	}
+	return datasetSimilarityEstimatorComputeStreaming(e)
+}
+
+// Similarity returns the similarity between two datasets
+func (e *MinHashEstimator) Similarity(a, b *Dataset) float64 {
+	sigA, err := e.signature(a)
+	if err != nil {
+		log.Println(err)
+		return 0.0
+	}
+	sigB, err := e.signature(b)
+	if err != nil {
+		log.Println(err)
+		return 0.0
+	}
+	equal := 0
+	for i := range sigA {
+		if sigA[i] == sigB[i] {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(sigA))
+}
+
+// Configure sets the necessary parameters before the similarity execution
+func (e *MinHashEstimator) Configure(conf map[string]string) {
+	e.configureColumnsPolicy(conf, DatasetColumnsIntersection)
+	e.concurrency = 1
+	if val, ok := conf["concurrency"]; ok {
+		conv, err := strconv.ParseInt(val, 10, 32)
+		if err != nil {
+			log.Println(err)
+		} else {
+			e.concurrency = int(conv)
+		}
+	}
+	e.signatureSize = 128
+	if val, ok := conf["signature.size"]; ok {
+		conv, err := strconv.ParseInt(val, 10, 32)
+		if err != nil || conv < 1 {
+			log.Println("Invalid signature size, using default (128)", err)
+		} else {
+			e.signatureSize = int(conv)
+		}
+	}
+	e.seed = 0
+	if val, ok := conf["seed"]; ok {
+		conv, err := strconv.ParseInt(val, 10, 32)
+		if err != nil {
+			log.Println(err)
+		} else {
+			e.seed = conv
+		}
+	}
+	e.initHashSeeds()
+}
+
+// Options returns a list of applicable parameters
+func (e *MinHashEstimator) Options() map[string]string {
+	return map[string]string{
+		"concurrency":    "max num of threads used (int)",
+		"signature.size": "number of hash functions of each signature (default is 128)",
+		"seed":           "seed used to generate the hash functions (default is 0)",
+		"align":          columnsPolicyOption(DatasetColumnsIntersection),
+	}
+}
+
+// Serialize returns a byte array containing the estimator.
+func (e *MinHashEstimator) Serialize() []byte {
+	buffer := new(bytes.Buffer)
+	buffer.Write(getBytesInt(int(SimilarityTypeMinHash)))
+	buffer.Write(
+		datasetSimilarityEstimatorSerialize(e.AbstractDatasetSimilarityEstimator))
+	buffer.Write(getBytesInt(e.signatureSize))
+	buffer.Write(getBytesInt(int(e.seed)))
+
+	// write the signatures
+	temp := make([]byte, 8)
+	for _, sig := range e.signatures {
+		for _, v := range sig {
+			binary.BigEndian.PutUint64(temp, v)
+			buffer.Write(temp)
+		}
+	}
+	return buffer.Bytes()
+}
+
+// Deserialize instantiates the estimator based on a byte array
+func (e *MinHashEstimator) Deserialize(b []byte) {
+	buffer := bytes.NewBuffer(b)
+	tempInt := make([]byte, 4)
+	buffer.Read(tempInt) // consume estimator type
+
+	buffer.Read(tempInt)
+	absEstBytes := make([]byte, getIntBytes(tempInt))
+	buffer.Read(absEstBytes)
+	e.AbstractDatasetSimilarityEstimator =
+		*datasetSimilarityEstimatorDeserialize(absEstBytes)
+
+	buffer.Read(tempInt)
+	e.signatureSize = getIntBytes(tempInt)
+	buffer.Read(tempInt)
+	e.seed = int64(int32(getIntBytes(tempInt)))
+	e.initHashSeeds()
+
+	e.inverseIndex = make(map[string]int)
+	for i, d := range e.datasets {
+		e.inverseIndex[d.Path()] = i
+	}
+	tempUint := make([]byte, 8)
+	e.signatures = make([][]uint64, len(e.datasets))
+	for i := range e.signatures {
+		e.signatures[i] = make([]uint64, e.signatureSize)
+		for j := range e.signatures[i] {
+			buffer.Read(tempUint)
+			e.signatures[i][j] = binary.BigEndian.Uint64(tempUint)
+		}
+	}
+}
+
+// datasetsUpdated reindexes the signatures of the datasets; the signatures
+// of the appended datasets are computed
+func (e *MinHashEstimator) datasetsUpdated(appended []*Dataset, removed []string) error {
+	computed, err := e.datasetSignatures(appended)
+	if err != nil {
+		return err
+	}
+	signatures := make([][]uint64, len(e.datasets))
+	e.inverseIndex = reindexDatasets(e.datasets, e.inverseIndex, appended,
+		func(i, j int) { signatures[i] = e.signatures[j] },
+		func(i, k int) { signatures[i] = computed[k] })
+	e.signatures = signatures
+	return nil
+}
+
+// initHashSeeds generates the seeds of the hash functions
+func (e *MinHashEstimator) initHashSeeds() {
+	r := rand.New(rand.NewSource(e.seed))
+	e.hashSeeds = make([]uint64, e.signatureSize)
+	for i := range e.hashSeeds {
+		e.hashSeeds[i] = uint64(r.Int63())<<1 ^ uint64(r.Int63())
+	}
+}
+
+// signature returns the signature of a dataset, which is computed if the
+// dataset is not indexed by the estimator
+func (e *MinHashEstimator) signature(d *Dataset) ([]uint64, error) {
+	if idx, ok := e.inverseIndex[d.Path()]; ok {
+		return e.signatures[idx], nil
+	}
+	d, err := e.alignedDataset(d)
+	if err != nil {
+		return nil, err
+	}
+	return e.cachedSignature(d)
+}
+
+// datasetSignatures computes the signatures of the datasets in parallel
+func (e *MinHashEstimator) datasetSignatures(datasets []*Dataset) ([][]uint64, error) {
+	signatures := make([][]uint64, len(datasets))
+	err := parallelDatasets(datasets, e.concurrency, func(i int, d *Dataset) error {
+		var err error
+		signatures[i], err = e.cachedSignature(d)
+		return err
+	})
+	return signatures, err
+}
+
+// cachedSignature returns the signature of a dataset through the summary
+// cache; the hash values are stored through their bit representation
+func (e *MinHashEstimator) cachedSignature(d *Dataset) ([]uint64, error) {
+	fingerprint := "minhash/" + strconv.FormatInt(e.seed, 10) + "/" +
+		strconv.Itoa(len(e.hashSeeds))
+	f, err := e.datasetFeatures(d, fingerprint, func(d *Dataset) ([]float64, error) {
+		sig, err := e.computeSignature(d)
+		if err != nil {
+			return nil, err
+		}
+		f := make([]float64, len(sig))
+		for i, v := range sig {
+			f[i] = math.Float64frombits(v)
+		}
+		return f, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sig := make([]uint64, len(f))
+	for i, v := range f {
+		sig[i] = math.Float64bits(v)
+	}
+	return sig, nil
+}
+
+// computeSignature traverses a dataset and computes its MinHash signature.
+// The tuples are compared through their serialized form, as in the
+// JaccardEstimator.
+func (e *MinHashEstimator) computeSignature(d *Dataset) ([]uint64, error) {
+	sig := make([]uint64, len(e.hashSeeds))
+	for i := range sig {
+		sig[i] = math.MaxUint64
+	}
+	it, err := d.Iterator()
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+	h := fnv.New64a()
+	for it.Next() {
+		t := it.Tuple()
+		h.Reset()
+		h.Write([]byte(t.Serialize()))
+		base := h.Sum64()
+		for i, s := range e.hashSeeds {
+			if v := minHashMix(base ^ s); v < sig[i] {
+				sig[i] = v
+			}
+		}
+	}
+	return sig, it.Err()
+}
+
+// minHashMix scrambles the bits of a hash value (the finalizer of the
+// SplitMix64 generator), so that each seed yields an independent hash function
+func minHashMix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMinHashCompute(t *testing.T) {
+	datasets := createPoolBasedDatasets(1000, 10, 2)
+	defer cleanDatasets(datasets)
+	est := NewDatasetSimilarityEstimator(SimilarityTypeMinHash, datasets)
+	est.Configure(map[string]string{"concurrency": "4", "signature.size": "512", "seed": "7"})
+	if err := est.Compute(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	smSanityCheck(est.SimilarityMatrix(), t)
+
+	jaccard := NewDatasetSimilarityEstimator(SimilarityTypeJaccard, datasets)
+	jaccard.Configure(map[string]string{"concurrency": "4"})
+	if err := jaccard.Compute(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	for i := range datasets {
+		for j := range datasets {
+			a, b := est.SimilarityMatrix().Get(i, j), jaccard.SimilarityMatrix().Get(i, j)
+			if math.Abs(a-b) > 0.15 {
+				t.Log("MinHash estimation too far from the Jaccard coefficient", i, j, a, b)
+				t.Fail()
+			}
+		}
+	}
+}
+
+func TestMinHashSeed(t *testing.T) {
+	datasets := createPoolBasedDatasets(1000, 2, 2)
+	defer cleanDatasets(datasets)
+	similarity := func(seed string) float64 {
+		est := NewDatasetSimilarityEstimator(SimilarityTypeMinHash, datasets)
+		est.Configure(map[string]string{"signature.size": "32", "seed": seed})
+		return est.Similarity(datasets[0], datasets[1])
+	}
+	if similarity("1") != similarity("1") {
+		t.Log("The same seed should give the same estimation")
+		t.Fail()
+	}
+	est := new(MinHashEstimator)
+	est.Configure(map[string]string{"signature.size": "32"})
+	if len(est.hashSeeds) != 32 {
+		t.Log("Wrong number of hash functions", len(est.hashSeeds))
+		t.Fail()
+	}
+}
+
+func TestMinHashSerialization(t *testing.T) {
+	datasets := createPoolBasedDatasets(1000, 8, 3)
+	defer cleanDatasets(datasets)
+	est := *new(MinHashEstimator)
+	est.datasets = datasets
+	pol := DatasetSimilarityPopulationPolicy{
+		PolicyType: PopulationPolicyAprx,
+		Parameters: map[string]float64{
+			"count": 3.0,
+		},
+	}
+	est.SetPopulationPolicy(pol)
+	est.Configure(map[string]string{"signature.size": "64", "seed": "-3"})
+	if err := est.Compute(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	newEst := *new(MinHashEstimator)
+	newEst.Deserialize(est.Serialize())
+	estimatorsCheck(est.AbstractDatasetSimilarityEstimator, newEst.AbstractDatasetSimilarityEstimator, t)
+	if newEst.signatureSize != 64 || newEst.seed != -3 || len(newEst.signatures) != len(datasets) {
+		t.Log("Wrong deserialized parameters", newEst.signatureSize, newEst.seed)
+		t.FailNow()
+	}
+	for i := range datasets {
+		for j := range datasets {
+			if est.Similarity(datasets[i], datasets[j]) != newEst.Similarity(datasets[i], datasets[j]) {
+				t.Log("Different similarities after deserialization", i, j)
+				t.Fail()
+			}
+		}
+	}
+}
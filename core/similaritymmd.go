@@ -0,0 +1,408 @@
+package core
+
+import (
+	"bytes"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+)
+
+// mmdBandwidthTuples is the max number of tuples examined by the median
+// heuristic
+const mmdBandwidthTuples = 1000
+
+// MMDEstimator estimates the similarity of the distribution of the datasets
+// through the Maximum Mean Discrepancy (MMD) of their tuples, using an RBF
+// kernel. The numeric columns are normalized to [0,1] and, unless specified,
+// the bandwidth of the kernel is set to the median distance of the tuples
+// (median heuristic). The MMD is either computed exactly on a sample of each
+// dataset, or approximated in linear time through random Fourier features.
+type MMDEstimator struct {
+	AbstractDatasetSimilarityEstimator
+	bandwidth    float64        // the kernel bandwidth, 0 for the median heuristic
+	features     int            // the number of random features, 0 for the exact MMD
+	maxTuples    int            // the max number of tuples sampled per dataset
+	seed         int64          // the seed of the samples and the random features
+	inverseIndex map[string]int // maps the dataset paths to indices
+	columns      []int          // the columns of the tuples that are examined
+	min, max     []float64      // the bounds used to normalize the columns
+	sigma        float64        // the kernel bandwidth in use
+	weights      [][]float64    // the frequencies of the random features
+	offsets      []float64      // the phases of the random features
+	embeddings   []mmdEmbedding // the embedding of each dataset
+}
+
+// mmdEmbedding holds the representation of a dataset in the kernel space:
+// the (normalized) sample along with its mean kernel value for the exact MMD,
+// or the mean of its random features for the approximated one
+type mmdEmbedding struct {
+	sample [][]float64
+	self   float64
+	mean   []float64
+}
+
+// Compute method constructs the Similarity Matrix
+func (e *MMDEstimator) Compute() error {
+	schema, err := e.unifySchemas()
+	if err == nil {
+		e.columns, err = sampleColumns(schema)
+	}
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	log.Println("Sampling the datasets")
+	samples, err := sampleDatasets(e.datasets, e.columns, e.maxTuples, e.seed, e.concurrency)
+	if err != nil {
+		return err
+	}
+	e.min, e.max = sampleBounds(samples, len(e.columns))
+	for i := range samples {
+		samples[i] = normalizeSample(samples[i], e.min, e.max)
+	}
+	e.sigma = e.bandwidth
+	if e.sigma <= 0 {
+		e.sigma = mmdMedianDistance(samples, e.seed)
+		log.Println("Kernel bandwidth (median heuristic):", e.sigma)
+	}
+	e.initFeatures()
+	e.embeddings = make([]mmdEmbedding, len(e.datasets))
+	e.inverseIndex = make(map[string]int)
+	for i, d := range e.datasets {
+		e.embeddings[i] = e.embed(samples[i])
+		e.inverseIndex[d.Path()] = i
+	}
+	return datasetSimilarityEstimatorComputeStreaming(e)
+}
+
+// Similarity returns the similarity between two datasets
+func (e *MMDEstimator) Similarity(a, b *Dataset) float64 {
+	embA, err := e.datasetEmbedding(a)
+	if err != nil {
+		log.Println(err)
+		return 0.0
+	}
+	embB, err := e.datasetEmbedding(b)
+	if err != nil {
+		log.Println(err)
+		return 0.0
+	}
+	var mmd2 float64
+	if e.features > 0 {
+		for i := range embA.mean {
+			mmd2 += (embA.mean[i] - embB.mean[i]) * (embA.mean[i] - embB.mean[i])
+		}
+	} else {
+		mmd2 = embA.self + embB.self - 2.0*e.meanKernel(embA.sample, embB.sample)
+	}
+	// the RBF kernel lies in [0,1], hence the squared MMD lies in [0,2]
+	return 1.0 - math.Sqrt(math.Min(math.Max(mmd2, 0.0), 2.0)/2.0)
+}
+
+// Configure sets the necessary parameters before the similarity execution
+func (e *MMDEstimator) Configure(conf map[string]string) {
+	e.configureColumnsPolicy(conf, DatasetColumnsIntersection)
+	e.concurrency = 1
+	if val, ok := conf["concurrency"]; ok {
+		conv, err := strconv.ParseInt(val, 10, 32)
+		if err != nil {
+			log.Println(err)
+		} else {
+			e.concurrency = int(conv)
+		}
+	}
+	e.bandwidth = 0
+	if val, ok := conf["bandwidth"]; ok {
+		conv, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			log.Println(err)
+		} else {
+			e.bandwidth = conv
+		}
+	}
+	e.features = 0
+	if val, ok := conf["features"]; ok {
+		conv, err := strconv.ParseInt(val, 10, 32)
+		if err != nil || conv < 0 {
+			log.Println("Invalid number of features, computing the exact MMD", err)
+		} else {
+			e.features = int(conv)
+		}
+	}
+	e.maxTuples = 1000
+	if val, ok := conf["tuples"]; ok {
+		conv, err := strconv.ParseInt(val, 10, 32)
+		if err != nil {
+			log.Println(err)
+		} else {
+			e.maxTuples = int(conv)
+		}
+	}
+	e.seed = 0
+	if val, ok := conf["seed"]; ok {
+		conv, err := strconv.ParseInt(val, 10, 32)
+		if err != nil {
+			log.Println(err)
+		} else {
+			e.seed = conv
+		}
+	}
+}
+
+// Options returns a list of applicable parameters
+func (e *MMDEstimator) Options() map[string]string {
+	return map[string]string{
+		"concurrency": "max num of threads used (int)",
+		"bandwidth":   "bandwidth of the RBF kernel on the normalized columns (default is 0, i.e., the median heuristic)",
+		"features":    "number of random Fourier features for the linear time approximation (default is 0, i.e., the exact MMD)",
+		"tuples":      "max number of tuples sampled from each dataset, 0 for all (default is 1000)",
+		"seed":        "seed used to generate the samples and the random features (default is 0)",
+		"align":       columnsPolicyOption(DatasetColumnsIntersection),
+	}
+}
+
+// Serialize returns a byte array containing the estimator.
+func (e *MMDEstimator) Serialize() []byte {
+	buffer := new(bytes.Buffer)
+	buffer.Write(getBytesInt(int(SimilarityTypeMMD)))
+	buffer.Write(
+		datasetSimilarityEstimatorSerialize(e.AbstractDatasetSimilarityEstimator))
+	buffer.Write(getBytesFloat(e.bandwidth))
+	buffer.Write(getBytesInt(e.features))
+	buffer.Write(getBytesInt(e.maxTuples))
+	buffer.Write(getBytesInt(int(e.seed)))
+	buffer.Write(getBytesFloat(e.sigma))
+
+	// write the columns and their bounds
+	buffer.Write(getBytesInt(len(e.columns)))
+	for i, c := range e.columns {
+		buffer.Write(getBytesInt(c))
+		buffer.Write(getBytesFloat(e.min[i]))
+		buffer.Write(getBytesFloat(e.max[i]))
+	}
+
+	// write the random features
+	for i := range e.weights {
+		for _, w := range e.weights[i] {
+			buffer.Write(getBytesFloat(w))
+		}
+		buffer.Write(getBytesFloat(e.offsets[i]))
+	}
+
+	// write the embeddings
+	for _, emb := range e.embeddings {
+		if e.features > 0 {
+			for _, v := range emb.mean {
+				buffer.Write(getBytesFloat(v))
+			}
+			continue
+		}
+		buffer.Write(getBytesInt(len(emb.sample)))
+		for _, t := range emb.sample {
+			for _, v := range t {
+				buffer.Write(getBytesFloat(v))
+			}
+		}
+		buffer.Write(getBytesFloat(emb.self))
+	}
+	return buffer.Bytes()
+}
+
+// Deserialize instantiates the estimator based on a byte array
+func (e *MMDEstimator) Deserialize(b []byte) {
+	buffer := bytes.NewBuffer(b)
+	tempInt := make([]byte, 4)
+	tempFloat := make([]byte, 8)
+	buffer.Read(tempInt) // consume estimator type
+
+	buffer.Read(tempInt)
+	absEstBytes := make([]byte, getIntBytes(tempInt))
+	buffer.Read(absEstBytes)
+	e.AbstractDatasetSimilarityEstimator =
+		*datasetSimilarityEstimatorDeserialize(absEstBytes)
+
+	buffer.Read(tempFloat)
+	e.bandwidth = getFloatBytes(tempFloat)
+	buffer.Read(tempInt)
+	e.features = getIntBytes(tempInt)
+	buffer.Read(tempInt)
+	e.maxTuples = getIntBytes(tempInt)
+	buffer.Read(tempInt)
+	e.seed = int64(int32(getIntBytes(tempInt)))
+	buffer.Read(tempFloat)
+	e.sigma = getFloatBytes(tempFloat)
+
+	buffer.Read(tempInt)
+	count := getIntBytes(tempInt)
+	e.columns = make([]int, count)
+	e.min, e.max = make([]float64, count), make([]float64, count)
+	for i := range e.columns {
+		buffer.Read(tempInt)
+		e.columns[i] = getIntBytes(tempInt)
+		buffer.Read(tempFloat)
+		e.min[i] = getFloatBytes(tempFloat)
+		buffer.Read(tempFloat)
+		e.max[i] = getFloatBytes(tempFloat)
+	}
+
+	e.weights, e.offsets = make([][]float64, e.features), make([]float64, e.features)
+	for i := range e.weights {
+		e.weights[i] = make([]float64, len(e.columns))
+		for j := range e.weights[i] {
+			buffer.Read(tempFloat)
+			e.weights[i][j] = getFloatBytes(tempFloat)
+		}
+		buffer.Read(tempFloat)
+		e.offsets[i] = getFloatBytes(tempFloat)
+	}
+
+	e.inverseIndex = make(map[string]int)
+	e.embeddings = make([]mmdEmbedding, len(e.datasets))
+	for i, d := range e.datasets {
+		e.inverseIndex[d.Path()] = i
+		if e.features > 0 {
+			e.embeddings[i].mean = make([]float64, e.features)
+			for j := range e.embeddings[i].mean {
+				buffer.Read(tempFloat)
+				e.embeddings[i].mean[j] = getFloatBytes(tempFloat)
+			}
+			continue
+		}
+		buffer.Read(tempInt)
+		e.embeddings[i].sample = make([][]float64, getIntBytes(tempInt))
+		for j := range e.embeddings[i].sample {
+			e.embeddings[i].sample[j] = make([]float64, len(e.columns))
+			for k := range e.embeddings[i].sample[j] {
+				buffer.Read(tempFloat)
+				e.embeddings[i].sample[j][k] = getFloatBytes(tempFloat)
+			}
+		}
+		buffer.Read(tempFloat)
+		e.embeddings[i].self = getFloatBytes(tempFloat)
+	}
+}
+
+// datasetsUpdated reindexes the embeddings of the datasets; the embeddings of
+// the appended datasets are computed according to the existing normalization,
+// bandwidth and random features
+func (e *MMDEstimator) datasetsUpdated(appended []*Dataset, removed []string) error {
+	samples, err := sampleDatasets(appended, e.columns, e.maxTuples, e.seed, e.concurrency)
+	if err != nil {
+		return err
+	}
+	embeddings := make([]mmdEmbedding, len(e.datasets))
+	e.inverseIndex = reindexDatasets(e.datasets, e.inverseIndex, appended,
+		func(i, j int) { embeddings[i] = e.embeddings[j] },
+		func(i, k int) { embeddings[i] = e.embed(normalizeSample(samples[k], e.min, e.max)) })
+	e.embeddings = embeddings
+	return nil
+}
+
+// initFeatures generates the random Fourier features of the RBF kernel, i.e.,
+// frequencies drawn from N(0, 1/sigma^2) and phases drawn from U[0, 2pi)
+func (e *MMDEstimator) initFeatures() {
+	r := rand.New(rand.NewSource(e.seed))
+	e.weights, e.offsets = make([][]float64, e.features), make([]float64, e.features)
+	for i := range e.weights {
+		e.weights[i] = make([]float64, len(e.columns))
+		for j := range e.weights[i] {
+			e.weights[i][j] = r.NormFloat64() / e.sigma
+		}
+		e.offsets[i] = r.Float64() * 2.0 * math.Pi
+	}
+}
+
+// datasetEmbedding returns the embedding of a dataset, which is computed if
+// the dataset is not indexed by the estimator
+func (e *MMDEstimator) datasetEmbedding(d *Dataset) (mmdEmbedding, error) {
+	if idx, ok := e.inverseIndex[d.Path()]; ok {
+		return e.embeddings[idx], nil
+	}
+	d, err := e.alignedDataset(d)
+	if err != nil {
+		return mmdEmbedding{}, err
+	}
+	sample, err := sampleDataset(d, e.columns, e.maxTuples, e.seed)
+	if err != nil {
+		return mmdEmbedding{}, err
+	}
+	return e.embed(normalizeSample(sample, e.min, e.max)), nil
+}
+
+// embed returns the embedding of a normalized sample
+func (e *MMDEstimator) embed(sample [][]float64) mmdEmbedding {
+	if e.features == 0 {
+		return mmdEmbedding{sample: sample, self: e.meanKernel(sample, sample)}
+	}
+	mean := make([]float64, e.features)
+	scale := math.Sqrt(2.0 / float64(e.features))
+	for _, t := range sample {
+		for i, w := range e.weights {
+			dot := e.offsets[i]
+			for j, v := range t {
+				dot += w[j] * v
+			}
+			mean[i] += scale * math.Cos(dot)
+		}
+	}
+	for i := range mean {
+		mean[i] /= float64(len(sample))
+	}
+	return mmdEmbedding{mean: mean}
+}
+
+// meanKernel returns the mean RBF kernel value over all pairs of tuples of
+// two samples
+func (e *MMDEstimator) meanKernel(a, b [][]float64) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0.0
+	}
+	gamma := 1.0 / (2.0 * e.sigma * e.sigma)
+	sum := 0.0
+	for _, x := range a {
+		for _, y := range b {
+			dist := 0.0
+			for i := range x {
+				dist += (x[i] - y[i]) * (x[i] - y[i])
+			}
+			sum += math.Exp(-gamma * dist)
+		}
+	}
+	return sum / float64(len(a)*len(b))
+}
+
+// mmdMedianDistance returns the median distance among the tuples of the
+// samples; up to mmdBandwidthTuples tuples, evenly drawn from the samples, are
+// examined. If the median is zero, one is returned.
+func mmdMedianDistance(samples [][][]float64, seed int64) float64 {
+	r := rand.New(rand.NewSource(seed))
+	perSample := mmdBandwidthTuples / len(samples)
+	if perSample < 1 {
+		perSample = 1
+	}
+	var pool [][]float64
+	for _, s := range samples {
+		for i, idx := range r.Perm(len(s)) {
+			if i >= perSample || len(pool) >= mmdBandwidthTuples {
+				break
+			}
+			pool = append(pool, s[idx])
+		}
+	}
+	var distances []float64
+	for i := range pool {
+		for j := i + 1; j < len(pool); j++ {
+			distances = append(distances, euclideanDistance(pool[i], pool[j]))
+		}
+	}
+	if len(distances) == 0 {
+		return 1.0
+	}
+	sort.Float64s(distances)
+	if median := distances[len(distances)/2]; median > 0 {
+		return median
+	}
+	return 1.0
+}
@@ -0,0 +1,90 @@
+package core
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMMDCompute(t *testing.T) {
+	datasets := createShiftedDatasets([]float64{0, 0, 0.2, 0.5, 1.0}, 300, 3)
+	defer cleanDatasets(datasets)
+	var exact *DatasetSimilarityMatrix
+	for _, features := range []string{"0", "2000"} {
+		est := NewDatasetSimilarityEstimator(SimilarityTypeMMD, datasets)
+		est.Configure(map[string]string{"concurrency": "4", "features": features, "seed": "3"})
+		if err := est.Compute(); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		sm := est.SimilarityMatrix()
+		smSanityCheck(sm, t)
+		if !(sm.Get(0, 1) > sm.Get(0, 2) && sm.Get(0, 2) > sm.Get(0, 3) && sm.Get(0, 3) > sm.Get(0, 4)) {
+			t.Log(features, "similarities do not decrease with the shift",
+				sm.Get(0, 1), sm.Get(0, 2), sm.Get(0, 3), sm.Get(0, 4))
+			t.Fail()
+		}
+		if exact == nil {
+			exact = sm
+			continue
+		}
+		// the random features approximate the exact MMD
+		for i := range datasets {
+			for j := range datasets {
+				if math.Abs(sm.Get(i, j)-exact.Get(i, j)) > 0.1 {
+					t.Log("Approximation too far from the exact MMD", i, j, sm.Get(i, j), exact.Get(i, j))
+					t.Fail()
+				}
+			}
+		}
+	}
+}
+
+func TestMMDMedianDistance(t *testing.T) {
+	samples := [][][]float64{{{0}, {1}}, {{0}, {1}}}
+	if d := mmdMedianDistance(samples, 0); d != 1.0 {
+		t.Log("Expected median distance 1, got", d)
+		t.Fail()
+	}
+	if d := mmdMedianDistance([][][]float64{{{0.5}, {0.5}}}, 0); d != 1.0 {
+		t.Log("Zero median distance should fall back to 1, got", d)
+		t.Fail()
+	}
+}
+
+func TestMMDSerialization(t *testing.T) {
+	datasets := createShiftedDatasets([]float64{0, 0.1, 0.3, 0.6}, 200, 2)
+	defer cleanDatasets(datasets)
+	for _, features := range []string{"0", "64"} {
+		est := *new(MMDEstimator)
+		est.datasets = datasets[:3]
+		est.SetPopulationPolicy(DatasetSimilarityPopulationPolicy{PolicyType: PopulationPolicyFull})
+		est.Configure(map[string]string{"features": features, "tuples": "100", "seed": "-5"})
+		if err := est.Compute(); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		newEst := *new(MMDEstimator)
+		newEst.Deserialize(est.Serialize())
+		estimatorsCheck(est.AbstractDatasetSimilarityEstimator, newEst.AbstractDatasetSimilarityEstimator, t)
+		if newEst.features != est.features || newEst.maxTuples != 100 || newEst.seed != -5 || newEst.sigma != est.sigma {
+			t.Log("Wrong deserialized parameters")
+			t.FailNow()
+		}
+		for i := range datasets {
+			for j := range datasets {
+				if est.Similarity(datasets[i], datasets[j]) != newEst.Similarity(datasets[i], datasets[j]) {
+					t.Log("Different similarities after deserialization", features, i, j)
+					t.Fail()
+				}
+			}
+		}
+		if err := AppendDatasets(&newEst, datasets[3:]); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		if v := newEst.SimilarityMatrix().Get(3, 0); v != est.Similarity(datasets[3], datasets[0]) {
+			t.Log("Wrong similarity of the appended dataset", v)
+			t.Fail()
+		}
+	}
+}
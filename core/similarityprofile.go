@@ -0,0 +1,485 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"hash/fnv"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// profileDistinctSketchSize is the number of hash values kept by the
+	// distinct count sketch (KMV) of each column
+	profileDistinctSketchSize = 256
+	// profileMoments is the number of moment features of each column (mean,
+	// standard deviation, skewness and kurtosis)
+	profileMoments = 4
+)
+
+// ProfileEstimator compares the datasets through the statistical profile of
+// their columns. The profile of each column consists of its moments, its
+// quantiles, the portion of its missing values and the portion of its
+// distinct values (estimated through a KMV sketch), and is computed once for
+// each dataset. The location features (mean, standard deviation, quantiles)
+// are scaled by the range of the column among the datasets, and the
+// per-column distances are combined through (configurable) column weights.
+type ProfileEstimator struct {
+	AbstractDatasetSimilarityEstimator
+	quantiles    int                            // the number of quantiles of each column
+	maxTuples    int                            // the size of the sample used for the quantiles
+	distType     ProfileSimilarityEstimatorType // the distance of the profiles
+	weights      []float64                      // the weight of each column
+	columns      int                            // the number of columns
+	inverseIndex map[string]int                 // maps the dataset paths to indices
+	ranges       []float64                      // the range of each column
+	profiles     [][]float64                    // holds the profile of each dataset
+}
+
+// ProfileSimilarityEstimatorType reflects the distance used by the
+// ProfileEstimator to compare the profiles
+type ProfileSimilarityEstimatorType uint8
+
+const (
+	profileSimilarityTypeManhattan ProfileSimilarityEstimatorType = iota
+	profileSimilarityTypeEuclidean ProfileSimilarityEstimatorType = iota + 1
+	profileSimilarityTypeChebyshev ProfileSimilarityEstimatorType = iota + 2
+)
+
+// Compute method constructs the Similarity Matrix
+func (e *ProfileEstimator) Compute() error {
+	schema, err := e.unifySchemas()
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	if len(schema) == 0 {
+		return errors.New("The datasets have no columns")
+	}
+	e.columns = len(schema)
+	log.Println("Profiling datasets")
+	e.profiles, err = e.datasetProfiles(e.datasets)
+	if err != nil {
+		return err
+	}
+	e.inverseIndex = make(map[string]int)
+	for i, d := range e.datasets {
+		e.inverseIndex[d.Path()] = i
+	}
+	features := len(e.profiles[0]) / e.columns
+	e.ranges = make([]float64, e.columns)
+	for c := range e.ranges {
+		min, max := math.Inf(1), math.Inf(-1)
+		for _, p := range e.profiles {
+			// the datasets with no values for the column (e.g., lacking it)
+			// have a zero distinct portion and no bounds
+			if p[c*features+features-1] == 0 {
+				continue
+			}
+			min = math.Min(min, p[c*features+profileMoments])
+			max = math.Max(max, p[c*features+profileMoments+e.quantiles-1])
+		}
+		if max > min {
+			e.ranges[c] = max - min
+		}
+	}
+	return datasetSimilarityEstimatorComputeStreaming(e)
+}
+
+// Similarity returns the similarity between the two datasets
+func (e *ProfileEstimator) Similarity(a, b *Dataset) float64 {
+	profileA, err := e.datasetProfile(a)
+	if err != nil {
+		log.Println(err)
+		return 0.0
+	}
+	profileB, err := e.datasetProfile(b)
+	if err != nil {
+		log.Println(err)
+		return 0.0
+	}
+	features := profileMoments + e.quantiles + 2
+	sum, weights := 0.0, 0.0
+	for c := 0; c < e.columns; c++ {
+		w := 1.0
+		if c < len(e.weights) {
+			w = e.weights[c]
+		}
+		if w == 0.0 {
+			continue
+		}
+		// the feature differences lie in [0,1], hence the column distance too
+		dist := 0.0
+		for f := 0; f < features; f++ {
+			diff := profileFeatureDistance(
+				profileA[c*features+f], profileB[c*features+f], f, e.quantiles, e.ranges[c])
+			if e.distType == profileSimilarityTypeManhattan {
+				dist += diff / float64(features)
+			} else if e.distType == profileSimilarityTypeEuclidean {
+				dist += diff * diff / float64(features)
+			} else {
+				dist = math.Max(dist, diff)
+			}
+		}
+		if e.distType == profileSimilarityTypeEuclidean {
+			dist = math.Sqrt(dist)
+		}
+		sum += w * dist
+		weights += w
+	}
+	if weights == 0.0 {
+		return 1.0
+	}
+	return 1.0 - sum/weights
+}
+
+// Configure sets a number of configuration parameters to the struct. Use this
+// method before the execution of the computation
+func (e *ProfileEstimator) Configure(conf map[string]string) {
+	e.configureColumnsPolicy(conf, DatasetColumnsUnion)
+	e.concurrency = 1
+	if val, ok := conf["concurrency"]; ok {
+		conv, err := strconv.ParseInt(val, 10, 32)
+		if err != nil {
+			log.Println(err)
+		} else {
+			e.concurrency = int(conv)
+		}
+	}
+	e.quantiles = 5
+	if val, ok := conf["quantiles"]; ok {
+		conv, err := strconv.ParseInt(val, 10, 32)
+		if err != nil || conv < 2 {
+			log.Println("Invalid number of quantiles, using default (5)", err)
+		} else {
+			e.quantiles = int(conv)
+		}
+	}
+	e.maxTuples = 10000
+	if val, ok := conf["tuples"]; ok {
+		conv, err := strconv.ParseInt(val, 10, 32)
+		if err != nil || conv < 1 {
+			log.Println("Invalid number of tuples, using default (10000)", err)
+		} else {
+			e.maxTuples = int(conv)
+		}
+	}
+	e.distType = profileSimilarityTypeManhattan
+	if val, ok := conf["type"]; ok {
+		if val == "manhattan" {
+			e.distType = profileSimilarityTypeManhattan
+		} else if val == "euclidean" {
+			e.distType = profileSimilarityTypeEuclidean
+		} else if val == "chebyshev" {
+			e.distType = profileSimilarityTypeChebyshev
+		} else {
+			log.Println("Similarity Type not known, valid values: [manhattan euclidean chebyshev]")
+		}
+	}
+	e.weights = nil
+	if val, ok := conf["weights"]; ok && val != "" {
+		for _, s := range strings.Split(val, ",") {
+			conv, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil || conv < 0 {
+				log.Println("Invalid column weight, using equal weights", err)
+				e.weights = nil
+				break
+			}
+			e.weights = append(e.weights, conv)
+		}
+	}
+}
+
+// Options returns a list of options that the user can set
+func (e *ProfileEstimator) Options() map[string]string {
+	return map[string]string{
+		"concurrency": "max number of threads to run in parallel",
+		"quantiles":   "number of quantiles of each column, including min and max (default is 5)",
+		"tuples":      "max number of values sampled from each column for the quantiles (default is 10000)",
+		"type":        "the distance of the profiles - one of:  [manhattan euclidean chebyshev] (default is manhattan)",
+		"weights":     "comma separated weights of the columns (default is equal weights)",
+		"align":       columnsPolicyOption(DatasetColumnsUnion),
+	}
+}
+
+// Serialize returns a byte array that represents the struct is a serialized version
+func (e *ProfileEstimator) Serialize() []byte {
+	buffer := new(bytes.Buffer)
+	buffer.Write(getBytesInt(int(SimilarityTypeProfile)))
+
+	buffer.Write(
+		datasetSimilarityEstimatorSerialize(
+			e.AbstractDatasetSimilarityEstimator))
+
+	buffer.Write(getBytesInt(int(e.distType)))
+	buffer.Write(getBytesInt(e.quantiles))
+	buffer.Write(getBytesInt(e.maxTuples))
+	buffer.Write(getBytesInt(e.columns))
+	buffer.Write(getBytesInt(len(e.weights)))
+	for _, w := range e.weights {
+		buffer.Write(getBytesFloat(w))
+	}
+
+	for _, v := range e.ranges {
+		buffer.Write(getBytesFloat(v))
+	}
+	// write number of features per dataset
+	buffer.Write(getBytesInt(len(e.profiles[0])))
+	for _, arr := range e.profiles {
+		for _, v := range arr {
+			buffer.Write(getBytesFloat(v))
+		}
+	}
+	return buffer.Bytes()
+}
+
+// Deserialize parses a byte array and forms a ProfileEstimator object
+func (e *ProfileEstimator) Deserialize(b []byte) {
+	buffer := bytes.NewBuffer(b)
+	tempInt := make([]byte, 4)
+	tempFloat := make([]byte, 8)
+	buffer.Read(tempInt) // consume estimator type
+	buffer.Read(tempInt)
+	absEstBytes := make([]byte, getIntBytes(tempInt))
+	buffer.Read(absEstBytes)
+	e.AbstractDatasetSimilarityEstimator =
+		*datasetSimilarityEstimatorDeserialize(absEstBytes)
+
+	buffer.Read(tempInt)
+	e.distType = ProfileSimilarityEstimatorType(getIntBytes(tempInt))
+	buffer.Read(tempInt)
+	e.quantiles = getIntBytes(tempInt)
+	buffer.Read(tempInt)
+	e.maxTuples = getIntBytes(tempInt)
+	buffer.Read(tempInt)
+	e.columns = getIntBytes(tempInt)
+	buffer.Read(tempInt)
+	e.weights = make([]float64, getIntBytes(tempInt))
+	for i := range e.weights {
+		buffer.Read(tempFloat)
+		e.weights[i] = getFloatBytes(tempFloat)
+	}
+
+	e.inverseIndex = make(map[string]int)
+	for i, d := range e.datasets {
+		e.inverseIndex[d.Path()] = i
+	}
+
+	e.ranges = make([]float64, e.columns)
+	for i := range e.ranges {
+		buffer.Read(tempFloat)
+		e.ranges[i] = getFloatBytes(tempFloat)
+	}
+	buffer.Read(tempInt)
+	count := getIntBytes(tempInt)
+	e.profiles = make([][]float64, len(e.datasets))
+	for i := range e.datasets {
+		e.profiles[i] = make([]float64, count)
+		for j := range e.profiles[i] {
+			buffer.Read(tempFloat)
+			e.profiles[i][j] = getFloatBytes(tempFloat)
+		}
+	}
+}
+
+// datasetsUpdated reindexes the profiles of the datasets; the appended
+// datasets are profiled and compared according to the existing column ranges
+func (e *ProfileEstimator) datasetsUpdated(appended []*Dataset, removed []string) error {
+	computed, err := e.datasetProfiles(appended)
+	if err != nil {
+		return err
+	}
+	profiles := make([][]float64, len(e.datasets))
+	e.inverseIndex = reindexDatasets(e.datasets, e.inverseIndex, appended,
+		func(i, j int) { profiles[i] = e.profiles[j] },
+		func(i, k int) { profiles[i] = computed[k] })
+	e.profiles = profiles
+	return nil
+}
+
+// datasetProfile returns the profile of a dataset, which is computed if the
+// dataset is not indexed by the estimator
+func (e *ProfileEstimator) datasetProfile(d *Dataset) ([]float64, error) {
+	if idx, ok := e.inverseIndex[d.Path()]; ok {
+		return e.profiles[idx], nil
+	}
+	d, err := e.alignedDataset(d)
+	if err != nil {
+		return nil, err
+	}
+	return e.cachedProfile(d)
+}
+
+// datasetProfiles computes the profiles of the datasets in parallel
+func (e *ProfileEstimator) datasetProfiles(datasets []*Dataset) ([][]float64, error) {
+	profiles := make([][]float64, len(datasets))
+	err := parallelDatasets(datasets, e.concurrency, func(i int, d *Dataset) error {
+		var err error
+		profiles[i], err = e.cachedProfile(d)
+		return err
+	})
+	return profiles, err
+}
+
+// cachedProfile returns the profile of a dataset through the summary cache
+func (e *ProfileEstimator) cachedProfile(d *Dataset) ([]float64, error) {
+	fingerprint := "profile/" + strconv.Itoa(e.quantiles) + "/" +
+		strconv.Itoa(e.maxTuples) + "/" + strconv.Itoa(e.columns)
+	return e.datasetFeatures(d, fingerprint, e.profile)
+}
+
+// profile traverses a dataset and returns the concatenated profiles of its
+// columns
+func (e *ProfileEstimator) profile(d *Dataset) ([]float64, error) {
+	it, err := d.Iterator()
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+	r := rand.New(rand.NewSource(0))
+	columns := make([]*columnProfile, e.columns)
+	for i := range columns {
+		columns[i] = newColumnProfile(e.maxTuples)
+	}
+	for it.Next() {
+		t := it.Tuple()
+		for i, c := range columns {
+			v := math.NaN()
+			if i < len(t.Data) {
+				v = t.Data[i]
+			}
+			c.add(v, r)
+		}
+	}
+	if it.Err() != nil {
+		return nil, it.Err()
+	}
+	var result []float64
+	for _, c := range columns {
+		result = append(result, c.features(e.quantiles)...)
+	}
+	return result, nil
+}
+
+// profileFeatureDistance returns the distance of the f-th feature of two
+// column profiles in [0,1]. The location features are scaled by the range of
+// the column, the skewness and kurtosis are compared relatively to their
+// magnitude and the portions are compared as they are.
+func profileFeatureDistance(a, b float64, f, quantiles int, columnRange float64) float64 {
+	diff := math.Abs(a - b)
+	if f == 2 || f == 3 {
+		return diff / (1.0 + math.Abs(a) + math.Abs(b))
+	}
+	if f < profileMoments+quantiles {
+		if columnRange <= 0 {
+			return 0.0
+		}
+		return math.Min(diff/columnRange, 1.0)
+	}
+	return diff
+}
+
+// columnProfile accumulates the statistics of a column in a single pass
+type columnProfile struct {
+	count, nulls       int       // the number of values and missing values
+	mean, m2, m3, m4   float64   // the mean and the central moment sums
+	min, max           float64   // the exact bounds of the values
+	sample             []float64 // reservoir sample of the values
+	maxSample          int       // the size of the reservoir
+	distinct           []uint64  // the smallest hash values (KMV sketch)
+	distinctSketchSize int       // the size of the KMV sketch
+}
+
+func newColumnProfile(maxSample int) *columnProfile {
+	return &columnProfile{maxSample: maxSample, distinctSketchSize: profileDistinctSketchSize}
+}
+
+// add updates the statistics with a new value; the moments are updated
+// through the one-pass formulas of Welford and Terriberry
+func (c *columnProfile) add(v float64, r *rand.Rand) {
+	if math.IsNaN(v) {
+		c.nulls++
+		return
+	}
+	if c.count == 0 {
+		c.min, c.max = v, v
+	}
+	c.min, c.max = math.Min(c.min, v), math.Max(c.max, v)
+	n1 := float64(c.count)
+	c.count++
+	n := float64(c.count)
+	delta := v - c.mean
+	deltaN := delta / n
+	deltaN2 := deltaN * deltaN
+	term := delta * deltaN * n1
+	c.mean += deltaN
+	c.m4 += term*deltaN2*(n*n-3*n+3) + 6*deltaN2*c.m2 - 4*deltaN*c.m3
+	c.m3 += term*deltaN*(n-2) - 3*deltaN*c.m2
+	c.m2 += term
+
+	if len(c.sample) < c.maxSample {
+		c.sample = append(c.sample, v)
+	} else if idx := r.Intn(c.count); idx < c.maxSample {
+		c.sample[idx] = v
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(strconv.FormatFloat(v, 'g', -1, 64)))
+	hash := minHashMix(h.Sum64())
+	idx := sort.Search(len(c.distinct), func(i int) bool { return c.distinct[i] >= hash })
+	if idx < len(c.distinct) && c.distinct[idx] == hash {
+		return
+	}
+	if len(c.distinct) < c.distinctSketchSize {
+		c.distinct = append(c.distinct, 0)
+	} else if idx == len(c.distinct) {
+		return
+	}
+	copy(c.distinct[idx+1:], c.distinct[idx:])
+	c.distinct[idx] = hash
+}
+
+// features returns the profile of the column: mean, standard deviation,
+// skewness, excess kurtosis, the quantiles (from min to max), the portion of
+// missing values and the portion of distinct values
+func (c *columnProfile) features(quantiles int) []float64 {
+	result := make([]float64, profileMoments+quantiles+2)
+	total := c.count + c.nulls
+	if total > 0 {
+		result[len(result)-2] = float64(c.nulls) / float64(total)
+	}
+	if c.count == 0 {
+		return result
+	}
+	result[0] = c.mean
+	result[1] = math.Sqrt(c.m2 / float64(c.count))
+	if c.m2 > 0 {
+		result[2] = math.Sqrt(float64(c.count)) * c.m3 / math.Pow(c.m2, 1.5)
+		result[3] = float64(c.count)*c.m4/(c.m2*c.m2) - 3.0
+	}
+	sorted := make([]float64, len(c.sample))
+	copy(sorted, c.sample)
+	sort.Float64s(sorted)
+	for q := 0; q < quantiles; q++ {
+		idx := int(math.Floor(float64(q)/float64(quantiles-1)*float64(len(sorted)-1) + 0.5))
+		result[profileMoments+q] = sorted[idx]
+	}
+	// the bounds are known exactly, regardless of the sample
+	result[profileMoments], result[profileMoments+quantiles-1] = c.min, c.max
+	result[len(result)-1] = math.Min(c.distinctCount()/float64(c.count), 1.0)
+	return result
+}
+
+// distinctCount estimates the number of distinct values of the column through
+// the KMV sketch
+func (c *columnProfile) distinctCount() float64 {
+	if len(c.distinct) < c.distinctSketchSize {
+		return float64(len(c.distinct))
+	}
+	kth := float64(c.distinct[len(c.distinct)-1]) / math.Pow(2, 64)
+	return float64(len(c.distinct)-1) / kth
+}
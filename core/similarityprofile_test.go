@@ -0,0 +1,144 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestColumnProfile(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+	c := newColumnProfile(1000)
+	for i := 0; i < 100; i++ {
+		c.add(float64(i%10), r)
+	}
+	c.add(math.NaN(), r)
+	f := c.features(5)
+	if len(f) != profileMoments+5+2 {
+		t.Log("Wrong number of features", len(f))
+		t.FailNow()
+	}
+	if math.Abs(f[0]-4.5) > 1e-9 || math.Abs(f[1]-math.Sqrt(8.25)) > 1e-9 {
+		t.Log("Wrong mean or standard deviation", f[0], f[1])
+		t.Fail()
+	}
+	if math.Abs(f[2]) > 1e-9 || math.Abs(f[3]-(-1.2242424242)) > 1e-6 {
+		t.Log("Wrong skewness or kurtosis", f[2], f[3])
+		t.Fail()
+	}
+	if f[profileMoments] != 0 || f[profileMoments+4] != 9 {
+		t.Log("Wrong min or max quantile", f[profileMoments], f[profileMoments+4])
+		t.Fail()
+	}
+	if math.Abs(f[len(f)-2]-1.0/101.0) > 1e-9 || f[len(f)-1] != 0.1 {
+		t.Log("Wrong null or distinct portion", f[len(f)-2], f[len(f)-1])
+		t.Fail()
+	}
+
+	// the KMV sketch estimates large distinct counts
+	c = newColumnProfile(10)
+	for i := 0; i < 20000; i++ {
+		c.add(float64(i), r)
+	}
+	if est := c.distinctCount(); math.Abs(est-20000)/20000 > 0.2 {
+		t.Log("Distinct count estimation too far from 20000:", est)
+		t.Fail()
+	}
+}
+
+func TestProfileCompute(t *testing.T) {
+	datasets := createShiftedDatasets([]float64{0, 0, 0.2, 0.5, 1.0}, 300, 3)
+	defer cleanDatasets(datasets)
+	for _, distance := range []string{"manhattan", "euclidean", "chebyshev"} {
+		est := NewDatasetSimilarityEstimator(SimilarityTypeProfile, datasets)
+		est.Configure(map[string]string{"concurrency": "4", "type": distance})
+		if err := est.Compute(); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		sm := est.SimilarityMatrix()
+		smSanityCheck(sm, t)
+		if !(sm.Get(0, 1) > sm.Get(0, 2) && sm.Get(0, 2) > sm.Get(0, 3) && sm.Get(0, 3) > sm.Get(0, 4)) {
+			t.Log(distance, "similarities do not decrease with the shift",
+				sm.Get(0, 1), sm.Get(0, 2), sm.Get(0, 3), sm.Get(0, 4))
+			t.Fail()
+		}
+	}
+
+	// a zero weight ignores the shifted column
+	est := NewDatasetSimilarityEstimator(SimilarityTypeProfile, datasets)
+	est.Configure(map[string]string{"weights": "0,0,0"})
+	if err := est.Compute(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if s := est.SimilarityMatrix().Get(0, 4); s != 1.0 {
+		t.Log("Expected similarity 1 with zero weights, got", s)
+		t.Fail()
+	}
+}
+
+func TestProfileSerialization(t *testing.T) {
+	datasets := createShiftedDatasets([]float64{0, 0.1, 0.3, 0.6}, 200, 2)
+	defer cleanDatasets(datasets)
+	est := *new(ProfileEstimator)
+	est.datasets = datasets[:3]
+	est.SetPopulationPolicy(DatasetSimilarityPopulationPolicy{PolicyType: PopulationPolicyFull})
+	est.Configure(map[string]string{"quantiles": "7", "type": "euclidean", "weights": "1,2"})
+	if err := est.Compute(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	newEst := *new(ProfileEstimator)
+	newEst.Deserialize(est.Serialize())
+	estimatorsCheck(est.AbstractDatasetSimilarityEstimator, newEst.AbstractDatasetSimilarityEstimator, t)
+	if newEst.quantiles != 7 || newEst.distType != profileSimilarityTypeEuclidean ||
+		len(newEst.weights) != 2 || newEst.weights[1] != 2 || newEst.columns != est.columns {
+		t.Log("Wrong deserialized parameters")
+		t.FailNow()
+	}
+	for i := range datasets {
+		for j := range datasets {
+			if est.Similarity(datasets[i], datasets[j]) != newEst.Similarity(datasets[i], datasets[j]) {
+				t.Log("Different similarities after deserialization", i, j)
+				t.Fail()
+			}
+		}
+	}
+}
+
+func TestProfileRangesMissingColumn(t *testing.T) {
+	// the third dataset lacks the x column, which must not widen its range
+	var datasets []*Dataset
+	for i, header := range []string{"x,y", "x,y", "y"} {
+		r := rand.New(rand.NewSource(int64(i)))
+		builder := new(bytes.Buffer)
+		builder.WriteString(header + "\n")
+		for j := 0; j < 100; j++ {
+			x, y := 10+float64(i)*0.5+r.Float64(), r.Float64()
+			if i == 2 {
+				builder.WriteString(fmt.Sprintf("%.5f\n", y))
+			} else {
+				builder.WriteString(fmt.Sprintf("%.5f,%.5f\n", x, y))
+			}
+		}
+		f, _ := ioutil.TempFile("/tmp", "profiledataset")
+		f.Write(builder.Bytes())
+		f.Close()
+		datasets = append(datasets, NewDataset(f.Name()))
+	}
+	defer cleanDatasets(datasets)
+	est := NewDatasetSimilarityEstimator(SimilarityTypeProfile, datasets).(*ProfileEstimator)
+	est.Configure(map[string]string{"align": "union"})
+	if err := est.Compute(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if est.ranges[0] < 1 || est.ranges[0] > 2 {
+		t.Log("Wrong range for the x column", est.ranges[0])
+		t.Fail()
+	}
+}
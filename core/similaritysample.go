@@ -0,0 +1,104 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+)
+
+// sampleColumns returns the columns examined by the estimators that compare
+// the tuples of the datasets, i.e., the numeric columns of the unified schema
+// of the datasets, or all of its columns if none is numeric
+func sampleColumns(schema DatasetSchema) ([]int, error) {
+	columns := schema.Columns(func(t DatasetColumnType) bool { return !t.Discrete() })
+	if len(columns) == 0 {
+		columns = schema.Columns(func(t DatasetColumnType) bool { return true })
+	}
+	if len(columns) == 0 {
+		return nil, errors.New("The datasets have no columns")
+	}
+	return columns, nil
+}
+
+// sampleDataset traverses a dataset and returns a uniform sample (reservoir
+// sampling) of up to maxTuples of its tuples (all of them if maxTuples<1),
+// projected to the specified columns. The tuples with missing values are
+// ignored.
+func sampleDataset(d *Dataset, columns []int, maxTuples int, seed int64) ([][]float64, error) {
+	it, err := d.Iterator()
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+	r := rand.New(rand.NewSource(seed))
+	var result [][]float64
+	seen := 0
+	for it.Next() {
+		t := it.Tuple()
+		values := make([]float64, len(columns))
+		valid := true
+		for i, c := range columns {
+			if c >= len(t.Data) || math.IsNaN(t.Data[c]) {
+				valid = false
+				break
+			}
+			values[i] = t.Data[c]
+		}
+		if !valid {
+			continue
+		}
+		seen++
+		if maxTuples < 1 || len(result) < maxTuples {
+			result = append(result, values)
+		} else if idx := r.Intn(seen); idx < maxTuples {
+			result[idx] = values
+		}
+	}
+	if it.Err() != nil {
+		return nil, it.Err()
+	}
+	if len(result) == 0 {
+		return nil, errors.New("No tuples to sample in " + d.Path())
+	}
+	return result, nil
+}
+
+// sampleDatasets samples the datasets in parallel
+func sampleDatasets(datasets []*Dataset, columns []int, maxTuples int, seed int64, concurrency int) ([][][]float64, error) {
+	samples := make([][][]float64, len(datasets))
+	err := parallelDatasets(datasets, concurrency, func(i int, d *Dataset) error {
+		var err error
+		samples[i], err = sampleDataset(d, columns, maxTuples, seed)
+		return err
+	})
+	return samples, err
+}
+
+// sampleBounds returns the min and max value of each column of the samples
+func sampleBounds(samples [][][]float64, columns int) ([]float64, []float64) {
+	min, max := make([]float64, columns), make([]float64, columns)
+	for i := range min {
+		min[i], max[i] = math.Inf(1), math.Inf(-1)
+		for _, s := range samples {
+			for _, t := range s {
+				min[i], max[i] = math.Min(min[i], t[i]), math.Max(max[i], t[i])
+			}
+		}
+	}
+	return min, max
+}
+
+// normalizeSample returns a copy of the sample, the columns of which are
+// scaled according to the provided bounds. Constant columns are set to zero.
+func normalizeSample(sample [][]float64, min, max []float64) [][]float64 {
+	result := make([][]float64, len(sample))
+	for j, t := range sample {
+		result[j] = make([]float64, len(t))
+		for i, v := range t {
+			if max[i] > min[i] {
+				result[j][i] = (v - min[i]) / (max[i] - min[i])
+			}
+		}
+	}
+	return result
+}
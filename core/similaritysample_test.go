@@ -0,0 +1,49 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestSampleDataset(t *testing.T) {
+	datasets := createShiftedDatasets([]float64{0, 2}, 200, 3)
+	defer cleanDatasets(datasets)
+	schema, err := unifyDatasetSchemas(datasets, DatasetColumnsIntersection)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	columns, err := sampleColumns(schema)
+	if err != nil || len(columns) != 3 {
+		t.Log("Wrong columns", columns, err)
+		t.FailNow()
+	}
+	sample, err := sampleDataset(datasets[0], columns[:2], 50, 1)
+	if err != nil || len(sample) != 50 || len(sample[0]) != 2 {
+		t.Log("Wrong sample size", len(sample), err)
+		t.FailNow()
+	}
+	if all, _ := sampleDataset(datasets[0], columns, 0, 1); len(all) != 200 {
+		t.Log("Expected all the tuples, got", len(all))
+		t.Fail()
+	}
+	samples, err := sampleDatasets(datasets, columns, 20, 1, 2)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	min, max := sampleBounds(samples, len(columns))
+	for _, s := range samples {
+		for _, tuple := range normalizeSample(s, min, max) {
+			for _, v := range tuple {
+				if v < 0 || v > 1 {
+					t.Log("Normalized value out of [0,1]", v)
+					t.FailNow()
+				}
+			}
+		}
+	}
+	if min[0] >= 1 || max[0] <= 2 {
+		t.Log("Wrong bounds", min, max)
+		t.Fail()
+	}
+}
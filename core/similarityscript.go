@@ -108,6 +108,23 @@ func (e *ScriptSimilarityEstimator) Configure(conf map[string]string) {
 
 }
 
+// datasetsUpdated reindexes the coordinates of the datasets; the appended
+// datasets are analyzed by the analysis script
+func (e *ScriptSimilarityEstimator) datasetsUpdated(appended []*Dataset, removed []string) error {
+	coordinates := make([][]float64, len(e.datasets))
+	inverseIndex := make(map[string]int)
+	for i, d := range e.datasets {
+		if idx, ok := e.inverseIndex[d.Path()]; ok {
+			coordinates[i] = e.datasetCoordinates[idx]
+		} else {
+			coordinates[i] = e.analyzeDataset(d.Path())
+		}
+		inverseIndex[d.Path()] = i
+	}
+	e.datasetCoordinates, e.inverseIndex = coordinates, inverseIndex
+	return nil
+}
+
 // Options returns a list of options that the user can set
 func (e *ScriptSimilarityEstimator) Options() map[string]string {
 	return map[string]string{
@@ -12,16 +12,40 @@ import (
 // union of the two datasets.
 type SizeEstimator struct {
 	AbstractDatasetSimilarityEstimator
+	// holds the number of tuples of each dataset, indexed by its path
+	sizes map[string]int
 }
 
-// Compute method constructs the Similarity Matrix
+// Compute method constructs the Similarity Matrix. The datasets are not
+// loaded in memory, since only their number of tuples is needed.
 func (e *SizeEstimator) Compute() error {
-	return datasetSimilarityEstimatorCompute(e)
+	e.sizes = make(map[string]int)
+	for _, d := range e.datasets {
+		count, err := e.datasetCount(d)
+		if err != nil {
+			log.Println(err)
+			return err
+		}
+		e.sizes[d.Path()] = count
+	}
+	return datasetSimilarityEstimatorComputeStreaming(e)
+}
+
+// size returns the number of tuples of a dataset
+func (e *SizeEstimator) size(d *Dataset) int {
+	if count, ok := e.sizes[d.Path()]; ok {
+		return count
+	}
+	count, err := e.datasetCount(d)
+	if err != nil {
+		log.Println(err)
+	}
+	return count
 }
 
 // Similarity returns the similarity between two datasets
 func (e *SizeEstimator) Similarity(a, b *Dataset) float64 {
-	sizeA, sizeB := float64(len(a.Data())), float64(len(b.Data()))
+	sizeA, sizeB := float64(e.size(a)), float64(e.size(b))
 	if sizeA >= sizeB {
 		return sizeB / sizeA
 	}
@@ -52,7 +76,7 @@ func (e *SizeEstimator) Options() map[string]string {
 // Serialize returns a byte array containing the estimator.
 func (e *SizeEstimator) Serialize() []byte {
 	buffer := new(bytes.Buffer)
-	buffer.Write(getBytesInt(int(SimilarityTypeJaccard)))
+	buffer.Write(getBytesInt(int(SimilarityTypeSize)))
 	buffer.Write(
 		datasetSimilarityEstimatorSerialize(e.AbstractDatasetSimilarityEstimator))
 	return buffer.Bytes()
@@ -0,0 +1,357 @@
+package core
+
+import (
+	"bytes"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WassersteinEstimator estimates the similarity of the distribution of the
+// datasets through the sliced Wasserstein (or energy) distance of their
+// tuples. The numeric columns are normalized to [0,1] and the tuples are
+// projected to random directions; each dataset is summarized by the
+// quantiles of a sample of its projected tuples, and the distance of two
+// datasets is averaged over the one-dimensional distances of the projections.
+type WassersteinEstimator struct {
+	AbstractDatasetSimilarityEstimator
+	energy       bool           // if true, the energy distance is estimated
+	projections  int            // the number of random projections
+	quantiles    int            // the number of quantiles kept per projection
+	maxTuples    int            // the max number of tuples sampled per dataset
+	seed         int64          // the seed of the projections and the samples
+	inverseIndex map[string]int // maps the dataset paths to indices
+	columns      []int          // the columns of the tuples that are examined
+	min, max     []float64      // the bounds used to normalize the columns
+	directions   [][]float64    // the directions of the projections
+	sketches     [][][]float64  // the quantiles of each projection, per dataset
+}
+
+// Compute method constructs the Similarity Matrix
+func (e *WassersteinEstimator) Compute() error {
+	schema, err := e.unifySchemas()
+	if err == nil {
+		e.columns, err = sampleColumns(schema)
+	}
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	log.Println("Sampling the datasets")
+	samples, err := sampleDatasets(e.datasets, e.columns, e.maxTuples, e.seed, e.concurrency)
+	if err != nil {
+		return err
+	}
+	e.min, e.max = sampleBounds(samples, len(e.columns))
+	e.initDirections()
+	e.sketches = make([][][]float64, len(e.datasets))
+	e.inverseIndex = make(map[string]int)
+	for i, d := range e.datasets {
+		e.sketches[i] = e.sketch(samples[i])
+		e.inverseIndex[d.Path()] = i
+	}
+	return datasetSimilarityEstimatorComputeStreaming(e)
+}
+
+// Similarity returns the similarity between two datasets
+func (e *WassersteinEstimator) Similarity(a, b *Dataset) float64 {
+	sketchA, err := e.datasetSketch(a)
+	if err != nil {
+		log.Println(err)
+		return 0.0
+	}
+	sketchB, err := e.datasetSketch(b)
+	if err != nil {
+		log.Println(err)
+		return 0.0
+	}
+	// the projections of the normalized tuples lie in an interval of length
+	// up to sqrt(dimensions), which bounds both distances
+	bound := math.Sqrt(float64(len(e.columns)))
+	sum := 0.0
+	for p := range sketchA {
+		sum += slicedDistance(sketchA[p], sketchB[p], e.energy)
+	}
+	distance := sum / float64(len(sketchA))
+	if e.energy {
+		return 1.0 - math.Sqrt(math.Min(distance/(2.0*bound), 1.0))
+	}
+	return 1.0 - math.Min(distance/bound, 1.0)
+}
+
+// Configure sets the necessary parameters before the similarity execution
+func (e *WassersteinEstimator) Configure(conf map[string]string) {
+	e.configureColumnsPolicy(conf, DatasetColumnsIntersection)
+	e.concurrency = 1
+	if val, ok := conf["concurrency"]; ok {
+		conv, err := strconv.ParseInt(val, 10, 32)
+		if err != nil {
+			log.Println(err)
+		} else {
+			e.concurrency = int(conv)
+		}
+	}
+	e.energy = false
+	if val, ok := conf["distance"]; ok {
+		if "energy" == strings.ToLower(val) {
+			e.energy = true
+		} else if "wasserstein" != strings.ToLower(val) {
+			log.Println("Unknown distance, using default (wasserstein)")
+		}
+	}
+	e.projections = 50
+	if val, ok := conf["projections"]; ok {
+		conv, err := strconv.ParseInt(val, 10, 32)
+		if err != nil || conv < 1 {
+			log.Println("Invalid number of projections, using default (50)", err)
+		} else {
+			e.projections = int(conv)
+		}
+	}
+	e.quantiles = 100
+	if val, ok := conf["quantiles"]; ok {
+		conv, err := strconv.ParseInt(val, 10, 32)
+		if err != nil || conv < 1 {
+			log.Println("Invalid number of quantiles, using default (100)", err)
+		} else {
+			e.quantiles = int(conv)
+		}
+	}
+	e.maxTuples = 1000
+	if val, ok := conf["tuples"]; ok {
+		conv, err := strconv.ParseInt(val, 10, 32)
+		if err != nil || conv < 1 {
+			log.Println("Invalid number of tuples, using default (1000)", err)
+		} else {
+			e.maxTuples = int(conv)
+		}
+	}
+	e.seed = 0
+	if val, ok := conf["seed"]; ok {
+		conv, err := strconv.ParseInt(val, 10, 32)
+		if err != nil {
+			log.Println(err)
+		} else {
+			e.seed = conv
+		}
+	}
+}
+
+// Options returns a list of applicable parameters
+func (e *WassersteinEstimator) Options() map[string]string {
+	return map[string]string{
+		"concurrency": "max num of threads used (int)",
+		"distance":    "the distance to estimate (one of wasserstein, energy - default is wasserstein)",
+		"projections": "number of random projections (default is 50)",
+		"quantiles":   "number of quantiles kept for each projection (default is 100)",
+		"tuples":      "max number of tuples sampled from each dataset (default is 1000)",
+		"seed":        "seed used to generate the projections and the samples (default is 0)",
+		"align":       columnsPolicyOption(DatasetColumnsIntersection),
+	}
+}
+
+// Serialize returns a byte array containing the estimator.
+func (e *WassersteinEstimator) Serialize() []byte {
+	buffer := new(bytes.Buffer)
+	buffer.Write(getBytesInt(int(SimilarityTypeWasserstein)))
+	buffer.Write(
+		datasetSimilarityEstimatorSerialize(e.AbstractDatasetSimilarityEstimator))
+	if e.energy {
+		buffer.Write(getBytesInt(1))
+	} else {
+		buffer.Write(getBytesInt(0))
+	}
+	buffer.Write(getBytesInt(e.projections))
+	buffer.Write(getBytesInt(e.quantiles))
+	buffer.Write(getBytesInt(e.maxTuples))
+	buffer.Write(getBytesInt(int(e.seed)))
+
+	// write the columns and their bounds
+	buffer.Write(getBytesInt(len(e.columns)))
+	for i, c := range e.columns {
+		buffer.Write(getBytesInt(c))
+		buffer.Write(getBytesFloat(e.min[i]))
+		buffer.Write(getBytesFloat(e.max[i]))
+	}
+
+	// write the sketches
+	for _, sketch := range e.sketches {
+		for _, q := range sketch {
+			for _, v := range q {
+				buffer.Write(getBytesFloat(v))
+			}
+		}
+	}
+	return buffer.Bytes()
+}
+
+// Deserialize instantiates the estimator based on a byte array
+func (e *WassersteinEstimator) Deserialize(b []byte) {
+	buffer := bytes.NewBuffer(b)
+	tempInt := make([]byte, 4)
+	tempFloat := make([]byte, 8)
+	buffer.Read(tempInt) // consume estimator type
+
+	buffer.Read(tempInt)
+	absEstBytes := make([]byte, getIntBytes(tempInt))
+	buffer.Read(absEstBytes)
+	e.AbstractDatasetSimilarityEstimator =
+		*datasetSimilarityEstimatorDeserialize(absEstBytes)
+
+	buffer.Read(tempInt)
+	e.energy = getIntBytes(tempInt) == 1
+	buffer.Read(tempInt)
+	e.projections = getIntBytes(tempInt)
+	buffer.Read(tempInt)
+	e.quantiles = getIntBytes(tempInt)
+	buffer.Read(tempInt)
+	e.maxTuples = getIntBytes(tempInt)
+	buffer.Read(tempInt)
+	e.seed = int64(int32(getIntBytes(tempInt)))
+
+	buffer.Read(tempInt)
+	count := getIntBytes(tempInt)
+	e.columns = make([]int, count)
+	e.min, e.max = make([]float64, count), make([]float64, count)
+	for i := range e.columns {
+		buffer.Read(tempInt)
+		e.columns[i] = getIntBytes(tempInt)
+		buffer.Read(tempFloat)
+		e.min[i] = getFloatBytes(tempFloat)
+		buffer.Read(tempFloat)
+		e.max[i] = getFloatBytes(tempFloat)
+	}
+	e.initDirections()
+
+	e.inverseIndex = make(map[string]int)
+	e.sketches = make([][][]float64, len(e.datasets))
+	for i, d := range e.datasets {
+		e.inverseIndex[d.Path()] = i
+		e.sketches[i] = make([][]float64, e.projections)
+		for p := range e.sketches[i] {
+			e.sketches[i][p] = make([]float64, e.quantiles)
+			for j := range e.sketches[i][p] {
+				buffer.Read(tempFloat)
+				e.sketches[i][p][j] = getFloatBytes(tempFloat)
+			}
+		}
+	}
+}
+
+// datasetsUpdated reindexes the sketches of the datasets; the sketches of the
+// appended datasets are computed according to the existing normalization
+func (e *WassersteinEstimator) datasetsUpdated(appended []*Dataset, removed []string) error {
+	samples, err := sampleDatasets(appended, e.columns, e.maxTuples, e.seed, e.concurrency)
+	if err != nil {
+		return err
+	}
+	sketches := make([][][]float64, len(e.datasets))
+	e.inverseIndex = reindexDatasets(e.datasets, e.inverseIndex, appended,
+		func(i, j int) { sketches[i] = e.sketches[j] },
+		func(i, k int) { sketches[i] = e.sketch(samples[k]) })
+	e.sketches = sketches
+	return nil
+}
+
+// initDirections generates the unit vectors of the random projections
+func (e *WassersteinEstimator) initDirections() {
+	r := rand.New(rand.NewSource(e.seed))
+	e.directions = make([][]float64, e.projections)
+	for p := range e.directions {
+		e.directions[p] = make([]float64, len(e.columns))
+		norm := 0.0
+		for norm == 0.0 {
+			for i := range e.directions[p] {
+				e.directions[p][i] = r.NormFloat64()
+				norm += e.directions[p][i] * e.directions[p][i]
+			}
+		}
+		for i := range e.directions[p] {
+			e.directions[p][i] /= math.Sqrt(norm)
+		}
+	}
+}
+
+// datasetSketch returns the sketch of a dataset, which is computed if the
+// dataset is not indexed by the estimator
+func (e *WassersteinEstimator) datasetSketch(d *Dataset) ([][]float64, error) {
+	if idx, ok := e.inverseIndex[d.Path()]; ok {
+		return e.sketches[idx], nil
+	}
+	d, err := e.alignedDataset(d)
+	if err != nil {
+		return nil, err
+	}
+	sample, err := sampleDataset(d, e.columns, e.maxTuples, e.seed)
+	if err != nil {
+		return nil, err
+	}
+	return e.sketch(sample), nil
+}
+
+// sketch normalizes and projects the sampled tuples and returns the quantiles
+// of each projection
+func (e *WassersteinEstimator) sketch(sample [][]float64) [][]float64 {
+	normalized := normalizeSample(sample, e.min, e.max)
+	result := make([][]float64, len(e.directions))
+	projected := make([]float64, len(normalized))
+	for p, direction := range e.directions {
+		for j, t := range normalized {
+			projected[j] = 0.0
+			for i, v := range t {
+				projected[j] += v * direction[i]
+			}
+		}
+		sort.Float64s(projected)
+		result[p] = make([]float64, e.quantiles)
+		for q := range result[p] {
+			idx := int((float64(q) + 0.5) * float64(len(projected)) / float64(e.quantiles))
+			if idx >= len(projected) {
+				idx = len(projected) - 1
+			}
+			result[p][q] = projected[idx]
+		}
+	}
+	return result
+}
+
+// slicedDistance returns the distance of two one-dimensional distributions,
+// given their quantiles (sorted values of equal weight). The distance is
+// computed through the cumulative distribution functions F and G, as the
+// integral of |F-G| (Wasserstein) or twice the integral of (F-G)^2 (energy).
+func slicedDistance(a, b []float64, energy bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0.0
+	}
+	sum := 0.0
+	i, j := 0, 0
+	prev := math.Min(a[0], b[0])
+	for i < len(a) || j < len(b) {
+		var x float64
+		fromA := j == len(b) || (i < len(a) && a[i] <= b[j])
+		if fromA {
+			x = a[i]
+		} else {
+			x = b[j]
+		}
+		diff := float64(i)/float64(len(a)) - float64(j)/float64(len(b))
+		if energy {
+			sum += diff * diff * (x - prev)
+		} else {
+			sum += math.Abs(diff) * (x - prev)
+		}
+		prev = x
+		if fromA {
+			i++
+		} else {
+			j++
+		}
+	}
+	if energy {
+		return 2.0 * sum
+	}
+	return sum
+}
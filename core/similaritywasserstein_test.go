@@ -0,0 +1,126 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// createShiftedDatasets creates datasets of uniformly distributed tuples, the
+// i-th of which is shifted by shifts[i] in all dimensions; the i-th dataset
+// is generated from the seed i, so that the fixture is deterministic
+func createShiftedDatasets(shifts []float64, size, attributes int) []*Dataset {
+	var result []*Dataset
+	for k, shift := range shifts {
+		r := rand.New(rand.NewSource(int64(k)))
+		builder := new(bytes.Buffer)
+		for i := 0; i < attributes-1; i++ {
+			builder.WriteString(fmt.Sprintf("x%d,", i))
+		}
+		builder.WriteString("class\n")
+		for j := 0; j < size; j++ {
+			for i := 0; i < attributes; i++ {
+				builder.WriteString(fmt.Sprintf("%.5f", r.Float64()+shift))
+				if i < attributes-1 {
+					builder.WriteString(",")
+				}
+			}
+			builder.WriteString("\n")
+		}
+		f, _ := ioutil.TempFile("/tmp", "shifteddataset")
+		f.Write(builder.Bytes())
+		f.Close()
+		result = append(result, NewDataset(f.Name()))
+	}
+	return result
+}
+
+func TestSlicedDistance(t *testing.T) {
+	a, b := []float64{0, 0, 0, 0}, []float64{1, 1, 1, 1}
+	if d := slicedDistance(a, b, false); math.Abs(d-1.0) > 1e-9 {
+		t.Log("Expected Wasserstein distance 1, got", d)
+		t.Fail()
+	}
+	if d := slicedDistance(a, b, true); math.Abs(d-2.0) > 1e-9 {
+		t.Log("Expected energy distance 2, got", d)
+		t.Fail()
+	}
+	if d := slicedDistance(b, b, false); d != 0.0 {
+		t.Log("Expected zero distance, got", d)
+		t.Fail()
+	}
+	// the Wasserstein distance of a shift equals the shift
+	c := []float64{0.1, 0.2, 0.3, 0.4}
+	e := []float64{0.35, 0.45, 0.55, 0.65}
+	if d := slicedDistance(c, e, false); math.Abs(d-0.25) > 1e-9 {
+		t.Log("Expected Wasserstein distance 0.25, got", d)
+		t.Fail()
+	}
+}
+
+func TestWassersteinCompute(t *testing.T) {
+	datasets := createShiftedDatasets([]float64{0, 0, 0.2, 0.5, 1.0}, 500, 3)
+	defer cleanDatasets(datasets)
+	for _, distance := range []string{"wasserstein", "energy"} {
+		est := NewDatasetSimilarityEstimator(SimilarityTypeWasserstein, datasets)
+		est.Configure(map[string]string{"concurrency": "4", "distance": distance})
+		if err := est.Compute(); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		sm := est.SimilarityMatrix()
+		smSanityCheck(sm, t)
+		// similarity decreases with the shift
+		if !(sm.Get(0, 1) > sm.Get(0, 2) && sm.Get(0, 2) > sm.Get(0, 3) && sm.Get(0, 3) > sm.Get(0, 4)) {
+			t.Log(distance, "similarities do not decrease with the shift",
+				sm.Get(0, 1), sm.Get(0, 2), sm.Get(0, 3), sm.Get(0, 4))
+			t.Fail()
+		}
+		if sm.Get(0, 1) < 0.9 {
+			t.Log(distance, "datasets of the same distribution are not similar", sm.Get(0, 1))
+			t.Fail()
+		}
+	}
+}
+
+func TestWassersteinSerialization(t *testing.T) {
+	datasets := createShiftedDatasets([]float64{0, 0.1, 0.3, 0.6}, 300, 2)
+	defer cleanDatasets(datasets)
+	est := *new(WassersteinEstimator)
+	est.datasets = datasets[:3]
+	est.SetPopulationPolicy(DatasetSimilarityPopulationPolicy{PolicyType: PopulationPolicyFull})
+	est.Configure(map[string]string{"distance": "energy", "projections": "20",
+		"quantiles": "50", "tuples": "100", "seed": "-2"})
+	if err := est.Compute(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	newEst := *new(WassersteinEstimator)
+	newEst.Deserialize(est.Serialize())
+	estimatorsCheck(est.AbstractDatasetSimilarityEstimator, newEst.AbstractDatasetSimilarityEstimator, t)
+	if !newEst.energy || newEst.projections != 20 || newEst.quantiles != 50 ||
+		newEst.maxTuples != 100 || newEst.seed != -2 {
+		t.Log("Wrong deserialized parameters")
+		t.FailNow()
+	}
+	for i := range datasets {
+		for j := range datasets {
+			if est.Similarity(datasets[i], datasets[j]) != newEst.Similarity(datasets[i], datasets[j]) {
+				t.Log("Different similarities after deserialization", i, j)
+				t.Fail()
+			}
+		}
+	}
+	// appended datasets are sketched according to the existing normalization
+	if err := AppendDatasets(&newEst, datasets[3:]); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	if v := newEst.SimilarityMatrix().Get(3, 0); v != est.Similarity(datasets[3], datasets[0]) {
+		t.Log("Wrong similarity of the appended dataset", v)
+		t.Fail()
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -75,6 +76,11 @@ func controllerDownload(w http.ResponseWriter, r *http.Request) Model {
 		if m != nil {
 			filePath = m.AppxValuesPath
 		}
+	} else if fileType == "model" {
+		m := modelDatasetModelGet(id)
+		if m != nil {
+			filePath = m.ModelPath
+		}
 	}
 
 	if filePath != "" {
@@ -104,7 +110,7 @@ func controllerSMVisual(w http.ResponseWriter, r *http.Request) Model {
 	_, id, _ := parseURL(r.URL.Path)
 	m := modelSimilarityMatrixGet(id)
 	ret := modelDatasetGetInfo(m.DatasetID)
-	ret.Files = modelDatasetGetFiles(m.DatasetID)
+	ret.Files = modelSimilarityMatrixFiles(m)
 	return ret
 }
 
@@ -119,7 +125,7 @@ func controllerSMtoCSV(w http.ResponseWriter, r *http.Request) Model {
 	}
 	sm.Deserialize(cnt)
 	w.Write([]byte("x,y,value\n"))
-	files := modelDatasetGetFiles(m.DatasetID)
+	files := modelSimilarityMatrixFiles(m)
 	for i := 0; i < sm.Capacity(); i++ {
 		for j := 0; j < sm.Capacity(); j++ {
 			w.Write([]byte(fmt.Sprintf("%s,%s,%.5f\n", files[i], files[j], sm.Get(i, j))))
@@ -137,6 +143,14 @@ func controllerSMDelete(w http.ResponseWriter, r *http.Request) Model {
 	return nil
 }
 
+// /sm/<id>/refresh
+func controllerSMRefresh(w http.ResponseWriter, r *http.Request) Model {
+	_, id, _ := parseURL(r.URL.Path)
+	TEngine.Submit(NewSMRefreshTask(id))
+	http.Redirect(w, r, "/tasks/", 307)
+	return nil
+}
+
 // /operator/<id>/delete
 func controllerOperatorDelete(w http.ResponseWriter, r *http.Request) Model {
 	datasetID := r.URL.Query().Get("datasetID")
@@ -216,7 +230,12 @@ func controllerMDSRun(w http.ResponseWriter, r *http.Request) Model {
 	if err != nil {
 		log.Println(err)
 	}
-	conf := map[string]string{"k": r.PostFormValue("k")}
+	conf := map[string]string{"k": r.PostFormValue("k"), "type": r.PostFormValue("type")}
+	for _, opt := range []string{"iterations", "tolerance", "restarts", "landmarks", "maxk", "gof"} {
+		if val := r.PostFormValue(opt); val != "" {
+			conf[opt] = val
+		}
+	}
 	task := NewMDSComputationTask(id, datasetID, conf)
 	TEngine.Submit(task)
 	http.Redirect(w, r, "/tasks/", 307)
@@ -259,7 +278,7 @@ func controllerCoordsVisual(w http.ResponseWriter, r *http.Request) Model {
 	}
 	datasetID := sm.DatasetID
 	fileNames := ""
-	files := modelDatasetGetFiles(datasetID)
+	files := modelSimilarityMatrixFiles(sm)
 	for i, n := range files {
 		fileNames += n
 		if i < len(files)-1 {
@@ -318,6 +337,7 @@ func controllerDatasetNew(w http.ResponseWriter, r *http.Request) Model {
 				"operators":  true,
 				"appx":       true,
 				"samples":    true,
+				"models":     true,
 			}
 			files, err := ioutil.ReadDir(path)
 			if err != nil {
@@ -419,13 +439,24 @@ func controllerModelNew(w http.ResponseWriter, r *http.Request) Model {
 		log.Println(err)
 	}
 
-	log.Println(operator, mlScript, modelType, matrixid, k, dataset, coordinates, regression)
+	// the parameters of the native modelers
+	options := make(map[string]string)
+	applicable := map[string][]string{"ridge": {"lambda"}, "rf": {"trees", "leaf"}, "gp": {"kernel"},
+		"krr": {"lambda", "repair"}, "svr": {"c", "epsilon", "repair"}}
+	for _, o := range applicable[modelType] {
+		if v := r.Form.Get(o); v != "" {
+			options[o] = v
+		}
+	}
+
+	log.Println(operator, mlScript, modelType, matrixid, k, dataset, coordinates, regression, options)
 	TEngine.Submit(
 		NewModelTrainTask(
 			dataset, operator, sr,
 			modelType,
 			coordinates, mlScript,
-			matrixid, k, regression))
+			matrixid, k, regression,
+			options))
 	http.Redirect(w, r, "/tasks/", 307)
 	return nil
 }
@@ -458,7 +489,20 @@ func controllerModelVisual(w http.ResponseWriter, r *http.Request) Model {
 	samples, _ := ioutil.ReadFile(m.SamplesPath)
 	apprx, _ := ioutil.ReadFile(m.AppxValuesPath)
 	coordinates, _ := ioutil.ReadFile(m.Coordinates.Path)
-	files := modelDatasetGetFiles(m.Dataset.ID)
+	// the second line of the appx file holds the variances (if estimated)
+	variances := ""
+	if lines := strings.SplitN(strings.TrimSpace(string(apprx)), "\n", 2); len(lines) == 2 {
+		apprx, variances = []byte(lines[0]+"\n"), lines[1]
+	}
+	// the values are given in the order of the rows of the matrix
+	matrix := m.SimilarityMatrix
+	if matrix == nil && m.Coordinates != nil {
+		matrix = m.Coordinates.SimilarityMatrix
+	}
+	var files []string
+	if matrix != nil {
+		files = modelSimilarityMatrixFiles(matrix)
+	}
 	fileStr := ""
 	for _, f := range files {
 		fileStr += f + "\n"
@@ -469,13 +513,100 @@ func controllerModelVisual(w http.ResponseWriter, r *http.Request) Model {
 	}
 
 	return struct {
+		ID                 string
+		Predictions        bool
 		Labels             string
 		Samples            string
 		ApproximatedValues string
+		Variances          string
 		Coordinates        string
 		ScoresID           string
 		Errors             map[string]string
-	}{fileStr, string(samples), string(apprx), string(coordinates), scoresID, m.Errors}
+	}{m.ID, m.ModelPath != "", fileStr, string(samples), string(apprx), variances, string(coordinates), scoresID, m.Errors}
+}
+
+// /modeling/<id>/predict
+// The new dataset is either uploaded (file) or referenced by its path on the
+// server (path); the predicted value is returned as text.
+func controllerModelPredict(w http.ResponseWriter, r *http.Request) Model {
+	_, id, _ := parseURL(r.URL.Path)
+	m := modelDatasetModelGet(id)
+	if m == nil || m.ModelPath == "" {
+		w.WriteHeader(404)
+		return nil
+	}
+	datasetPath := r.FormValue("path")
+	if datasetPath == "" {
+		f, _, err := r.FormFile("file")
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(400)
+			return nil
+		}
+		defer f.Close()
+		tmp, err := ioutil.TempFile("/tmp", "dataset")
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(500)
+			return nil
+		}
+		io.Copy(tmp, f)
+		tmp.Close()
+		defer os.Remove(tmp.Name())
+		datasetPath = tmp.Name()
+	}
+
+	cnt, err := ioutil.ReadFile(m.ModelPath)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(500)
+		return nil
+	}
+	modeler := core.DeserializeModeler(cnt)
+	// the similarity based modelers keep their matrix, the rest use the
+	// matrix of their coordinates
+	matrix := m.SimilarityMatrix
+	if matrix == nil && m.Coordinates != nil {
+		matrix = m.Coordinates.SimilarityMatrix
+	}
+	if modeler == nil || matrix == nil || matrix.EstimatorPath == "" {
+		w.WriteHeader(500)
+		w.Write([]byte("The model cannot be used for predictions\n"))
+		return nil
+	}
+	cnt, err = ioutil.ReadFile(matrix.EstimatorPath)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(500)
+		return nil
+	}
+	est := core.DeserializeSimilarityEstimator(cnt)
+	if est == nil {
+		w.WriteHeader(500)
+		return nil
+	}
+	if SummaryCache != nil {
+		est.SetSummaryCache(SummaryCache)
+	}
+	var indexer *core.OnlineIndexer
+	if m.Coordinates != nil {
+		cnt, err = ioutil.ReadFile(m.Coordinates.Path)
+		if err != nil {
+			log.Println(err)
+		} else {
+			indexer = core.NewOnlineIndexer(est, core.DeserializeCoordinates(cnt), Conf.Scripts.Indexer)
+		}
+	}
+
+	val, err := core.PredictDataset(modeler, core.NewDataset(datasetPath), est, indexer)
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(500)
+		w.Write([]byte(err.Error() + "\n"))
+		return nil
+	}
+	w.Write([]byte(fmt.Sprintf("%.5f\n", val)))
+	return nil
 }
 
 func controllerModelDelete(w http.ResponseWriter, r *http.Request) Model {
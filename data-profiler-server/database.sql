@@ -47,7 +47,10 @@ CREATE TABLE IF NOT EXISTS `models` (
 		`errors` VARCHAR(2000),
 		`samplespath` VARCHAR(500), 
 		`appxvaluespath` VARCHAR(500), 
+		`matrixid` INTEGER,
+		`modelpath` VARCHAR(500),
 		FOREIGN KEY(datasetid) REFERENCES datasets(id),
 		FOREIGN KEY(coordinatesid) REFERENCES coordinates(id),
+		FOREIGN KEY(matrixid) REFERENCES matrices(id),
 		FOREIGN KEY(operatorid) REFERENCES operators(id)
 );
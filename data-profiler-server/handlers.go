@@ -55,14 +55,16 @@ var routingControllerTemplates = map[string]cntTmpltCouple{
 	"modeling/new":   {controllerModelNew, "forms/new_model_form.html"},
 
 	// No GUI urls
-	"download/":       {controllerDownload, ""},
-	"sm/csv":          {controllerSMtoCSV, ""},
-	"sm/delete":       {controllerSMDelete, ""},
-	"operator/run":    {controllerOperatorRun, ""},
-	"operator/delete": {controllerOperatorDelete, ""},
-	"modeling/delete": {controllerModelDelete, ""},
-	"scores/text":     {controllerScoresText, ""},
-	"datasets/delete": {controllerDatasetDelete, ""},
+	"download/":        {controllerDownload, ""},
+	"sm/csv":           {controllerSMtoCSV, ""},
+	"sm/delete":        {controllerSMDelete, ""},
+	"sm/refresh":       {controllerSMRefresh, ""},
+	"operator/run":     {controllerOperatorRun, ""},
+	"operator/delete":  {controllerOperatorDelete, ""},
+	"modeling/delete":  {controllerModelDelete, ""},
+	"modeling/predict": {controllerModelPredict, ""},
+	"scores/text":      {controllerScoresText, ""},
+	"datasets/delete":  {controllerDatasetDelete, ""},
 
 	// TODO: implement these URLs
 	"about/":  {nil, "about.html"},
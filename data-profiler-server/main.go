@@ -9,6 +9,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/giagiannis/data-profiler/core"
 	"gopkg.in/yaml.v2"
 )
 
@@ -18,6 +19,10 @@ var Conf *Configuration
 // TEngine is the TaskEngine used to submit new tasks
 var TEngine *TaskEngine
 
+// SummaryCache holds the dataset summaries, reused among the similarity
+// matrix computations (nil if no cache directory is configured)
+var SummaryCache *core.DatasetSummaryCache
+
 // Configuration dictates the schema of the yml conf file
 type Configuration struct {
 	Server struct {
@@ -26,13 +31,15 @@ type Configuration struct {
 			Templates string
 			Static    string
 			Datasets  string
+			Cache     string
 		}
 	}
 	Database string
 	Logfile  string
 	Scripts  struct {
-		MDS string
-		ML  map[string]string
+		MDS     string
+		Indexer string // optional, the native indexer is used if empty
+		ML      map[string]string
 	}
 }
 
@@ -65,7 +72,18 @@ func main() {
 	}
 	rand.Seed(int64(time.Now().Nanosecond()))
 	setLogger(Conf.Logfile)
+	if err := dbMigrate(); err != nil {
+		os.Exit(1)
+	}
 	TEngine = NewTaskEngine()
+	if Conf.Server.Dirs.Cache != "" {
+		cache, err := core.NewDatasetSummaryCache(Conf.Server.Dirs.Cache)
+		if err != nil {
+			log.Println(err)
+		} else {
+			SummaryCache = cache
+		}
+	}
 
 	fs := http.FileServer(http.Dir(Conf.Server.Dirs.Static))
 	http.Handle("/static/", http.StripPrefix("/static/", fs))
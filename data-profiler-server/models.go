@@ -76,6 +76,10 @@ type ModelDatasetModel struct {
 	Errors         map[string]string
 	SamplesPath    string
 	AppxValuesPath string
+	ModelPath      string
+
+	// the similarity matrix of the similarity based modelers
+	SimilarityMatrix *ModelSimilarityMatrix
 }
 
 // FUNCTIONS
@@ -91,6 +95,50 @@ func dbConnect() *sql.DB {
 	return db
 }
 
+// dbMigrate adds the columns introduced after the creation of the database,
+// since database.sql is only executed for new databases
+func dbMigrate() error {
+	db := dbConnect()
+	defer db.Close()
+	rows, err := db.Query("PRAGMA table_info(models)")
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk)
+		existing[name] = true
+	}
+	rows.Close()
+	if len(existing) == 0 {
+		return nil
+	}
+	columns := [][2]string{
+		{"matrixid", "INTEGER REFERENCES matrices(id)"},
+		{"modelpath", "VARCHAR(500)"},
+	}
+	for _, c := range columns {
+		if existing[c[0]] {
+			continue
+		}
+		log.Println("Adding column", c[0], "to the models table")
+		if _, err := db.Exec("ALTER TABLE models ADD COLUMN `" + c[0] + "` " + c[1]); err != nil {
+			log.Println(err)
+			return err
+		}
+	}
+	// the coordinate based models used to store an empty matrixid
+	if _, err := db.Exec("UPDATE models SET matrixid = NULL WHERE matrixid == ''"); err != nil {
+		log.Println(err)
+		return err
+	}
+	return nil
+}
+
 func modelDatasetsList() []*ModelDataset {
 	db := dbConnect()
 	defer db.Close()
@@ -259,7 +307,7 @@ func modelSimilarityMatrixGet(id string) *ModelSimilarityMatrix {
 	db := dbConnect()
 	defer db.Close()
 
-	rows, err := db.Query("SELECT id,path,filename,configuration,datasetid" +
+	rows, err := db.Query("SELECT id,path,filename,configuration,datasetid,estimatorpath" +
 		" FROM matrices WHERE id == " + id)
 	if err != nil {
 		log.Println(err)
@@ -274,7 +322,8 @@ func modelSimilarityMatrixGet(id string) *ModelSimilarityMatrix {
 			&obj.Path,
 			&obj.Filename,
 			&confString,
-			&obj.DatasetID)
+			&obj.DatasetID,
+			&obj.EstimatorPath)
 		obj.Configuration = make(map[string]string)
 		json.Unmarshal([]byte(confString), &obj.Configuration)
 		return obj
@@ -282,6 +331,36 @@ func modelSimilarityMatrixGet(id string) *ModelSimilarityMatrix {
 	return nil
 }
 
+// modelSimilarityMatrixDatasets returns the datasets of a SM, in the order of
+// its rows. Since the refreshed matrices hold the appended datasets last, the
+// order is given by the stored estimator; for the matrices without one, it is
+// the order of the dataset directory.
+func modelSimilarityMatrixDatasets(m *ModelSimilarityMatrix) []*core.Dataset {
+	if m.EstimatorPath != "" {
+		cnt, err := ioutil.ReadFile(m.EstimatorPath)
+		if err != nil {
+			log.Println(err)
+		} else if est := core.DeserializeSimilarityEstimator(cnt); est != nil {
+			return est.Datasets()
+		}
+	}
+	dts := modelDatasetGetInfo(m.DatasetID)
+	if dts == nil {
+		return nil
+	}
+	return core.DiscoverDatasets(dts.Path)
+}
+
+// modelSimilarityMatrixFiles returns the file names of the datasets of a SM,
+// in the order of its rows
+func modelSimilarityMatrixFiles(m *ModelSimilarityMatrix) []string {
+	var results []string
+	for _, d := range modelSimilarityMatrixDatasets(m) {
+		results = append(results, path.Base(d.Path()))
+	}
+	return results
+}
+
 func modelSimilarityMatrixDelete(id string) *ModelSimilarityMatrix {
 	m := modelSimilarityMatrixGet(id)
 	if m != nil {
@@ -485,23 +564,29 @@ func modelOperatorScoresInsert(operatorID string, content []byte) *ModelOperator
 }
 
 func modelDatasetModelInsert(
-	coordinatesID, operatorID, datasetID string,
-	samples, appxValues []byte,
+	coordinatesID, operatorID, datasetID, matrixID string,
+	samples, appxValues, model []byte,
 	conf, errors map[string]string,
 	samplingRate float64) *ModelDatasetModel {
 	dts := modelDatasetGetInfo(datasetID)
 	samplesPath := writeBufferToFile(dts, "samples", samples)
 	appxValuesPath := writeBufferToFile(dts, "appx", appxValues)
+	modelPath := writeBufferToFile(dts, "models", model)
 	db := dbConnect()
 	defer db.Close()
 	stmt, err := db.Prepare(
 		"INSERT INTO models(coordinatesid, operatorid, datasetid, samplingrate, " +
-			"configuration, samplespath, appxvaluespath, errors) " +
-			"VALUES(?,?,?,?,?,?,?,?)")
+			"configuration, samplespath, appxvaluespath, errors, matrixid, modelpath) " +
+			"VALUES(?,?,?,?,?,?,?,?,?,?)")
 	defer stmt.Close()
 	if err != nil {
 		log.Println(err)
 	}
+	// the coordinate based models refer to no similarity matrix
+	var matrix interface{}
+	if matrixID != "" {
+		matrix = matrixID
+	}
 	_, err = stmt.Exec(
 		coordinatesID,
 		operatorID,
@@ -511,6 +596,8 @@ func modelDatasetModelInsert(
 		samplesPath,
 		appxValuesPath,
 		jsonToString(errors),
+		matrix,
+		modelPath,
 	)
 	if err != nil {
 		log.Println(err)
@@ -523,6 +610,7 @@ func modelDatasetModelDelete(id string) *ModelDatasetModel {
 	if m != nil {
 		os.Remove(m.SamplesPath)
 		os.Remove(m.AppxValuesPath)
+		os.Remove(m.ModelPath)
 	}
 	deleteByID("models", id)
 	return nil
@@ -534,7 +622,7 @@ func modelDatasetModelGet(id string) *ModelDatasetModel {
 
 	rows, err := db.Query(
 		"SELECT id, coordinatesid, operatorid, datasetid, samplingrate, " +
-			"configuration, samplespath, appxvaluespath, errors " +
+			"configuration, samplespath, appxvaluespath, errors, matrixid, modelpath " +
 			"FROM models WHERE id == " + id)
 	if err != nil {
 		log.Println(err)
@@ -545,6 +633,7 @@ func modelDatasetModelGet(id string) *ModelDatasetModel {
 	if rows.Next() {
 		obj := new(ModelDatasetModel)
 		coordinatesID, operatorID, datasetID := "", "", ""
+		var matrixID, modelPath sql.NullString
 		rows.Scan(&obj.ID,
 			&coordinatesID,
 			&operatorID,
@@ -553,8 +642,14 @@ func modelDatasetModelGet(id string) *ModelDatasetModel {
 			&confString,
 			&obj.SamplesPath,
 			&obj.AppxValuesPath,
-			&errorsString)
+			&errorsString,
+			&matrixID,
+			&modelPath)
 		obj.Errors = stringToJSON(errorsString)
+		obj.ModelPath = modelPath.String
+		if matrixID.Valid {
+			obj.SimilarityMatrix = modelSimilarityMatrixGet(matrixID.String)
+		}
 		obj.Configuration = stringToJSON(confString)
 		obj.Coordinates = modelCoordinatesGet(coordinatesID)
 		obj.Operator = modelOperatorGet(operatorID)
@@ -611,6 +706,13 @@ func writeBufferToFile(dts *ModelDataset, prefix string, buffer []byte) string {
 		}
 	}
 	dstPath := dstDir + "/" + prefix + currentTimeSuffix()
+	// files written within the same second must not overwrite each other
+	for i, base := 1, dstPath; ; i++ {
+		if _, err := os.Stat(dstPath); os.IsNotExist(err) {
+			break
+		}
+		dstPath = fmt.Sprintf("%s-%d", base, i)
+	}
 	err = ioutil.WriteFile(dstPath, buffer, 0777)
 	if err != nil {
 		log.Println(err)
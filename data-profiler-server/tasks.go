@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -73,6 +74,9 @@ func NewSMComputationTask(datasetID string, conf map[string]string) *Task {
 		datasets := core.DiscoverDatasets(dts.Path)
 		estType := *core.NewDatasetSimilarityEstimatorType(conf["estimatorType"])
 		est := core.NewDatasetSimilarityEstimator(estType, datasets)
+		if SummaryCache != nil {
+			est.SetSummaryCache(SummaryCache)
+		}
 		est.Configure(conf)
 		if conf["popPolicy"] == "aprx" {
 			pop := new(core.DatasetSimilarityPopulationPolicy)
@@ -105,6 +109,77 @@ func NewSMComputationTask(datasetID string, conf map[string]string) *Task {
 	return task
 }
 
+// NewSMRefreshTask creates a new Similarity Matrix from an existing one,
+// according to the dataset files that were added to or removed from the
+// dataset directory. Only the similarities of the new datasets are computed
+// and they are appended after the existing ones. The existing matrix is kept,
+// since its coordinates and models are indexed by its rows.
+func NewSMRefreshTask(smID string) *Task {
+	smModel := modelSimilarityMatrixGet(smID)
+	if smModel == nil {
+		log.Println("SM not found")
+		return nil
+	}
+	if smModel.EstimatorPath == "" {
+		log.Println("The estimator of the SM was not stored")
+		return nil
+	}
+	dts := modelDatasetGetInfo(smModel.DatasetID)
+	task := new(Task)
+	task.Dataset = dts
+	task.Description = fmt.Sprintf("SM Refresh for %s (%s)\n",
+		dts.Name, smModel.Filename)
+	task.fnc = func() error {
+		cnt, err := ioutil.ReadFile(smModel.EstimatorPath)
+		if err != nil {
+			return err
+		}
+		est := core.DeserializeSimilarityEstimator(cnt)
+		if est == nil {
+			return errors.New("Estimator could not be deserialized")
+		}
+		if SummaryCache != nil {
+			est.SetSummaryCache(SummaryCache)
+		}
+		current := make(map[string]bool)
+		for _, d := range est.Datasets() {
+			current[d.Path()] = true
+		}
+		discovered := make(map[string]bool)
+		var removed []string
+		var appended []*core.Dataset
+		for _, d := range core.DiscoverDatasets(dts.Path) {
+			discovered[d.Path()] = true
+			if !current[d.Path()] {
+				appended = append(appended, d)
+			}
+		}
+		for _, d := range est.Datasets() {
+			if !discovered[d.Path()] {
+				removed = append(removed, d.Path())
+			}
+		}
+		if len(removed) == 0 && len(appended) == 0 {
+			log.Println("SM is up to date")
+			return nil
+		}
+		if err := core.RemoveDatasets(est, removed); err != nil {
+			return err
+		}
+		if err := core.AppendDatasets(est, appended); err != nil {
+			return err
+		}
+		conf := make(map[string]string)
+		for k, v := range smModel.Configuration {
+			conf[k] = v
+		}
+		conf["refreshedFrom"] = smID
+		modelSimilarityMatrixInsert(smModel.DatasetID, est.SimilarityMatrix().Serialize(), est.Serialize(), conf)
+		return nil
+	}
+	return task
+}
+
 // NewMDSComputationTask initializes a new Multidimensional Scaling execution task.
 func NewMDSComputationTask(smID, datasetID string, conf map[string]string) *Task {
 	smModel := modelSimilarityMatrixGet(smID)
@@ -119,25 +194,39 @@ func NewMDSComputationTask(smID, datasetID string, conf map[string]string) *Task
 	}
 	sm := new(core.DatasetSimilarityMatrix)
 	sm.Deserialize(cnt)
-	k, err := strconv.ParseInt(conf["k"], 10, 64)
-	if err != nil {
-		log.Println(err)
+	var k int64 // k=0 stands for the automatic selection of k
+	if conf["k"] != "auto" {
+		k, err = strconv.ParseInt(conf["k"], 10, 64)
+		if err != nil {
+			log.Println(err)
+		}
 	}
 
+	mdsType := core.NewMDScalingType(conf["type"])
+
 	dat := modelDatasetGetInfo(datasetID)
 	task := new(Task)
 	task.Dataset = dat
-	task.Description = fmt.Sprintf("MDS Execution for %s with k=%d\n",
-		dat.Name, k)
+	task.Description = fmt.Sprintf("MDS Execution (%s) for %s with k=%s\n",
+		mdsType, dat.Name, conf["k"])
 	task.fnc = func() error {
-		mds := core.NewMDScaling(sm, int(k), Conf.Scripts.MDS)
+		var mds *core.MDScaling
+		if mdsType == core.MDScalingScriptType {
+			mds = core.NewMDScaling(sm, int(k), Conf.Scripts.MDS)
+		} else {
+			mds = core.NewMDScalingWithType(sm, int(k), mdsType)
+		}
+		mds.Configure(conf)
 		err = mds.Compute()
 		if err != nil {
 			return err
 		}
+		for _, c := range mds.Curve() {
+			log.Printf("k=%d gof=%.5f stress=%.5f\n", c.K, c.Gof, c.Stress)
+		}
 		gof := fmt.Sprintf("%.5f", mds.Gof())
 		stress := fmt.Sprintf("%.5f", mds.Stress())
-		modelCoordinatesInsert(mds.Coordinates(), dat.ID, conf["k"], gof, stress, smID)
+		modelCoordinatesInsert(mds.Coordinates(), dat.ID, strconv.Itoa(mds.K()), gof, stress, smID)
 		return nil
 	}
 	return task
@@ -187,13 +276,16 @@ func NewOperatorRunTask(operatorID string) *Task {
 func NewModelTrainTask(datasetID, operatorID string, sr float64,
 	modelType string,
 	coordinatesID, mlScript string,
-	matrixID, k,  regression string) *Task {
+	matrixID, k,  regression string,
+	options map[string]string) *Task {
 	m := modelDatasetGetInfo(datasetID)
 	task := new(Task)
 	task.Description = fmt.Sprintf("Model training (%s for %s)", path.Base(mlScript), m.Name)
+	if t := core.NewModelerType(modelType); t != core.ScriptBasedModelerType && t != core.KNNModelerType {
+		task.Description = fmt.Sprintf("Model training (%s for %s)", modelType, m.Name)
+	}
 	task.Dataset = m
 	task.fnc = func() error {
-		datasets := core.DiscoverDatasets(m.Path)
 		o := modelOperatorGet(operatorID)
 		var evaluator core.DatasetEvaluator
 		var err error
@@ -207,31 +299,62 @@ func NewModelTrainTask(datasetID, operatorID string, sr float64,
 			return err
 		}
 		t := core.NewModelerType(modelType)
-		modeler := core.NewModeler(t, datasets, sr, evaluator)
 		var conf map[string]string
+		// only the similarity based models refer to the matrix
+		modelMatrixID := ""
+		// the matrix whose rows determine the order of the datasets
+		var matrix *ModelSimilarityMatrix
 		if t == core.ScriptBasedModelerType {
 			c := modelCoordinatesGet(coordinatesID)
 			conf = map[string]string{"script": mlScript, "coordinates": c.Path}
+			matrix = c.SimilarityMatrix
 		} else if t == core.KNNModelerType {
-			m := modelSimilarityMatrixGet(matrixID)
-			conf = map[string]string{"k": k, "smatrix": m.Path, "regression": regression}
+			matrix = modelSimilarityMatrixGet(matrixID)
+			conf = map[string]string{"k": k, "smatrix": matrix.Path, "regression": regression}
+			modelMatrixID = matrixID
+		} else if t == core.KernelRidgeModelerType || t == core.SVRModelerType {
+			matrix = modelSimilarityMatrixGet(matrixID)
+			conf = map[string]string{"smatrix": matrix.Path}
+			modelMatrixID = matrixID
+			for k, v := range options {
+				conf[k] = v
+			}
+		} else {
+			c := modelCoordinatesGet(coordinatesID)
+			conf = map[string]string{"coordinates": c.Path}
+			matrix = c.SimilarityMatrix
+			for k, v := range options {
+				conf[k] = v
+			}
+		}
+		if matrix == nil {
+			return errors.New("The similarity matrix of the model was not found")
+		}
+		modeler := core.NewModeler(t, modelSimilarityMatrixDatasets(matrix), sr, evaluator)
+		err = modeler.Configure(conf)
+		if err != nil {
+			log.Println(err)
+			return err
 		}
-		modeler.Configure(conf)
 		err = modeler.Run()
 		if err != nil {
 			log.Println(err)
 			return err
 		}
-		// serialze appxValues
+		// serialze appxValues (and their variances, if estimated)
 		var cnt [][]float64
 		cnt = append(cnt, modeler.AppxValues())
+		if modeler.AppxVariances() != nil {
+			cnt = append(cnt, modeler.AppxVariances())
+		}
 		appxBuffer := serializeCSVFile(cnt)
 		samplesBuffer, _ := json.Marshal(modeler.Samples())
 		errors := make(map[string]string)
 		for k, v := range modeler.ErrorMetrics() {
 			errors[k] = fmt.Sprintf("%.5f", v)
 		}
-		modelDatasetModelInsert(coordinatesID, operatorID, datasetID, samplesBuffer, appxBuffer, conf, errors, sr)
+		modelDatasetModelInsert(coordinatesID, operatorID, datasetID, modelMatrixID,
+			samplesBuffer, appxBuffer, modeler.Serialize(), conf, errors, sr)
 		return nil
 	}
 	return task
@@ -17,6 +17,7 @@ import (
 type expAccuracyParams struct {
 	output      *string          // output file path
 	appxOutput  *string          // output approximation files path
+	modelOutput *string          // output trained modeler files path
 	repetitions *int             // number of times to repeat experiment
 	threads     *int             // number of threads to utilize
 	datasets    []*core.Dataset  //datasets to use
@@ -26,18 +27,23 @@ type expAccuracyParams struct {
 	k        *int    // k of knn
 	mlScript *string // script used for approximation
 	coords   *string // coords of datasets
+	options  *string // options of the native modelers
+
+	sampling        *string // the sampling strategy
+	samplingOptions *string // the options of the sampling strategy
 
 	evaluator core.DatasetEvaluator // evaluator of the datasets
 
 	samplingRates []float64 // samplings rates to run
 
 	writeAppxScores bool // write approximations to file
+	writeModels     bool // write trained modelers to file
 }
 
 func expAccuracyParseParams() *expAccuracyParams {
 	params := new(expAccuracyParams)
 	modelerTypeStr :=
-		flag.String("mt", "script", "modeler type [knn | script]")
+		flag.String("mt", "script", "modeler type [knn | script | linear | ridge | rf | gp | krr | svr]")
 	params.mlScript =
 		flag.String("ml", "", "ML script to use for approximation (from script ML)")
 	params.output =
@@ -47,13 +53,21 @@ func expAccuracyParseParams() *expAccuracyParams {
 	params.threads =
 		flag.Int("t", 1, "number of threads")
 	params.coords =
-		flag.String("c", "", "coordinates file (from script ml)")
+		flag.String("c", "", "coordinates file (from script, linear, ridge, rf and gp ml)")
 	params.smpath =
-		flag.String("sm", "", "similarity matrix (from knn ml)")
+		flag.String("sm", "", "similarity matrix (from knn, krr and svr ml)")
 	params.k =
 		flag.Int("k", 5, "k (from knn ml)")
+	params.options =
+		flag.String("mo", "", "options of the native modelers (e.g. lambda=0.1, trees=100 or kernel=matern52)")
 	params.appxOutput =
 		flag.String("a", "", "approximations output file")
+	params.modelOutput =
+		flag.String("ms", "", "trained modelers output file (used by predict)")
+	params.sampling =
+		flag.String("st", "uniform", "sampling strategy [uniform | kcenter | cluster | uncertainty]")
+	params.samplingOptions =
+		flag.String("so", "", "options of the sampling strategy (e.g. smatrix=<path> or batch=5)")
 	loger :=
 		flag.String("l", "", "log file")
 	scoresFile :=
@@ -97,10 +111,13 @@ func expAccuracyParseParams() *expAccuracyParams {
 		params.modelerType = core.ScriptBasedModelerType
 	} else if *modelerTypeStr == "knn" {
 		params.modelerType = core.KNNModelerType
+	} else {
+		params.modelerType = core.NewModelerType(*modelerTypeStr)
 	}
 
 	// write approximations to file
 	params.writeAppxScores = *params.appxOutput != ""
+	params.writeModels = *params.modelOutput != ""
 
 	return params
 }
@@ -121,16 +138,43 @@ func expAccuracyRun() {
 		sync <- true
 	}
 
+	// the sampling strategy is configured once and shared by the modelers
+	samplingType := core.NewSamplingStrategyType(*params.sampling)
+	sampling := core.NewSamplingStrategy(samplingType, params.datasets)
+	samplingConf := parseOptions(*params.samplingOptions)
+	if _, ok := samplingConf["coordinates"]; !ok && *params.coords != "" {
+		samplingConf["coordinates"] = *params.coords
+	}
+	if _, ok := samplingConf["smatrix"]; !ok && *params.smpath != "" {
+		samplingConf["smatrix"] = *params.smpath
+	}
+	if err := sampling.Configure(samplingConf); err != nil {
+		log.Fatalln(err)
+	}
+
 	go func() {
 		for r := 0; r < *params.repetitions; r++ {
 			for _, sr := range params.samplingRates {
 				modeler := core.NewModeler(params.modelerType, params.datasets, sr, params.evaluator)
 				if params.modelerType == core.ScriptBasedModelerType {
 					modeler.Configure(map[string]string{"script": *params.mlScript, "coordinates": *params.coords})
+				} else if params.modelerType == core.KernelRidgeModelerType || params.modelerType == core.SVRModelerType {
+					conf := parseOptions(*params.options)
+					conf["smatrix"] = *params.smpath
+					if err := modeler.Configure(conf); err != nil {
+						log.Fatalln(err)
+					}
+				} else if params.modelerType != core.KNNModelerType {
+					conf := parseOptions(*params.options)
+					conf["coordinates"] = *params.coords
+					if err := modeler.Configure(conf); err != nil {
+						log.Fatalln(err)
+					}
 				} else {
 					modeler.Configure(map[string]string{"k": fmt.Sprintf("%d", *params.k), "smatrix": *params.smpath})
 				}
-				go runModeler(sr, params.writeAppxScores, modeler, sync, resChannel)
+				modeler.SetSamplingStrategy(sampling)
+				go runModeler(sr, params.writeAppxScores, params.writeModels, modeler, sync, resChannel)
 			}
 		}
 	}()
@@ -149,6 +193,11 @@ func expAccuracyRun() {
 				log.Println(err)
 			}
 		}
+		if params.writeModels {
+			modelOutput := setOutput(fmt.Sprintf("%s_%f_%d", *params.modelOutput, v.sr, i))
+			modelOutput.Write(v.model)
+			modelOutput.Close()
+		}
 	}
 	log.Println(results)
 
@@ -219,12 +268,13 @@ func writeAppxScores(repetition int, params *expAccuracyParams, res *resChannelR
 }
 
 type resChannelResult struct {
-	sr  float64
-	res map[string]float64
-	appx *core.DatasetScores
+	sr    float64
+	res   map[string]float64
+	appx  *core.DatasetScores
+	model []byte
 }
 
-func runModeler(sr float64, writeAppxScores bool, modeler core.Modeler, sync chan bool, resChannel chan resChannelResult) {
+func runModeler(sr float64, writeAppxScores, writeModel bool, modeler core.Modeler, sync chan bool, resChannel chan resChannelResult) {
 	<-sync
 	err := modeler.Run()
 	if err != nil {
@@ -241,6 +291,11 @@ func runModeler(sr float64, writeAppxScores bool, modeler core.Modeler, sync cha
 		appxScores = core.AppxScores(modeler)
 	}
 
-	resChannel <- resChannelResult{sr, res, appxScores}
+	var model []byte
+	if writeModel {
+		model = modeler.Serialize()
+	}
+
+	resChannel <- resChannelResult{sr, res, appxScores, model}
 	sync <- true
 }
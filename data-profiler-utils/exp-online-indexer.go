@@ -28,7 +28,7 @@ func expOnlineIndexerParseParams() *expOnlineIndexerParams {
 	estimatorPath :=
 		flag.String("e", "", "path of the serialized estimator object")
 	saScript :=
-		flag.String("s", "", "Script that executes Simulated Annealing")
+		flag.String("s", "", "Script that executes Simulated Annealing (default: native solver)")
 	logger :=
 		flag.String("l", "", "log file")
 	params.outputStress =
@@ -42,7 +42,7 @@ func expOnlineIndexerParseParams() *expOnlineIndexerParams {
 
 	flag.Parse()
 	setLogger(*logger)
-	if *coordinatesPath == "" || *estimatorPath == "" || *saScript == "" {
+	if *coordinatesPath == "" || *estimatorPath == "" {
 		fmt.Println("Options:")
 		flag.PrintDefaults()
 		os.Exit(1)
@@ -25,6 +25,10 @@ type indexingParams struct {
 	k           *string                         // determines the number of datasets to compare
 	concurrency *int                            //number of threads to be utilized for the exec
 	repetition  *int                            //number of threads to be utilized for the exec
+
+	coordsOutput    *string // where to store the coordinates of the batch indexing
+	update          *bool   // append the indexed datasets to the estimator
+	estimatorOutput *string // where to store the updated estimator
 }
 
 func indexingParseParams() *indexingParams {
@@ -34,13 +38,19 @@ func indexingParseParams() *indexingParams {
 	coordinatesFile :=
 		flag.String("c", "", "the dataset coordinates file")
 	params.script =
-		flag.String("s", "", "the script file to be used by the indexer")
+		flag.String("s", "", "the script file to be used by the indexer (default: native solver)")
 	params.output =
-		flag.String("o", "", "the output file")
+		flag.String("o", "", "the output file (of the comparison experiment)")
+	params.coordsOutput =
+		flag.String("oc", "", "index all the new datasets and store their coordinates to this file")
+	params.update =
+		flag.Bool("u", false, "append the indexed datasets to the estimator (coordinates of all the datasets are stored)")
+	params.estimatorOutput =
+		flag.String("oe", "", "the output file of the updated estimator (requires -u)")
 	params.logfile =
 		flag.String("l", "", "the log file")
 	datasetsPath :=
-		flag.String("i", "", "the new dataset to be indexed - if a dir, only the first dataset is considered")
+		flag.String("i", "", "the new dataset to be indexed - if a dir, only the first dataset is considered, unless -oc is set")
 	params.k =
 		flag.String("k", "0", "comma separated list of datasets to be used for comparison - 0 means all")
 	params.concurrency =
@@ -50,8 +60,8 @@ func indexingParseParams() *indexingParams {
 	flag.Parse()
 	setLogger(*params.logfile)
 
-	if *estimatorFile == "" || *coordinatesFile == "" || *params.script == "" ||
-		*params.output == "" || *datasetsPath == "" {
+	if *estimatorFile == "" || *coordinatesFile == "" ||
+		(*params.output == "" && *params.coordsOutput == "") || *datasetsPath == "" {
 		fmt.Fprintln(os.Stderr, "Missing arguments, usage:")
 		flag.PrintDefaults()
 		os.Exit(1)
@@ -82,22 +92,27 @@ func indexingParseParams() *indexingParams {
 		os.Exit(1)
 	}
 
-	params.coordinates = make([]core.DatasetCoordinates, 0)
-	for _, l := range strings.Split(string(coordBuffer), "\n") {
-		e := false
-		tuple := make(core.DatasetCoordinates, 0)
-		for _, v := range strings.Split(l, " ") {
-			if !e {
-				v = strings.TrimSpace(v)
-				if v != "" {
-					val, err := strconv.ParseFloat(v, 64)
-					e = (err != nil)
-					tuple = append(tuple, val)
+	// both the CSV (mds) and the space separated formats are accepted
+	if strings.Contains(string(coordBuffer), ",") {
+		params.coordinates = core.DeserializeCoordinates(coordBuffer)
+	} else {
+		params.coordinates = make([]core.DatasetCoordinates, 0)
+		for _, l := range strings.Split(string(coordBuffer), "\n") {
+			e := false
+			tuple := make(core.DatasetCoordinates, 0)
+			for _, v := range strings.Split(l, " ") {
+				if !e {
+					v = strings.TrimSpace(v)
+					if v != "" {
+						val, err := strconv.ParseFloat(v, 64)
+						e = (err != nil)
+						tuple = append(tuple, val)
+					}
 				}
 			}
-		}
-		if !e && len(tuple) > 0 {
-			params.coordinates = append(params.coordinates, tuple)
+			if !e && len(tuple) > 0 {
+				params.coordinates = append(params.coordinates, tuple)
+			}
 		}
 	}
 
@@ -109,6 +124,10 @@ func indexingParseParams() *indexingParams {
 
 func indexingRun() {
 	params := indexingParseParams()
+	if *params.coordsOutput != "" {
+		indexingBatchRun(params)
+		return
+	}
 	c := make(chan bool, *params.concurrency)
 	done := make(chan resultsStruct)
 	for j := 0; j < *params.concurrency; j++ {
@@ -171,6 +190,44 @@ func indexingRun() {
 	}
 }
 
+// indexingBatchRun indexes all the new datasets and stores their coordinates
+func indexingBatchRun(params *indexingParams) {
+	k, err := strconv.Atoi(strings.Split(*params.k, ",")[0])
+	if err != nil {
+		log.Fatalln(err)
+	}
+	indexer := core.NewOnlineIndexer(params.estimator, params.coordinates, *params.script)
+	indexer.DatasetsToCompare(k)
+	results, err := indexer.CalculateBatch(params.datasets, *params.concurrency, *params.update)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	var coordinates []core.DatasetCoordinates
+	if *params.update {
+		// the coordinates follow the order of the datasets of the estimator
+		coordinates = indexer.Coordinates()
+		if *params.estimatorOutput != "" {
+			if err := ioutil.WriteFile(*params.estimatorOutput, params.estimator.Serialize(), 0644); err != nil {
+				log.Fatalln(err)
+			}
+		}
+	} else {
+		for _, r := range results {
+			if r.Error != nil {
+				log.Fatalln(r.Dataset.Path(), r.Error)
+			}
+			coordinates = append(coordinates, r.Coordinates)
+		}
+	}
+	for _, r := range results {
+		log.Println(r.Dataset.Path(), r.Coordinates, r.Stress)
+	}
+	if err := ioutil.WriteFile(*params.coordsOutput, core.SerializeCoordinates(coordinates), 0644); err != nil {
+		log.Fatalln(err)
+	}
+}
+
 type resultsStruct struct {
 	id       int
 	coords   core.DatasetCoordinates
@@ -15,6 +15,8 @@ var commandsDescription = map[string]string{
 	"clustering":    "clusters the datasets based on the similarity matrix and prints their accuracy vs their cluster",
 	"simcomparison": "compares a list of similarity matrices",
 	"mds":           "executes Multidimensional Scaling to a similarity matrix",
+	"tune":          "searches the parameters of a modeler through cross validation",
+	"predict":       "predicts the scores of new datasets with a trained modeler",
 }
 
 var expDescription = map[string]string{
@@ -35,6 +37,8 @@ var commandsToFunctions = map[string]func(){
 	"clustering":         clusteringRun,
 	"simcomparison":      simcomparisonRun,
 	"mds":                mdsRun,
+	"tune":               tuneRun,
+	"predict":            predictRun,
 	"indexing":           indexingRun,
 	"exp-accuracy":       expAccuracyRun,
 	"exp-ordering":       expOrderingRun,
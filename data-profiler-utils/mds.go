@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/giagiannis/data-profiler/core"
@@ -18,6 +19,8 @@ type mdsParams struct {
 	modules map[string]bool // which modules to activate for the util
 
 	script       *string                       // the mds script to run
+	mdsType      *string                       // the mds algorithm to run
+	options      map[string]string             // the options of the mds algorithm
 	k            *int                          // the number of the coordinates to eval
 	similarities *core.DatasetSimilarityMatrix // the similarity matrix
 }
@@ -30,9 +33,13 @@ var mdsModules = map[string]string{
 func mdsParseParams() *mdsParams {
 	params := new(mdsParams)
 	params.k =
-		flag.Int("k", 2, "the number of the principal coordinates to use - 0 for autosearch")
+		flag.Int("k", 2, "the number of the principal coordinates to use - 0 for autosearch (see the maxk and gof options)")
 	params.script =
-		flag.String("sc", "", "the script to be used for the MDS eval")
+		flag.String("sc", "", "the script to be used for the MDS eval - if empty, classical MDS is executed natively")
+	params.mdsType =
+		flag.String("t", "", "the MDS algorithm [classical|smacof|smacof-nonmetric|landmark|script] - if empty, the script is used when specified")
+	options :=
+		flag.String("opt", "", "options in the form val1=key1,val2=key2 (list for opts list)")
 	similaritiesPath :=
 		flag.String("sim", "", "the path of the similarity matrix file")
 	params.logfile =
@@ -56,7 +63,23 @@ func mdsParseParams() *mdsParams {
 		}
 	}
 
-	if *similaritiesPath == "" || *params.output == "" || *params.script == "" {
+	if *options == "list" {
+		for k, v := range new(core.MDScaling).Options() {
+			fmt.Printf("\t%s: %s\n", k, v)
+		}
+		os.Exit(1)
+	}
+	params.options = parseOptions(*options)
+
+	if *params.mdsType == "" && *params.script != "" {
+		*params.mdsType = "script"
+	}
+	if *params.mdsType == "script" && *params.script == "" {
+		fmt.Println("The MDS script must be specified")
+		os.Exit(1)
+	}
+
+	if *similaritiesPath == "" || *params.output == "" {
 		fmt.Println("Options:")
 		flag.PrintDefaults()
 		os.Exit(1)
@@ -81,6 +104,31 @@ func mdsParseParams() *mdsParams {
 	return params
 }
 
+// mdsNew initializes a new MDScaling object, based on the provided parameters
+func mdsNew(params *mdsParams, k int) *core.MDScaling {
+	var mds *core.MDScaling
+	mdsType := core.NewMDScalingType(*params.mdsType)
+	if mdsType == core.MDScalingScriptType {
+		mds = core.NewMDScaling(params.similarities, k, *params.script)
+	} else {
+		mds = core.NewMDScalingWithType(params.similarities, k, mdsType)
+	}
+	mds.Configure(params.options)
+	return mds
+}
+
+// mdsGofThreshold returns the target gof of the automatic selection of k
+func mdsGofThreshold(params *mdsParams) float64 {
+	if val, ok := params.options["gof"]; ok {
+		conv, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			log.Println(err)
+		}
+		return conv
+	}
+	return 0
+}
+
 func mdsRun() {
 	params := mdsParseParams()
 
@@ -95,7 +143,7 @@ func mdsRun() {
 		defer outfile.Close()
 
 		log.Println("Executing MDS")
-		mds := core.NewMDScaling(params.similarities, *params.k, *params.script)
+		mds := mdsNew(params, *params.k)
 		err := mds.Compute()
 		log.Println("Done")
 		if err != nil {
@@ -132,9 +180,19 @@ func mdsRun() {
 		defer outfile.Close()
 
 		fmt.Fprintf(outfile, "dimensions gof stress\n")
+		if *params.k < 1 { // sweep up to maxk and report the selected k
+			curve, err := mdsNew(params, 0).Sweep(0)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			for _, c := range curve {
+				fmt.Fprintf(outfile, "%d %.5f %5f\n", c.K, c.Gof, c.Stress)
+			}
+			log.Println("Selected k =", core.SelectDimensions(curve, mdsGofThreshold(params)))
+		}
 		for k := 1; k <= *params.k; k++ {
 			log.Println("Executing MDS for k =", k)
-			mds := core.NewMDScaling(params.similarities, k, *params.script)
+			mds := mdsNew(params, k)
 			err := mds.Compute()
 			log.Println("Done")
 			if err != nil {
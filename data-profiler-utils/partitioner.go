@@ -13,6 +13,7 @@ type partitionerParams struct {
 	output        *string
 	splits        *int
 	partitionType *core.PartitionerType
+	compression   core.DatasetCompression
 }
 
 func partitionerParseParams() *partitionerParams {
@@ -21,6 +22,7 @@ func partitionerParseParams() *partitionerParams {
 	params.output = flag.String("o", "", "Input file to partition")
 	params.splits = flag.Int("c", 0, "Number of splits to create")
 	part := flag.String("t", "UNIFORM", "Type of partitioning")
+	compression := flag.String("z", "none", "Compression of the splits (none, gzip, zstd)")
 	if *part == "UNIFORM" {
 		params.partitionType = new(core.PartitionerType)
 		*params.partitionType = core.PartitionerUniform
@@ -31,6 +33,12 @@ func partitionerParseParams() *partitionerParams {
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
+	var err error
+	params.compression, err = core.NewDatasetCompression(*compression)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 	return params
 }
 
@@ -41,6 +49,7 @@ func partitionerRun() {
 		*params.output,
 		*params.splits,
 		*params.partitionType)
+	partitioner.SetCompression(params.compression)
 	partitioner.Partition()
 	fmt.Println("Partitioning finished")
 }
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/giagiannis/data-profiler/core"
+)
+
+type predictParams struct {
+	modeler  core.Modeler                    // the trained modeler
+	datasets []*core.Dataset                 // the datasets to predict
+	est      core.DatasetSimilarityEstimator // the estimator of the similarities
+	indexer  *core.OnlineIndexer             // the indexer of the coordinates
+	output   *string                         // output file path
+}
+
+func predictParseParams() *predictParams {
+	params := new(predictParams)
+	modelPath :=
+		flag.String("m", "", "path of the serialized modeler (see exp-accuracy -ms)")
+	inputPath :=
+		flag.String("i", "", "dataset file or directory of datasets to predict")
+	estimatorPath :=
+		flag.String("e", "", "path of the serialized estimator object")
+	coordinatesPath :=
+		flag.String("c", "", "coordinates file (for the coordinate based ml)")
+	saScript :=
+		flag.String("s", "", "script that executes Simulated Annealing (default: native solver)")
+	compDatasets :=
+		flag.Int("com", 0, "datasets to be used for the indexing")
+	params.output =
+		flag.String("o", "", "output file (default: stdout)")
+	logger :=
+		flag.String("l", "", "log file")
+	format :=
+		flag.String("fmt", "", "datasets format in the form val1=key1,val2=key2 (list for opts list, default: the format of the estimator's datasets)")
+	types :=
+		flag.String("types", "", "column types of the datasets to predict in the form type1,type2 [numeric|categorical|boolean|timestamp] (default: inferred)")
+	mapping :=
+		flag.String("columns", "", "file mapping column names to canonical ones, one name,canonical pair per line (default: the mapping of the estimator's datasets)")
+	flag.Parse()
+	setLogger(*logger)
+
+	if *format == "list" {
+		for k, v := range core.DatasetFormatOptions() {
+			fmt.Println("\t", k, ":", v)
+		}
+		os.Exit(0)
+	}
+	if *modelPath == "" || *inputPath == "" || *estimatorPath == "" {
+		fmt.Println("Options:")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	buf, err := ioutil.ReadFile(*modelPath)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	params.modeler = core.DeserializeModeler(buf)
+	if params.modeler == nil {
+		log.Fatalln("Could not parse the modeler")
+	}
+
+	buf, err = ioutil.ReadFile(*estimatorPath)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	params.est = core.DeserializeSimilarityEstimator(buf)
+	if params.est == nil || len(params.est.Datasets()) == 0 {
+		log.Fatalln("Could not parse the estimator")
+	}
+
+	// the datasets to predict are read as the datasets of the estimator,
+	// unless a format or a column mapping is given for all of them
+	datasetFormat := params.est.Datasets()[0].Format()
+	datasetMapping := params.est.Datasets()[0].ColumnMapping()
+	if *format != "" {
+		datasetFormat, err = core.NewDatasetFormat(parseOptions(*format))
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+	if *mapping != "" {
+		datasetMapping, err = core.NewDatasetColumnMapping(*mapping)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+	var datasetSchema core.DatasetSchema
+	if *types != "" {
+		datasetSchema, err = core.NewDatasetSchema(nil, *types)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+	for _, d := range params.est.Datasets() {
+		if *format != "" {
+			d.SetFormat(datasetFormat)
+		}
+		if *mapping != "" {
+			d.SetColumnMapping(datasetMapping)
+		}
+	}
+
+	if *coordinatesPath != "" {
+		buf, err = ioutil.ReadFile(*coordinatesPath)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		params.indexer = core.NewOnlineIndexer(params.est, core.DeserializeCoordinates(buf), *saScript)
+		params.indexer.DatasetsToCompare(*compDatasets)
+	}
+
+	if info, err := os.Stat(*inputPath); err != nil {
+		log.Fatalln(err)
+	} else if info.IsDir() {
+		params.datasets = core.DiscoverDatasets(*inputPath)
+	} else {
+		params.datasets = []*core.Dataset{core.NewDataset(*inputPath)}
+	}
+	for _, d := range params.datasets {
+		d.SetFormat(datasetFormat)
+		if datasetSchema != nil {
+			d.SetSchema(datasetSchema)
+		}
+		d.SetColumnMapping(datasetMapping)
+	}
+	return params
+}
+
+func predictRun() {
+	params := predictParseParams()
+	output := setOutput(*params.output)
+	defer output.Close()
+
+	for _, d := range params.datasets {
+		val, err := core.PredictDataset(params.modeler, d, params.est, params.indexer)
+		if err != nil {
+			log.Println(d.Path(), err)
+			continue
+		}
+		fmt.Fprintf(output, "%s\t%.5f\n", d.Path(), val)
+	}
+}
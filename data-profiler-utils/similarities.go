@@ -19,6 +19,10 @@ type similaritiesParams struct {
 	options          *string                                 // options for the estimators
 	populationPolicy *core.DatasetSimilarityPopulationPolicy // defines the population policy
 	estimatorPath    *string                                 // place to store estimator object
+	format           core.DatasetFormat                      // the CSV dialect of the datasets
+	schema           core.DatasetSchema                      // the column types of the datasets
+	mapping          core.DatasetColumnMapping               // the canonical names of the columns
+	cacheDir         *string                                 // directory of the dataset summaries cache
 }
 
 func similaritiesParseParams() *similaritiesParams {
@@ -37,9 +41,44 @@ func similaritiesParseParams() *similaritiesParams {
 		flag.String("p", "FULL", "population policy [FULL|APRX] along with options in the form POLICY,val1=key1,val2=key2")
 	params.estimatorPath =
 		flag.String("e", "", "if set, serializes the estimator to the specified path")
+	format :=
+		flag.String("fmt", "", "datasets format in the form val1=key1,val2=key2 (list for opts list)")
+	types :=
+		flag.String("types", "", "column types in the form type1,type2 [numeric|categorical|boolean|timestamp] (default: inferred)")
+	params.cacheDir =
+		flag.String("cache", "", "if set, dataset summaries are cached in the specified directory")
+	mapping :=
+		flag.String("columns", "", "file mapping column names to canonical ones, one name,canonical pair per line (used when the headers differ)")
 	flag.Parse()
 	setLogger(*params.logfile)
 
+	if *format == "list" {
+		for k, v := range core.DatasetFormatOptions() {
+			fmt.Println("\t", k, ":", v)
+		}
+		os.Exit(0)
+	}
+	var err error
+	params.format, err = core.NewDatasetFormat(parseOptions(*format))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *types != "" {
+		params.schema, err = core.NewDatasetSchema(nil, *types)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if *mapping != "" {
+		params.mapping, err = core.NewDatasetColumnMapping(*mapping)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
 	// population policy parsing
 	popPolicyType := strings.Split(*popPolicy, ",")[0]
 	params.populationPolicy = new(core.DatasetSimilarityPopulationPolicy)
@@ -90,10 +129,26 @@ func similaritiesParseParams() *similaritiesParams {
 func similaritiesRun() {
 	params := similaritiesParseParams()
 	datasets := core.DiscoverDatasets(*params.input)
+	for _, d := range datasets {
+		d.SetFormat(params.format)
+		if params.schema != nil {
+			d.SetSchema(params.schema)
+		}
+		d.SetColumnMapping(params.mapping)
+	}
 	est := core.NewDatasetSimilarityEstimator(*params.simType, datasets)
+	if *params.cacheDir != "" {
+		cache, err := core.NewDatasetSummaryCache(*params.cacheDir)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		est.SetSummaryCache(cache)
+	}
 	est.Configure(parseOptions(*params.options))
 	est.SetPopulationPolicy(*params.populationPolicy)
-	est.Compute()
+	if err := est.Compute(); err != nil {
+		log.Fatalln(err)
+	}
 	log.Printf("Similarity Matrix computation took %.5f sec\n", est.Duration())
 
 	outfile, er := os.OpenFile(*params.output, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
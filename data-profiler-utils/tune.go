@@ -0,0 +1,144 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/giagiannis/data-profiler/core"
+)
+
+const valuesDelimiter = "|"
+
+type tuneParams struct {
+	datasets    []*core.Dataset       // the datasets to use
+	evaluator   core.DatasetEvaluator // evaluator of the datasets
+	modelerType core.ModelerType      // type of modeler
+	tunerConf   map[string]string     // the options of the tuner
+	base        map[string]string     // the fixed configuration of the modeler
+	space       map[string][]string   // the candidate values of the parameters
+	output      *string               // output file path
+	top         *int                  // the number of configurations to print
+}
+
+func tuneParseParams() *tuneParams {
+	params := new(tuneParams)
+	inputPath :=
+		flag.String("i", "", "input path")
+	scoresFile :=
+		flag.String("s", "", "scores file")
+	modelerTypeStr :=
+		flag.String("mt", "knn", "modeler type [knn | script | linear | ridge | rf | gp | krr | svr]")
+	smPath :=
+		flag.String("sm", "", "similarity matrix (for knn, krr, svr ml and the mds.k parameter)")
+	coords :=
+		flag.String("c", "", "coordinates file (for the coordinate based ml)")
+	baseStr :=
+		flag.String("b", "", "fixed options of the modeler (e.g. regression=true)")
+	spaceStr :=
+		flag.String("p", "", "parameter space, values separated by "+valuesDelimiter+
+			" (e.g. k=1|3|5,mds.k=2|3,estimator.partitions=32|64)")
+	tunerStr :=
+		flag.String("opt", "", "options of the tuner (list for options list)")
+	params.output =
+		flag.String("o", "", "output file (default: stdout)")
+	params.top =
+		flag.Int("top", 5, "number of best configurations to print")
+	loger :=
+		flag.String("l", "", "log file")
+	flag.Parse()
+	setLogger(*loger)
+
+	if *tunerStr == "list" {
+		for k, v := range new(core.ModelerTuner).Options() {
+			fmt.Printf("\t%s: %s\n", k, v)
+		}
+		os.Exit(1)
+	}
+	if *inputPath == "" || *scoresFile == "" || *spaceStr == "" {
+		fmt.Println("Options:")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	params.datasets = core.DiscoverDatasets(*inputPath)
+	var err error
+	params.evaluator, err = core.NewDatasetEvaluator(core.FileBasedEval, map[string]string{"scores": *scoresFile})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	params.modelerType = core.NewModelerType(*modelerTypeStr)
+	params.tunerConf = parseOptions(*tunerStr)
+	params.base = parseOptions(*baseStr)
+	if *smPath != "" {
+		params.base["smatrix"] = *smPath
+	}
+	if *coords != "" {
+		params.base["coordinates"] = *coords
+	}
+	params.space = make(map[string][]string)
+	for k, v := range parseOptions(*spaceStr) {
+		params.space[k] = strings.Split(v, valuesDelimiter)
+	}
+	return params
+}
+
+func tuneRun() {
+	params := tuneParseParams()
+	output := setOutput(*params.output)
+	defer output.Close()
+
+	tuner := core.NewModelerTuner(params.modelerType, params.datasets, params.evaluator)
+	if err := tuner.Configure(params.tunerConf); err != nil {
+		log.Fatalln(err)
+	}
+	results, err := tuner.Tune(params.base, params.space)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	var keys, metrics []string
+	for k := range params.space {
+		keys = append(keys, k)
+	}
+	for k := range results[0].Metrics {
+		metrics = append(metrics, k)
+	}
+	sort.Strings(keys)
+	sort.Strings(metrics)
+
+	// the best configurations, with their mean metrics
+	fmt.Fprintf(output, "%s\t%s\n", strings.Join(keys, "\t"), strings.Join(metrics, "\t"))
+	for i, r := range results {
+		if i >= *params.top {
+			break
+		}
+		for _, k := range keys {
+			fmt.Fprintf(output, "%s\t", r.Configuration[k])
+		}
+		for j, m := range metrics {
+			fmt.Fprintf(output, "%.5f", r.Metrics[m])
+			if j < len(metrics)-1 {
+				fmt.Fprintf(output, "\t")
+			}
+		}
+		fmt.Fprintf(output, "\n")
+	}
+
+	// the metrics of each fold for the best configuration
+	fmt.Fprintf(output, "\nBest configuration:")
+	for _, k := range keys {
+		fmt.Fprintf(output, " %s=%s", k, results[0].Configuration[k])
+	}
+	fmt.Fprintf(output, "\nfold\t%s\n", strings.Join(metrics, "\t"))
+	for f, fold := range results[0].Folds {
+		fmt.Fprintf(output, "%d", f+1)
+		for _, m := range metrics {
+			fmt.Fprintf(output, "\t%.5f", fold[m])
+		}
+		fmt.Fprintf(output, "\n")
+	}
+}